@@ -7,41 +7,83 @@
 #include <Python.h>
 #include <string.h>
 #include <stdlib.h>
+#ifdef _WIN32
+#include <windows.h>
+#else
+#include <pthread.h>
+#endif
 
 // Python模块路径
 #define PYTHON_MODULE "src.amdb.database"
 #define PYTHON_CLASS "Database"
 
+// 引擎用于标记删除的值
+#define DELETED_MARKER "__DELETED__"
+#define DELETED_MARKER_LEN (sizeof(DELETED_MARKER) - 1)
+
 // 全局Python模块
 static PyObject* g_amdb_module = NULL;
 static PyObject* g_database_class = NULL;
 
-// 初始化Python环境
-static int init_python() {
-    if (Py_IsInitialized()) {
-        return 0;
-    }
-    
-    Py_Initialize();
-    if (!Py_IsInitialized()) {
-        return -1;
+// 初始化只执行一次：并发的首次调用须等待同一次初始化完成
+#ifdef _WIN32
+static INIT_ONCE g_init_once = INIT_ONCE_STATIC_INIT;
+#else
+static pthread_once_t g_init_once = PTHREAD_ONCE_INIT;
+#endif
+static int g_init_result = -1;
+
+// 初始化Python环境并导入引擎模块
+// 由本库初始化解释器时，完成后释放GIL，之后每个入口函数经PyGILState_Ensure在调用线程上获取GIL；
+// 宿主程序已初始化解释器时只在GIL下导入模块，GIL的归属不变
+static void init_python_once(void) {
+    int owned = !Py_IsInitialized();
+    PyGILState_STATE gil = PyGILState_UNLOCKED;
+    if (owned) {
+        Py_Initialize();
+        if (!Py_IsInitialized()) {
+            return;
+        }
+    } else {
+        gil = PyGILState_Ensure();
     }
     
     // 导入模块
     g_amdb_module = PyImport_ImportModule(PYTHON_MODULE);
     if (!g_amdb_module) {
         PyErr_Print();
-        return -1;
+    } else {
+        // 获取Database类
+        g_database_class = PyObject_GetAttrString(g_amdb_module, PYTHON_CLASS);
+        if (!g_database_class) {
+            PyErr_Print();
+        } else {
+            g_init_result = 0;
+        }
     }
     
-    // 获取Database类
-    g_database_class = PyObject_GetAttrString(g_amdb_module, PYTHON_CLASS);
-    if (!g_database_class) {
-        PyErr_Print();
-        return -1;
+    if (owned) {
+        PyEval_SaveThread();
+    } else {
+        PyGILState_Release(gil);
     }
-    
-    return 0;
+}
+
+#ifdef _WIN32
+static BOOL CALLBACK init_python_once_win(PINIT_ONCE once, PVOID param, PVOID* context) {
+    init_python_once();
+    return TRUE;
+}
+#endif
+
+// 初始化Python环境（可重复调用，返回首次初始化的结果）
+static int init_python() {
+#ifdef _WIN32
+    InitOnceExecuteOnce(&g_init_once, init_python_once_win, NULL, NULL);
+#else
+    pthread_once(&g_init_once, init_python_once);
+#endif
+    return g_init_result;
 }
 
 // 清理Python环境
@@ -80,11 +122,7 @@ static amdb_status_t handle_python_error() {
     return AMDB_OK;
 }
 
-amdb_status_t amdb_init(const char* data_dir, amdb_handle_t* handle) {
-    if (init_python() != 0) {
-        return AMDB_ERROR;
-    }
-    
+static amdb_status_t init_impl(const char* data_dir, amdb_handle_t* handle) {
     PyObject* db = create_database_instance(data_dir);
     if (!db) {
         return handle_python_error();
@@ -94,7 +132,7 @@ amdb_status_t amdb_init(const char* data_dir, amdb_handle_t* handle) {
     return AMDB_OK;
 }
 
-amdb_status_t amdb_close(amdb_handle_t handle) {
+static amdb_status_t close_impl(amdb_handle_t handle) {
     if (!handle) {
         return AMDB_INVALID_ARG;
     }
@@ -111,7 +149,23 @@ amdb_status_t amdb_close(amdb_handle_t handle) {
     return AMDB_OK;
 }
 
-amdb_status_t amdb_put(amdb_handle_t handle,
+static amdb_status_t flush_impl(amdb_handle_t handle) {
+    if (!handle) {
+        return AMDB_INVALID_ARG;
+    }
+    
+    PyObject* db = (PyObject*)handle;
+    
+    // flush(async_mode=False, force_sync=True)：同步刷新全部组件，跳过防抖合并
+    PyObject* result = PyObject_CallMethod(db, "flush", "OO", Py_False, Py_True);
+    if (!result) {
+        return handle_python_error();
+    }
+    Py_DECREF(result);
+    return AMDB_OK;
+}
+
+static amdb_status_t put_impl(amdb_handle_t handle,
                        const uint8_t* key, size_t key_len,
                        const uint8_t* value, size_t value_len,
                        uint8_t* root_hash) {
@@ -155,7 +209,7 @@ amdb_status_t amdb_put(amdb_handle_t handle,
     return AMDB_OK;
 }
 
-amdb_status_t amdb_get(amdb_handle_t handle,
+static amdb_status_t get_impl(amdb_handle_t handle,
                        const uint8_t* key, size_t key_len,
                        uint32_t version,
                        amdb_result_t* result) {
@@ -196,7 +250,8 @@ amdb_status_t amdb_get(amdb_handle_t handle,
         const char* data = PyBytes_AsString(value_obj);
         size_t data_len = PyBytes_Size(value_obj);
         
-        result->data = malloc(data_len);
+        // 空值也分配1字节，避免malloc(0)返回NULL被误判为内存不足
+        result->data = malloc(data_len > 0 ? data_len : 1);
         if (!result->data) {
             Py_DECREF(value_obj);
             return AMDB_MEMORY_ERROR;
@@ -215,15 +270,30 @@ amdb_status_t amdb_get(amdb_handle_t handle,
     return result->status;
 }
 
-amdb_status_t amdb_delete(amdb_handle_t handle,
+static amdb_status_t delete_impl(amdb_handle_t handle,
                           const uint8_t* key, size_t key_len) {
-    // 简化实现：通过put空值实现删除
-    uint8_t empty_value = 0;
-    uint8_t root_hash[32];
-    return amdb_put(handle, key, key_len, &empty_value, 0, root_hash);
+    if (!handle || !key) {
+        return AMDB_INVALID_ARG;
+    }
+    
+    PyObject* db = (PyObject*)handle;
+    
+    // 调用delete方法写入删除标记版本，之后读取该键返回AMDB_NOT_FOUND
+    // （写入空值会使删除后的键读出空值，与空值键无法区分）
+    PyObject* key_obj = PyBytes_FromStringAndSize((const char*)key, key_len);
+    PyObject* result = PyObject_CallMethod(db, "delete", "O", key_obj);
+    Py_DECREF(key_obj);
+    
+    if (!result) {
+        return handle_python_error();
+    }
+    
+    int ok = PyObject_IsTrue(result);
+    Py_DECREF(result);
+    return ok ? AMDB_OK : AMDB_ERROR;
 }
 
-amdb_status_t amdb_batch_put(amdb_handle_t handle,
+static amdb_status_t batch_put_impl(amdb_handle_t handle,
                              const uint8_t** keys, const size_t* key_lens,
                              const uint8_t** values, const size_t* value_lens,
                              size_t count,
@@ -252,19 +322,23 @@ amdb_status_t amdb_batch_put(amdb_handle_t handle,
         return handle_python_error();
     }
     
+    // 引擎失败时返回(False, b'')，必须检查成功标志，且根哈希不足32字节时不能复制
+    amdb_status_t status = AMDB_OK;
     if (PyTuple_Check(result) && PyTuple_Size(result) == 2) {
+        PyObject* success = PyTuple_GetItem(result, 0);
         PyObject* root_hash_obj = PyTuple_GetItem(result, 1);
-        if (PyBytes_Check(root_hash_obj)) {
-            const char* hash_data = PyBytes_AsString(root_hash_obj);
-            memcpy(root_hash, hash_data, 32);
+        if (!PyObject_IsTrue(success)) {
+            status = AMDB_ERROR;
+        } else if (PyBytes_Check(root_hash_obj) && PyBytes_Size(root_hash_obj) >= 32) {
+            memcpy(root_hash, PyBytes_AsString(root_hash_obj), 32);
         }
     }
     
     Py_DECREF(result);
-    return AMDB_OK;
+    return status;
 }
 
-amdb_status_t amdb_get_root_hash(amdb_handle_t handle, uint8_t* root_hash) {
+static amdb_status_t get_root_hash_impl(amdb_handle_t handle, uint8_t* root_hash) {
     if (!handle || !root_hash) {
         return AMDB_INVALID_ARG;
     }
@@ -287,6 +361,228 @@ amdb_status_t amdb_get_root_hash(amdb_handle_t handle, uint8_t* root_hash) {
     return AMDB_ERROR;
 }
 
+static amdb_status_t list_versions_impl(amdb_handle_t handle,
+                                 amdb_version_info_t** infos, size_t* info_count) {
+    if (!handle || !infos || !info_count) {
+        return AMDB_INVALID_ARG;
+    }
+    
+    PyObject* db = (PyObject*)handle;
+    PyObject* vm = PyObject_GetAttrString(db, "version_manager");
+    if (!vm) {
+        return handle_python_error();
+    }
+    PyObject* versions = PyObject_GetAttrString(vm, "versions");
+    Py_DECREF(vm);
+    if (!versions || !PyDict_Check(versions)) {
+        Py_XDECREF(versions);
+        return handle_python_error();
+    }
+    
+    // 统计版本总数
+    size_t total = 0;
+    Py_ssize_t pos = 0;
+    PyObject* key_obj;
+    PyObject* list_obj;
+    while (PyDict_Next(versions, &pos, &key_obj, &list_obj)) {
+        if (PyList_Check(list_obj)) {
+            total += PyList_Size(list_obj);
+        }
+    }
+    
+    amdb_version_info_t* out = calloc(total > 0 ? total : 1, sizeof(amdb_version_info_t));
+    if (!out) {
+        Py_DECREF(versions);
+        return AMDB_MEMORY_ERROR;
+    }
+    
+    // 复制每个键的版本记录
+    size_t n = 0;
+    pos = 0;
+    while (PyDict_Next(versions, &pos, &key_obj, &list_obj) && n < total) {
+        if (!PyBytes_Check(key_obj) || !PyList_Check(list_obj)) {
+            continue;
+        }
+        const char* key_data = PyBytes_AsString(key_obj);
+        size_t key_len = PyBytes_Size(key_obj);
+        
+        Py_ssize_t list_len = PyList_Size(list_obj);
+        for (Py_ssize_t i = 0; i < list_len && n < total; i++) {
+            PyObject* version_obj = PyList_GetItem(list_obj, i);
+            PyObject* ver = PyObject_GetAttrString(version_obj, "version");
+            PyObject* ts = PyObject_GetAttrString(version_obj, "timestamp");
+            if (!ver || !ts) {
+                Py_XDECREF(ver);
+                Py_XDECREF(ts);
+                PyErr_Clear();
+                continue;
+            }
+            
+            out[n].key = malloc(key_len > 0 ? key_len : 1);
+            if (!out[n].key) {
+                Py_DECREF(ver);
+                Py_DECREF(ts);
+                amdb_free_version_infos(out, n);
+                Py_DECREF(versions);
+                return AMDB_MEMORY_ERROR;
+            }
+            memcpy(out[n].key, key_data, key_len);
+            out[n].key_len = key_len;
+            out[n].version = (uint32_t)PyLong_AsUnsignedLong(ver);
+            out[n].timestamp = PyFloat_AsDouble(ts);
+            Py_DECREF(ver);
+            Py_DECREF(ts);
+            n++;
+        }
+    }
+    
+    Py_DECREF(versions);
+    *infos = out;
+    *info_count = n;
+    return AMDB_OK;
+}
+
+void amdb_free_version_infos(amdb_version_info_t* infos, size_t count) {
+    if (infos) {
+        for (size_t i = 0; i < count; i++) {
+            free(infos[i].key);
+        }
+        free(infos);
+    }
+}
+
+// collect_state 收集时间点的键值；keys_only时跳过删除标记且不复制值
+static amdb_status_t collect_state(amdb_handle_t handle, double timestamp, int keys_only,
+                                   amdb_kv_t** kvs, size_t* kv_count) {
+    if (!handle || !kvs || !kv_count) {
+        return AMDB_INVALID_ARG;
+    }
+    
+    PyObject* db = (PyObject*)handle;
+    PyObject* vm = PyObject_GetAttrString(db, "version_manager");
+    if (!vm) {
+        return handle_python_error();
+    }
+    PyObject* versions = PyObject_GetAttrString(vm, "versions");
+    Py_DECREF(vm);
+    if (!versions || !PyDict_Check(versions)) {
+        Py_XDECREF(versions);
+        return handle_python_error();
+    }
+    
+    Py_ssize_t total = PyDict_Size(versions);
+    amdb_kv_t* out = calloc(total > 0 ? total : 1, sizeof(amdb_kv_t));
+    if (!out) {
+        Py_DECREF(versions);
+        return AMDB_MEMORY_ERROR;
+    }
+    
+    size_t n = 0;
+    Py_ssize_t pos = 0;
+    PyObject* key_obj;
+    PyObject* list_obj;
+    while (PyDict_Next(versions, &pos, &key_obj, &list_obj) && n < (size_t)total) {
+        if (!PyBytes_Check(key_obj) || !PyList_Check(list_obj)) {
+            continue;
+        }
+        
+        // 找到该时间点及之前的最后一个版本（版本按时间顺序追加）
+        PyObject* value_obj = NULL;
+        Py_ssize_t list_len = PyList_Size(list_obj);
+        for (Py_ssize_t i = list_len - 1; i >= 0; i--) {
+            PyObject* version_obj = PyList_GetItem(list_obj, i);
+            PyObject* ts = PyObject_GetAttrString(version_obj, "timestamp");
+            if (!ts) {
+                PyErr_Clear();
+                continue;
+            }
+            double version_ts = PyFloat_AsDouble(ts);
+            Py_DECREF(ts);
+            if (timestamp <= 0 || version_ts <= timestamp) {
+                value_obj = PyObject_GetAttrString(version_obj, "value");
+                break;
+            }
+        }
+        if (!value_obj) {
+            PyErr_Clear();
+            continue;
+        }
+        if (!PyBytes_Check(value_obj)) {
+            Py_DECREF(value_obj);
+            continue;
+        }
+        
+        size_t key_len = PyBytes_Size(key_obj);
+        size_t value_len = PyBytes_Size(value_obj);
+        if (keys_only) {
+            if (value_len == DELETED_MARKER_LEN &&
+                memcmp(PyBytes_AsString(value_obj), DELETED_MARKER, DELETED_MARKER_LEN) == 0) {
+                Py_DECREF(value_obj);
+                continue;
+            }
+            out[n].key = malloc(key_len > 0 ? key_len : 1);
+            if (!out[n].key) {
+                Py_DECREF(value_obj);
+                amdb_free_kvs(out, n);
+                Py_DECREF(versions);
+                return AMDB_MEMORY_ERROR;
+            }
+            memcpy(out[n].key, PyBytes_AsString(key_obj), key_len);
+            out[n].key_len = key_len;
+            Py_DECREF(value_obj);
+            n++;
+            continue;
+        }
+        out[n].key = malloc(key_len > 0 ? key_len : 1);
+        out[n].value = malloc(value_len > 0 ? value_len : 1);
+        if (!out[n].key || !out[n].value) {
+            free(out[n].key);
+            free(out[n].value);
+            Py_DECREF(value_obj);
+            amdb_free_kvs(out, n);
+            Py_DECREF(versions);
+            return AMDB_MEMORY_ERROR;
+        }
+        memcpy(out[n].key, PyBytes_AsString(key_obj), key_len);
+        memcpy(out[n].value, PyBytes_AsString(value_obj), value_len);
+        out[n].key_len = key_len;
+        out[n].value_len = value_len;
+        Py_DECREF(value_obj);
+        n++;
+    }
+    
+    Py_DECREF(versions);
+    *kvs = out;
+    *kv_count = n;
+    return AMDB_OK;
+}
+
+amdb_status_t amdb_get_state(amdb_handle_t handle, double timestamp,
+                             amdb_kv_t** kvs, size_t* kv_count) {
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = collect_state(handle, timestamp, 0, kvs, kv_count);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_get_state_keys(amdb_handle_t handle, double timestamp,
+                                  amdb_kv_t** kvs, size_t* kv_count) {
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = collect_state(handle, timestamp, 1, kvs, kv_count);
+    PyGILState_Release(gil);
+    return status;
+}
+
+void amdb_free_kvs(amdb_kv_t* kvs, size_t count) {
+    if (kvs) {
+        for (size_t i = 0; i < count; i++) {
+            free(kvs[i].key);
+            free(kvs[i].value);
+        }
+        free(kvs);
+    }
+}
+
 void amdb_free_result(amdb_result_t* result) {
     if (result && result->data) {
         free(result->data);
@@ -311,6 +607,166 @@ const char* amdb_error_string(amdb_status_t status) {
     }
 }
 
+// 存储格式的长度上限：B+树以2字节记录键长度，SSTable以4字节记录值长度
+#define MAX_KEY_SIZE 0xFFFFu
+#define MAX_VALUE_SIZE 0xFFFFFFFFu
+
+static amdb_status_t library_info_impl(amdb_library_info_t* info) {
+    memset(info, 0, sizeof(*info));
+    // 引擎的Merkle树只使用SHA-256
+    info->hash_algorithms = AMDB_HASH_SHA256;
+    info->max_key_size = MAX_KEY_SIZE;
+    info->max_value_size = MAX_VALUE_SIZE;
+    
+    PyObject* package = PyImport_ImportModule("src.amdb");
+    if (!package) {
+        return handle_python_error();
+    }
+    PyObject* version = PyObject_GetAttrString(package, "__version__");
+    Py_DECREF(package);
+    if (!version) {
+        return handle_python_error();
+    }
+    const char* version_str = PyUnicode_AsUTF8(version);
+    if (!version_str) {
+        Py_DECREF(version);
+        return handle_python_error();
+    }
+    strncpy(info->version, version_str, sizeof(info->version) - 1);
+    Py_DECREF(version);
+    
+    // 压缩模块依赖zlib与lzma，精简的Python环境中可能缺失
+    PyObject* compression = PyImport_ImportModule("src.amdb.compression");
+    if (compression) {
+        info->compression = true;
+        Py_DECREF(compression);
+    } else {
+        PyErr_Clear();
+    }
+    return AMDB_OK;
+}
+
+// 以下入口函数在调用线程上获取GIL后调用对应的实现，可以从任意线程并发调用；
+// 同一个句柄上的并发调用由引擎自身的锁保证一致性
+
+amdb_status_t amdb_init(const char* data_dir, amdb_handle_t* handle) {
+    if (!data_dir || !handle) {
+        return AMDB_INVALID_ARG;
+    }
+    if (init_python() != 0) {
+        return AMDB_ERROR;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = init_impl(data_dir, handle);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_close(amdb_handle_t handle) {
+    if (!handle) {
+        return AMDB_INVALID_ARG;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = close_impl(handle);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_flush(amdb_handle_t handle) {
+    if (!handle) {
+        return AMDB_INVALID_ARG;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = flush_impl(handle);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_put(amdb_handle_t handle,
+                       const uint8_t* key, size_t key_len,
+                       const uint8_t* value, size_t value_len,
+                       uint8_t* root_hash) {
+    if (!handle || !key || !value) {
+        return AMDB_INVALID_ARG;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = put_impl(handle, key, key_len, value, value_len, root_hash);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_get(amdb_handle_t handle,
+                       const uint8_t* key, size_t key_len,
+                       uint32_t version,
+                       amdb_result_t* result) {
+    if (!handle || !key || !result) {
+        return AMDB_INVALID_ARG;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = get_impl(handle, key, key_len, version, result);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_delete(amdb_handle_t handle,
+                          const uint8_t* key, size_t key_len) {
+    if (!handle || !key) {
+        return AMDB_INVALID_ARG;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = delete_impl(handle, key, key_len);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_batch_put(amdb_handle_t handle,
+                             const uint8_t** keys, const size_t* key_lens,
+                             const uint8_t** values, const size_t* value_lens,
+                             size_t count,
+                             uint8_t* root_hash) {
+    if (!handle || !keys || !values || count == 0) {
+        return AMDB_INVALID_ARG;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = batch_put_impl(handle, keys, key_lens, values, value_lens, count, root_hash);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_get_root_hash(amdb_handle_t handle, uint8_t* root_hash) {
+    if (!handle || !root_hash) {
+        return AMDB_INVALID_ARG;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = get_root_hash_impl(handle, root_hash);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_list_versions(amdb_handle_t handle,
+                                 amdb_version_info_t** infos, size_t* info_count) {
+    if (!handle || !infos || !info_count) {
+        return AMDB_INVALID_ARG;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = list_versions_impl(handle, infos, info_count);
+    PyGILState_Release(gil);
+    return status;
+}
+
+amdb_status_t amdb_library_info(amdb_library_info_t* info) {
+    if (!info) {
+        return AMDB_INVALID_ARG;
+    }
+    if (init_python() != 0) {
+        return AMDB_ERROR;
+    }
+    PyGILState_STATE gil = PyGILState_Ensure();
+    amdb_status_t status = library_info_impl(info);
+    PyGILState_Release(gil);
+    return status;
+}
+
 // 其他函数的简化实现
 amdb_status_t amdb_range_query(amdb_handle_t handle,
                                const uint8_t* start_key, size_t start_key_len,
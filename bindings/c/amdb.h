@@ -1,6 +1,8 @@
 /**
  * AmDb C API
  * 提供C语言接口，作为其他语言绑定的基础
+ * 访问引擎的函数在调用线程上获取Python GIL，可以从任意线程并发调用；
+ * 首次调用amdb_init或amdb_library_info时初始化解释器，由本库初始化的解释器在初始化后释放GIL
  */
 
 #ifndef AMDB_H
@@ -38,6 +40,34 @@ typedef struct {
     size_t data_len;
 } amdb_result_t;
 
+// 版本记录结构
+typedef struct {
+    uint8_t* key;
+    size_t key_len;
+    uint32_t version;   // 键内版本号
+    double timestamp;   // 提交时间（Unix时间戳，秒）
+} amdb_version_info_t;
+
+// 键值对结构
+typedef struct {
+    uint8_t* key;
+    size_t key_len;
+    void* value;
+    size_t value_len;
+} amdb_kv_t;
+
+// 哈希算法位（amdb_library_info_t.hash_algorithms）
+#define AMDB_HASH_SHA256 (1u << 0)
+
+// 库信息结构
+typedef struct {
+    char version[32];          // 引擎版本号（以NUL结尾）
+    uint32_t hash_algorithms;  // 支持的Merkle哈希算法，AMDB_HASH_*按位或
+    size_t max_key_size;       // 存储格式允许的最大键长度（字节）
+    size_t max_value_size;     // 存储格式允许的最大值长度（字节）
+    bool compression;          // 引擎的压缩模块可用
+} amdb_library_info_t;
+
 /**
  * 初始化数据库
  * @param data_dir 数据目录路径
@@ -53,6 +83,13 @@ amdb_status_t amdb_init(const char* data_dir, amdb_handle_t* handle);
  */
 amdb_status_t amdb_close(amdb_handle_t handle);
 
+/**
+ * 将内存中的数据同步刷新到磁盘（LSM树刷新时按需合并SSTable）
+ * @param handle 数据库句柄
+ * @return 状态码
+ */
+amdb_status_t amdb_flush(amdb_handle_t handle);
+
 /**
  * 写入键值对
  * @param handle 数据库句柄
@@ -83,7 +120,7 @@ amdb_status_t amdb_get(amdb_handle_t handle,
                        amdb_result_t* result);
 
 /**
- * 删除键值对
+ * 删除键值对（写入删除标记版本，之后读取该键返回AMDB_NOT_FOUND，历史版本仍可读取）
  * @param handle 数据库句柄
  * @param key 键
  * @param key_len 键长度
@@ -149,6 +186,38 @@ amdb_status_t amdb_get_history(amdb_handle_t handle,
  */
 amdb_status_t amdb_get_root_hash(amdb_handle_t handle, uint8_t* root_hash);
 
+/**
+ * 列出所有键的版本记录
+ * @param handle 数据库句柄
+ * @param infos 输出版本记录数组（需调用amdb_free_version_infos释放）
+ * @param info_count 输出版本记录数量
+ * @return 状态码
+ */
+amdb_status_t amdb_list_versions(amdb_handle_t handle,
+                                 amdb_version_info_t** infos, size_t* info_count);
+
+/**
+ * 获取指定时间点的全部键值（每个键取该时间点及之前的最后一个版本，包含删除标记）
+ * @param handle 数据库句柄
+ * @param timestamp 时间点（Unix时间戳，秒；0表示最新状态）
+ * @param kvs 输出键值对数组（需调用amdb_free_kvs释放）
+ * @param kv_count 输出键值对数量
+ * @return 状态码
+ */
+amdb_status_t amdb_get_state(amdb_handle_t handle, double timestamp,
+                             amdb_kv_t** kvs, size_t* kv_count);
+
+/**
+ * 获取指定时间点的全部存活键（不含已删除的键），不复制值
+ * @param handle 数据库句柄
+ * @param timestamp 时间点（Unix时间戳，秒；0表示最新状态）
+ * @param kvs 输出键值对数组，value为NULL（需调用amdb_free_kvs释放）
+ * @param kv_count 输出键数量
+ * @return 状态码
+ */
+amdb_status_t amdb_get_state_keys(amdb_handle_t handle, double timestamp,
+                                  amdb_kv_t** kvs, size_t* kv_count);
+
 /**
  * 验证数据
  * @param handle 数据库句柄
@@ -204,6 +273,27 @@ void amdb_free_result(amdb_result_t* result);
  */
 void amdb_free_results(amdb_result_t* results, size_t count);
 
+/**
+ * 释放版本记录数组
+ * @param infos 版本记录数组
+ * @param count 数量
+ */
+void amdb_free_version_infos(amdb_version_info_t* infos, size_t count);
+
+/**
+ * 释放键值对数组
+ * @param kvs 键值对数组
+ * @param count 数量
+ */
+void amdb_free_kvs(amdb_kv_t* kvs, size_t count);
+
+/**
+ * 获取库的版本与能力信息（不需要数据库句柄，首次调用时初始化Python环境）
+ * @param info 输出库信息
+ * @return 状态码
+ */
+amdb_status_t amdb_library_info(amdb_library_info_t* info);
+
 /**
  * 获取错误信息
  * @param status 状态码
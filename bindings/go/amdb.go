@@ -7,141 +7,991 @@ package amdb
 
 /*
 #cgo CFLAGS: -I${SRCDIR}/../c
-#cgo LDFLAGS: -L${SRCDIR}/../c -lamdb
+#cgo LDFLAGS: -L${SRCDIR}/../c -Wl,-rpath,${SRCDIR}/../c -lamdb
 #include "amdb.h"
 #include <stdlib.h>
 */
 import "C"
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
 // Database 数据库句柄
+//
+// 绑定层会在调用C API前校验参数（空键、空批量等返回ErrInvalidArg），
+// C层的Python异常统一返回ErrInternal而不会终止进程。
+// Close或Shutdown开始后新的调用返回ErrClosed，正在C层执行的调用会先完成再释放句柄。
 type Database struct {
-	handle C.amdb_handle_t
+	handle  C.amdb_handle_t
+	dataDir string
+	lock    *os.File
+	tracer  TracerProvider
+
+	// wmu 串行化经由本句柄的写入
+	wmu sync.Mutex
+
+	tlMu sync.Mutex
+	tl   *timeline
+
+	// rootsMu 保护roots：已计算的各版本根哈希，版本提交后不可变
+	rootsMu sync.Mutex
+	roots   map[uint32][]byte
+
+	bloom    *bloomFilter
+	cgo      *cgoCounter
+	writeAmp *writeAmpCounter
+	maxDepth int
+	hashKeys bool
+	keySalt  []byte // 非空时存储形式的键为HMAC-SHA256(keySalt, key)
+	proofs   *proofCache
+	values   *valueCache
+	// chunks 大值的块存储（nil表示未启用且数据目录中没有块），见Options.ChunkLargeValues
+	chunks *chunkStore
+	// prefixes 键的前缀字典（nil表示未启用前缀压缩），见Options.PrefixCompression
+	prefixes *prefixDict
+	// mirror 同步镜像的数据库（nil表示未配置），见Options.MirrorDir
+	mirror *Database
+	// keyRange OpenRangeView限定的键范围（nil表示不限制）
+	keyRange *keyRange
+	// journal 批量写入日志（nil表示不记录），见journal.go
+	journal *batchJournal
+	// compare 迭代与范围查询使用的键顺序（nil表示字节序），见Options.Comparator；经keyOrder读取
+	compare func(a, b []byte) int
+	// leafHashes 按键和写入版本缓存的叶子哈希（nil表示未启用）
+	leafHashes *leafHashCache
+	limiter    *writeLimiter
+	safeMode   bool
+	plain      bool // PlainMode：写入不更新引擎的Merkle树，根哈希与证明接口返回ErrNotAuthenticated
+	lazyRoot   bool // LazyRoot：写入不更新引擎的Merkle树，根哈希在查询时由状态计算
+	sealed     bool // 数据库已封存，由wmu保护
+	// stateRoot 引擎的Merkle树缺少经批量写入路径提交的写入，根哈希由状态计算，见stateroot.go
+	stateRoot atomic.Bool
+
+	readRetry *RetryPolicy
+	coalesce  *coalescer
+	valuePool *sync.Pool
+	openInfo  OpenInfo
+	tempDir   string // 非空表示临时数据库，Close时删除
+	watches   watchHub
+	pins      versionPins
+	borrowed  bool // 句柄由FromHandle包装且不归本实例所有，Close不关闭句柄
+
+	opts          Options // 生效的选项，见EffectiveOptions
+	onMaintenance func(MaintenanceEvent)
+	tempParent    string // 维护操作临时目录的父目录（空表示数据目录）
+	async         asyncWriter
+	group         commitGroup
+	auditLog      *auditLog
+
+	// resolveConflict 导入与合并的冲突解析函数（nil表示不解析），见Options.ResolveConflict
+	resolveConflict func(key, existing, incoming []byte) []byte
+
+	gate      opGate
+	closeOnce sync.Once
 }
 
 // NewDatabase 创建新数据库实例
 func NewDatabase(dataDir string) (*Database, error) {
+	return NewDatabaseWithOptions(dataDir, nil)
+}
+
+// NewDatabaseWithOptions 使用指定选项创建数据库实例
+func NewDatabaseWithOptions(dataDir string, opts *Options) (*Database, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.InMemory || dataDir == MemoryDataDir {
+		return openTemp(opts)
+	}
+
+	created := missingDirs(dataDir)
+	fresh := len(created) > 0 || isEmptyDir(dataDir)
+
+	var db *Database
+	err := opts.OpenRetry.do(func() error {
+		var err error
+		db, err = openDatabase(dataDir)
+		return err
+	}, isTransient)
+	if err != nil {
+		return nil, err
+	}
+	clean, err := consumeCleanMarker(dataDir)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	db.openInfo.CleanShutdown = fresh || clean
+	if db.sealed, err = isSealed(dataDir); err != nil {
+		db.Close()
+		return nil, err
+	}
+	marked, err := hasStateRootMarker(dataDir)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	db.stateRoot.Store(marked)
+	if fresh && opts.syncOnCreate() {
+		if err := syncCreated(dataDir, created); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	db.tracer = opts.TracerProvider
+	db.hashKeys = opts.HashKeys || len(opts.KeySalt) > 0
+	db.keySalt = bytes.Clone(opts.KeySalt)
+	db.readRetry = opts.ReadRetry
+	db.valuePool = opts.ValueBufferPool
+	db.onMaintenance = opts.OnMaintenance
+	db.resolveConflict = opts.ResolveConflict
+	if db.chunks, err = openChunkStore(dataDir, opts); err != nil {
+		db.Close()
+		return nil, err
+	}
+	db.prefixes, err = openPrefixDict(dataDir, opts, func() (bool, error) {
+		current, err := db.CurrentVersion()
+		return current == 0, err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	db.compare, err = openComparator(dataDir, opts, func() (bool, error) {
+		current, err := db.CurrentVersion()
+		return current == 0, err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if opts.AuditLogPath != "" {
+		if db.auditLog, db.openInfo.RecoveredAuditLog, err = openAuditLog(opts.AuditLogPath); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if opts.CoalesceWindow > 0 {
+		db.coalesce = &coalescer{window: opts.CoalesceWindow}
+	}
+	if opts.ProofCacheEntries > 0 {
+		db.proofs = newProofCache(opts.ProofCacheEntries)
+	}
+	db.limiter = newWriteLimiter(opts.WriteRateLimit)
+	db.safeMode = opts.SafeMode
+	db.plain = opts.PlainMode
+	db.lazyRoot = opts.LazyRoot
+	db.tempParent = opts.TempDir
+	if opts.ValueHashEntries > 0 {
+		db.leafHashes = newLeafHashCache(opts.ValueHashEntries)
+	}
+	if opts.ValueCacheEntries > 0 {
+		db.values = newValueCache(opts.ValueCacheEntries)
+	}
+	db.maxDepth = opts.MaxTreeDepth
+	if opts.TrackWriteAmplification {
+		db.writeAmp = &writeAmpCounter{}
+	}
+	if opts.CollectCGOStats {
+		db.cgo = &cgoCounter{}
+	}
+
+	if opts.BloomFilterBits > 0 {
+		keys, err := db.liveKeys()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		db.bloom = newBloomFilter(opts.BloomFilterBits, keys)
+	}
+	db.opts = effectiveOptions(*opts)
+	db.journal = newBatchJournal(dataDir)
+	if db.openInfo.RolledBackBatch, err = db.recoverBatches(); err != nil {
+		// 保留日志，下次打开时重新检查
+		db.journal = nil
+		db.Close()
+		return nil, err
+	}
+	if opts.MirrorDir != "" {
+		if err := db.openMirror(opts); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// openDatabase 加锁并初始化C句柄
+func openDatabase(dataDir string) (*Database, error) {
+	lock, err := lockDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	// 引擎打开时会以当前格式重写元数据文件，须在此之前检查
+	if err := checkFormat(dataDir); err != nil {
+		unlockDir(lock)
+		return nil, err
+	}
+
 	cDataDir := C.CString(dataDir)
 	defer C.free(unsafe.Pointer(cDataDir))
 
 	var handle C.amdb_handle_t
 	status := C.amdb_init(cDataDir, &handle)
 	if status != C.AMDB_OK {
-		return nil, errors.New(C.GoString(C.amdb_error_string(status)))
+		unlockDir(lock)
+		return nil, statusError(status)
 	}
 
-	return &Database{handle: handle}, nil
+	return &Database{handle: handle, dataDir: dataDir, lock: lock}, nil
 }
 
-// Close 关闭数据库
+// Close 关闭数据库，实现io.Closer
+// 关闭前等待正在执行的调用完成。重复调用是安全的：之后的调用等待首次关闭完成后返回nil，
+// 关闭失败的错误只由首次调用返回
 func (db *Database) Close() error {
+	idle := db.beginClose()
+	<-idle
+	return db.closeHandle()
+}
+
+var _ io.Closer = (*Database)(nil)
+
+// Closed 报告Close或Shutdown是否已经开始，为true时新的调用返回ErrClosed
+// Shutdown超时返回后句柄仍不接受新调用，因此同样报告true
+func (db *Database) Closed() bool {
+	return db.isClosing()
+}
+
+// closeHandle 刷盘并释放C句柄，只执行一次；重复调用等待首次执行完成后返回nil
+func (db *Database) closeHandle() error {
+	err := ErrClosed
+	db.closeOnce.Do(func() { err = db.releaseHandle() })
+	if err == ErrClosed {
+		return nil
+	}
+	return err
+}
+
+// releaseHandle closeHandle的实际关闭步骤
+func (db *Database) releaseHandle() error {
+	if db.mirror != nil {
+		defer db.mirror.Close()
+	}
+	defer db.watches.closeAll()
+	defer db.async.stop()
+	defer db.auditLog.close()
+	if db.borrowed {
+		return nil
+	}
+	start := db.cgoStart()
 	status := C.amdb_close(db.handle)
+	db.cgoEnd(start)
+	if db.tempDir != "" {
+		unlockDir(db.lock)
+		err := os.RemoveAll(db.tempDir)
+		if status != C.AMDB_OK {
+			return statusError(status)
+		}
+		return err
+	}
+	defer unlockDir(db.lock)
 	if status != C.AMDB_OK {
-		return errors.New(C.GoString(C.amdb_error_string(status)))
+		return statusError(status)
 	}
-	return nil
+	if db.dataDir == "" {
+		return nil
+	}
+	if err := db.batchesDurable(); err != nil {
+		return err
+	}
+	return writeCleanMarker(db.dataDir)
 }
 
 // Put 写入键值对
-func (db *Database) Put(key, value []byte) ([]byte, error) {
+// 在BeginCommit与EndCommit之间调用时只暂存写入并返回nil根哈希
+func (db *Database) Put(key, value []byte) (root []byte, err error) {
+	return db.PutContext(context.Background(), key, value)
+}
+
+// PutContext 与Put相同，配置了Options.WriteRateLimit时按限速等待，等待期间ctx取消则返回ctx.Err()且不写入
+func (db *Database) PutContext(ctx context.Context, key, value []byte) (root []byte, err error) {
+	ctx, span := db.startSpan(ctx, "amdb.Put")
+	if span != nil {
+		span.SetAttribute(attrKeySize, len(key))
+		span.SetAttribute(attrValueSize, len(value))
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	if ok, err := db.grouped(batchOp{key: key, value: value}); ok {
+		return nil, err
+	}
+	if err := db.limiter.throttle(ctx, 1, len(key)+len(value)); err != nil {
+		return nil, err
+	}
+	if db.coalesce != nil {
+		return db.coalescedPut(key, value)
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	return db.put(key, value)
+}
+
+// put 写入键值对（调用方需持有wmu）
+func (db *Database) put(key, value []byte) ([]byte, error) {
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	defer db.leave()
+	return db.putEntered(db.owned(key), db.owned(value))
+}
+
+// putEntered 写入键值对（调用方需持有wmu，且已通过enter登记为进行中的调用）
+func (db *Database) putEntered(key, value []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, ErrInvalidArg
+	}
+	if err := db.checkSealed(); err != nil {
+		return nil, err
+	}
+	key, value = db.storedEntry(key, value)
+	if err := db.checkWriteKey(key); err != nil {
+		return nil, err
+	}
+	defer db.invalidateTimeline()
+
+	cKey, err := db.encodeKey(key, true)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := db.encodeValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if db.plain || db.lazyRoot {
+		if err := db.markStateRoot(); err != nil {
+			return nil, err
+		}
+	}
 	var rootHash [32]C.uint8_t
-	status := C.amdb_put(
-		db.handle,
-		(*C.uint8_t)(unsafe.Pointer(&key[0])), C.size_t(len(key)),
-		(*C.uint8_t)(unsafe.Pointer(&value[0])), C.size_t(len(value)),
-		&rootHash[0],
-	)
+	var status C.amdb_status_t
+	written := db.writeAmpStart()
+	start := db.cgoStart()
+	if db.plain || db.lazyRoot {
+		status = db.plainWrite(cKey, encoded)
+	} else {
+		status = C.amdb_put(
+			db.handle,
+			cBytes(cKey), C.size_t(len(cKey)),
+			cBytes(encoded), C.size_t(len(encoded)),
+			&rootHash[0],
+		)
+	}
+	db.cgoEnd(start)
+	db.values.invalidate(key)
 	if status != C.AMDB_OK {
-		return nil, errors.New(C.GoString(C.amdb_error_string(status)))
+		return nil, statusError(status)
 	}
-	return C.GoBytes(unsafe.Pointer(&rootHash[0]), 32), nil
+	db.writeAmpEnd(written, len(key)+len(value))
+	db.updatePinned([][]byte{key}, [][]byte{value})
+	db.bloomAdd(key)
+	db.notifyChange(key, value)
+	if err := db.mirrorWrite([][]byte{key}, [][]byte{value}); err != nil {
+		return nil, err
+	}
+	root, err := db.writtenRoot(&rootHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.audit(auditPut, key, value, root); err != nil {
+		return nil, err
+	}
+	return root, nil
 }
 
 // Get 读取键值对
+// version为数据库版本（0表示最新版本），返回键在该版本时的值。
+// 等价于AllowStale为true的GetWithOptions
 func (db *Database) Get(key []byte, version uint32) ([]byte, error) {
+	return db.get(key, ReadOptions{Version: version, AllowStale: true}, nil)
+}
+
+// GetWithOptions 按读取选项读取键值对
+func (db *Database) GetWithOptions(key []byte, opts ReadOptions) ([]byte, error) {
+	return db.get(key, opts, nil)
+}
+
+// GetLimited 与Get相同，但值超过maxBytes字节时返回*ValueTooLargeError（可用errors.Is匹配ErrValueTooLarge），
+// 大小在把值复制到Go内存之前检查
+func (db *Database) GetLimited(key []byte, version uint32, maxBytes int) ([]byte, error) {
+	return db.get(key, ReadOptions{Version: version, AllowStale: true, MaxValueSize: maxBytes}, nil)
+}
+
+// get 读取键值对，rng非nil时只返回值的该区间
+func (db *Database) get(key []byte, opts ReadOptions, rng *valueRange) (value []byte, err error) {
+	version := opts.Version
+	if _, span := db.startSpan(context.Background(), "amdb.Get"); span != nil {
+		span.SetAttribute(attrKeySize, len(key))
+		span.SetAttribute(attrVersion, version)
+		defer func() {
+			span.SetAttribute(attrValueSize, len(value))
+			endSpan(span, nil, err)
+		}()
+	}
+
+	err = db.readRetry.do(func() error {
+		var err error
+		value, err = db.getOnce(key, opts, rng)
+		return err
+	}, isTransientRead)
+	return value, err
+}
+
+// getOnce 执行一次读取
+func (db *Database) getOnce(key []byte, opts ReadOptions, rng *valueRange) ([]byte, error) {
+	version := opts.Version
+	if db.isClosing() {
+		return nil, ErrClosed
+	}
+	if len(key) == 0 {
+		return nil, ErrInvalidArg
+	}
+	if !db.inKeyRange(key) {
+		return nil, ErrNotFound
+	}
+	userKey := key
+	key = db.storedKey(key)
+	if opts.AllowStale {
+		if !db.bloomCheck(key, version) {
+			return nil, ErrNotFound
+		}
+	} else {
+		db.invalidateTimeline()
+	}
+	// 时间线查询自行登记调用，须在enter之前完成，否则Rewind暂停期间会等待自身
+	keyVer, err := db.keyVersionAt(key, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	defer db.leave()
+	// 值缓存只服务AllowStale的最新版本读取，代数须在C层调用前取得
+	cacheable := opts.AllowStale && version == 0 && db.values != nil
+	var gen uint64
+	if cacheable {
+		if cached, ok := db.values.get(key); ok {
+			return db.copyValue(cached, opts, rng)
+		}
+		gen = db.values.generation()
+	}
+
+	cKey, err := db.encodeKey(key, false)
+	if err != nil {
+		return nil, err
+	}
 	var result C.amdb_result_t
+	start := db.cgoStart()
 	status := C.amdb_get(
 		db.handle,
-		(*C.uint8_t)(unsafe.Pointer(&key[0])), C.size_t(len(key)),
-		C.uint32_t(version),
+		cBytes(cKey), C.size_t(len(cKey)),
+		C.uint32_t(keyVer),
 		&result,
 	)
+	db.cgoEnd(start)
 	defer C.amdb_free_result(&result)
 
+	if status == C.AMDB_NOT_FOUND && opts.AllowStale {
+		db.bloomMiss(version)
+	}
 	if status != C.AMDB_OK {
-		if status == C.AMDB_NOT_FOUND {
-			return nil, errors.New("key not found")
-		}
-		return nil, errors.New(C.GoString(C.amdb_error_string(status)))
+		return nil, statusError(status)
 	}
 
 	if result.data == nil {
 		return nil, errors.New("no data")
 	}
 
-	data := C.GoBytes(result.data, C.int(result.data_len))
+	if opts.MaxValueSize > 0 {
+		size := int(result.data_len)
+		if db.hashKeys {
+			size -= 4 + len(userKey)
+		}
+		if size > opts.MaxValueSize {
+			return nil, &ValueTooLargeError{Size: size, Limit: opts.MaxValueSize}
+		}
+	}
+
+	// raw引用C层内存，在函数返回时释放，返回前须复制
+	size, err := cLength(result.data_len)
+	if err != nil {
+		return nil, err
+	}
+	raw := unsafe.Slice((*byte)(result.data), size)
+	if raw, err = db.decodeValue(raw); err != nil {
+		return nil, err
+	}
+	if db.hashKeys {
+		entry, err := db.userEntry(kv{key: key, value: raw})
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(entry.key, userKey) {
+			return nil, ErrCorrupted
+		}
+		raw = entry.value
+	}
+	if cacheable {
+		db.values.put(key, raw, gen)
+	}
+	return db.copyValue(raw, opts, rng)
+}
+
+// copyValue 按读取选项截取用户形式的值raw并复制到返回的缓冲区
+func (db *Database) copyValue(raw []byte, opts ReadOptions, rng *valueRange) ([]byte, error) {
+	if opts.MaxValueSize > 0 && len(raw) > opts.MaxValueSize {
+		return nil, &ValueTooLargeError{Size: len(raw), Limit: opts.MaxValueSize}
+	}
+	if rng != nil {
+		var err error
+		if raw, err = rng.slice(raw); err != nil {
+			return nil, err
+		}
+	}
+	data := db.valueBuffer(len(raw))
+	copy(data, raw)
 	return data, nil
 }
 
+// Has 判断键在最新版本中是否存在（已删除的键视为不存在）
+func (db *Database) Has(key []byte) (bool, error) {
+	_, err := db.Get(key, 0)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Delete 删除键值对
-func (db *Database) Delete(key []byte) error {
-	status := C.amdb_delete(
-		db.handle,
-		(*C.uint8_t)(unsafe.Pointer(&key[0])), C.size_t(len(key)),
-	)
+// 引擎保留历史，删除写入一个删除标记版本：之后读取最新版本返回ErrNotFound，删除前的版本仍可读取
+// 在BeginCommit与EndCommit之间调用时只暂存删除
+func (db *Database) Delete(key []byte) (err error) {
+	if _, span := db.startSpan(context.Background(), "amdb.Delete"); span != nil {
+		span.SetAttribute(attrKeySize, len(key))
+		defer func() { endSpan(span, nil, err) }()
+	}
+
+	if ok, err := db.grouped(batchOp{key: key, delete: true}); ok {
+		return err
+	}
+	if len(key) == 0 {
+		return ErrInvalidArg
+	}
+	key = db.storedKey(db.owned(key))
+	if err := db.checkWriteKey(key); err != nil {
+		return err
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	if err := db.enter(); err != nil {
+		return err
+	}
+	defer db.leave()
+	if err := db.checkSealed(); err != nil {
+		return err
+	}
+	defer db.invalidateTimeline()
+
+	cKey, err := db.encodeKey(key, true)
+	if err != nil {
+		return err
+	}
+	if db.plain || db.lazyRoot {
+		if err := db.markStateRoot(); err != nil {
+			return err
+		}
+	}
+	var status C.amdb_status_t
+	written := db.writeAmpStart()
+	start := db.cgoStart()
+	if db.plain || db.lazyRoot {
+		status = db.plainWrite(cKey, deletedValue)
+	} else {
+		status = C.amdb_delete(
+			db.handle,
+			cBytes(cKey), C.size_t(len(cKey)),
+		)
+	}
+	db.cgoEnd(start)
+	db.values.invalidate(key)
 	if status != C.AMDB_OK {
-		return errors.New(C.GoString(C.amdb_error_string(status)))
+		return statusError(status)
+	}
+	db.writeAmpEnd(written, len(key))
+	db.bloomRemove()
+	db.notifyChange(key, deletedValue)
+	if err := db.mirrorWrite([][]byte{key}, [][]byte{deletedValue}); err != nil {
+		return err
+	}
+	if db.auditLog != nil {
+		root, err := db.rootHash()
+		if err != nil {
+			return err
+		}
+		return db.audit(auditDelete, key, deletedValue, root)
 	}
 	return nil
 }
 
 // BatchPut 批量写入
-func (db *Database) BatchPut(items map[string][]byte) ([]byte, error) {
-	keys := make([]*C.uint8_t, len(items))
-	keyLens := make([]C.size_t, len(items))
-	values := make([]*C.uint8_t, len(items))
-	valueLens := make([]C.size_t, len(items))
+// 条目按键的字典序提交。某个条目无效时返回*BatchError，其Index为该键在排序后批次中的下标，
+// 且整批都不写入；C层写入失败时无法定位具体条目，BatchError.Index为-1
+// 崩溃时整批要么完整保留、要么全部丢弃：批次先记入批量写入日志，重新打开时回退只有部分键落盘的批次，
+// 见OpenInfo.RolledBackBatch
+func (db *Database) BatchPut(items map[string][]byte) (root []byte, err error) {
+	return db.BatchPutContext(context.Background(), items)
+}
 
-	// 保存Go数据，防止被GC
-	keyData := make([][]byte, 0, len(items))
-	valueData := make([][]byte, 0, len(items))
+// BatchPutContext 与BatchPut相同，配置了Options.WriteRateLimit时按限速等待，等待期间ctx取消则返回ctx.Err()且不写入
+func (db *Database) BatchPutContext(ctx context.Context, items map[string][]byte) (root []byte, err error) {
+	ctx, span := db.startSpan(ctx, "amdb.BatchPut")
+	if span != nil {
+		span.SetAttribute(attrBatchSize, len(items))
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	stored, err := db.prepareBatch(ctx, items)
+	if err != nil {
+		return nil, err
+	}
 
-	i := 0
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	return db.batchPut(stored)
+}
+
+// prepareBatch 校验批量写入的键并按限速等待，返回存储形式的批次
+func (db *Database) prepareBatch(ctx context.Context, items map[string][]byte) (map[string][]byte, error) {
+	for i, k := range sortedKeys(items) {
+		err := ErrInvalidArg
+		if len(k) > 0 {
+			err = db.checkWriteKey(db.storedKey(stringBytes(k)))
+		}
+		if err != nil {
+			return nil, &BatchError{Key: []byte(k), Index: i, Err: err}
+		}
+	}
+	size := 0
 	for k, v := range items {
-		keyBytes := []byte(k)
-		keyData = append(keyData, keyBytes)
-		valueData = append(valueData, v)
+		size += len(k) + len(v)
+	}
+	if err := db.limiter.throttle(ctx, len(items), size); err != nil {
+		return nil, err
+	}
+	return db.storedBatch(items), nil
+}
+
+// BatchPutSlices 以平行切片批量写入，keys[i]对应values[i]，避免BatchPut的map分配与键的字符串转换
+// 条目按给定顺序一次性提交，同一个键出现多次时以最后一次为准。len(keys)与len(values)不等时返回ErrInvalidArg；
+// 某个条目无效时返回*BatchError，其Index为该条目在keys中的下标，且整批都不写入。
+// 配置了Options.WriteRateLimit时按限速等待
+func (db *Database) BatchPutSlices(keys, values [][]byte) (root []byte, err error) {
+	if _, span := db.startSpan(context.Background(), "amdb.BatchPutSlices"); span != nil {
+		span.SetAttribute(attrBatchSize, len(keys))
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("%w: %d keys but %d values", ErrInvalidArg, len(keys), len(values))
+	}
+	for i, k := range keys {
+		err := ErrInvalidArg
+		if len(k) > 0 {
+			err = db.checkWriteKey(db.storedKey(k))
+		}
+		if err != nil {
+			return nil, &BatchError{Key: k, Index: i, Err: err}
+		}
+	}
+	size := 0
+	for i := range keys {
+		size += len(keys[i]) + len(values[i])
+	}
+	if err := db.limiter.throttle(context.Background(), len(keys), size); err != nil {
+		return nil, err
+	}
+	if db.hashKeys {
+		storedKeys := make([][]byte, len(keys))
+		storedValues := make([][]byte, len(values))
+		for i := range keys {
+			storedKeys[i], storedValues[i] = db.storedEntry(keys[i], values[i])
+		}
+		keys, values = storedKeys, storedValues
+	}
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	return db.batchPutSlices(keys, values)
+}
+
+// chunkKeys 分批写入（导入、Fork、Merge、Rehash等）时每批提交的键数
+// 每批是引擎的一次批量写入并各自产生新版本，批次越小，检查点越密、取消越及时，引擎为一批构造的临时对象也越少
+const chunkKeys = 500
+
+// batchPut 按存储形式批量写入，条目按键的字典序提交（调用方需持有wmu）
+// 键直接引用map中字符串的内存而不复制：amdb_batch_put在返回前将数据复制为Python对象、不保留指针，
+// batchPutSlices也只读取键，因此共享只读内存是安全的
+func (db *Database) batchPut(items map[string][]byte) ([]byte, error) {
+	sorted := sortedKeys(items)
+	keys := make([][]byte, len(sorted))
+	values := make([][]byte, len(sorted))
+	for i, k := range sorted {
+		keys[i], values[i] = stringBytes(k), items[k]
+	}
+	return db.batchPutSlices(keys, values)
+}
+
+// batchPutSlices 按存储形式批量写入，keys与values一一对应并按给定顺序提交（调用方需持有wmu）
+// 不修改也不保留keys的内容，返回的BatchError携带键的副本
+func (db *Database) batchPutSlices(keyItems, valueItems [][]byte) ([]byte, error) {
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	defer db.leave()
+	if err := db.checkSealed(); err != nil {
+		return nil, err
+	}
+	defer db.invalidateTimeline()
+	if db.safeMode {
+		keyItems = ownedAll(keyItems)
+		valueItems = ownedAll(valueItems)
+	}
 
-		keys[i] = (*C.uint8_t)(unsafe.Pointer(&keyData[i][0]))
-		keyLens[i] = C.size_t(len(keyData[i]))
-		values[i] = (*C.uint8_t)(unsafe.Pointer(&valueData[i][0]))
-		valueLens[i] = C.size_t(len(valueData[i]))
-		i++
+	if len(keyItems) == 0 {
+		return db.rootHash()
+	}
+
+	keys := make([]*C.uint8_t, len(keyItems))
+	keyLens := make([]C.size_t, len(keyItems))
+	values := make([]*C.uint8_t, len(keyItems))
+	valueLens := make([]C.size_t, len(keyItems))
+
+	// 指针数组位于Go内存中，其指向的数据必须固定，防止被GC移动或回收
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	for i, k := range keyItems {
+		if err := db.checkWriteKey(k); err != nil {
+			return nil, &BatchError{Key: bytes.Clone(k), Index: i, Err: err}
+		}
+		cKey, err := db.encodeKey(k, true)
+		if err != nil {
+			return nil, &BatchError{Key: bytes.Clone(k), Index: i, Err: err}
+		}
+		encoded, err := db.encodeValue(valueItems[i])
+		if err != nil {
+			return nil, &BatchError{Key: bytes.Clone(k), Index: i, Err: err}
+		}
+		keys[i] = pinBytes(&pinner, cKey)
+		keyLens[i] = C.size_t(len(cKey))
+		values[i] = pinBytes(&pinner, encoded)
+		valueLens[i] = C.size_t(len(encoded))
+	}
+	if err := db.journalBatch(keyItems, valueItems); err != nil {
+		return nil, &BatchError{Index: -1, Err: err}
+	}
+	if err := db.markStateRoot(); err != nil {
+		return nil, &BatchError{Index: -1, Err: err}
 	}
 
 	var rootHash [32]C.uint8_t
+	written := db.writeAmpStart()
+	start := db.cgoStart()
 	status := C.amdb_batch_put(
 		db.handle,
 		&keys[0], &keyLens[0],
 		&values[0], &valueLens[0],
-		C.size_t(len(items)),
+		C.size_t(len(keyItems)),
 		&rootHash[0],
 	)
+	db.cgoEnd(start)
+	db.values.invalidate(keyItems...)
 	if status != C.AMDB_OK {
-		return nil, errors.New(C.GoString(C.amdb_error_string(status)))
+		return nil, &BatchError{Index: -1, Err: statusError(status)}
 	}
-	return C.GoBytes(unsafe.Pointer(&rootHash[0]), 32), nil
+	db.writeAmpEnd(written, entrySize(keyItems, valueItems))
+	db.checkpointBatches()
+	db.updatePinned(keyItems, valueItems)
+	db.bloomAdd(keyItems...)
+	for i, k := range keyItems {
+		db.notifyChange(k, valueItems[i])
+	}
+	if err := db.mirrorWrite(keyItems, valueItems); err != nil {
+		return nil, err
+	}
+	root, err := db.writtenRoot(&rootHash)
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range keyItems {
+		op := auditBatchPut
+		if isDeleted(valueItems[i]) {
+			op = auditBatchDelete
+		}
+		if err := db.auditAppend(op, k, valueItems[i], root); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.auditCommit(); err != nil {
+		return nil, err
+	}
+	return root, nil
 }
 
-// GetRootHash 获取Merkle根哈希
+// GetRootHash 获取Merkle根哈希，PlainMode下返回ErrNotAuthenticated
+// 启用块存储或前缀压缩时引擎的Merkle树覆盖的是编码后的键值，根哈希改由当前状态按原始的键值计算，即RootHashAtVersion(0)；
+// LazyRoot下同样由当前状态计算，一次计算涵盖之前的全部写入。
+// 数据目录中有过批量写入之后引擎的Merkle树缺少经批量写入路径提交的键，同样改由当前状态计算（见stateRootMarkerName），
+// 此后每个新版本的首次查询需要重建一次树
 func (db *Database) GetRootHash() ([]byte, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	if db.rootFromState() {
+		return db.RootHashAtVersion(0)
+	}
+	return db.engineRootHash()
+}
+
+// rootHash 返回写入操作报告的根哈希，PlainMode下为nil
+func (db *Database) rootHash() ([]byte, error) {
+	if db.plain {
+		return nil, nil
+	}
+	if db.rootFromState() {
+		return db.RootHashAtVersion(0)
+	}
+	return db.engineRootHash()
+}
+
+// writtenRoot 返回写入操作报告的根哈希：engine为C层写入返回的根哈希，PlainMode和LazyRoot下为nil，
+// 启用块存储或前缀压缩时及有过批量写入之后由提交后的状态计算（引擎的批量写入不返回根哈希）
+func (db *Database) writtenRoot(engine *[32]C.uint8_t) ([]byte, error) {
+	if db.plain || db.lazyRoot {
+		return nil, nil
+	}
+	if db.rootFromState() {
+		db.invalidateTimeline()
+		return db.RootHashAtVersion(0)
+	}
+	return C.GoBytes(unsafe.Pointer(&engine[0]), 32), nil
+}
+
+// engineRootHash 读取引擎维护的Merkle根哈希
+func (db *Database) engineRootHash() ([]byte, error) {
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	defer db.leave()
 	var rootHash [32]C.uint8_t
+	start := db.cgoStart()
 	status := C.amdb_get_root_hash(db.handle, &rootHash[0])
+	db.cgoEnd(start)
 	if status != C.AMDB_OK {
-		return nil, errors.New(C.GoString(C.amdb_error_string(status)))
+		return nil, statusError(status)
 	}
 	return C.GoBytes(unsafe.Pointer(&rootHash[0]), 32), nil
 }
+
+// Ping 检查句柄是否可用且存储可正常响应，用于存活/就绪探针
+// 只读取根哈希，不做完整校验；已关闭时返回ErrClosed，存储不可用时返回包装后的底层错误
+func (db *Database) Ping() error {
+	if _, err := db.engineRootHash(); err != nil {
+		if errors.Is(err, ErrClosed) {
+			return err
+		}
+		return fmt.Errorf("ping: %w", err)
+	}
+	return nil
+}
+
+// sortedKeys 返回批次中按字典序排序的键
+func sortedKeys(items map[string][]byte) []string {
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// owned SafeMode下返回b的副本，否则原样返回b
+func (db *Database) owned(b []byte) []byte {
+	if !db.safeMode {
+		return b
+	}
+	return bytes.Clone(b)
+}
+
+// ownedAll 复制切片列表及其中的每个切片
+func ownedAll(items [][]byte) [][]byte {
+	out := make([][]byte, len(items))
+	for i, b := range items {
+		out[i] = bytes.Clone(b)
+	}
+	return out
+}
+
+// checkWriteKey 校验待写入的键：不能为空，且不能使树深度超过maxDepth
+// 键最多与另一个键共享2*len(key)个nibble，因此树深度不超过2*最长键长度+1
+func (db *Database) checkWriteKey(key []byte) error {
+	if len(key) == 0 {
+		return ErrInvalidArg
+	}
+	if db.maxDepth > 0 && 2*len(key)+1 > db.maxDepth {
+		return ErrTreeTooDeep
+	}
+	if !db.inKeyRange(key) {
+		return ErrOutOfRange
+	}
+	return nil
+}
+
+// emptyBytes 空数据的占位指针：C API不接受NULL，长度为0时不会读取其内容
+var emptyBytes = (*C.uint8_t)(C.malloc(1))
+
+// cBytes 返回传给C API的数据指针
+func cBytes(b []byte) *C.uint8_t {
+	if len(b) == 0 {
+		return emptyBytes
+	}
+	return (*C.uint8_t)(unsafe.Pointer(&b[0]))
+}
+
+// stringBytes 返回与s共享内存的字节切片，避免复制；切片只读，任何修改都是未定义行为
+func stringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+// pinBytes 固定b的底层数组并返回其数据指针
+func pinBytes(pinner *runtime.Pinner, b []byte) *C.uint8_t {
+	if len(b) == 0 {
+		return emptyBytes
+	}
+	pinner.Pin(&b[0])
+	return (*C.uint8_t)(unsafe.Pointer(&b[0]))
+}
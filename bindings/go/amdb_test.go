@@ -0,0 +1,346 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenRetryWaitsForLock(t *testing.T) {
+	dir := t.TempDir()
+	// 模拟尚未退出的旧进程持有数据目录锁
+	lock, err := lockDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDatabase(dir); !errors.Is(err, ErrLocked) {
+		t.Fatalf("open without retry: got %v, want ErrLocked", err)
+	}
+
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		unlockDir(lock)
+		close(released)
+	}()
+	db, err := NewDatabaseWithOptions(dir, &Options{
+		OpenRetry: &RetryPolicy{MaxAttempts: 10, Backoff: 20 * time.Millisecond, MaxBackoff: 100 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("open with retry: %v", err)
+	}
+	defer db.Close()
+	select {
+	case <-released:
+	default:
+		t.Fatal("opened while the lock was still held")
+	}
+	mustPut(t, db, "k", "v")
+	if got := mustGet(t, db, "k", 0); got != "v" {
+		t.Fatalf("get: %q", got)
+	}
+}
+
+func TestOpenRetryGivesUp(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := lockDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlockDir(lock)
+	_, err = NewDatabaseWithOptions(dir, &Options{
+		OpenRetry: &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+	})
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("got %v, want ErrLocked", err)
+	}
+}
+
+func TestOpenRetryFailsFastOnPermanentError(t *testing.T) {
+	// 数据目录的父路径是普通文件，无法创建目录，不属于临时错误
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err := NewDatabaseWithOptions(filepath.Join(file, "db"), &Options{
+		OpenRetry: &RetryPolicy{MaxAttempts: 5, Backoff: time.Second},
+	})
+	if err == nil {
+		t.Fatal("open succeeded")
+	}
+	if d := time.Since(start); d >= time.Second {
+		t.Fatalf("retried a permanent error: took %v", d)
+	}
+}
+
+func TestBatchPutLarge(t *testing.T) {
+	db := openTestDB(t, nil)
+	// 超过500个键的批量写入走引擎的另一条创建版本路径
+	items := make(map[string][]byte, 1200)
+	for i := 0; i < 1200; i++ {
+		items[fmt.Sprintf("k%05d", i)] = []byte(fmt.Sprintf("v%05d", i))
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatalf("batch put: %v", err)
+	}
+	for _, key := range []string{"k00000", "k00600", "k01199"} {
+		if got, want := mustGet(t, db, key, 0), "v"+key[1:]; got != want {
+			t.Fatalf("get %s: %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEmptyInputsReturnErrors(t *testing.T) {
+	db := openTestDB(t, nil)
+	// 以下输入曾在取&key[0]时panic
+	if _, err := db.Put(nil, []byte("v")); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("put nil key: %v", err)
+	}
+	if _, err := db.Get([]byte{}, 0); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("get empty key: %v", err)
+	}
+	if err := db.Delete(nil); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("delete nil key: %v", err)
+	}
+	if _, err := db.BatchPut(map[string][]byte{"": []byte("v")}); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("batch with empty key: %v", err)
+	}
+	if _, err := db.BatchPut(nil); err != nil {
+		t.Fatalf("empty batch: %v", err)
+	}
+
+	// 空值是合法的
+	if _, err := db.Put([]byte("k"), nil); err != nil {
+		t.Fatalf("put nil value: %v", err)
+	}
+	if _, err := db.BatchPut(map[string][]byte{"b": nil}); err != nil {
+		t.Fatalf("batch nil value: %v", err)
+	}
+	for _, key := range []string{"k", "b"} {
+		value, err := db.Get([]byte(key), 0)
+		if err != nil || value == nil || len(value) != 0 {
+			t.Fatalf("get %s: %q, %v", key, value, err)
+		}
+	}
+}
+
+func TestMaxTreeDepth(t *testing.T) {
+	db := openTestDB(t, &Options{MaxTreeDepth: 21})
+	prefix := strings.Repeat("p", 9)
+
+	// 深度上限21对应最长10字节的键
+	if _, err := db.Put([]byte(prefix+"a"), []byte("v")); err != nil {
+		t.Fatalf("put at limit: %v", err)
+	}
+	if _, err := db.Put([]byte(prefix+"ab"), []byte("v")); !errors.Is(err, ErrTreeTooDeep) {
+		t.Fatalf("put over limit: %v", err)
+	}
+	items := map[string][]byte{prefix + "b": []byte("v"), prefix + "bc": []byte("v")}
+	var be *BatchError
+	if _, err := db.BatchPut(items); !errors.As(err, &be) || !errors.Is(err, ErrTreeTooDeep) || string(be.Key) != prefix+"bc" {
+		t.Fatalf("batch over limit: %v", err)
+	}
+	if _, err := db.Get([]byte(prefix+"b"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("rejected batch was partly written: %v", err)
+	}
+	if err := db.Delete([]byte(prefix + "ab")); !errors.Is(err, ErrTreeTooDeep) {
+		t.Fatalf("delete over limit: %v", err)
+	}
+}
+
+func TestMaxTreeDepthUnlimitedByDefault(t *testing.T) {
+	long := []byte(strings.Repeat("k", 600))
+	for _, opts := range []*Options{nil, {MaxTreeDepth: -1}} {
+		db := openTestDB(t, opts)
+		mustPut(t, db, "short", "s")
+		before := rootOf(t, db)
+		if _, err := db.Put(long, []byte("v")); err != nil {
+			t.Fatalf("600-byte key: %v", err)
+		}
+		if got, err := db.Get(long, 0); err != nil || string(got) != "v" {
+			t.Fatalf("get 600-byte key: %q, %v", got, err)
+		}
+		root := rootOf(t, db)
+		if bytes.Equal(root, before) {
+			t.Fatal("root unchanged by the 600-byte key")
+		}
+		proof, err := db.GetWithProof(long, 0)
+		if err != nil || !proof.Verify(root) {
+			t.Fatalf("proof of 600-byte key: %v", err)
+		}
+	}
+	if _, err := openTestDB(t, &Options{MaxTreeDepth: 961}).Put(long, []byte("v")); !errors.Is(err, ErrTreeTooDeep) {
+		t.Fatalf("explicit limit: %v", err)
+	}
+}
+
+func TestGetWithOptions(t *testing.T) {
+	db := openTestDB(t, &Options{BloomFilterBits: 1 << 10})
+	mustPut(t, db, "k", "1")
+	mustPut(t, db, "k", "2")
+	for _, stale := range []bool{true, false} {
+		for version, want := range map[uint32]string{0: "2", 1: "1", 2: "2"} {
+			got, err := db.GetWithOptions([]byte("k"), ReadOptions{Version: version, AllowStale: stale})
+			if err != nil || string(got) != want {
+				t.Fatalf("stale=%v v%d: %q, %v", stale, version, got, err)
+			}
+		}
+		if _, err := db.GetWithOptions([]byte("k"), ReadOptions{Version: 3, AllowStale: stale}); !errors.Is(err, ErrVersionNotFound) {
+			t.Fatalf("stale=%v future version: %v", stale, err)
+		}
+		if _, err := db.GetWithOptions([]byte("missing"), ReadOptions{AllowStale: stale}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("stale=%v missing key: %v", stale, err)
+		}
+	}
+}
+
+func TestPingAndClosed(t *testing.T) {
+	db, err := NewDatabase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatalf("ping empty db: %v", err)
+	}
+	mustPut(t, db, "k", "v")
+	if err := db.Ping(); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	db.Close()
+
+	if err := db.Ping(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("ping after close: %v", err)
+	}
+	if _, err := db.Get([]byte("k"), 0); !errors.Is(err, ErrClosed) {
+		t.Fatalf("get after close: %v", err)
+	}
+	if _, err := db.Put([]byte("k"), []byte("v")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("put after close: %v", err)
+	}
+	if _, err := db.CurrentVersion(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("version after close: %v", err)
+	}
+}
+
+func TestGetLimited(t *testing.T) {
+	db := openTestDB(t, nil)
+	value := strings.Repeat("v", 100)
+	mustPut(t, db, "k", value)
+
+	if got, err := db.GetLimited([]byte("k"), 0, 100); err != nil || string(got) != value {
+		t.Fatalf("at limit: %d bytes, %v", len(got), err)
+	}
+	_, err := db.GetLimited([]byte("k"), 0, 99)
+	var tooLarge *ValueTooLargeError
+	if !errors.Is(err, ErrValueTooLarge) || !errors.As(err, &tooLarge) || tooLarge.Size != 100 || tooLarge.Limit != 99 {
+		t.Fatalf("over limit: %v", err)
+	}
+	if _, err := db.GetLimited([]byte("missing"), 0, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing key: %v", err)
+	}
+	if got, err := db.GetLimited([]byte("k"), 0, 0); err != nil || len(got) != 100 {
+		t.Fatalf("no limit: %d bytes, %v", len(got), err)
+	}
+}
+
+func TestBatchPutMatchesSlices(t *testing.T) {
+	items := make(map[string][]byte)
+	var keys, values [][]byte
+	for i := 0; i < 50; i++ {
+		k, v := fmt.Sprintf("key%03d", i), []byte(fmt.Sprintf("value%d", i))
+		items[k] = v
+		keys, values = append(keys, []byte(k)), append(values, v)
+	}
+	for _, opts := range []*Options{nil, {HashKeys: true}} {
+		byMap, err := openTestDB(t, opts).BatchPut(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bySlices, err := openTestDB(t, opts).BatchPutSlices(keys, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(byMap) == 0 || !bytes.Equal(byMap, bySlices) {
+			t.Fatalf("opts %+v: BatchPut root %x, BatchPutSlices root %x", opts, byMap, bySlices)
+		}
+	}
+	for k := range items {
+		if string(stringBytes(k)) != k {
+			t.Fatalf("stringBytes(%q) differs", k)
+		}
+	}
+}
+
+func BenchmarkBatchPut(b *testing.B) {
+	db := openTestDB(b, nil)
+	items := make(map[string][]byte, 1000)
+	for i := 0; i < 1000; i++ {
+		items[fmt.Sprintf("key%04d", i)] = []byte("v")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.BatchPut(items); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "k", "1")
+	mustPut(t, db, "k", "2")
+	current, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mustGet(t, db, "k", LatestVersion), mustGet(t, db, "k", current); got != want || got != "2" {
+		t.Fatalf("LatestVersion read %q, CurrentVersion read %q", got, want)
+	}
+	mustPut(t, db, "k", "3")
+	if got := mustGet(t, db, "k", LatestVersion); got != "3" {
+		t.Fatalf("LatestVersion not resolved at call time: %q", got)
+	}
+}
+
+func TestCloseIdempotent(t *testing.T) {
+	db, err := NewDatabase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var closer io.Closer = db
+	if db.Closed() {
+		t.Fatal("new handle reports Closed")
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !db.Closed() {
+		t.Fatal("Closed is false after Close")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	// 并发关闭同样安全，且都返回nil
+	db, err = NewDatabase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- db.Close() }()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("concurrent Close: %v", err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package amdb
+
+import (
+	"context"
+	"errors"
+)
+
+// Append 在键的当前值末尾追加suffix，返回追加后的完整值和新的根哈希
+// 读取与写入在同一把写锁内完成，并发Append互不覆盖；键不存在（或已删除）时以空值为基础，即写入suffix
+func (db *Database) Append(key, suffix []byte) (newValue []byte, root []byte, err error) {
+	if _, span := db.startSpan(context.Background(), "amdb.Append"); span != nil {
+		span.SetAttribute(attrKeySize, len(key))
+		span.SetAttribute(attrValueSize, len(suffix))
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	base, err := db.Get(key, 0)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, nil, err
+	}
+	newValue = make([]byte, 0, len(base)+len(suffix))
+	newValue = append(append(newValue, base...), suffix...)
+
+	root, err = db.put(key, newValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newValue, root, nil
+}
@@ -0,0 +1,70 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAppendAbsentKey(t *testing.T) {
+	db := openTestDB(t, nil)
+	value, root, err := db.Append([]byte("log"), []byte("a"))
+	if err != nil || string(value) != "a" {
+		t.Fatalf("append to absent key: %q, %v", value, err)
+	}
+	if !bytes.Equal(root, rootOf(t, db)) {
+		t.Fatalf("returned root %x differs from current root", root)
+	}
+
+	// 删除过的键同样以空值为基础
+	if err := db.Delete([]byte("log")); err != nil {
+		t.Fatal(err)
+	}
+	if value, _, err := db.Append([]byte("log"), []byte("b")); err != nil || string(value) != "b" {
+		t.Fatalf("append to deleted key: %q, %v", value, err)
+	}
+	if value, _, err := db.Append([]byte("log"), []byte("c")); err != nil || string(value) != "bc" {
+		t.Fatalf("append: %q, %v", value, err)
+	}
+	if got := mustGet(t, db, "log", 0); got != "bc" {
+		t.Fatalf("stored %q", got)
+	}
+}
+
+func TestAppendConcurrent(t *testing.T) {
+	db := openTestDB(t, nil)
+	const writers, appends = 4, 10
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < appends; i++ {
+				if _, _, err := db.Append([]byte("log"), []byte(fmt.Sprintf("[%d.%d]", w, i))); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// 每个后缀恰好出现一次，且同一写入方的后缀保持顺序
+	got := mustGet(t, db, "log", 0)
+	parts := strings.SplitAfter(got, "]")
+	parts = parts[:len(parts)-1]
+	if len(parts) != writers*appends {
+		t.Fatalf("%d suffixes in %q, want %d", len(parts), got, writers*appends)
+	}
+	next := make([]int, writers)
+	for _, part := range parts {
+		var w, i int
+		if _, err := fmt.Sscanf(part, "[%d.%d]", &w, &i); err != nil || i != next[w] {
+			t.Fatalf("suffix %q out of order in %q", part, got)
+		}
+		next[w]++
+	}
+}
@@ -0,0 +1,162 @@
+package amdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrConditionFailed ApplyIf读取到的值与期望值不一致，批次未提交
+var ErrConditionFailed = errors.New("apply condition failed")
+
+// ConditionError ApplyIf中第一个与期望值不一致的读取
+type ConditionError struct {
+	// Key 不一致的键
+	Key []byte
+	// Index 该键在reads中的下标
+	Index int
+}
+
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("%v: key %q (index %d)", ErrConditionFailed, e.Key, e.Index)
+}
+
+// Is 使errors.Is(err, ErrConditionFailed)成立
+func (e *ConditionError) Is(target error) bool {
+	return target == ErrConditionFailed
+}
+
+// Apply 在一次调用中读取reads在数据库版本version（0表示调用时的最新版本）时的值，并原子提交writes
+// 读取与提交在同一把写锁内完成，期间不会有其他经由本句柄的写入插入；readValues与reads一一对应，
+// 键不存在（或已删除）时为nil。writes为nil或为空时只读取，root为当前根哈希
+func (db *Database) Apply(reads [][]byte, writes *WriteBatch, version uint32) (readValues [][]byte, root []byte, err error) {
+	return db.apply(reads, nil, writes, version)
+}
+
+// ApplyIf 与Apply相同，但只在reads的最新值与expected逐一相等时才提交writes（多键比较并交换）
+// expected与reads一一对应，nil表示要求该键不存在；不一致时不提交，返回读取到的值和*ConditionError
+// （可用errors.Is匹配ErrConditionFailed）。len(expected)与len(reads)不同时返回ErrInvalidArg
+func (db *Database) ApplyIf(reads, expected [][]byte, writes *WriteBatch) (readValues [][]byte, root []byte, err error) {
+	if len(expected) != len(reads) {
+		return nil, nil, ErrInvalidArg
+	}
+	if expected == nil {
+		expected = [][]byte{}
+	}
+	return db.apply(reads, expected, writes, 0)
+}
+
+// apply Apply与ApplyIf的实现，expected为nil时不检查条件
+func (db *Database) apply(reads, expected [][]byte, writes *WriteBatch, version uint32) (readValues [][]byte, root []byte, err error) {
+	if _, span := db.startSpan(context.Background(), "amdb.Apply"); span != nil {
+		if writes != nil {
+			span.SetAttribute(attrBatchSize, writes.Len())
+		}
+		span.SetAttribute(attrVersion, version)
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	if version == 0 {
+		if version, err = db.CurrentVersion(); err != nil {
+			return nil, nil, err
+		}
+	}
+	readValues = make([][]byte, len(reads))
+	if version > 0 {
+		for i, key := range reads {
+			value, err := db.Get(key, version)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			readValues[i] = value
+		}
+	}
+
+	if expected != nil {
+		for i, want := range expected {
+			got := readValues[i]
+			if (got == nil) != (want == nil) || !bytes.Equal(got, want) {
+				return readValues, nil, &ConditionError{Key: reads[i], Index: i}
+			}
+		}
+	}
+
+	if writes == nil || writes.Len() == 0 {
+		root, err = db.rootHash()
+	} else {
+		root, err = db.write(writes)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return readValues, root, nil
+}
+
+// CASOp MultiCAS中的一次比较并交换
+type CASOp struct {
+	Key []byte
+	// Expected 键的当前值须与之相等（nil表示要求键不存在或已删除）
+	Expected []byte
+	// New 条件满足时写入的新值（nil表示删除该键）
+	New []byte
+}
+
+// MultiCAS 在一次原子提交中执行多次比较并交换，results与ops一一对应，表示该操作的条件是否满足
+// allOrNothing为true时任一条件不满足则整批都不写入，root为nil，返回的*ConditionError指向第一个不满足的操作
+// （可用errors.Is匹配ErrConditionFailed），results仍给出每个操作的比较结果；
+// 为false时只写入条件满足的操作，其余跳过。没有可写入的操作时不产生新版本，root为当前根哈希。
+// 比较与提交在同一把写锁内完成；同一个键出现多次时都与提交前的值比较，写入以最后一个满足条件的操作为准
+func (db *Database) MultiCAS(ops []CASOp, allOrNothing bool) (root []byte, results []bool, err error) {
+	if _, span := db.startSpan(context.Background(), "amdb.MultiCAS"); span != nil {
+		span.SetAttribute(attrBatchSize, len(ops))
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	results = make([]bool, len(ops))
+	batch := NewWriteBatch()
+	var failed *ConditionError
+	for i, op := range ops {
+		current, err := db.Get(op.Key, 0)
+		if errors.Is(err, ErrNotFound) {
+			current, err = nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if (current == nil) != (op.Expected == nil) || !bytes.Equal(current, op.Expected) {
+			if failed == nil {
+				failed = &ConditionError{Key: op.Key, Index: i}
+			}
+			continue
+		}
+		results[i] = true
+		if op.New == nil {
+			batch.Delete(op.Key)
+		} else {
+			batch.Put(op.Key, op.New)
+		}
+	}
+
+	if allOrNothing && failed != nil {
+		return nil, results, failed
+	}
+	if batch.Len() == 0 {
+		root, err = db.rootHash()
+	} else {
+		root, err = db.write(batch)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return root, results, nil
+}
@@ -0,0 +1,125 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "a", "2")
+
+	writes := NewWriteBatch()
+	writes.Put([]byte("b"), []byte("new"))
+	values, root, err := db.Apply([][]byte{[]byte("a"), []byte("missing")}, writes, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]byte{[]byte("1"), nil}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("reads at version 1 = %q, want %q", values, want)
+	}
+	if got := rootOf(t, db); len(root) == 0 || string(got) != string(root) || mustGet(t, db, "b", 0) != "new" {
+		t.Fatalf("writes not committed: root %x, current %x", root, got)
+	}
+}
+
+func TestApplyIfAbortsOnMismatch(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "balance", "10")
+	before, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writes := NewWriteBatch()
+	writes.Put([]byte("balance"), []byte("5"))
+	writes.Put([]byte("log"), []byte("spent 5"))
+	reads := [][]byte{[]byte("balance"), []byte("log")}
+
+	values, root, err := db.ApplyIf(reads, [][]byte{[]byte("9"), nil}, writes)
+	var cond *ConditionError
+	if !errors.Is(err, ErrConditionFailed) || !errors.As(err, &cond) || cond.Index != 0 || string(cond.Key) != "balance" {
+		t.Fatalf("mismatched balance: %v", err)
+	}
+	if root != nil || string(values[0]) != "10" || values[1] != nil {
+		t.Fatalf("aborted apply returned root %x values %q", root, values)
+	}
+	if v, _ := db.CurrentVersion(); v != before {
+		t.Fatalf("aborted apply created version %d", v)
+	}
+
+	// 期望log不存在但其已存在时同样中止
+	mustPut(t, db, "log", "x")
+	if _, _, err := db.ApplyIf(reads, [][]byte{[]byte("10"), nil}, writes); !errors.As(err, &cond) || cond.Index != 1 {
+		t.Fatalf("unexpected existing key: %v", err)
+	}
+
+	if _, _, err := db.ApplyIf(reads, [][]byte{[]byte("10"), []byte("x")}, writes); err != nil {
+		t.Fatal(err)
+	}
+	if mustGet(t, db, "balance", 0) != "5" || mustGet(t, db, "log", 0) != "spent 5" {
+		t.Fatal("matching ApplyIf did not commit")
+	}
+	if _, _, err := db.ApplyIf(reads, nil, writes); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("length mismatch: %v", err)
+	}
+}
+
+func TestMultiCAS(t *testing.T) {
+	ops := func() []CASOp {
+		return []CASOp{
+			{Key: []byte("a"), Expected: []byte("1"), New: []byte("10")}, // 满足
+			{Key: []byte("b"), Expected: []byte("x"), New: []byte("20")}, // 不满足
+			{Key: []byte("c"), Expected: nil, New: []byte("30")},         // 要求不存在，满足
+			{Key: []byte("d"), Expected: []byte("4"), New: nil},          // 满足，删除
+		}
+	}
+	seed := func() *Database {
+		db := openTestDB(t, nil)
+		if _, err := db.BatchPut(map[string][]byte{"a": []byte("1"), "b": []byte("2"), "d": []byte("4")}); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	wantResults := []bool{true, false, true, true}
+
+	db := seed()
+	root, results, err := db.MultiCAS(ops(), true)
+	var cond *ConditionError
+	if !errors.As(err, &cond) || cond.Index != 1 || root != nil {
+		t.Fatalf("all-or-nothing: root %x, %v", root, err)
+	}
+	if !reflect.DeepEqual(results, wantResults) {
+		t.Fatalf("all-or-nothing results %v, want %v", results, wantResults)
+	}
+	if v, _ := db.CurrentVersion(); v != 1 || mustGet(t, db, "a", 0) != "1" {
+		t.Fatal("all-or-nothing wrote despite a failed condition")
+	}
+
+	db = seed()
+	root, results, err = db.MultiCAS(ops(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, wantResults) {
+		t.Fatalf("per-op results %v, want %v", results, wantResults)
+	}
+	if v, _ := db.CurrentVersion(); v != 2 || !bytes.Equal(root, rootOf(t, db)) {
+		t.Fatalf("per-op commit: version %d", v)
+	}
+	if mustGet(t, db, "a", 0) != "10" || mustGet(t, db, "b", 0) != "2" || mustGet(t, db, "c", 0) != "30" {
+		t.Fatal("per-op mode applied the wrong operations")
+	}
+	if _, err := db.Get([]byte("d"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("CAS delete: %v", err)
+	}
+
+	before := rootOf(t, db)
+	root, results, err = db.MultiCAS([]CASOp{{Key: []byte("a"), Expected: []byte("nope"), New: []byte("x")}}, false)
+	if err != nil || results[0] || !bytes.Equal(root, before) {
+		t.Fatalf("no matching ops: %x %v %v", root, results, err)
+	}
+}
@@ -0,0 +1,75 @@
+package amdb
+
+import "sync"
+
+// asyncQueueSize PutAsync队列的容量，队列满时PutAsync阻塞
+const asyncQueueSize = 1024
+
+// PutResult 异步写入的结果
+type PutResult struct {
+	Root []byte
+	Err  error
+}
+
+// asyncWriter 按入队顺序提交PutAsync写入的后台goroutine，首次使用时启动
+type asyncWriter struct {
+	once  sync.Once
+	queue chan asyncPut
+	done  chan struct{}
+}
+
+// asyncPut 队列中的一次写入
+type asyncPut struct {
+	key, value []byte
+	result     chan PutResult
+}
+
+// PutAsync 将写入加入后台队列并立即返回，写入提交后从返回的通道收到一次结果（通道随后关闭）
+// 写入按调用顺序逐个提交，与同步写入一样产生各自的数据库版本；队列已满时阻塞直到有空位。
+// 入队的写入视为进行中的调用：Close和Shutdown会等待它们全部提交后再关闭，关闭开始后的调用立即以ErrClosed结束。
+// 提交前调用方不得修改key和value（启用Options.SafeMode时入队前即复制，不受此限制）
+func (db *Database) PutAsync(key, value []byte) <-chan PutResult {
+	result := make(chan PutResult, 1)
+	if err := db.enterQueued(); err != nil {
+		result <- PutResult{Err: err}
+		close(result)
+		return result
+	}
+	db.async.start(db)
+	db.async.queue <- asyncPut{key: db.owned(key), value: db.owned(value), result: result}
+	return result
+}
+
+// start 启动后台写入goroutine（只启动一次）
+func (w *asyncWriter) start(db *Database) {
+	w.once.Do(func() {
+		w.queue = make(chan asyncPut, asyncQueueSize)
+		w.done = make(chan struct{})
+		go w.run(db)
+	})
+}
+
+// run 逐个提交队列中的写入，直到stop
+func (w *asyncWriter) run(db *Database) {
+	for {
+		select {
+		case req := <-w.queue:
+			db.wmu.Lock()
+			root, err := db.putEntered(req.key, req.value)
+			db.wmu.Unlock()
+			db.leaveQueued()
+			req.result <- PutResult{Root: root, Err: err}
+			close(req.result)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// stop 停止后台写入goroutine；只在关闭时调用，此时入队的写入已全部完成
+func (w *asyncWriter) stop() {
+	w.once.Do(func() {})
+	if w.done != nil {
+		close(w.done)
+	}
+}
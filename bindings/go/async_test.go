@@ -0,0 +1,57 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPutAsync(t *testing.T) {
+	db := openTestDB(t, nil)
+	const n = 200
+	results := make([]<-chan PutResult, n)
+	for i := range results {
+		results[i] = db.PutAsync([]byte(fmt.Sprintf("k%03d", i)), []byte(fmt.Sprint(i)))
+	}
+	var last []byte
+	for i, ch := range results {
+		res, ok := <-ch
+		if !ok || res.Err != nil {
+			t.Fatalf("put %d: %v", i, res.Err)
+		}
+		if _, open := <-ch; open {
+			t.Fatalf("result channel %d not closed", i)
+		}
+		last = res.Root
+	}
+
+	if v, err := db.CurrentVersion(); err != nil || v != n {
+		t.Fatalf("version %d, %v", v, err)
+	}
+	for i := 0; i < n; i++ {
+		if got := mustGet(t, db, fmt.Sprintf("k%03d", i), 0); got != fmt.Sprint(i) {
+			t.Fatalf("k%03d = %q", i, got)
+		}
+	}
+	if root := rootOf(t, db); !bytes.Equal(root, last) {
+		t.Fatalf("final root %x, last async root %x", root, last)
+	}
+}
+
+func TestPutAsyncAfterClose(t *testing.T) {
+	db, err := NewDatabase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := db.PutAsync([]byte("k"), []byte("v"))
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if res := <-pending; res.Err != nil {
+		t.Fatalf("write queued before Close: %v", res.Err)
+	}
+	if res := <-db.PutAsync([]byte("k"), []byte("v")); !errors.Is(res.Err, ErrClosed) {
+		t.Fatalf("PutAsync after Close: %v", res.Err)
+	}
+}
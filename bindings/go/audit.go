@@ -0,0 +1,327 @@
+package amdb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrAuditLogTampered 审计日志的哈希链或序号不连续
+var ErrAuditLogTampered = errors.New("audit log tampered")
+
+// 审计日志格式：每行一条JSON记录，按提交顺序追加
+//
+//	{"seq":n,"timestamp":ns,"op":"put","keyHash":"…","valueHash":"…","resultRoot":"…","prevEntryHash":"…"}
+//
+// seq从1连续递增；timestamp为Unix纳秒；op为put、delete、batch_put或batch_delete（批量写入的每个键一条），
+// 或rewind（Rewind回退版本，keyHash为空、省略valueHash，resultRoot为回退后的根哈希）；
+// keyHash和valueHash为原始键、值的SHA-256（删除时省略valueHash）；resultRoot为操作后的根哈希；
+// prevEntryHash为上一行（不含换行符）的SHA-256，第一条为空。修改任意一行都会使下一行的prevEntryHash失配。
+//
+// 哈希链本身发现不了对最后一条记录的修改或截去末尾的记录，因此每次提交记录后还把最后一条记录的序号和哈希
+// 写入日志旁的头文件（路径为日志路径加auditHeadSuffix），格式为[8字节序号（大端）][32字节SHA-256]。
+// 一次写入的全部记录追加完后先对日志fsync，再写入头文件并fsync：头文件只会落后于日志，不会超前。
+// 头文件与日志在同一位置，能同时改写两者的人仍可伪造日志；需要防范时应另行保存头文件的内容并与之比较
+
+// auditHeadSuffix 审计日志头文件相对日志路径的后缀
+const auditHeadSuffix = ".head"
+
+// auditHeadSize 头文件的长度：8字节序号和32字节哈希
+const auditHeadSize = 8 + sha256.Size
+
+// 审计日志记录的操作
+const (
+	auditPut         = "put"
+	auditDelete      = "delete"
+	auditBatchPut    = "batch_put"
+	auditBatchDelete = "batch_delete"
+	auditRewind      = "rewind"
+)
+
+// auditEntry 审计日志中的一条记录
+type auditEntry struct {
+	Seq           uint64   `json:"seq"`
+	Timestamp     int64    `json:"timestamp"`
+	Op            string   `json:"op"`
+	KeyHash       hexBytes `json:"keyHash"`
+	ValueHash     hexBytes `json:"valueHash,omitempty"`
+	ResultRoot    hexBytes `json:"resultRoot"`
+	PrevEntryHash hexBytes `json:"prevEntryHash"`
+}
+
+// auditLog 追加写入的审计日志（由wmu串行化）
+type auditLog struct {
+	f    *os.File
+	head *os.File
+	seq  uint64
+	prev []byte
+	// committed 头文件中记录的序号
+	committed uint64
+}
+
+// auditTail scanAuditLog读到的日志末尾
+type auditTail struct {
+	// seq、hash 最后一条完整记录的序号和哈希（没有记录时为0和nil）
+	seq  uint64
+	hash []byte
+	// atHead 序号为scanAuditLog的headSeq参数的记录的哈希（headSeq为0时为nil）
+	atHead []byte
+	// size 完整记录占用的字节数
+	size int64
+	// torn 末尾有一行没有换行符的不完整记录（追加时崩溃）
+	torn bool
+}
+
+// openAuditLog 打开（或创建）审计日志，校验已有记录与头文件并从末尾继续，返回是否修复了上次会话崩溃留下的日志
+// 修复只针对崩溃的正常结果：截去末尾不完整的一行；头文件落后于日志时（追加后、写头文件前崩溃）把它推进到最后一条记录。
+// 其他不一致（包括日志比头文件短，或头文件所指的记录被修改）返回ErrAuditLogTampered
+func openAuditLog(path string) (*auditLog, bool, error) {
+	headSeq, headHash, err := readAuditHead(path)
+	if err != nil {
+		return nil, false, err
+	}
+	tail, err := scanAuditLog(path, headSeq)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	if err == nil {
+		err = checkAuditHead(tail, headSeq, headHash, true)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	head, err := os.OpenFile(path+auditHeadSuffix, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	l := &auditLog{f: f, head: head, seq: tail.seq, prev: tail.hash, committed: headSeq}
+	if tail.torn {
+		if err = f.Truncate(tail.size); err == nil {
+			err = f.Sync()
+		}
+	}
+	if err == nil && errors.Is(statErr, fs.ErrNotExist) {
+		err = syncDir(filepath.Dir(path))
+	}
+	if err == nil {
+		err = l.commit()
+	}
+	if err != nil {
+		f.Close()
+		head.Close()
+		return nil, false, err
+	}
+	return l, tail.torn || tail.seq > headSeq, nil
+}
+
+// readAuditHead 读取日志的头文件，头文件不存在时返回0和nil
+func readAuditHead(path string) (uint64, []byte, error) {
+	data, err := os.ReadFile(path + auditHeadSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) != auditHeadSize {
+		return 0, nil, fmt.Errorf("%w: head of %d bytes", ErrAuditLogTampered, len(data))
+	}
+	return wireOrder.Uint64(data), data[8:], nil
+}
+
+// checkAuditHead 核对日志末尾与头文件：序号为headSeq的记录必须存在且哈希与头文件一致，
+// lag为false时还要求它就是最后一条记录且末尾没有不完整的一行
+func checkAuditHead(tail auditTail, headSeq uint64, headHash []byte, lag bool) error {
+	switch {
+	case tail.seq < headSeq:
+		return fmt.Errorf("%w: entry %d: missing, head records %d entries", ErrAuditLogTampered, tail.seq+1, headSeq)
+	case headSeq > 0 && !bytes.Equal(tail.atHead, headHash):
+		return fmt.Errorf("%w: entry %d: does not match head", ErrAuditLogTampered, headSeq)
+	case !lag && tail.seq > headSeq:
+		return fmt.Errorf("%w: entry %d: not recorded in head", ErrAuditLogTampered, headSeq+1)
+	case !lag && tail.torn:
+		return fmt.Errorf("%w: entry %d: truncated", ErrAuditLogTampered, tail.seq+1)
+	}
+	return nil
+}
+
+// append 追加一条记录并写入文件，commit之前不fsync也不更新头文件
+func (l *auditLog) append(op string, keyHash, valueHash, root []byte) error {
+	entry := auditEntry{
+		Seq:           l.seq + 1,
+		Timestamp:     time.Now().UnixNano(),
+		Op:            op,
+		KeyHash:       keyHash,
+		ValueHash:     valueHash,
+		ResultRoot:    root,
+		PrevEntryHash: l.prev,
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	h := sha256.Sum256(line)
+	l.seq, l.prev = entry.Seq, h[:]
+	return nil
+}
+
+// commit 将已追加的记录fsync到磁盘，再把最后一条记录的序号和哈希写入头文件并fsync；没有新记录时不做任何事
+func (l *auditLog) commit() error {
+	if l.seq == l.committed {
+		return nil
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	head := append(wireOrder.AppendUint64(nil, l.seq), l.prev...)
+	if _, err := l.head.WriteAt(head, 0); err != nil {
+		return err
+	}
+	if err := l.head.Sync(); err != nil {
+		return err
+	}
+	if l.committed == 0 {
+		if err := syncDir(filepath.Dir(l.head.Name())); err != nil {
+			return err
+		}
+	}
+	l.committed = l.seq
+	return nil
+}
+
+// close 提交尚未提交的记录并关闭日志文件
+func (l *auditLog) close() error {
+	if l == nil {
+		return nil
+	}
+	err := l.commit()
+	if closeErr := l.f.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := l.head.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// audit 为一次成功提交的变更追加审计记录并提交，key和value为存储形式（未启用审计日志时不做任何事）
+// 变更已经提交，写日志失败时返回的错误不代表变更被撤销
+func (db *Database) audit(op string, key, value, root []byte) error {
+	if err := db.auditAppend(op, key, value, root); err != nil {
+		return err
+	}
+	return db.auditCommit()
+}
+
+// auditAppend 为一次变更追加审计记录但不提交，一次写入的全部记录追加完后调用auditCommit
+func (db *Database) auditAppend(op string, key, value, root []byte) error {
+	if db.auditLog == nil {
+		return nil
+	}
+	// 哈希键模式下存储形式的键即原始键的哈希（配置KeySalt时为HMAC）
+	keyHash := key
+	if !db.hashKeys {
+		h := sha256.Sum256(key)
+		keyHash = h[:]
+	}
+	var valueHash []byte
+	if !isDeleted(value) {
+		entry, err := db.userEntry(kv{key: key, value: value})
+		if err != nil {
+			return err
+		}
+		h := sha256.Sum256(entry.value)
+		valueHash = h[:]
+	}
+	if err := db.auditLog.append(op, keyHash, valueHash, root); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	return nil
+}
+
+// auditCommit 提交已追加的审计记录（见auditLog.commit）
+func (db *Database) auditCommit() error {
+	if db.auditLog == nil {
+		return nil
+	}
+	if err := db.auditLog.commit(); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	return nil
+}
+
+// VerifyAuditLog 校验审计日志的哈希链与头文件：序号从1连续递增，每条记录的prevEntryHash等于上一行的SHA-256，
+// 且最后一条记录的序号和哈希与头文件一致。校验失败时返回包装ErrAuditLogTampered的错误并指明第一处不一致的记录序号。
+// 哈希链发现对已有记录的修改、删除和插入，头文件发现对最后一条记录的修改和截去末尾的记录。
+// 上次会话崩溃后、下一次打开数据库之前，日志末尾可能有不完整的一行或头文件尚未记录的记录，同样报告为不一致；
+// 打开数据库时会修复这两种情况（见OpenInfo.RecoveredAuditLog）
+func VerifyAuditLog(path string) error {
+	headSeq, headHash, err := readAuditHead(path)
+	if err != nil {
+		return err
+	}
+	tail, err := scanAuditLog(path, headSeq)
+	if err != nil {
+		return err
+	}
+	return checkAuditHead(tail, headSeq, headHash, false)
+}
+
+// scanAuditLog 校验审计日志的哈希链，返回日志末尾的信息，并记下序号为headSeq的记录的哈希
+// 末尾没有换行符的一行视为追加时崩溃留下的不完整记录，不做解析
+func scanAuditLog(path string, headSeq uint64) (tail auditTail, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return auditTail{}, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			break
+		}
+		if err != nil {
+			tail.torn = true
+			break
+		}
+		size := int64(len(line))
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+
+		var entry auditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return auditTail{}, fmt.Errorf("%w: entry %d: %v", ErrAuditLogTampered, tail.seq+1, err)
+		}
+		if entry.Seq != tail.seq+1 {
+			return auditTail{}, fmt.Errorf("%w: entry %d: unexpected seq %d", ErrAuditLogTampered, tail.seq+1, entry.Seq)
+		}
+		if !bytes.Equal(entry.PrevEntryHash, tail.hash) {
+			return auditTail{}, fmt.Errorf("%w: entry %d: previous entry hash mismatch", ErrAuditLogTampered, entry.Seq)
+		}
+		h := sha256.Sum256(line)
+		tail.seq, tail.hash = entry.Seq, h[:]
+		tail.size += size
+		if tail.seq == headSeq {
+			tail.atHead = tail.hash
+		}
+	}
+	return tail, nil
+}
@@ -0,0 +1,183 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	db, err := NewDatabaseWithOptions(t.TempDir(), &Options{AuditLogPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "1")
+	if err := db.Delete([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.BatchPut(map[string][]byte{"c": []byte("1"), "d": []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAuditLog(path); err != nil {
+		t.Fatalf("untouched log: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'})
+	if len(lines) != 5 {
+		t.Fatalf("got %d entries, want 5", len(lines))
+	}
+	for i, op := range []string{auditPut, auditPut, auditDelete, auditBatchPut, auditBatchPut} {
+		if !bytes.Contains(lines[i], []byte(`"op":"`+op+`"`)) {
+			t.Errorf("entry %d: %s, want op %s", i+1, lines[i], op)
+		}
+	}
+
+	// 把第2条记录的操作改为delete：该行本身仍是合法JSON，不一致在第3条的prevEntryHash处暴露
+	lines[1] = bytes.Replace(lines[1], []byte(`"op":"put"`), []byte(`"op":"delete"`), 1)
+	if err := os.WriteFile(path, append(bytes.Join(lines, []byte{'\n'}), '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyAuditLog(path)
+	if !errors.Is(err, ErrAuditLogTampered) || !strings.Contains(err.Error(), "entry 3") {
+		t.Fatalf("tampered log: %v", err)
+	}
+}
+
+func TestAuditLogResumesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		db, err := NewDatabaseWithOptions(dir, &Options{AuditLogPath: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustPut(t, db, "k", "v")
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := VerifyAuditLog(path); err != nil {
+		t.Fatalf("log across reopen: %v", err)
+	}
+}
+
+// auditedDB 在dir中写入n条审计记录后关闭，返回日志路径与日志内容
+func auditedDB(t *testing.T, dir string, n int) (string, []byte) {
+	t.Helper()
+	path := filepath.Join(dir, "audit.log")
+	db, err := NewDatabaseWithOptions(filepath.Join(dir, "db"), &Options{AuditLogPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		mustPut(t, db, "k", strings.Repeat("v", i+1))
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func TestAuditLogDetectsTamperedTail(t *testing.T) {
+	for name, tamper := range map[string]func(lines [][]byte) [][]byte{
+		"modify last line": func(lines [][]byte) [][]byte {
+			last := len(lines) - 1
+			lines[last] = bytes.Replace(lines[last], []byte(`"op":"put"`), []byte(`"op":"delete"`), 1)
+			return lines
+		},
+		"truncate last line": func(lines [][]byte) [][]byte {
+			return lines[:len(lines)-1]
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path, data := auditedDB(t, dir, 3)
+			lines := bytes.Split(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'})
+			lines = tamper(lines)
+			if err := os.WriteFile(path, append(bytes.Join(lines, []byte{'\n'}), '\n'), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := VerifyAuditLog(path)
+			if !errors.Is(err, ErrAuditLogTampered) || !strings.Contains(err.Error(), "entry 3") {
+				t.Fatalf("verify: %v", err)
+			}
+			if _, err := NewDatabaseWithOptions(filepath.Join(dir, "db"), &Options{AuditLogPath: path}); !errors.Is(err, ErrAuditLogTampered) {
+				t.Fatalf("open: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuditLogRecoversAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	path, data := auditedDB(t, dir, 2)
+
+	// 追加第3条记录并fsync后、写入头文件前崩溃，之后又在追加第4条时崩溃，只写出半行
+	l, _, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.append(auditPut, make([]byte, 32), nil, make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	l.f.Sync()
+	l.f.Close()
+	l.head.Close()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"seq":4,"timest`))
+	f.Close()
+	if err := VerifyAuditLog(path); !errors.Is(err, ErrAuditLogTampered) {
+		t.Fatalf("verify before recovery: %v", err)
+	}
+
+	db, err := NewDatabaseWithOptions(filepath.Join(dir, "db"), &Options{AuditLogPath: path})
+	if err != nil {
+		t.Fatalf("open after crash: %v", err)
+	}
+	if !db.OpenInfo().RecoveredAuditLog {
+		t.Fatal("recovery not reported")
+	}
+	mustPut(t, db, "k", "after")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAuditLog(path); err != nil {
+		t.Fatalf("verify after recovery: %v", err)
+	}
+	recovered, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSuffix(recovered, []byte{'\n'}), []byte{'\n'})
+	if len(lines) != 4 || !bytes.HasPrefix(recovered, data) || !bytes.Contains(lines[3], []byte(`"seq":4`)) {
+		t.Fatalf("recovered log:\n%s", recovered)
+	}
+
+	db, err = NewDatabaseWithOptions(filepath.Join(dir, "db"), &Options{AuditLogPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if db.OpenInfo().RecoveredAuditLog {
+		t.Fatal("clean log reported as recovered")
+	}
+}
@@ -0,0 +1,163 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBatchWriterCommitted BatchWriter已Commit，不再接受写入
+var ErrBatchWriterCommitted = errors.New("batch writer already committed")
+
+// BatchWriterOptions BatchWriter的自动提交条件，两者都为零时只在批次达到chunkKeys个操作或Commit时提交
+type BatchWriterOptions struct {
+	// MaxBytes 累计的值字节数达到该值时，在当次Put中提交已累计的写入（<=0表示不按大小提交）
+	MaxBytes int
+	// MaxInterval 自第一个未提交的写入起经过该时长后在后台提交（<=0表示不按时间提交）
+	MaxInterval time.Duration
+	// OnFlush 每次提交（含Commit）成功后以提交后的根哈希和提交的操作数调用，可为nil
+	// 调用时持有写入器的锁，回调中不能再调用该BatchWriter的方法
+	OnFlush func(root []byte, ops int)
+}
+
+// BatchWriter 用于持续写入的流式批量写入器，按大小或时间自动分批提交
+// 每批作为一次WriteBatch原子提交，批与批之间不是原子的。批次达到chunkKeys个操作时不论MaxBytes都会提交，
+// 每批的操作数不超过chunkKeys。按大小的提交在触发它的Put中同步完成，
+// 提交期间其他Put等待，从而对写入方形成背压；按时间的提交在后台进行，其错误由之后的Put或Commit返回，
+// 此后写入器不再提交。写入的键值在Put时复制，调用方可复用缓冲区。BatchWriter可被多个goroutine并发使用
+type BatchWriter struct {
+	db   *Database
+	opts BatchWriterOptions
+
+	mu        sync.Mutex
+	batch     *WriteBatch
+	bytes     int
+	timer     *time.Timer
+	root      []byte
+	flushes   int
+	err       error
+	committed bool
+}
+
+// NewBatchWriter 创建按opts自动提交的流式批量写入器
+func (db *Database) NewBatchWriter(opts BatchWriterOptions) *BatchWriter {
+	return &BatchWriter{db: db, opts: opts, batch: NewWriteBatch()}
+}
+
+// Put 加入一次写入，累计的值字节数达到MaxBytes或操作数达到chunkKeys时提交当前批次
+func (w *BatchWriter) Put(key, value []byte) error {
+	return w.add(key, value, false)
+}
+
+// Delete 加入一次删除
+func (w *BatchWriter) Delete(key []byte) error {
+	return w.add(key, nil, true)
+}
+
+// add 将操作加入当前批次，按需启动计时器或提交（累计的值字节数达到MaxBytes或操作数达到chunkKeys时）
+func (w *BatchWriter) add(key, value []byte, del bool) error {
+	if len(key) == 0 {
+		return ErrInvalidArg
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.usable(); err != nil {
+		return err
+	}
+	if del {
+		w.batch.Delete(bytes.Clone(key))
+	} else {
+		w.batch.Put(bytes.Clone(key), bytes.Clone(value))
+		w.bytes += len(value)
+	}
+	if w.batch.Len() >= chunkKeys || (w.opts.MaxBytes > 0 && w.bytes >= w.opts.MaxBytes) {
+		return w.flushLocked()
+	}
+	if w.opts.MaxInterval > 0 && w.timer == nil {
+		batch := w.batch
+		w.timer = time.AfterFunc(w.opts.MaxInterval, func() { w.flushTimed(batch) })
+	}
+	return nil
+}
+
+// Root 返回最近一次提交后的根哈希，尚未提交过时为nil
+func (w *BatchWriter) Root() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.root
+}
+
+// Flushes 返回已成功提交的批次数
+func (w *BatchWriter) Flushes() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flushes
+}
+
+// Pending 返回尚未提交的操作数
+func (w *BatchWriter) Pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.batch.Len()
+}
+
+// Commit 提交剩余的写入并结束写入器，返回最后的根哈希
+// 之前有提交失败时返回该错误且不再提交；没有剩余写入时返回最近一次提交的根哈希（从未提交时为当前根哈希）。
+// 重复调用返回ErrBatchWriterCommitted
+func (w *BatchWriter) Commit() ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.usable(); err != nil {
+		return nil, err
+	}
+	w.committed = true
+	if w.batch.Len() > 0 {
+		if err := w.flushLocked(); err != nil {
+			return nil, err
+		}
+	}
+	if w.root == nil {
+		return w.db.rootHash()
+	}
+	return w.root, nil
+}
+
+// usable 返回写入器不可用的原因（调用方需持有mu）
+func (w *BatchWriter) usable() error {
+	if w.committed {
+		return ErrBatchWriterCommitted
+	}
+	return w.err
+}
+
+// flushTimed 计时器到期时提交batch，batch已被按大小提交时不做任何事
+func (w *BatchWriter) flushTimed(batch *WriteBatch) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.batch != batch || w.err != nil || batch.Len() == 0 {
+		return
+	}
+	w.flushLocked()
+}
+
+// flushLocked 提交当前批次并开始新批次，失败时记录错误（调用方需持有mu）
+func (w *BatchWriter) flushLocked() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	batch := w.batch
+	w.batch, w.bytes = NewWriteBatch(), 0
+	root, err := w.db.Write(batch)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	w.root = root
+	w.flushes++
+	if w.opts.OnFlush != nil {
+		w.opts.OnFlush(root, batch.Len())
+	}
+	return nil
+}
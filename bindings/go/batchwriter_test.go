@@ -0,0 +1,118 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBatchWriterFlushesPastMaxBytes(t *testing.T) {
+	db := openTestDB(t, nil)
+	var roots [][]byte
+	var ops []int
+	w := db.NewBatchWriter(BatchWriterOptions{MaxBytes: 100, OnFlush: func(root []byte, n int) {
+		roots = append(roots, root)
+		ops = append(ops, n)
+	}})
+	value := bytes.Repeat([]byte{'v'}, 30)
+	for i := 0; i < 4; i++ {
+		if err := w.Put([]byte(fmt.Sprintf("k%d", i)), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 第4次写入使累计字节数达到120，触发一次中间提交
+	if w.Flushes() != 1 || w.Pending() != 0 || fmt.Sprint(ops) != "[4]" {
+		t.Fatalf("flushes %d, pending %d, ops %v", w.Flushes(), w.Pending(), ops)
+	}
+	if !bytes.Equal(w.Root(), rootOf(t, db)) || !bytes.Equal(roots[0], w.Root()) {
+		t.Fatal("root of the intermediate flush differs from the database root")
+	}
+	if mustGet(t, db, "k3", 0) != string(value) {
+		t.Fatal("flushed data not readable")
+	}
+
+	if err := w.Put([]byte("k4"), []byte("tail")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Delete([]byte("k0")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get([]byte("k4"), 0); err == nil {
+		t.Fatal("unflushed write visible before Commit")
+	}
+	root, err := w.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, rootOf(t, db)) || len(roots) != 2 || fmt.Sprint(ops) != "[4 2]" {
+		t.Fatalf("final commit: flushes %v", ops)
+	}
+	for i := 1; i <= 4; i++ {
+		if _, err := db.Get([]byte(fmt.Sprintf("k%d", i)), 0); err != nil {
+			t.Fatalf("k%d after Commit: %v", i, err)
+		}
+	}
+	if _, err := db.Get([]byte("k0"), 0); err == nil {
+		t.Fatal("deleted key still present")
+	}
+	if _, err := w.Commit(); err != ErrBatchWriterCommitted {
+		t.Fatalf("second Commit: %v", err)
+	}
+	if err := w.Put([]byte("k"), nil); err != ErrBatchWriterCommitted {
+		t.Fatalf("Put after Commit: %v", err)
+	}
+}
+
+func TestBatchWriterCapsBatchAtChunkKeys(t *testing.T) {
+	db := openTestDB(t, nil)
+	w := db.NewBatchWriter(BatchWriterOptions{})
+	const n = 2*chunkKeys + 10
+	for i := 0; i < n; i++ {
+		if err := w.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.Flushes() != 2 || w.Pending() != 10 {
+		t.Fatalf("flushes %d, pending %d", w.Flushes(), w.Pending())
+	}
+	if _, err := w.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	history, err := db.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, h := range history {
+		if h.KeysChanged > chunkKeys {
+			t.Fatalf("version %d committed %d keys", h.Version, h.KeysChanged)
+		}
+		total += h.KeysChanged
+	}
+	if total != n || len(history) != 3 {
+		t.Fatalf("%d keys in %d versions", total, len(history))
+	}
+}
+
+func TestBatchWriterFlushesAfterInterval(t *testing.T) {
+	db := openTestDB(t, nil)
+	flushed := make(chan []byte, 1)
+	w := db.NewBatchWriter(BatchWriterOptions{MaxInterval: 20 * time.Millisecond, OnFlush: func(root []byte, _ int) {
+		flushed <- root
+	}})
+	if err := w.Put([]byte("k"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case root := <-flushed:
+		if !bytes.Equal(root, rootOf(t, db)) || mustGet(t, db, "k", 0) != "v" {
+			t.Fatal("timed flush did not commit the write")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no flush after MaxInterval")
+	}
+	if root, err := w.Commit(); err != nil || !bytes.Equal(root, rootOf(t, db)) {
+		t.Fatalf("Commit with nothing pending: %v", err)
+	}
+}
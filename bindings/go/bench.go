@@ -0,0 +1,175 @@
+package amdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// BenchConfig Benchmark的负载配置，零值字段使用括号中的默认值
+type BenchConfig struct {
+	// Duration 计时阶段的持续时间（1秒）
+	Duration time.Duration
+	// Keys 键空间大小，计时前预先写入全部键（1000）
+	Keys int
+	// KeySize 键的字节数，至少为4（16）
+	KeySize int
+	// ValueSize 值的字节数（100）
+	ValueSize int
+	// PutWeight、GetWeight、DeleteWeight 三种操作的相对比例，全为0时使用1:1:0
+	PutWeight    int
+	GetWeight    int
+	DeleteWeight int
+	// Seed 随机数种子（0表示按当前时间取种子），相同种子产生相同的操作序列
+	Seed int64
+}
+
+// BenchLatency 一类操作的延迟分布
+type BenchLatency struct {
+	Count uint64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// BenchResult Benchmark的测量结果
+type BenchResult struct {
+	// Duration 计时阶段的实际耗时
+	Duration time.Duration
+	// Ops 完成的操作总数
+	Ops uint64
+	// OpsPerSecond 吞吐量
+	OpsPerSecond float64
+	// Latency 全部操作的延迟分布
+	Latency BenchLatency
+	// Put、Get、Delete 各类操作的延迟分布
+	Put    BenchLatency
+	Get    BenchLatency
+	Delete BenchLatency
+}
+
+// Benchmark 按cfg运行随机Put/Get/Delete混合负载，返回吞吐量与延迟分位数，用于在目标硬件上评估性能
+// 负载运行在一次性的临时数据库中（使用本句柄的选项，审计日志和维护回调除外，目录位于Options.TempDir下），
+// 结束时删除，不会在本数据库中留下任何版本。操作在调用方goroutine中串行执行，测量的是单线程延迟；
+// Get与Delete命中已删除的键计为正常完成的操作
+func (db *Database) Benchmark(cfg BenchConfig) (BenchResult, error) {
+	if db.isClosing() {
+		return BenchResult{}, ErrClosed
+	}
+	cfg = cfg.withDefaults()
+	if cfg.KeySize < 4 || cfg.Keys < 1 || cfg.ValueSize < 0 ||
+		cfg.PutWeight < 0 || cfg.GetWeight < 0 || cfg.DeleteWeight < 0 {
+		return BenchResult{}, ErrInvalidArg
+	}
+
+	opts := db.opts
+	opts.AuditLogPath = ""
+	opts.OnMaintenance = nil
+	bench, err := openTemp(&opts)
+	if err != nil {
+		return BenchResult{}, err
+	}
+	defer bench.Close()
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	keys := make([][]byte, cfg.Keys)
+	preload := make(map[string][]byte, cfg.Keys)
+	for i := range keys {
+		key := make([]byte, cfg.KeySize)
+		rng.Read(key[:cfg.KeySize-4])
+		binary.BigEndian.PutUint32(key[cfg.KeySize-4:], uint32(i))
+		keys[i] = key
+		preload[string(key)] = make([]byte, cfg.ValueSize)
+	}
+	if _, err := bench.BatchPut(preload); err != nil {
+		return BenchResult{}, err
+	}
+
+	value := make([]byte, cfg.ValueSize)
+	total := cfg.PutWeight + cfg.GetWeight + cfg.DeleteWeight
+	var puts, gets, deletes []time.Duration
+	start := time.Now()
+	deadline := start.Add(cfg.Duration)
+	for time.Now().Before(deadline) {
+		key := keys[rng.Intn(len(keys))]
+		op := rng.Intn(total)
+		opStart := time.Now()
+		switch {
+		case op < cfg.PutWeight:
+			rng.Read(value)
+			_, err = bench.Put(key, value)
+			puts = append(puts, time.Since(opStart))
+		case op < cfg.PutWeight+cfg.GetWeight:
+			_, err = bench.Get(key, 0)
+			gets = append(gets, time.Since(opStart))
+		default:
+			err = bench.Delete(key)
+			deletes = append(deletes, time.Since(opStart))
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return BenchResult{}, err
+		}
+	}
+	elapsed := time.Since(start)
+
+	all := make([]time.Duration, 0, len(puts)+len(gets)+len(deletes))
+	all = append(append(append(all, puts...), gets...), deletes...)
+	result := BenchResult{
+		Duration: elapsed,
+		Ops:      uint64(len(all)),
+		Latency:  latencyOf(all),
+		Put:      latencyOf(puts),
+		Get:      latencyOf(gets),
+		Delete:   latencyOf(deletes),
+	}
+	if elapsed > 0 {
+		result.OpsPerSecond = float64(result.Ops) / elapsed.Seconds()
+	}
+	return result, nil
+}
+
+// withDefaults 将未设置的字段替换为默认值
+func (c BenchConfig) withDefaults() BenchConfig {
+	if c.Duration <= 0 {
+		c.Duration = time.Second
+	}
+	if c.Keys == 0 {
+		c.Keys = 1000
+	}
+	if c.KeySize == 0 {
+		c.KeySize = 16
+	}
+	if c.ValueSize == 0 {
+		c.ValueSize = 100
+	}
+	if c.PutWeight == 0 && c.GetWeight == 0 && c.DeleteWeight == 0 {
+		c.PutWeight, c.GetWeight = 1, 1
+	}
+	return c
+}
+
+// latencyOf 计算samples的延迟分布（会对samples排序）
+func latencyOf(samples []time.Duration) BenchLatency {
+	if len(samples) == 0 {
+		return BenchLatency{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	at := func(p float64) time.Duration {
+		return samples[int(p*float64(len(samples)-1))]
+	}
+	return BenchLatency{
+		Count: uint64(len(samples)),
+		P50:   at(0.50),
+		P90:   at(0.90),
+		P99:   at(0.99),
+		Max:   samples[len(samples)-1],
+	}
+}
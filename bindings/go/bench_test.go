@@ -0,0 +1,47 @@
+package amdb
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBenchmark(t *testing.T) {
+	scratch := t.TempDir()
+	db := openTestDB(t, &Options{TempDir: scratch})
+	mustPut(t, db, "k", "v")
+
+	res, err := db.Benchmark(BenchConfig{
+		Duration:     200 * time.Millisecond,
+		Keys:         50,
+		PutWeight:    2,
+		GetWeight:    2,
+		DeleteWeight: 1,
+		Seed:         1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Ops == 0 || res.OpsPerSecond <= 0 || res.Duration < 200*time.Millisecond {
+		t.Fatalf("implausible result %+v", res)
+	}
+	if res.Put.Count+res.Get.Count+res.Delete.Count != res.Ops || res.Put.Count == 0 || res.Get.Count == 0 {
+		t.Fatalf("per-op counts %d+%d+%d, total %d", res.Put.Count, res.Get.Count, res.Delete.Count, res.Ops)
+	}
+	if l := res.Latency; l.P50 <= 0 || l.P50 > l.P90 || l.P90 > l.P99 || l.P99 > l.Max {
+		t.Fatalf("latency percentiles out of order: %+v", l)
+	}
+
+	// 负载在临时数据库中运行：本库不变，临时目录已删除
+	if v, err := db.CurrentVersion(); err != nil || v != 1 {
+		t.Fatalf("benchmark changed the database: version %d, %v", v, err)
+	}
+	if entries, _ := os.ReadDir(scratch); len(entries) != 0 {
+		t.Fatalf("benchmark left %v in TempDir", entries)
+	}
+
+	if _, err := db.Benchmark(BenchConfig{KeySize: 2}); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("KeySize 2: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package amdb
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// bloomMinCapacity 过滤器的最小容量（键数）
+const bloomMinCapacity = 1024
+
+// bloomFilter 最新版本存活键的布隆过滤器
+// 只可能误报（键不存在却判定可能存在），不会漏报已写入的键。
+// 删除无法从过滤器中移除，只记为过期，过期键过多或键数超过容量时重建。
+// 所有方法对nil接收者安全，nil表示未启用过滤器。
+type bloomFilter struct {
+	bitsPerKey int
+
+	mu       sync.RWMutex
+	bits     []uint64
+	m        uint64 // 位数
+	k        int    // 哈希函数个数
+	set      uint64 // 已置位的位数
+	keys     int    // 已加入的键数（含已删除）
+	stale    int    // 已删除但仍在过滤器中的键数
+	capacity int
+
+	negatives      atomic.Uint64
+	falsePositives atomic.Uint64
+}
+
+// newBloomFilter 创建每键bitsPerKey位的过滤器，并加入keys
+func newBloomFilter(bitsPerKey int, keys [][]byte) *bloomFilter {
+	f := &bloomFilter{bitsPerKey: bitsPerKey}
+	f.reset(keys)
+	return f
+}
+
+// bloomHashes 键的两个基础哈希值，第i个哈希为h1+i*h2
+func bloomHashes(key []byte) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write(key)
+	h1 := h.Sum64()
+	h2 := h1>>33 | h1<<31 | 1
+	return h1, h2
+}
+
+// reset 按当前键数重新分配位数组并加入keys（调用方需持有写锁或独占f）
+func (f *bloomFilter) reset(keys [][]byte) {
+	f.resetWithCapacity(keys, 2*len(keys))
+}
+
+// resetWithCapacity 以至少capacity个键的容量重新分配位数组并加入keys（调用方需持有写锁或独占f）
+func (f *bloomFilter) resetWithCapacity(keys [][]byte, capacity int) {
+	f.capacity = capacity
+	if f.capacity < bloomMinCapacity {
+		f.capacity = bloomMinCapacity
+	}
+	f.m = uint64(f.capacity * f.bitsPerKey)
+	f.bits = make([]uint64, (f.m+63)/64)
+	f.k = int(math.Round(float64(f.bitsPerKey) * math.Ln2))
+	if f.k < 1 {
+		f.k = 1
+	}
+	if f.k > 30 {
+		f.k = 30
+	}
+	f.set, f.keys, f.stale = 0, 0, 0
+	for _, key := range keys {
+		f.addLocked(key)
+	}
+}
+
+// addLocked 加入一个键（调用方需持有写锁）
+func (f *bloomFilter) addLocked(key []byte) {
+	h1, h2 := bloomHashes(key)
+	for i := 0; i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if f.bits[word]&mask == 0 {
+			f.bits[word] |= mask
+			f.set++
+		}
+	}
+	f.keys++
+}
+
+// add 加入写入的键，返回是否需要重建
+func (f *bloomFilter) add(keys ...[]byte) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		f.addLocked(key)
+	}
+	return f.keys > f.capacity
+}
+
+// remove 记录一个已删除的键，返回是否需要重建
+func (f *bloomFilter) remove() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stale++
+	return f.stale > f.keys/2
+}
+
+// rebuild 用最新的存活键重建过滤器
+func (f *bloomFilter) rebuild(keys [][]byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reset(keys)
+}
+
+// needsCapacity 报告再加入extra个键是否会超出容量而触发重建
+func (f *bloomFilter) needsCapacity(extra uint64) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return uint64(f.keys)+extra > uint64(f.capacity)
+}
+
+// reserve 用最新的存活键重建过滤器，并为之后再加入的extra个键预留容量
+func (f *bloomFilter) reserve(keys [][]byte, extra int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resetWithCapacity(keys, 2*(len(keys)+extra))
+}
+
+// mayContain 判断键是否可能存在：false表示一定不存在
+func (f *bloomFilter) mayContain(key []byte) bool {
+	if f == nil {
+		return true
+	}
+	h1, h2 := bloomHashes(key)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for i := 0; i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		if f.bits[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			f.negatives.Add(1)
+			return false
+		}
+	}
+	return true
+}
+
+// falsePositiveRate 按当前置位比例估算的误报率
+func (f *bloomFilter) falsePositiveRate() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return math.Pow(float64(f.set)/float64(f.m), float64(f.k))
+}
+
+// bloomCheck 读取最新版本前查询过滤器，返回false时键一定不存在
+// 过滤器只覆盖最新版本的存活键，历史版本不经过过滤器
+func (db *Database) bloomCheck(key []byte, version uint32) bool {
+	return version != 0 || db.bloom.mayContain(key)
+}
+
+// bloomMiss 过滤器判定可能存在但实际不存在时调用，用于统计误报
+func (db *Database) bloomMiss(version uint32) {
+	if version == 0 && db.bloom != nil {
+		db.bloom.falsePositives.Add(1)
+	}
+}
+
+// bloomAdd 将写入的键加入过滤器（调用方需持有wmu）
+func (db *Database) bloomAdd(keys ...[]byte) {
+	if db.bloom.add(keys...) {
+		db.bloomRebuild()
+	}
+}
+
+// bloomRemove 记录删除的键（调用方需持有wmu）
+func (db *Database) bloomRemove() {
+	if db.bloom.remove() {
+		db.bloomRebuild()
+	}
+}
+
+// bloomRebuild 从引擎读取最新状态重建过滤器（调用方需持有wmu）
+// 读取失败时保留旧过滤器：旧过滤器包含所有已写入的键，只是误报率更高
+func (db *Database) bloomRebuild() {
+	keys, err := db.liveKeys()
+	if err != nil {
+		return
+	}
+	db.bloom.rebuild(keys)
+}
+
+// liveKeys 返回最新版本的全部存活键
+func (db *Database) liveKeys() ([][]byte, error) {
+	items, err := db.stateAt(0)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([][]byte, 0, len(items))
+	for _, item := range items {
+		if !isDeleted(item.value) {
+			keys = append(keys, item.key)
+		}
+	}
+	return keys, nil
+}
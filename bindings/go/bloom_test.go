@@ -0,0 +1,83 @@
+package amdb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilterNoFalseNegatives(t *testing.T) {
+	db := openTestDB(t, &Options{BloomFilterBits: 10})
+	items := make(map[string][]byte, 2000)
+	for i := 0; i < 2000; i++ {
+		items[fmt.Sprintf("present-%d", i)] = []byte("v")
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "single", "v")
+
+	for k := range items {
+		if !db.bloom.mayContain([]byte(k)) {
+			t.Fatalf("filter rejects present key %s", k)
+		}
+	}
+	if ok, err := db.Has([]byte("single")); err != nil || !ok {
+		t.Fatalf("has single: %v, %v", ok, err)
+	}
+
+	for i := 0; i < 2000; i++ {
+		if _, err := db.Get([]byte(fmt.Sprintf("absent-%d", i)), 0); !errors.Is(err, ErrNotFound) {
+			t.Fatal(err)
+		}
+	}
+	s := db.Stats()
+	if s.BloomFilterBits != 10 || s.BloomFilterKeys != 2001 {
+		t.Fatalf("stats %+v", s)
+	}
+	// 10位每键的误报率约1%，绝大多数不存在的键由过滤器直接拒绝
+	if s.BloomFilterNegatives < 1900 || s.BloomFilterNegatives+s.BloomFilterFalsePositives != 2000 {
+		t.Fatalf("negatives %d, false positives %d", s.BloomFilterNegatives, s.BloomFilterFalsePositives)
+	}
+	if s.BloomFilterFalsePositiveRate <= 0 || s.BloomFilterFalsePositiveRate > 0.05 {
+		t.Fatalf("false positive rate %v", s.BloomFilterFalsePositiveRate)
+	}
+}
+
+func TestBloomFilterAfterDeleteAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabaseWithOptions(dir, &Options{BloomFilterBits: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+	if err := db.Delete([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get([]byte("a"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted key: %v", err)
+	}
+	// 删除后重新写入的键不会被拒绝
+	mustPut(t, db, "a", "3")
+	if got := mustGet(t, db, "a", 0); got != "3" {
+		t.Fatalf("a: %q", got)
+	}
+	// 历史版本的读取不经过滤器
+	if got := mustGet(t, db, "a", 1); got != "1" {
+		t.Fatalf("a@1: %q", got)
+	}
+	db.Close()
+
+	// 打开时由存活键重建过滤器
+	db, err = NewDatabaseWithOptions(dir, &Options{BloomFilterBits: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, k := range []string{"a", "b"} {
+		if ok, err := db.Has([]byte(k)); err != nil || !ok {
+			t.Fatalf("has %s after reopen: %v, %v", k, ok, err)
+		}
+	}
+}
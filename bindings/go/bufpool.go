@@ -0,0 +1,27 @@
+package amdb
+
+// valueBuffer 返回长度为n的值缓冲区
+// 配置了ValueBufferPool时优先取用池中容量足够的缓冲区；容量不足或类型不符的元素直接丢弃
+func (db *Database) valueBuffer(n int) []byte {
+	if db.valuePool != nil {
+		if p, ok := db.valuePool.Get().(*[]byte); ok && p != nil && cap(*p) >= n {
+			return (*p)[:n]
+		}
+	}
+	return make([]byte, n)
+}
+
+// ReleaseValue 将Get、GetWithOptions或GetLimited返回的值缓冲区归还ValueBufferPool
+//
+// 所有权约定：读取方法返回的值归调用方所有，不调用ReleaseValue时与普通切片无异，由GC回收；
+// 调用ReleaseValue即交还所有权，之后调用方及其持有的任何子切片都不得再读写该缓冲区，
+// 否则可能看到其他请求写入的数据。同一缓冲区只能归还一次，
+// 也不应归还来自其他方法（迭代器、快照等）或自行分配的切片。
+// 未配置ValueBufferPool或value为空时不做任何事
+func (db *Database) ReleaseValue(value []byte) {
+	if db.valuePool == nil || cap(value) == 0 {
+		return
+	}
+	buf := value[:0]
+	db.valuePool.Put(&buf)
+}
@@ -0,0 +1,59 @@
+package amdb
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestValueBufferPoolReuse(t *testing.T) {
+	var allocs atomic.Int64
+	pool := &sync.Pool{New: func() interface{} {
+		allocs.Add(1)
+		buf := make([]byte, 0, 64)
+		return &buf
+	}}
+	db := openTestDB(t, &Options{ValueBufferPool: pool})
+	for i := 0; i < 4; i++ {
+		mustPut(t, db, fmt.Sprint("k", i), strings.Repeat(fmt.Sprint(i), 10+i))
+	}
+
+	const rounds = 100
+	reused := 0
+	var last *byte
+	for r := 0; r < rounds; r++ {
+		i := r % 4
+		value, err := db.Get([]byte(fmt.Sprint("k", i)), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := strings.Repeat(fmt.Sprint(i), 10+i); string(value) != want {
+			t.Fatalf("round %d: %q, want %q", r, value, want)
+		}
+		if &value[:1][0] == last {
+			reused++
+		}
+		last = &value[:1][0]
+		db.ReleaseValue(value)
+	}
+	// sync.Pool可能随时丢弃元素，只要求大多数读取复用了缓冲区
+	if n := allocs.Load(); n > rounds/2 || reused < rounds/2 {
+		t.Fatalf("%d allocations and %d reuses in %d reads", n, reused, rounds)
+	}
+}
+
+func TestValueBufferPoolTooSmall(t *testing.T) {
+	pool := &sync.Pool{New: func() interface{} {
+		buf := make([]byte, 0, 4)
+		return &buf
+	}}
+	db := openTestDB(t, &Options{ValueBufferPool: pool})
+	mustPut(t, db, "k", "longer than four bytes")
+	if got := mustGet(t, db, "k", 0); got != "longer than four bytes" {
+		t.Fatalf("k: %q", got)
+	}
+	// 未配置池时ReleaseValue不做任何事
+	openTestDB(t, nil).ReleaseValue([]byte("x"))
+}
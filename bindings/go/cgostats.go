@@ -0,0 +1,49 @@
+package amdb
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CGOStats CGO边界调用统计
+type CGOStats struct {
+	// Calls 经由本句柄进入C API的调用次数（不含释放内存的调用和打开时的初始化）
+	Calls uint64
+	// Duration 上述调用在C层累计花费的时间
+	Duration time.Duration
+}
+
+// cgoCounter CGO调用计数器，nil表示未启用统计
+type cgoCounter struct {
+	calls atomic.Uint64
+	nanos atomic.Int64
+}
+
+// cgoStart 在调用C API前调用，未启用统计时返回零值
+func (db *Database) cgoStart() time.Time {
+	if db.cgo == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// cgoEnd 在C API返回后调用，记录一次调用及其耗时
+func (db *Database) cgoEnd(start time.Time) {
+	if db.cgo == nil {
+		return
+	}
+	db.cgo.calls.Add(1)
+	db.cgo.nanos.Add(int64(time.Since(start)))
+}
+
+// CGOStats 返回CGO调用统计（需启用Options.CollectCGOStats，否则为零值）
+// 一次公开操作可能包含多次C调用，例如读取历史版本时需要先加载版本时间线
+func (db *Database) CGOStats() CGOStats {
+	if db.cgo == nil {
+		return CGOStats{}
+	}
+	return CGOStats{
+		Calls:    db.cgo.calls.Load(),
+		Duration: time.Duration(db.cgo.nanos.Load()),
+	}
+}
@@ -0,0 +1,37 @@
+package amdb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCGOStatsCountsCalls(t *testing.T) {
+	db := openTestDB(t, &Options{CollectCGOStats: true})
+	base := db.CGOStats().Calls
+
+	const puts, gets = 5, 7
+	for i := 0; i < puts; i++ {
+		mustPut(t, db, fmt.Sprint(i), "v")
+	}
+	if got := db.CGOStats().Calls - base; got != puts {
+		t.Fatalf("%d puts made %d calls", puts, got)
+	}
+	for i := 0; i < gets; i++ {
+		mustGet(t, db, fmt.Sprint(i%puts), 0)
+	}
+	s := db.CGOStats()
+	if got := s.Calls - base; got != puts+gets {
+		t.Fatalf("%d operations made %d calls", puts+gets, got)
+	}
+	if s.Duration <= 0 {
+		t.Fatalf("duration %v", s.Duration)
+	}
+}
+
+func TestCGOStatsDisabled(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "k", "v")
+	if s := db.CGOStats(); s != (CGOStats{}) {
+		t.Fatalf("stats without CollectCGOStats: %+v", s)
+	}
+}
@@ -0,0 +1,114 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ContentChecksum 返回数据库版本version（0表示当前版本）中全部存活键值的内容校验和
+// 与Merkle根不同，校验和只取决于键值内容，与树结构、哈希键模式和树的哈希算法无关，
+// 可用于与非amdb数据源比较。计算方式（整数均为大端）：
+//
+//	SHA-256( 按原始键字典序拼接每个键值对的 [4字节长度][键][4字节长度][值] )
+//
+// 已删除的键不计入；没有存活键时为SHA-256空串
+func (db *Database) ContentChecksum(version uint32) ([]byte, error) {
+	state, err := db.stateAt(version)
+	if err != nil {
+		return nil, err
+	}
+	items, err := db.userEntries(liveRange(state, nil, nil, bytes.Compare))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return bytes.Compare(items[i].key, items[j].key) < 0 })
+
+	h := sha256.New()
+	var buf []byte
+	for _, item := range items {
+		buf = appendBytes32(buf[:0], item.key)
+		buf = appendBytes32(buf, item.value)
+		h.Write(buf)
+	}
+	return h.Sum(nil), nil
+}
+
+// checksumFileExts FileChecksum计入的引擎数据与索引文件：LSM的SSTable、B+树节点与元数据、
+// Merkle树、二级索引和版本文件。WAL每次打开都新建文件，元数据文件database.amdb在打开时重写，
+// 锁文件、标记文件、审计日志与统计文件也会随会话变化，均不计入
+var checksumFileExts = map[string]bool{
+	".sst":  true,
+	".bpt":  true,
+	".meta": true,
+	".mpt":  true,
+	".idx":  true,
+	".ver":  true,
+}
+
+// FileChecksum 返回数据目录中引擎数据与索引文件的SHA-256校验和，用于发现存储介质上的位翻转等损坏
+// 校验的是磁盘上的文件字节而非逻辑内容，与ContentChecksum和Merkle根互为补充：记录下的值可在之后重新计算比较。
+// 计入的文件见checksumFileExts，计算方式（整数均为大端）：
+//
+//	SHA-256( 按相对路径（以/分隔）字典序拼接每个文件的 [4字节长度][路径][8字节长度][内容] )
+//
+// 未刷盘的写入只在引擎内存和WAL中，不影响校验和；需要覆盖最新写入时先调用Compact。
+// 没有写入时重新打开数据库不改变校验和。计算期间持有写锁，阻塞经由本句柄的写入
+func (db *Database) FileChecksum() ([]byte, error) {
+	if db.dataDir == "" {
+		return nil, ErrInvalidArg
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	defer db.leave()
+
+	var files []string
+	err := filepath.WalkDir(db.dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && checksumFileExts[filepath.Ext(path)] {
+			rel, err := filepath.Rel(db.dataDir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	var buf []byte
+	for _, rel := range files {
+		f, err := os.Open(filepath.Join(db.dataDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err == nil {
+			buf = appendBytes32(buf[:0], []byte(rel))
+			buf = wireOrder.AppendUint64(buf, uint64(info.Size()))
+			h.Write(buf)
+			var n int64
+			if n, err = io.Copy(h, f); err == nil && n != info.Size() {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,219 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// 大值分块：超过阈值的值按内容定义的边界切分为块，块以SHA-256为名存放在数据目录的chunks子目录中，
+// 引擎中只存放分块清单。相同内容的块只存一份，只有局部不同的大值共享其余的块。
+// 编码与解码位于与C层交换值的最底层，其上的读取、迭代、导出、根哈希与证明都只看到原始的值。
+//
+// 分块清单格式（整数均为大端）：[chunkManifestMagic][8字节原始长度] 之后每块一个32字节SHA-256
+const chunkDirName = "chunks"
+
+// chunkManifestMagic 分块清单的前缀，以该前缀开头的值在启用分块时总是分块存储，以免与清单混淆
+var chunkManifestMagic = []byte("\x00amdb-chunks\x01")
+
+const (
+	// defaultChunkThreshold Options.ChunkThreshold的默认值
+	defaultChunkThreshold = 64 << 10
+	// chunkMinSize、chunkMaxSize 块的最小与最大长度
+	chunkMinSize = 2 << 10
+	chunkMaxSize = 64 << 10
+	// chunkAvgBits 滚动哈希的高chunkAvgBits位全为0时切分，平均块长约为2^chunkAvgBits加最小长度
+	chunkAvgBits = 13
+)
+
+// gearTable Gear滚动哈希的字节映射表，由固定种子生成：切分边界必须在不同进程和版本之间保持一致
+var gearTable = func() (t [256]uint64) {
+	// splitmix64
+	x := uint64(0x616d64622d636463)
+	for i := range t {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		t[i] = z ^ (z >> 31)
+	}
+	return t
+}()
+
+// chunkStore 数据目录中按内容寻址的块存储
+type chunkStore struct {
+	dir string
+	// threshold 分块的最小值长度，0表示只解码已有的清单而不分块写入
+	threshold int
+}
+
+// openChunkStore 按选项打开dataDir的块存储：启用分块时创建目录；未启用但目录已存在时只读打开，
+// 保证之前分块写入的值仍可读取；两者都不满足时返回nil
+func openChunkStore(dataDir string, opts *Options) (*chunkStore, error) {
+	dir := filepath.Join(dataDir, chunkDirName)
+	if !opts.ChunkLargeValues {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return &chunkStore{dir: dir}, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	threshold := opts.ChunkThreshold
+	if threshold <= 0 {
+		threshold = defaultChunkThreshold
+	}
+	return &chunkStore{dir: dir, threshold: threshold}, nil
+}
+
+// isChunkManifest 报告存储的值是否为分块清单
+func isChunkManifest(value []byte) bool {
+	return bytes.HasPrefix(value, chunkManifestMagic)
+}
+
+// encode 返回写入引擎的值：达到阈值的值写入各块后返回其清单，其余原样返回
+// 只读的块存储拒绝以清单前缀开头的值，以免读取时被当作清单
+func (s *chunkStore) encode(value []byte) ([]byte, error) {
+	if s.threshold == 0 {
+		if isChunkManifest(value) {
+			return nil, ErrInvalidArg
+		}
+		return value, nil
+	}
+	if len(value) < s.threshold && !isChunkManifest(value) {
+		return value, nil
+	}
+	cuts := chunkBoundaries(value)
+	manifest := make([]byte, 0, len(chunkManifestMagic)+8+len(cuts)*sha256.Size)
+	manifest = append(manifest, chunkManifestMagic...)
+	manifest = wireOrder.AppendUint64(manifest, uint64(len(value)))
+	start := 0
+	for _, end := range cuts {
+		sum := sha256.Sum256(value[start:end])
+		if err := s.writeChunk(sum[:], value[start:end]); err != nil {
+			return nil, err
+		}
+		manifest = append(manifest, sum[:]...)
+		start = end
+	}
+	return manifest, nil
+}
+
+// decode 将存储的值还原为原始的值，不是清单的值原样返回；块缺失或内容不符时返回ErrCorrupted
+func (s *chunkStore) decode(value []byte) ([]byte, error) {
+	if !isChunkManifest(value) {
+		return value, nil
+	}
+	body := value[len(chunkManifestMagic):]
+	if len(body) < 8 || (len(body)-8)%sha256.Size != 0 {
+		return nil, ErrCorrupted
+	}
+	size := wireOrder.Uint64(body)
+	if size > uint64((len(body)-8)/sha256.Size)*chunkMaxSize {
+		return nil, ErrCorrupted
+	}
+	out := make([]byte, 0, int(size))
+	for hashes := body[8:]; len(hashes) > 0; hashes = hashes[sha256.Size:] {
+		chunk, err := os.ReadFile(s.chunkPath(hashes[:sha256.Size]))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrCorrupted
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sum := sha256.Sum256(chunk); !bytes.Equal(sum[:], hashes[:sha256.Size]) {
+			return nil, ErrCorrupted
+		}
+		out = append(out, chunk...)
+	}
+	if uint64(len(out)) != size {
+		return nil, ErrCorrupted
+	}
+	return out, nil
+}
+
+// chunkPath 返回哈希为sum的块的路径，按哈希的第一个字节分子目录
+func (s *chunkStore) chunkPath(sum []byte) string {
+	name := hex.EncodeToString(sum)
+	return filepath.Join(s.dir, name[:2], name)
+}
+
+// writeChunk 原子写入块并刷入磁盘，已存在的块不再写入
+// 块须在引擎提交引用它的清单之前落盘，否则崩溃后清单可能指向缺失的块
+func (s *chunkStore) writeChunk(sum, data []byte) error {
+	path := s.chunkPath(sum)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	sub := filepath.Dir(path)
+	if _, err := os.Stat(sub); errors.Is(err, fs.ErrNotExist) {
+		if err := os.Mkdir(sub, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		if err := syncDir(s.dir); err != nil {
+			return err
+		}
+	}
+	f, err := os.CreateTemp(sub, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return syncDir(sub)
+}
+
+// chunkBoundaries 以Gear滚动哈希计算data的块边界，返回各块的结束偏移（最后一个为len(data)）
+// 边界只取决于其前面最多64字节的内容，因此局部修改只影响附近的块
+func chunkBoundaries(data []byte) []int {
+	var cuts []int
+	var h uint64
+	start := 0
+	for i, b := range data {
+		h = h<<1 + gearTable[b]
+		size := i + 1 - start
+		if size >= chunkMaxSize || (size >= chunkMinSize && h>>(64-chunkAvgBits) == 0) {
+			cuts = append(cuts, i+1)
+			start, h = i+1, 0
+		}
+	}
+	if start < len(data) || len(data) == 0 {
+		cuts = append(cuts, len(data))
+	}
+	return cuts
+}
+
+// encodeValue 返回写入C层的值，未启用块存储时原样返回
+func (db *Database) encodeValue(value []byte) ([]byte, error) {
+	if db.chunks == nil {
+		return value, nil
+	}
+	return db.chunks.encode(value)
+}
+
+// decodeValue 将从C层读取的值还原为原始的值，未启用块存储时原样返回
+func (db *Database) decodeValue(value []byte) ([]byte, error) {
+	if db.chunks == nil {
+		return value, nil
+	}
+	return db.chunks.decode(value)
+}
@@ -0,0 +1,85 @@
+package amdb
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChunkLargeValuesSharesChunks(t *testing.T) {
+	db := openTestDB(t, &Options{ChunkLargeValues: true})
+	plain := openTestDB(t, nil)
+	const size = 1 << 20
+	first := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(first)
+	// 第二个值只在中间的一段与第一个不同
+	second := bytes.Clone(first)
+	copy(second[size/2:], bytes.Repeat([]byte{0xAB}, 4<<10))
+
+	usage := func() int64 {
+		t.Helper()
+		n, err := db.DiskUsage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	base := usage()
+	for _, d := range []*Database{db, plain} {
+		if _, err := d.Put([]byte("doc1"), first); err != nil {
+			t.Fatal(err)
+		}
+	}
+	afterFirst := usage()
+	if grown := afterFirst - base; grown < size {
+		t.Fatalf("first value grew the directory by %d bytes", grown)
+	}
+	for _, d := range []*Database{db, plain} {
+		if _, err := d.Put([]byte("doc2"), second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if grown := usage() - afterFirst; grown > size/4 {
+		t.Fatalf("near-identical value grew the directory by %d bytes, want most chunks shared", grown)
+	}
+
+	for key, want := range map[string][]byte{"doc1": first, "doc2": second} {
+		got, err := db.Get([]byte(key), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s not reassembled exactly", key)
+		}
+	}
+
+	// 叶子哈希与根哈希按原始的值计算，与不分块的库一致
+	root := rootOf(t, db)
+	if !bytes.Equal(root, rootOf(t, plain)) {
+		t.Fatal("root differs from the unchunked database")
+	}
+	proof, err := db.GetWithProof([]byte("doc2"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(proof.Value, second) || !VerifyProof(root, []byte("doc2"), second, proof) {
+		t.Fatal("proof is not over the original value")
+	}
+
+	// 低于阈值的值不分块
+	chunks := func() int {
+		t.Helper()
+		entries, err := os.ReadDir(filepath.Join(db.dataDir, chunkDirName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+	n := chunks()
+	mustPut(t, db, "small", "v")
+	if chunks() != n || mustGet(t, db, "small", 0) != "v" {
+		t.Fatal("small value was chunked")
+	}
+}
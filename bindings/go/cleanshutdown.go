@@ -0,0 +1,58 @@
+package amdb
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// cleanMarkerName 正常关闭标记文件名
+// Close成功后在数据目录中创建，打开时删除；打开时标记不存在说明上次会话未经Close结束（崩溃或被杀）
+const cleanMarkerName = "CLEAN_SHUTDOWN"
+
+// OpenInfo 打开数据库时获得的信息
+type OpenInfo struct {
+	// CleanShutdown 上一次会话是否经Close或Shutdown正常关闭；新建的数据库为true
+	// 为false时数据可能停留在崩溃前的中间状态，调用方可据此安排校验
+	CleanShutdown bool
+	// RolledBackBatch 打开时是否回退了上次会话中只有部分键落盘的批量写入（见BatchPut），
+	// 为true时该批量写入及其之后的全部版本都已丢弃，数据库处于该批量写入之前的状态
+	RolledBackBatch bool
+	// RecoveredAuditLog 打开时是否修复了上次会话崩溃留下的审计日志（见Options.AuditLogPath）：
+	// 截去了末尾不完整的一行，或把头文件推进到崩溃前已追加但尚未记入头文件的记录
+	RecoveredAuditLog bool
+}
+
+// OpenInfo 返回打开数据库时获得的信息
+func (db *Database) OpenInfo() OpenInfo {
+	return db.openInfo
+}
+
+// consumeCleanMarker 删除数据目录中的正常关闭标记，返回标记原本是否存在
+func consumeCleanMarker(dataDir string) (bool, error) {
+	err := os.Remove(filepath.Join(dataDir, cleanMarkerName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, syncDir(dataDir)
+}
+
+// writeCleanMarker 在数据目录中创建正常关闭标记并刷入磁盘
+func writeCleanMarker(dataDir string) error {
+	f, err := os.OpenFile(filepath.Join(dataDir, cleanMarkerName), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return syncDir(dataDir)
+}
@@ -0,0 +1,48 @@
+package amdb
+
+import "testing"
+
+func TestOpenInfoCleanShutdown(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.OpenInfo().CleanShutdown {
+		t.Fatal("new database reported unclean")
+	}
+	mustPut(t, db, "k", "v")
+	db.Close()
+
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.OpenInfo().CleanShutdown {
+		t.Fatal("reopen after Close reported unclean")
+	}
+	db.Close()
+
+	crashAfterOpen(t, dir)
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.OpenInfo().CleanShutdown {
+		t.Fatal("reopen after crash reported clean")
+	}
+	if got := mustGet(t, db, "k", 0); got != "v" {
+		t.Fatalf("k: %q", got)
+	}
+	db.Close()
+
+	// 下一次正常关闭之后恢复为clean
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if !db.OpenInfo().CleanShutdown {
+		t.Fatal("reopen after recovery reported unclean")
+	}
+}
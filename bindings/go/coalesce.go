@@ -0,0 +1,90 @@
+package amdb
+
+import (
+	"sync"
+	"time"
+)
+
+// coalescer 将并发提交的Put在一个时间窗口内合并为一次C层批量写入
+//
+// 顺序保证：
+//   - 每个Put在返回前已经提交，返回的根哈希为其所在合并批次提交后的根
+//   - 同一批次内同一个键被多次写入时，以最后进入批次的写入为准；
+//     批次之间按形成的先后顺序提交，因此先返回的Put不会被其之前启动的批次覆盖
+//   - 同一批次内不同键之间没有先后之分，它们共享同一个数据库版本
+//
+// 批次达到chunkKeys个键时不再等待窗口结束，立即提交，之后的Put进入新的批次。
+// 批次整体提交失败时，批次内所有Put都返回该错误
+type coalescer struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending *putGroup
+	last    *putGroup // 最近形成的批次，下一个批次在其提交之后提交
+}
+
+// putGroup 一个待提交的合并批次
+type putGroup struct {
+	items map[string][]byte // 存储形式
+	prev  *putGroup         // 先于本批次形成的批次，提交前等待其完成
+	timer *time.Timer
+	done  chan struct{}
+	root  []byte
+	err   error
+}
+
+// submit 将一次写入加入当前批次，等待批次提交后返回其根哈希
+func (c *coalescer) submit(db *Database, key, value []byte) ([]byte, error) {
+	c.mu.Lock()
+	g := c.pending
+	if g == nil {
+		g = &putGroup{items: make(map[string][]byte), prev: c.last, done: make(chan struct{})}
+		c.pending, c.last = g, g
+		g.timer = time.AfterFunc(c.window, func() { c.flush(db, g) })
+	}
+	g.items[string(key)] = value
+	if len(g.items) >= chunkKeys {
+		// 计时器已触发时由其负责提交
+		c.pending = nil
+		if g.timer.Stop() {
+			go c.flush(db, g)
+		}
+	}
+	c.mu.Unlock()
+
+	<-g.done
+	return g.root, g.err
+}
+
+// flush 提交批次并唤醒所有等待者
+func (c *coalescer) flush(db *Database, g *putGroup) {
+	c.mu.Lock()
+	if c.pending == g {
+		c.pending = nil
+	}
+	c.mu.Unlock()
+
+	if g.prev != nil {
+		<-g.prev.done
+		g.prev = nil
+	}
+	db.wmu.Lock()
+	g.root, g.err = db.batchPut(g.items)
+	db.wmu.Unlock()
+	close(g.done)
+}
+
+// coalescedPut 经合并写入器写入键值对
+func (db *Database) coalescedPut(key, value []byte) ([]byte, error) {
+	if db.isClosing() {
+		return nil, ErrClosed
+	}
+	if len(key) == 0 {
+		return nil, ErrInvalidArg
+	}
+	key, value = db.storedEntry(db.owned(key), db.owned(value))
+	if err := db.checkWriteKey(key); err != nil {
+		return nil, err
+	}
+	return db.coalesce.submit(db, key, value)
+}
@@ -0,0 +1,101 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrentPuts 由goroutines个goroutine共写入n个不同的键，返回每次Put得到的根哈希
+func concurrentPuts(t testing.TB, db *Database, goroutines, n int) [][]byte {
+	t.Helper()
+	roots := make([][]byte, n)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < n; i += goroutines {
+				root, err := db.Put([]byte(fmt.Sprintf("k%05d", i)), []byte(fmt.Sprint(i)))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				roots[i] = root
+			}
+		}(g)
+	}
+	wg.Wait()
+	return roots
+}
+
+func TestCoalescedPuts(t *testing.T) {
+	db := openTestDB(t, &Options{CoalesceWindow: 5 * time.Millisecond})
+	const n = 400
+	roots := concurrentPuts(t, db, 16, n)
+
+	current, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current == 0 || current >= n {
+		t.Fatalf("%d puts produced %d versions", n, current)
+	}
+	known := make(map[string]bool)
+	for v := uint32(1); v <= current; v++ {
+		root, err := db.RootHashAtVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		known[string(root)] = true
+	}
+	for i, root := range roots {
+		if !known[string(root)] {
+			t.Fatalf("put %d returned %x, not the root of any version", i, root)
+		}
+		if got := mustGet(t, db, fmt.Sprintf("k%05d", i), 0); got != fmt.Sprint(i) {
+			t.Fatalf("k%05d: %q", i, got)
+		}
+	}
+	if !bytes.Equal(rootOf(t, db), stateRoot(t, db)) {
+		t.Fatal("current root does not match state")
+	}
+}
+
+func TestCoalescedBatchCapped(t *testing.T) {
+	// 窗口远长于写入耗时，批次只会因达到上限而提前提交
+	db := openTestDB(t, &Options{CoalesceWindow: time.Second})
+	const n = 2*chunkKeys + 100
+	start := time.Now()
+	concurrentPuts(t, db, n, n)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("puts took %v", elapsed)
+	}
+
+	history, err := db.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, h := range history {
+		if h.KeysChanged > chunkKeys {
+			t.Fatalf("version %d committed %d keys", h.Version, h.KeysChanged)
+		}
+		total += h.KeysChanged
+	}
+	if total != n || len(history) < 3 {
+		t.Fatalf("%d keys in %d versions", total, len(history))
+	}
+}
+
+func BenchmarkConcurrentPut(b *testing.B) {
+	for _, window := range []time.Duration{0, time.Millisecond} {
+		b.Run(fmt.Sprintf("window=%v", window), func(b *testing.B) {
+			db := openTestDB(b, &Options{CoalesceWindow: window})
+			b.ResetTimer()
+			concurrentPuts(b, db, 64, b.N)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrCommitInProgress BeginCommit时已有未结束的提交组
+	ErrCommitInProgress = errors.New("commit already in progress")
+	// ErrNoCommit EndCommit或AbortCommit时没有进行中的提交组
+	ErrNoCommit = errors.New("no commit in progress")
+)
+
+// commitGroup BeginCommit与EndCommit之间暂存的写入
+type commitGroup struct {
+	mu    sync.Mutex
+	batch *WriteBatch // nil表示没有进行中的提交组
+}
+
+// BeginCommit 开始一个提交组：之后经由本句柄的Put和Delete（来自任意goroutine）先暂存，
+// 由EndCommit作为一次批量写入提交，只产生一个新版本和一个根哈希
+// 这只是版本分组而非事务：提交前暂存的写入对读取不可见，也不隔离其他写入路径
+// （BatchPut、Write、PutAsync等照常立即提交）。组内的Put返回nil根哈希。
+// 已有未结束的提交组时返回ErrCommitInProgress
+func (db *Database) BeginCommit() error {
+	if db.isClosing() {
+		return ErrClosed
+	}
+	g := &db.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.batch != nil {
+		return ErrCommitInProgress
+	}
+	g.batch = NewWriteBatch()
+	return nil
+}
+
+// EndCommit 原子提交BeginCommit以来暂存的全部写入，返回提交后的根哈希
+// 组内没有写入时不产生新版本，返回当前根哈希；没有进行中的提交组时返回ErrNoCommit。
+// 无论提交是否成功提交组都会结束
+func (db *Database) EndCommit() ([]byte, error) {
+	batch, err := db.takeGroup()
+	if err != nil {
+		return nil, err
+	}
+	if batch.Len() == 0 {
+		return db.rootHash()
+	}
+	return db.Write(batch)
+}
+
+// AbortCommit 丢弃BeginCommit以来暂存的全部写入并结束提交组
+// 没有进行中的提交组时返回ErrNoCommit
+func (db *Database) AbortCommit() error {
+	_, err := db.takeGroup()
+	return err
+}
+
+// takeGroup 结束提交组并返回其暂存的批次
+func (db *Database) takeGroup() (*WriteBatch, error) {
+	g := &db.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	batch := g.batch
+	if batch == nil {
+		return nil, ErrNoCommit
+	}
+	g.batch = nil
+	return batch, nil
+}
+
+// grouped 有进行中的提交组时将op（原始形式）暂存到组中并返回true
+// 键在暂存时即校验，键值被复制，调用方返回后可复用缓冲区
+func (db *Database) grouped(op batchOp) (bool, error) {
+	g := &db.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.batch == nil {
+		return false, nil
+	}
+	if len(op.key) == 0 {
+		return true, ErrInvalidArg
+	}
+	if err := db.checkWriteKey(db.storedKey(op.key)); err != nil {
+		return true, err
+	}
+	op.key = bytes.Clone(op.key)
+	if !op.delete {
+		op.value = append([]byte{}, op.value...)
+	}
+	g.batch.ops = append(g.batch.ops, op)
+	return true, nil
+}
@@ -0,0 +1,82 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCommitGroup(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "old", "1")
+
+	if err := db.BeginCommit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.BeginCommit(); !errors.Is(err, ErrCommitInProgress) {
+		t.Fatalf("nested BeginCommit: %v", err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		root, err := db.Put([]byte(k), []byte("v"))
+		if err != nil || root != nil {
+			t.Fatalf("grouped put %q: %x, %v", k, root, err)
+		}
+	}
+	if err := db.Delete([]byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get([]byte("a"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("staged write visible before EndCommit: %v", err)
+	}
+
+	root, err := db.EndCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, err := db.RootsSince(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || roots[0].Version != 2 || !bytes.Equal(roots[0].Root, root) {
+		t.Fatalf("RootsSince(2) = %+v, want one root %x", roots, root)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if got := mustGet(t, db, k, 2); got != "v" {
+			t.Fatalf("%s = %q", k, got)
+		}
+	}
+	if _, err := db.Get([]byte("old"), 2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("grouped delete: %v", err)
+	}
+	if _, err := db.EndCommit(); !errors.Is(err, ErrNoCommit) {
+		t.Fatalf("EndCommit without BeginCommit: %v", err)
+	}
+}
+
+func TestCommitGroupEmptyAndAbort(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "k", "1")
+	before := rootOf(t, db)
+
+	if err := db.BeginCommit(); err != nil {
+		t.Fatal(err)
+	}
+	root, err := db.EndCommit()
+	if err != nil || !bytes.Equal(root, before) {
+		t.Fatalf("empty group: %x, %v; want %x", root, err, before)
+	}
+
+	if err := db.BeginCommit(); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "k", "2")
+	if err := db.AbortCommit(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.CurrentVersion(); err != nil || v != 1 || mustGet(t, db, "k", 0) != "1" {
+		t.Fatalf("aborted group changed the database: version %d, %v", v, err)
+	}
+	if err := db.AbortCommit(); !errors.Is(err, ErrNoCommit) {
+		t.Fatalf("AbortCommit without BeginCommit: %v", err)
+	}
+}
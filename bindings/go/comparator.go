@@ -0,0 +1,73 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// comparatorFileName 数据目录中记录创建时比较器名称的文件
+// 比较器本身是函数，无法持久化，重新打开时只能按名称判断是否与创建时一致
+const comparatorFileName = "COMPARATOR"
+
+// ErrComparatorMismatch 打开时的比较器与创建数据库时的不一致
+var ErrComparatorMismatch = errors.New("comparator does not match the one the database was created with")
+
+// openComparator 按选项确定dataDir的键比较函数：数据目录中记录了比较器名称时，选项须给出同名的比较器；
+// 否则只有空数据库（empty报告数据库是否为空）可以启用比较器，并记录其名称；未设置比较器时返回nil（字节序）
+func openComparator(dataDir string, opts *Options, empty func() (bool, error)) (func(a, b []byte) int, error) {
+	path := filepath.Join(dataDir, comparatorFileName)
+	name, err := os.ReadFile(path)
+	if err == nil {
+		if opts.Comparator == nil || opts.ComparatorName != string(name) {
+			return nil, ErrComparatorMismatch
+		}
+		return opts.Comparator, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if opts.Comparator == nil {
+		return nil, nil
+	}
+	if opts.ComparatorName == "" || opts.HashKeys || len(opts.KeySalt) > 0 {
+		// 哈希键模式下迭代按哈希后的键进行，比较器无从作用于原始键
+		return nil, ErrInvalidArg
+	}
+	ok, err := empty()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// 已有数据按字节序创建，相当于以另一个比较器创建
+		return nil, ErrComparatorMismatch
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.WriteString(opts.ComparatorName)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = syncDir(dataDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return opts.Comparator, nil
+}
+
+// keyOrder 返回迭代与范围查询使用的键比较函数
+func (db *Database) keyOrder() func(a, b []byte) int {
+	if db.compare == nil {
+		return bytes.Compare
+	}
+	return db.compare
+}
@@ -0,0 +1,114 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// semverCompare 按各段的数值比较形如1.2.10的版本号
+func semverCompare(a, b []byte) int {
+	as, bs := strings.Split(string(a), "."), strings.Split(string(b), ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, _ := strconv.Atoi(as[i])
+		y, _ := strconv.Atoi(bs[i])
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(as) - len(bs)
+}
+
+func TestComparatorOrdersIteration(t *testing.T) {
+	dir := t.TempDir()
+	opts := &Options{Comparator: semverCompare, ComparatorName: "semver"}
+	db, err := NewDatabaseWithOptions(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := openTestDB(t, nil)
+	for _, v := range []string{"1.10.0", "1.2.10", "1.2.9", "2.0.0", "1.2.0"} {
+		mustPut(t, db, v, "r"+v)
+		mustPut(t, plain, v, "r"+v)
+	}
+
+	keys := func(it *Iterator, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, entry := range collect(t, it) {
+			got = append(got, entry[:strings.IndexByte(entry, '=')])
+		}
+		return fmt.Sprint(got)
+	}
+	if got := keys(db.NewIterator()); got != "[1.2.0 1.2.9 1.2.10 1.10.0 2.0.0]" {
+		t.Fatalf("iteration order %s", got)
+	}
+	if got := keys(db.NewRangeIterator([]byte("1.2.9"), []byte("1.10.0"), 0)); got != "[1.2.9 1.2.10]" {
+		t.Fatalf("range in comparator order %s", got)
+	}
+	if got := keys(db.NewPrefixIterator([]byte("1.2."), 0)); got != "[1.2.0 1.2.9 1.2.10]" {
+		t.Fatalf("prefix iteration %s", got)
+	}
+	if next, err := db.NextKey([]byte("1.2.9"), 0); err != nil || string(next) != "1.2.10" {
+		t.Fatalf("NextKey(1.2.9) = %q, %v", next, err)
+	}
+	if last, err := db.LastKey(0); err != nil || string(last) != "2.0.0" {
+		t.Fatalf("LastKey = %q, %v", last, err)
+	}
+	// 根哈希只取决于键值本身
+	if !bytes.Equal(rootOf(t, db), rootOf(t, plain)) {
+		t.Fatal("comparator changed the root")
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for name, bad := range map[string]*Options{
+		"no comparator": nil,
+		"other name":    {Comparator: semverCompare, ComparatorName: "semver-v2"},
+	} {
+		if _, err := NewDatabaseWithOptions(dir, bad); !errors.Is(err, ErrComparatorMismatch) {
+			t.Fatalf("reopen with %s: %v", name, err)
+		}
+	}
+	db, err = NewDatabaseWithOptions(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := keys(db.NewIterator()); got != "[1.2.0 1.2.9 1.2.10 1.10.0 2.0.0]" {
+		t.Fatalf("order after reopen %s", got)
+	}
+}
+
+func TestComparatorOnlyAtCreation(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "k", "v")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDatabaseWithOptions(dir, &Options{Comparator: semverCompare, ComparatorName: "semver"}); !errors.Is(err, ErrComparatorMismatch) {
+		t.Fatalf("existing database: %v", err)
+	}
+	for name, bad := range map[string]*Options{
+		"no name":   {Comparator: semverCompare},
+		"hash keys": {Comparator: semverCompare, ComparatorName: "semver", HashKeys: true},
+	} {
+		if _, err := NewDatabaseWithOptions(t.TempDir(), bad); err != ErrInvalidArg {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package amdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"time"
+)
+
+// compressedProofFormatV1 压缩证明编码格式版本
+const compressedProofFormatV1 = 1
+
+// CompressedProof MerkleProof的紧凑形式
+// 每一步经过的nibble等于键在该深度的nibble，可由Key推出，因此不再逐步保存；
+// 步骤类型压缩为位图，分支节点只保存非空兄弟的位图和按下标顺序排列的哈希
+type CompressedProof struct {
+	Key         []byte
+	Value       []byte
+	Root        []byte
+	LeafHash    []byte
+	Version     uint32
+	GeneratedAt time.Time
+
+	// Depth 证明的步数
+	Depth int
+	// BranchBits 第i位（BranchBits[i/8]的第i%8位）为1表示第i步为分支节点，否则为扩展节点
+	BranchBits []byte
+	// SiblingMasks 每个分支步骤一个位图，第j位为1表示下标j的兄弟非空
+	SiblingMasks []uint16
+	// Siblings 全部分支步骤的非空兄弟哈希，依次按步骤、再按下标排列
+	Siblings [][]byte
+}
+
+// Compress 返回证明的紧凑形式，哈希与键值字节与原证明共享
+func (p *MerkleProof) Compress() *CompressedProof {
+	c := &CompressedProof{
+		Key:         p.Key,
+		Value:       p.Value,
+		Root:        p.Root,
+		LeafHash:    p.LeafHash,
+		Version:     p.Version,
+		GeneratedAt: p.GeneratedAt,
+		Depth:       len(p.Steps),
+		BranchBits:  make([]byte, (len(p.Steps)+7)/8),
+	}
+	for i, step := range p.Steps {
+		if !step.Branch {
+			continue
+		}
+		c.BranchBits[i/8] |= 1 << (i % 8)
+		var mask uint16
+		for j, h := range step.Siblings {
+			if h != nil {
+				mask |= 1 << j
+				c.Siblings = append(c.Siblings, h)
+			}
+		}
+		c.SiblingMasks = append(c.SiblingMasks, mask)
+	}
+	return c
+}
+
+// branch 判断第i步是否为分支节点
+func (c *CompressedProof) branch(i int) bool {
+	return c.BranchBits[i/8]&(1<<(i%8)) != 0
+}
+
+// valid 检查各字段的长度是否一致
+func (c *CompressedProof) valid() bool {
+	if c == nil || c.Depth < 0 || len(c.BranchBits) != (c.Depth+7)/8 {
+		return false
+	}
+	branches, siblings := 0, 0
+	for i := 0; i < c.Depth; i++ {
+		if c.branch(i) {
+			branches++
+		}
+	}
+	if branches != len(c.SiblingMasks) {
+		return false
+	}
+	for _, mask := range c.SiblingMasks {
+		siblings += bits.OnesCount16(mask)
+	}
+	return siblings == len(c.Siblings)
+}
+
+// Decompress 还原为完整的MerkleProof，字段不一致时返回ErrBadProof
+func (c *CompressedProof) Decompress() (*MerkleProof, error) {
+	if !c.valid() {
+		return nil, ErrBadProof
+	}
+	p := &MerkleProof{
+		Key:         c.Key,
+		Value:       c.Value,
+		Root:        c.Root,
+		LeafHash:    c.LeafHash,
+		Version:     c.Version,
+		GeneratedAt: c.GeneratedAt,
+		Steps:       make([]ProofStep, c.Depth),
+	}
+	b, s := 0, 0
+	for i := range p.Steps {
+		step := ProofStep{Nibble: keyNibble(c.Key, i)}
+		if c.branch(i) {
+			step.Branch = true
+			mask := c.SiblingMasks[b]
+			b++
+			if mask&(1<<step.Nibble) != 0 {
+				return nil, ErrBadProof
+			}
+			for j := 0; j < 16; j++ {
+				if mask&(1<<j) != 0 {
+					step.Siblings[j] = c.Siblings[s]
+					s++
+				}
+			}
+		}
+		p.Steps[i] = step
+	}
+	return p, nil
+}
+
+// Verify 直接校验紧凑证明中的键值（不含值时按LeafHash）是否包含在根为root的树中，不还原完整证明
+func (c *CompressedProof) Verify(root []byte) bool {
+	if !c.valid() {
+		return false
+	}
+	h := c.LeafHash
+	if c.Value != nil || len(h) == 0 {
+		h = LeafHash(c.Key, c.Value)
+	}
+
+	// 自底向上处理，分支与兄弟哈希从末尾开始消费
+	b, s := len(c.SiblingMasks), len(c.Siblings)
+	var err error
+	for i := c.Depth - 1; i >= 0; i-- {
+		nibble := keyNibble(c.Key, i)
+		if !c.branch(i) {
+			if h, err = HashSHA256.sum(extContent(nibble, h)); err != nil {
+				return false
+			}
+			continue
+		}
+		b--
+		mask := c.SiblingMasks[b]
+		if mask&(1<<nibble) != 0 {
+			return false
+		}
+		s -= bits.OnesCount16(mask)
+		var children [16][]byte
+		next := s
+		for j := 0; j < 16; j++ {
+			if mask&(1<<j) != 0 {
+				children[j] = c.Siblings[next]
+				next++
+			}
+		}
+		children[nibble] = h
+		if h, err = HashSHA256.sum(branchContent(&children)); err != nil {
+			return false
+		}
+	}
+	return bytes.Equal(h, root)
+}
+
+// 紧凑证明二进制编码格式（整数均为大端）：
+//
+//	[1字节格式版本]
+//	[4字节长度][键][4字节长度][值][4字节长度][根][4字节长度][叶子哈希]
+//	[4字节版本][8字节生成时间UnixNano，0表示未知]
+//	[4字节步数][ceil(步数/8)字节分支位图]
+//	每个分支步骤：[2字节兄弟位图] 位图中每个置位下标：[1字节哈希长度][哈希]
+
+// MarshalBinary 实现encoding.BinaryMarshaler
+func (c *CompressedProof) MarshalBinary() ([]byte, error) {
+	if !c.valid() {
+		return nil, ErrBadProof
+	}
+	buf := []byte{compressedProofFormatV1}
+	buf = appendBytes32(buf, c.Key)
+	buf = appendBytes32(buf, c.Value)
+	buf = appendBytes32(buf, c.Root)
+	buf = appendBytes32(buf, c.LeafHash)
+	buf = wireOrder.AppendUint32(buf, c.Version)
+	var generated int64
+	if !c.GeneratedAt.IsZero() {
+		generated = c.GeneratedAt.UnixNano()
+	}
+	buf = wireOrder.AppendUint64(buf, uint64(generated))
+	buf = wireOrder.AppendUint32(buf, uint32(c.Depth))
+	buf = append(buf, c.BranchBits...)
+	s := 0
+	for _, mask := range c.SiblingMasks {
+		buf = wireOrder.AppendUint16(buf, mask)
+		for n := bits.OnesCount16(mask); n > 0; n-- {
+			h := c.Siblings[s]
+			s++
+			if len(h) > 0xFF {
+				return nil, ErrBadProof
+			}
+			buf = append(buf, byte(len(h)))
+			buf = append(buf, h...)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler
+func (c *CompressedProof) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	format, err := r.ReadByte()
+	if err != nil || format != compressedProofFormatV1 {
+		return ErrBadProof
+	}
+	var out CompressedProof
+	for _, field := range []*[]byte{&out.Key, &out.Value, &out.Root, &out.LeafHash} {
+		if *field, err = readLengthPrefixed(r); err != nil {
+			return ErrBadProof
+		}
+	}
+	if len(out.LeafHash) == 0 {
+		out.LeafHash = nil
+	} else if len(out.Value) == 0 {
+		out.Value = nil
+	}
+	var generated int64
+	var depth uint32
+	if binary.Read(r, wireOrder, &out.Version) != nil ||
+		binary.Read(r, wireOrder, &generated) != nil ||
+		binary.Read(r, wireOrder, &depth) != nil ||
+		int64(depth) > int64(r.Len())*8 {
+		return ErrBadProof
+	}
+	if generated != 0 {
+		out.GeneratedAt = time.Unix(0, generated)
+	}
+	out.Depth = int(depth)
+	out.BranchBits = make([]byte, (out.Depth+7)/8)
+	if _, err := io.ReadFull(r, out.BranchBits); err != nil {
+		return ErrBadProof
+	}
+	for i := 0; i < out.Depth; i++ {
+		if !out.branch(i) {
+			continue
+		}
+		var mask uint16
+		if binary.Read(r, wireOrder, &mask) != nil {
+			return ErrBadProof
+		}
+		out.SiblingMasks = append(out.SiblingMasks, mask)
+		for n := bits.OnesCount16(mask); n > 0; n-- {
+			size, err := r.ReadByte()
+			if err != nil || int(size) > r.Len() {
+				return ErrBadProof
+			}
+			h := make([]byte, size)
+			r.Read(h)
+			out.Siblings = append(out.Siblings, h)
+		}
+	}
+	if r.Len() != 0 {
+		return ErrBadProof
+	}
+	*c = out
+	return nil
+}
@@ -0,0 +1,79 @@
+package amdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressedProofRoundTrip(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	root := rootOf(t, db)
+	for _, k := range keys {
+		proof, err := db.GetWithProof([]byte(k), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := proof.Compress()
+		full, err := c.Decompress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !full.Equal(proof) {
+			t.Fatalf("%s: decompressed proof differs", k)
+		}
+		if c.Verify(root) != proof.Verify(root) || !c.Verify(root) {
+			t.Fatalf("%s: compressed verify %v, full verify %v", k, c.Verify(root), proof.Verify(root))
+		}
+
+		data, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fullData, err := proof.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) >= len(fullData) {
+			t.Errorf("%s: compressed %d bytes, full %d", k, len(data), len(fullData))
+		}
+		var decoded CompressedProof
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Verify(root) {
+			t.Fatalf("%s: decoded compressed proof does not verify", k)
+		}
+	}
+}
+
+func TestCompressedProofRejectsTampering(t *testing.T) {
+	db, _ := proofDB(t, nil)
+	root := rootOf(t, db)
+	proof, err := db.GetWithProof([]byte("abc"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := proof.Compress()
+	c.Value = []byte("forged")
+	if c.Verify(root) {
+		t.Fatal("forged value verified")
+	}
+	c = proof.Compress()
+	if len(c.Siblings) == 0 {
+		t.Fatal("proof has no siblings to tamper with")
+	}
+	c.Siblings[0] = bytes.Repeat([]byte{0xaa}, len(c.Siblings[0]))
+	if c.Verify(root) {
+		t.Fatal("tampered sibling verified")
+	}
+
+	c = proof.Compress()
+	c.SiblingMasks = c.SiblingMasks[:0]
+	if c.Verify(root) {
+		t.Fatal("inconsistent masks verified")
+	}
+	if _, err := c.Decompress(); err == nil {
+		t.Fatal("inconsistent masks decompressed")
+	}
+}
@@ -0,0 +1,62 @@
+package amdb
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// missingDirs 返回打开前尚不存在、将由打开过程创建的目录（从dataDir向上直到第一个已存在的目录）
+func missingDirs(dataDir string) []string {
+	var dirs []string
+	dir := filepath.Clean(dataDir)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dirs
+		}
+		dirs = append(dirs, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dirs
+		}
+		dir = parent
+	}
+}
+
+// isEmptyDir 判断dir是否为已存在的空目录
+func isEmptyDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err == nil && len(entries) == 0
+}
+
+// syncCreated 新建数据库后将初始文件及其所在目录项刷入磁盘，
+// 避免创建后立即崩溃导致整个目录丢失：
+// 依次同步dataDir下的全部文件和目录，再同步每个新建目录的父目录（created为空时只同步dataDir内部）
+func syncCreated(dataDir string, created []string) error {
+	err := filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return syncDir(path)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return f.Sync()
+	})
+	if err != nil {
+		return err
+	}
+	for _, dir := range created {
+		if err := syncDir(filepath.Dir(dir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package amdb
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// crashDirEnv 设置时TestCreateCrashChild打开（必要时新建）该目录中的数据库后不关闭直接退出
+const crashDirEnv = "AMDB_TEST_CRASH_DIR"
+
+func TestCreateCrashChild(t *testing.T) {
+	dir := os.Getenv(crashDirEnv)
+	if dir == "" {
+		t.Skip("only run as a subprocess of TestCreateRecoverableAfterCrash")
+	}
+	if _, err := NewDatabase(dir); err != nil {
+		t.Fatal(err)
+	}
+	os.Exit(0)
+}
+
+// crashAfterOpen 在子进程中打开dir处的数据库后不关闭直接退出
+func crashAfterOpen(t *testing.T, dir string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCreateCrashChild$")
+	cmd.Env = append(os.Environ(), crashDirEnv+"="+dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child: %v\n%s", err, out)
+	}
+}
+
+func TestCreateRecoverableAfterCrash(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b", "db")
+	crashAfterOpen(t, dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("reopen after crash: %v", err)
+	}
+	defer db.Close()
+	if v, err := db.CurrentVersion(); err != nil || v != 0 {
+		t.Fatalf("version %d, %v", v, err)
+	}
+	mustPut(t, db, "k", "v")
+}
+
+func TestMissingDirsAndSyncOption(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "a", "b")
+	want := []string{dir, filepath.Join(base, "a")}
+	if got := missingDirs(dir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("missingDirs = %v, want %v", got, want)
+	}
+	if got := missingDirs(base); len(got) != 0 {
+		t.Fatalf("existing dir: %v", got)
+	}
+
+	disabled := false
+	if !(&Options{}).syncOnCreate() || (&Options{SyncOnCreate: &disabled}).syncOnCreate() {
+		t.Fatal("SyncOnCreate must default to true and honour false")
+	}
+}
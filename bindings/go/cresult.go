@@ -0,0 +1,55 @@
+package amdb
+
+/*
+#include "amdb.h"
+*/
+import "C"
+import (
+	"math"
+	"unsafe"
+)
+
+// maxCLength C层返回的单个长度或数量的上限，超过即视为结果损坏
+// C.GoBytes以C.int接收长度，更大的值会溢出为负数或被截断
+const maxCLength = math.MaxInt32
+
+// cLength 校验C层返回的长度或数量，超出上限时返回ErrCorrupted
+func cLength(n C.size_t) (int, error) {
+	return checkLength(uint64(n))
+}
+
+// checkLength 校验长度或数量不超过maxCLength
+func checkLength(n uint64) (int, error) {
+	if n > maxCLength {
+		return 0, ErrCorrupted
+	}
+	return int(n), nil
+}
+
+// goBytes 将C层返回的n字节数据复制为Go切片，复制前校验长度
+// 长度超出上限、或data为空而长度非零时返回ErrCorrupted，复制中出现的Go panic同样转换为ErrCorrupted。
+// 校验只能拦截不合理的长度：长度在上限内但超出实际缓冲区时仍会越界读取，无法在Go中恢复
+func goBytes(data unsafe.Pointer, n C.size_t) (b []byte, err error) {
+	size, err := checkBytes(data, uint64(n))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if recover() != nil {
+			b, err = nil, ErrCorrupted
+		}
+	}()
+	return C.GoBytes(data, C.int(size)), nil
+}
+
+// checkBytes 校验C层返回的数据指针与长度，返回可安全传给C.GoBytes的长度
+func checkBytes(data unsafe.Pointer, n uint64) (int, error) {
+	size, err := checkLength(n)
+	if err != nil {
+		return 0, err
+	}
+	if data == nil && size > 0 {
+		return 0, ErrCorrupted
+	}
+	return size, nil
+}
@@ -0,0 +1,37 @@
+package amdb
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"unsafe"
+)
+
+func TestCheckBytesRejectsBogusLengths(t *testing.T) {
+	buf := []byte("data")
+	data := unsafe.Pointer(&buf[0])
+	for _, tc := range []struct {
+		name string
+		data unsafe.Pointer
+		n    uint64
+		ok   bool
+	}{
+		{"valid", data, 4, true},
+		{"empty", nil, 0, true},
+		{"at limit", data, maxCLength, true},
+		{"over int32", data, math.MaxInt32 + 1, false},
+		{"size_t max", data, math.MaxUint64, false},
+		{"nil data", nil, 4, false},
+	} {
+		size, err := checkBytes(tc.data, tc.n)
+		if tc.ok {
+			if err != nil || uint64(size) != tc.n {
+				t.Fatalf("%s: %d, %v", tc.name, size, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrCorrupted) {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package amdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// FindDuplicateValues 返回数据库版本version（0表示最新版本）中值完全相同的键组，用于评估按内容去重的收益
+// 结果以值的SHA-256（十六进制）为键，只包含两个及以上键共享的值，组内的键按NewKeyIterator的遍历顺序排列。
+// 逐键读取并只保留值的哈希，内存占用与键数量成正比而与值的大小无关；空值同样参与分组
+func (db *Database) FindDuplicateValues(version uint32) (map[string][][]byte, error) {
+	if version == 0 {
+		current, err := db.CurrentVersion()
+		if err != nil {
+			return nil, err
+		}
+		if current == 0 {
+			return map[string][][]byte{}, nil
+		}
+		version = current
+	}
+	it, err := db.NewKeyIterator(nil, nil, version)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	groups := make(map[string][][]byte)
+	for it.Next() {
+		value, err := db.Get(it.Key(), version)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(value)
+		db.ReleaseValue(value)
+		h := hex.EncodeToString(sum[:])
+		groups[h] = append(groups[h], it.Key())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	for h, keys := range groups {
+		if len(keys) < 2 {
+			delete(groups, h)
+		}
+	}
+	return groups, nil
+}
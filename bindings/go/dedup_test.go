@@ -0,0 +1,59 @@
+package amdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestFindDuplicateValues(t *testing.T) {
+	db := openTestDB(t, nil)
+	items := map[string][]byte{
+		"a": []byte("shared"), "b": []byte("shared"), "c": []byte("shared"),
+		"d": []byte("pair"), "e": []byte("pair"),
+		"f": []byte("unique"),
+		"g": {}, "h": {},
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "e", "changed")
+
+	groupOf := func(value string) string {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:])
+	}
+	check := func(version uint32, want map[string][]string) {
+		t.Helper()
+		groups, err := db.FindDuplicateValues(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string][]string, len(groups))
+		for h, keys := range groups {
+			for _, k := range keys {
+				got[h] = append(got[h], string(k))
+			}
+			sort.Strings(got[h])
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("version %d: groups %v, want %v", version, got, want)
+		}
+	}
+	check(1, map[string][]string{
+		groupOf("shared"): {"a", "b", "c"},
+		groupOf("pair"):   {"d", "e"},
+		groupOf(""):       {"g", "h"},
+	})
+	check(0, map[string][]string{
+		groupOf("shared"): {"a", "b", "c"},
+		groupOf(""):       {"g", "h"},
+	})
+
+	empty, err := openTestDB(t, nil).FindDuplicateValues(0)
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("empty database: %v, %v", empty, err)
+	}
+}
@@ -0,0 +1,105 @@
+package amdb
+
+import (
+	"bytes"
+	"sort"
+)
+
+// DiffType 键的变化类型
+type DiffType int
+
+const (
+	// DiffAdded 键在新版本中新增
+	DiffAdded DiffType = iota
+	// DiffModified 键的值发生变化
+	DiffModified
+	// DiffDeleted 键在新版本中被删除
+	DiffDeleted
+)
+
+// String 返回变化类型名称
+func (t DiffType) String() string {
+	switch t {
+	case DiffAdded:
+		return "added"
+	case DiffModified:
+		return "modified"
+	case DiffDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// DiffEntry 两个版本之间一个键的变化
+type DiffEntry struct {
+	Key  []byte
+	Type DiffType
+	// OldValue 旧版本的值（DiffAdded时为nil）
+	OldValue []byte
+	// NewValue 新版本的值（DiffDeleted时为nil）
+	NewValue []byte
+}
+
+// Diff 返回从数据库版本fromVersion到toVersion（0表示最新版本）之间所有发生变化的键，按键排序
+func (db *Database) Diff(fromVersion, toVersion uint32) ([]DiffEntry, error) {
+	var entries []DiffEntry
+	err := db.DiffStream(fromVersion, toVersion, func(e DiffEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// DiffStream 与Diff相同，但对每个变化的键按键顺序调用cb而不构建结果切片；
+// cb返回错误时立即停止并返回该错误。
+// 两个版本的状态仍需读入内存，该方法只避免保存全部变化条目
+func (db *Database) DiffStream(fromVersion, toVersion uint32, cb func(DiffEntry) error) error {
+	from, err := db.diffState(fromVersion)
+	if err != nil {
+		return err
+	}
+	to, err := db.diffState(toVersion)
+	if err != nil {
+		return err
+	}
+
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		var e DiffEntry
+		switch {
+		case j >= len(to) || (i < len(from) && bytes.Compare(from[i].key, to[j].key) < 0):
+			e = DiffEntry{Key: from[i].key, Type: DiffDeleted, OldValue: from[i].value}
+			i++
+		case i >= len(from) || bytes.Compare(from[i].key, to[j].key) > 0:
+			e = DiffEntry{Key: to[j].key, Type: DiffAdded, NewValue: to[j].value}
+			j++
+		default:
+			same := bytes.Equal(from[i].value, to[j].value)
+			e = DiffEntry{Key: to[j].key, Type: DiffModified, OldValue: from[i].value, NewValue: to[j].value}
+			i++
+			j++
+			if same {
+				continue
+			}
+		}
+		if err := cb(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffState 返回版本version的存活键值（原始形式），按键排序
+func (db *Database) diffState(version uint32) ([]kv, error) {
+	state, err := db.stateAt(version)
+	if err != nil {
+		return nil, err
+	}
+	items, err := db.userEntries(liveRange(state, nil, nil, bytes.Compare))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return bytes.Compare(items[i].key, items[j].key) < 0 })
+	return items, nil
+}
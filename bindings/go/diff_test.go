@@ -0,0 +1,69 @@
+package amdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiffStreamMatchesDiff(t *testing.T) {
+	db := openTestDB(t, nil)
+	if _, err := db.BatchPut(map[string][]byte{"a": []byte("1"), "b": []byte("1"), "d": []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "2")
+	if err := db.Delete([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "c", "1")
+	mustPut(t, db, "d", "1") // 值未变化，不计入差异
+
+	want := []DiffEntry{
+		{Key: []byte("a"), Type: DiffModified, OldValue: []byte("1"), NewValue: []byte("2")},
+		{Key: []byte("b"), Type: DiffDeleted, OldValue: []byte("1")},
+		{Key: []byte("c"), Type: DiffAdded, NewValue: []byte("1")},
+	}
+	diff, err := db.Diff(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("Diff = %+v", diff)
+	}
+	var streamed []DiffEntry
+	if err := db.DiffStream(1, 0, func(e DiffEntry) error {
+		streamed = append(streamed, e)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(streamed, diff) {
+		t.Fatalf("DiffStream = %+v", streamed)
+	}
+
+	// 反方向：新增与删除互换
+	back, err := db.Diff(5, 1)
+	if err != nil || len(back) != 3 || back[1].Type != DiffAdded || back[2].Type != DiffDeleted {
+		t.Fatalf("reverse diff: %+v, %v", back, err)
+	}
+	if same, err := db.Diff(4, 5); err != nil || len(same) != 0 {
+		t.Fatalf("unchanged rewrite: %+v, %v", same, err)
+	}
+}
+
+func TestDiffStreamStopsEarly(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	if _, err := db.BatchPut(map[string][]byte{"a": []byte("2"), "b": []byte("2"), "c": []byte("2")}); err != nil {
+		t.Fatal(err)
+	}
+	stop := errors.New("stop")
+	calls := 0
+	err := db.DiffStream(1, 2, func(DiffEntry) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("err %v after %d calls", err, calls)
+	}
+}
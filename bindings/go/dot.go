@@ -0,0 +1,136 @@
+package amdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// defaultDOTMaxNodes DumpDOT默认输出的最大节点数
+const defaultDOTMaxNodes = 1000
+
+// DOTOptions DumpDOTWithOptions的输出范围
+type DOTOptions struct {
+	// Prefix 只输出键前缀为Prefix的子树（nil表示整棵树），子树之上的节点省略
+	Prefix []byte
+	// MaxNodes 最多输出的节点数（0表示defaultDOTMaxNodes，负数表示不限制）
+	// 超出时在截断处输出一个标记节点，输出仍是合法的DOT
+	MaxNodes int
+}
+
+// DumpDOT 将数据库版本version（0表示最新版本）的MPT以Graphviz DOT格式写入w，用于调试树结构
+// 最多输出defaultDOTMaxNodes个节点，指定前缀或节点上限使用DumpDOTWithOptions
+func (db *Database) DumpDOT(w io.Writer, version uint32) error {
+	return db.DumpDOTWithOptions(w, version, DOTOptions{})
+}
+
+// DumpDOTWithOptions 与DumpDOT相同，按opts限定输出范围
+// 节点标注类型与哈希前8个十六进制字符，叶子另标注键（十六进制，过长时截断），
+// 扩展节点标注其nibble，分支的边标注子节点下标。删除标记作为普通叶子输出并注明。
+// 哈希键模式下键为存储形式
+func (db *Database) DumpDOTWithOptions(w io.Writer, version uint32, opts DOTOptions) error {
+	if err := db.checkAuthenticated(); err != nil {
+		return err
+	}
+	root, err := db.trieAt(version)
+	if err != nil {
+		return err
+	}
+	limit := opts.MaxNodes
+	if limit == 0 {
+		limit = defaultDOTMaxNodes
+	}
+
+	d := &dotWriter{w: bufio.NewWriter(w), limit: limit}
+	d.printf("digraph amdb {\n\tnode [shape=box, fontname=\"monospace\"];\n")
+	if node := subtrieAt(root, opts.Prefix); node != nil {
+		d.node(node)
+	}
+	d.printf("}\n")
+	if d.err != nil {
+		return d.err
+	}
+	return d.w.Flush()
+}
+
+// subtrieAt 沿prefix的nibble路径下行，返回覆盖前缀为prefix的全部键的最高节点（没有这样的键时为nil）
+func subtrieAt(root *trieNode, prefix []byte) *trieNode {
+	node := root
+	for pos := 0; node != nil && pos < len(prefix)*2; pos++ {
+		switch node.kind {
+		case leafNode:
+			if !bytes.HasPrefix(node.key, prefix) {
+				return nil
+			}
+			return node
+		case extNode:
+			if node.nibble != keyNibble(prefix, pos) {
+				return nil
+			}
+			node = node.child
+		case branchNode:
+			node = node.children[keyNibble(prefix, pos)]
+		}
+	}
+	return node
+}
+
+// dotWriter 按深度优先顺序输出节点，记录第一个写入错误
+type dotWriter struct {
+	w     *bufio.Writer
+	limit int // 负数表示不限制
+	count int
+	err   error
+}
+
+func (d *dotWriter) printf(format string, args ...interface{}) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+// node 输出节点及其子树，返回节点ID
+func (d *dotWriter) node(n *trieNode) string {
+	id := fmt.Sprintf("n%d", d.count)
+	d.count++
+	if d.limit >= 0 && d.count > d.limit {
+		d.printf("\t%s [label=\"…truncated\", shape=plaintext];\n", id)
+		return id
+	}
+
+	hashPrefix := hex.EncodeToString(n.hash)
+	if len(hashPrefix) > 8 {
+		hashPrefix = hashPrefix[:8]
+	}
+	switch n.kind {
+	case leafNode:
+		key := hex.EncodeToString(n.key)
+		if len(key) > 32 {
+			key = key[:32] + "…"
+		}
+		note := ""
+		if isDeleted(n.value) {
+			note = "\\n(deleted)"
+		}
+		d.printf("\t%s [label=\"leaf %s\\nkey %s%s\", shape=ellipse];\n", id, hashPrefix, key, note)
+	case extNode:
+		d.printf("\t%s [label=\"ext %s\\nnibble %x\"];\n", id, hashPrefix, n.nibble)
+		child := d.node(n.child)
+		d.printf("\t%s -> %s;\n", id, child)
+	case branchNode:
+		d.printf("\t%s [label=\"branch %s\"];\n", id, hashPrefix)
+		for i, c := range n.children {
+			if c == nil {
+				continue
+			}
+			if d.limit >= 0 && d.count > d.limit {
+				break
+			}
+			child := d.node(c)
+			d.printf("\t%s -> %s [label=\"%x\"];\n", id, child, i)
+		}
+	}
+	return id
+}
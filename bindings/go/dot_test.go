@@ -0,0 +1,79 @@
+package amdb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	dotNodeLine = regexp.MustCompile(`^\t(n\d+) \[label="[^"]*"(, shape=\w+)?\];$`)
+	dotEdgeLine = regexp.MustCompile(`^\t(n\d+) -> (n\d+)( \[label="[0-9a-f]"\])?;$`)
+)
+
+// parseDOT 校验DumpDOT的输出是合法的有向图：每条边的两端都已声明，返回节点标签出现的叶子数和总节点数
+func parseDOT(t *testing.T, out string) (leaves, nodes int) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) < 3 || lines[0] != "digraph amdb {" || lines[len(lines)-1] != "}" {
+		t.Fatalf("not a digraph:\n%s", out)
+	}
+	declared := make(map[string]bool)
+	for _, line := range lines[2 : len(lines)-1] {
+		if m := dotNodeLine.FindStringSubmatch(line); m != nil {
+			declared[m[1]] = true
+			if strings.Contains(line, `label="leaf `) {
+				leaves++
+			}
+			continue
+		}
+		m := dotEdgeLine.FindStringSubmatch(line)
+		if m == nil {
+			t.Fatalf("malformed line %q", line)
+		}
+		if !declared[m[1]] || !declared[m[2]] {
+			t.Fatalf("edge to undeclared node: %q", line)
+		}
+	}
+	return leaves, len(declared)
+}
+
+func TestDumpDOT(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	var buf bytes.Buffer
+	if err := db.DumpDOT(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if leaves, _ := parseDOT(t, buf.String()); leaves != len(keys) {
+		t.Fatalf("%d leaves for %d keys", leaves, len(keys))
+	}
+
+	buf.Reset()
+	if err := db.DumpDOTWithOptions(&buf, 0, DOTOptions{Prefix: []byte("key-01")}); err != nil {
+		t.Fatal(err)
+	}
+	if leaves, _ := parseDOT(t, buf.String()); leaves != 10 {
+		t.Fatalf("prefix key-01: %d leaves, want 10", leaves)
+	}
+	if !strings.Contains(buf.String(), hex.EncodeToString([]byte("key-015"))) || strings.Contains(buf.String(), hex.EncodeToString([]byte("key-020"))) {
+		t.Fatalf("prefix subtree has wrong keys:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := db.DumpDOTWithOptions(&buf, 0, DOTOptions{MaxNodes: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, nodes := parseDOT(t, buf.String()); nodes > 6 || !strings.Contains(buf.String(), "truncated") {
+		t.Fatalf("MaxNodes 5 produced %d nodes:\n%s", nodes, buf.String())
+	}
+
+	buf.Reset()
+	if err := openTestDB(t, nil).DumpDOT(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, nodes := parseDOT(t, buf.String()); nodes != 0 {
+		t.Fatalf("empty database produced %d nodes", nodes)
+	}
+}
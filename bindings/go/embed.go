@@ -0,0 +1,25 @@
+package amdb
+
+/*
+#include "amdb.h"
+*/
+import "C"
+import "unsafe"
+
+// FromHandle 将C代码通过amdb_init创建的amdb_handle_t包装为*Database，不重新初始化引擎
+// 用于在同时使用C API和Go绑定的宿主程序中共享同一个数据库实例。
+// ownsHandle为false时Close只停止Go侧的使用而不调用amdb_close，句柄仍由创建方负责关闭；
+// 为true时Close关闭句柄，之后C代码不得再使用它。
+// 包装的句柄没有数据目录：绑定层不对目录加锁，也不读写正常关闭标记（OpenInfo().CleanShutdown为false），
+// ImportResumable因无处保存检查点而返回ErrInvalidArg，
+// 其他选项均取默认值。同一句柄在C侧的并发写入不经过Go的写锁，需由宿主自行协调
+func FromHandle(handle unsafe.Pointer, ownsHandle bool) (*Database, error) {
+	if handle == nil {
+		return nil, ErrInvalidArg
+	}
+	return &Database{
+		handle:   C.amdb_handle_t(handle),
+		borrowed: !ownsHandle,
+		opts:     effectiveOptions(Options{}),
+	}, nil
+}
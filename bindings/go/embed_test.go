@@ -0,0 +1,66 @@
+package amdb
+
+import (
+	"errors"
+	"testing"
+	"unsafe"
+)
+
+func TestFromHandleBorrowed(t *testing.T) {
+	owner := openTestDB(t, nil)
+	mustPut(t, owner, "a", "1")
+
+	wrapped, err := FromHandle(unsafe.Pointer(owner.handle), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, wrapped, "a", 0); got != "1" {
+		t.Fatalf("a via wrapper: %q", got)
+	}
+	mustPut(t, wrapped, "b", "2")
+	if err := wrapped.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrapped.Get([]byte("a"), 0); !errors.Is(err, ErrClosed) {
+		t.Fatalf("wrapper after Close: %v", err)
+	}
+
+	// 句柄不归包装所有，Close之后创建方仍可使用
+	if got := mustGet(t, owner, "b", 0); got != "2" {
+		t.Fatalf("b via owner: %q", got)
+	}
+	mustPut(t, owner, "c", "3")
+
+	if _, err := FromHandle(nil, false); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("nil handle: %v", err)
+	}
+}
+
+func TestFromHandleOwned(t *testing.T) {
+	dir := t.TempDir()
+	owner, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := FromHandle(unsafe.Pointer(owner.handle), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, wrapped, "k", "v")
+	// 包装拥有句柄，由它关闭；创建方只释放目录锁
+	if err := wrapped.Close(); err != nil {
+		t.Fatal(err)
+	}
+	owner.borrowed = true
+	owner.Close()
+	unlockDir(owner.lock)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := mustGet(t, db, "k", 0); got != "v" {
+		t.Fatalf("k after owned Close: %q", got)
+	}
+}
@@ -0,0 +1,167 @@
+package amdb
+
+/*
+#include "amdb.h"
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+)
+
+// 预定义错误，可使用errors.Is判断
+var (
+	// ErrNotFound 键不存在（从未写入或已删除）
+	//
+	// 全部读取方法遵循同一约定：不存在的键返回(nil, ErrNotFound)，
+	// 存在但值为空的键返回非nil的空切片[]byte{}和nil错误，调用方可以用v == nil区分两者。
+	// 适用于Get、GetWithOptions、GetLimited、GetValue、Snapshot.Get、迭代器的Value以及Swap的旧值；
+	// MultiGetVersions不为单个键返回错误，以nil元素表示不存在、[]byte{}表示空值
+	ErrNotFound = errors.New("key not found")
+	// ErrClosed 数据库已关闭
+	ErrClosed = errors.New("database is closed")
+	// ErrLocked 数据目录被其他进程或句柄锁定
+	ErrLocked = errors.New("database is locked")
+	// ErrInvalidArg 参数无效（如空键）
+	ErrInvalidArg = errors.New("invalid argument")
+	// ErrTreeTooDeep 写入的键可能使Merkle树深度超过Options.MaxTreeDepth
+	ErrTreeTooDeep = errors.New("tree too deep")
+	// ErrCorrupted 引擎中存储的数据无法按预期格式解析
+	ErrCorrupted = errors.New("corrupted data")
+	// ErrValueTooLarge 值超过读取时指定的大小上限
+	ErrValueTooLarge = errors.New("value too large")
+	// ErrInternal 引擎内部错误（C层捕获的异常）
+	ErrInternal = errors.New("internal error")
+	// ErrIO 底层存储I/O错误
+	ErrIO = errors.New("i/o error")
+	// ErrVersionNotFound 请求的数据库版本不存在
+	ErrVersionNotFound = errors.New("version not found")
+	// ErrUnexpectedVersion 写入的版本号不是期望的下一个版本
+	ErrUnexpectedVersion = errors.New("unexpected version")
+	// ErrRangeOutOfBounds 读取区间的起点超出值的末尾
+	ErrRangeOutOfBounds = errors.New("range out of bounds")
+	// ErrNotAuthenticated 句柄以Options.PlainMode打开，不提供根哈希与证明
+	ErrNotAuthenticated = errors.New("database is not authenticated")
+	// ErrOutOfMemory 引擎或C层内存分配失败
+	ErrOutOfMemory = errors.New("out of memory")
+)
+
+// StatusCode C层的状态码，与amdb.h中的AMDB_*一一对应
+type StatusCode int
+
+// C层状态码
+const (
+	StatusOK          StatusCode = C.AMDB_OK
+	StatusError       StatusCode = C.AMDB_ERROR
+	StatusNotFound    StatusCode = C.AMDB_NOT_FOUND
+	StatusInvalidArg  StatusCode = C.AMDB_INVALID_ARG
+	StatusIOError     StatusCode = C.AMDB_IO_ERROR
+	StatusMemoryError StatusCode = C.AMDB_MEMORY_ERROR
+)
+
+// String 返回C层对状态码的说明（amdb_error_string）
+func (c StatusCode) String() string {
+	return C.GoString(C.amdb_error_string(C.amdb_status_t(c)))
+}
+
+// statusSentinels 各状态码对应的预定义错误
+var statusSentinels = []struct {
+	code StatusCode
+	err  error
+}{
+	{StatusError, ErrInternal},
+	{StatusNotFound, ErrNotFound},
+	{StatusInvalidArg, ErrInvalidArg},
+	{StatusIOError, ErrIO},
+	{StatusMemoryError, ErrOutOfMemory},
+}
+
+// StatusOf 返回err对应的C层状态码，用于需要按具体状态码分支的调用方
+// err为nil时返回StatusOK；err链中含有与状态码对应的预定义错误（ErrInternal、ErrNotFound、ErrInvalidArg、
+// ErrIO、ErrOutOfMemory）或C层返回的未知状态码时返回该状态码。绑定层自身产生的这些错误同样映射到对应的状态码，
+// 例如布隆过滤器判定不存在的ErrNotFound；其他错误返回false
+func StatusOf(err error) (StatusCode, bool) {
+	if err == nil {
+		return StatusOK, true
+	}
+	var unknown *unknownStatusError
+	if errors.As(err, &unknown) {
+		return unknown.code, true
+	}
+	for _, s := range statusSentinels {
+		if errors.Is(err, s.err) {
+			return s.code, true
+		}
+	}
+	return 0, false
+}
+
+// unknownStatusError 绑定层未定义对应错误的C状态码
+type unknownStatusError struct {
+	code StatusCode
+}
+
+func (e *unknownStatusError) Error() string {
+	return fmt.Sprintf("%v (status %d)", e.code, int(e.code))
+}
+
+// statusError 将C状态码转换为Go错误
+func statusError(status C.amdb_status_t) error {
+	for _, s := range statusSentinels {
+		if StatusCode(status) == s.code {
+			return s.err
+		}
+	}
+	return &unknownStatusError{code: StatusCode(status)}
+}
+
+// isTransient 判断错误是否为可重试的临时错误
+// 锁竞争和I/O错误视为临时错误；参数错误、数据损坏等其他错误立即失败
+func isTransient(err error) bool {
+	return errors.Is(err, ErrLocked) || errors.Is(err, ErrIO)
+}
+
+// isTransientRead 判断读取错误是否可重试
+// 只有I/O错误（C层AMDB_IO_ERROR）视为临时错误，例如网络文件系统的短暂读取失败；
+// AMDB_NOT_FOUND（ErrNotFound）、数据损坏（ErrCorrupted）、参数错误、版本不存在、
+// 引擎内部错误（AMDB_ERROR）及句柄已关闭均不重试
+func isTransientRead(err error) bool {
+	return errors.Is(err, ErrIO)
+}
+
+// ValueTooLargeError 值超过读取上限，携带实际大小
+type ValueTooLargeError struct {
+	Size  int
+	Limit int
+}
+
+func (e *ValueTooLargeError) Error() string {
+	return fmt.Sprintf("%v: %d bytes exceeds limit of %d", ErrValueTooLarge, e.Size, e.Limit)
+}
+
+// Is 使errors.Is(err, ErrValueTooLarge)成立
+func (e *ValueTooLargeError) Is(target error) bool {
+	return target == ErrValueTooLarge
+}
+
+// BatchError 批量写入失败的条目
+type BatchError struct {
+	// Key 出错条目的键（无法定位具体条目时为nil）
+	Key []byte
+	// Index 出错条目在按键排序后批次中的下标（无法定位时为-1）
+	Index int
+	// Err 具体错误
+	Err error
+}
+
+func (e *BatchError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("batch put: %v", e.Err)
+	}
+	return fmt.Sprintf("batch put: entry %d (key %q): %v", e.Index, e.Key, e.Err)
+}
+
+// Unwrap 返回具体错误，便于errors.Is判断
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
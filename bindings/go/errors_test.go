@@ -0,0 +1,145 @@
+package amdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBatchErrorIdentifiesEntry(t *testing.T) {
+	db := openTestDB(t, &Options{MaxTreeDepth: 21})
+	bad := strings.Repeat("x", 11)
+	items := map[string][]byte{"a": []byte("1"), "c": []byte("3"), bad: []byte("2")}
+
+	_, err := db.BatchPut(items)
+	var be *BatchError
+	if !errors.As(err, &be) || !errors.Is(err, ErrTreeTooDeep) {
+		t.Fatalf("BatchPut: %v", err)
+	}
+	// 下标按排序后的批次计算："a" < "c" < "xxx…"
+	if string(be.Key) != bad || be.Index != 2 || !strings.Contains(err.Error(), "entry 2") {
+		t.Fatalf("BatchError %+v", be)
+	}
+	if _, err := db.Get([]byte("a"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("batch was partly written: %v", err)
+	}
+
+	keys := [][]byte{[]byte("z"), {}, []byte("b")}
+	values := [][]byte{[]byte("1"), []byte("2"), []byte("3")}
+	_, err = db.BatchPutSlices(keys, values)
+	if !errors.As(err, &be) || !errors.Is(err, ErrInvalidArg) || be.Index != 1 || len(be.Key) != 0 {
+		t.Fatalf("BatchPutSlices: %v", err)
+	}
+
+	if _, err := db.BatchPut(map[string][]byte{"a": []byte("1")}); err != nil {
+		t.Fatalf("corrected batch: %v", err)
+	}
+}
+
+func TestBatchErrorWithoutEntry(t *testing.T) {
+	err := error(&BatchError{Index: -1, Err: ErrIO})
+	if !errors.Is(err, ErrIO) || strings.Contains(err.Error(), "entry") {
+		t.Fatalf("%v", err)
+	}
+}
+
+// TestReadContractNilVsEmpty 检查ErrNotFound文档中的约定：不存在的键返回(nil, ErrNotFound)，空值返回非nil的空切片
+func TestReadContractNilVsEmpty(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "empty", "")
+	mustPut(t, db, "gone", "x")
+	if err := db.Delete([]byte("gone")); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Release()
+
+	reads := map[string]func(key []byte) ([]byte, error){
+		"Get":            func(k []byte) ([]byte, error) { return db.Get(k, 0) },
+		"GetWithOptions": func(k []byte) ([]byte, error) { return db.GetWithOptions(k, ReadOptions{}) },
+		"GetLimited":     func(k []byte) ([]byte, error) { return db.GetLimited(k, 0, 10) },
+		"GetValue": func(k []byte) ([]byte, error) {
+			v, err := db.GetValue(k, 0)
+			if err != nil {
+				return nil, err
+			}
+			return v.Data, nil
+		},
+		"Snapshot.Get": snap.Get,
+	}
+	for name, read := range reads {
+		for _, absent := range []string{"missing", "gone"} {
+			if v, err := read([]byte(absent)); v != nil || !errors.Is(err, ErrNotFound) {
+				t.Fatalf("%s(%s) = %q, %v", name, absent, v, err)
+			}
+		}
+		if v, err := read([]byte("empty")); err != nil || v == nil || len(v) != 0 {
+			t.Fatalf("%s(empty) = %#v, %v", name, v, err)
+		}
+	}
+
+	values, err := db.MultiGetVersions([]KeyVersion{{Key: []byte("empty")}, {Key: []byte("missing")}, {Key: []byte("gone")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0] == nil || len(values[0]) != 0 || values[1] != nil || values[2] != nil {
+		t.Fatalf("MultiGetVersions = %#v", values)
+	}
+
+	it, err := db.NewIterator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	for it.Next() {
+		if string(it.Key()) != "empty" || it.Value() == nil || len(it.Value()) != 0 {
+			t.Fatalf("iterator %q = %#v", it.Key(), it.Value())
+		}
+	}
+
+	if old, _, err := db.Swap([]byte("empty"), []byte("x")); err != nil || old == nil || len(old) != 0 {
+		t.Fatalf("Swap old = %#v, %v", old, err)
+	}
+	if old, _, err := db.Swap([]byte("gone"), []byte("x")); err != nil || old != nil {
+		t.Fatalf("Swap absent old = %#v, %v", old, err)
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	db := openTestDB(t, nil)
+	_, err := db.Get([]byte("missing"), 0)
+	if code, ok := StatusOf(err); !ok || code != StatusNotFound {
+		t.Fatalf("not found: %v, %v", code, ok)
+	}
+	if code, ok := StatusOf(fmt.Errorf("wrapped: %w", err)); !ok || code != StatusNotFound {
+		t.Fatalf("wrapped not found: %v, %v", code, ok)
+	}
+	_, err = db.Put(nil, []byte("v"))
+	if code, ok := StatusOf(err); !ok || code != StatusInvalidArg {
+		t.Fatalf("empty key: %v, %v", code, ok)
+	}
+	if code, ok := StatusOf(nil); !ok || code != StatusOK {
+		t.Fatalf("nil: %v, %v", code, ok)
+	}
+	if _, ok := StatusOf(errors.New("other")); ok {
+		t.Fatal("unrelated error mapped to a status code")
+	}
+
+	// C层返回的未知状态码原样保留
+	unknown := &unknownStatusError{code: 77}
+	if code, ok := StatusOf(fmt.Errorf("op: %w", unknown)); !ok || code != 77 {
+		t.Fatalf("unknown status: %v, %v", code, ok)
+	}
+	if !strings.Contains(unknown.Error(), "status 77") {
+		t.Fatalf("unknown status message %q", unknown.Error())
+	}
+	for _, code := range []StatusCode{StatusOK, StatusNotFound, StatusIOError} {
+		if code.String() == "" {
+			t.Fatalf("status %d has no message", int(code))
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package amdb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+// 导出流格式版本，两种导出流互不通用
+const (
+	filteredExportFormatV1   = 1
+	verifiableExportFormatV1 = 2
+)
+
+// ErrExportRootMismatch 导出流中的键值与其携带的根哈希不一致
+var ErrExportRootMismatch = errors.New("export root mismatch")
+
+// 过滤导出流格式（整数均为大端）：
+//	[1字节格式版本][4字节长度][子集根哈希][4字节记录数] 每条记录：[4字节长度][键][4字节长度][值]
+// 记录按存储形式的键排序，键值均为存储形式。两种导出流只有格式版本不同
+
+// ExportFiltered 将数据库版本version（0表示最新版本）中keep返回true的存活键值写入w，用于导出部分数据（如单个租户）
+// keep以用户形式的键调用，返回false的键以及已删除的键不导出。流中携带只由导出的键值构建的MPT根哈希（没有键时为空），
+// 它与数据库的根哈希不同：不含未导出的键和删除标记。由ImportFiltered导入空数据库后，其RootHashAtVersion与该子集根哈希一致。
+// 键值以存储形式导出，导入方须使用与本库相同的HashKeys和KeySalt设置
+func (db *Database) ExportFiltered(w io.Writer, version uint32, keep func(key []byte) bool) error {
+	if err := db.checkAuthenticated(); err != nil {
+		return err
+	}
+	if keep == nil {
+		return ErrInvalidArg
+	}
+	state, err := db.stateAt(version)
+	if err != nil {
+		return err
+	}
+	items := state[:0]
+	for _, item := range state {
+		if isDeleted(item.value) {
+			continue
+		}
+		entry, err := db.userEntry(item)
+		if err != nil {
+			return err
+		}
+		if keep(entry.key) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return bytes.Compare(items[i].key, items[j].key) < 0 })
+	root, err := subtreeRoot(items)
+	if err != nil {
+		return err
+	}
+
+	return writeExportStream(w, []byte{filteredExportFormatV1}, root, items)
+}
+
+// ImportFiltered 读取ExportFiltered写出的流，校验子集根哈希后将全部键值作为一次批量写入提交，返回写入后的根哈希
+// 根哈希不一致时返回ErrExportRootMismatch且不写入，流格式错误时返回ErrBadImportRecord。
+// 导入与本库已有的键合并，冲突键见Options.ResolveConflict；流中没有键值时不写入
+func (db *Database) ImportFiltered(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	format, err := br.ReadByte()
+	if err != nil || format != filteredExportFormatV1 {
+		return nil, ErrBadImportRecord
+	}
+	batch, err := readExportStream(br)
+	if err != nil {
+		return nil, err
+	}
+	if len(batch) == 0 {
+		return db.rootHash()
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	return db.importBatch(batch)
+}
+
+// ExportVerifiable 将数据库版本version（0表示最新版本）的完整状态写入w，供第三方独立重建数据库并核对根哈希
+// 与ExportFiltered不同，流中包含全部键（含删除标记）和该版本的数据库根哈希，即RootHashAtVersion(version)：
+// ImportVerifiable由流中的键值重新计算根哈希并与之比对，流被篡改时导入失败。
+// 键值以存储形式导出，导入方须使用与本库相同的HashKeys和KeySalt设置才能按用户形式的键读取
+func (db *Database) ExportVerifiable(w io.Writer, version uint32) error {
+	if err := db.checkAuthenticated(); err != nil {
+		return err
+	}
+	if version == 0 {
+		current, err := db.CurrentVersion()
+		if err != nil {
+			return err
+		}
+		version = current
+	}
+	var items []kv
+	if version > 0 {
+		state, err := db.stateAt(version)
+		if err != nil {
+			return err
+		}
+		items = state
+	}
+	sort.Slice(items, func(i, j int) bool { return bytes.Compare(items[i].key, items[j].key) < 0 })
+	root, err := subtreeRoot(items)
+	if err != nil {
+		return err
+	}
+	return writeExportStream(w, []byte{verifiableExportFormatV1}, root, items)
+}
+
+// ImportVerifiable 读取ExportVerifiable写出的流，重新计算根哈希并与流中的根哈希比对，一致时将全部键值
+// 作为一次批量写入提交，返回写入后的根哈希（与流中的根哈希相同）
+// 根哈希不一致时返回ErrExportRootMismatch且不写入，流格式错误时返回ErrBadImportRecord。
+// 为使导入结果与导出的状态完全一致，本库必须为空（CurrentVersion为0），否则返回ErrInvalidArg；
+// 导入只产生一个版本，原库的历史版本不随流导出。流中没有键值时不写入
+func (db *Database) ImportVerifiable(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	format, err := br.ReadByte()
+	if err != nil || format != verifiableExportFormatV1 {
+		return nil, ErrBadImportRecord
+	}
+	batch, err := readExportStream(br)
+	if err != nil {
+		return nil, err
+	}
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	current, err := db.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current != 0 {
+		return nil, ErrInvalidArg
+	}
+	if len(batch) == 0 {
+		return []byte{}, nil
+	}
+	if _, err := db.importBatch(batch); err != nil {
+		return nil, err
+	}
+	return db.RootHashAtVersion(0)
+}
+
+// writeExportStream 写出导出流：header（格式版本）之后依次为根哈希、记录数和按键排序的记录
+func writeExportStream(w io.Writer, header, root []byte, items []kv) error {
+	bw := bufio.NewWriter(w)
+	header = appendBytes32(header, root)
+	header = wireOrder.AppendUint32(header, uint32(len(items)))
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+	var record []byte
+	for _, item := range items {
+		record = appendBytes32(appendBytes32(record[:0], item.key), item.value)
+		if _, err := bw.Write(record); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// 导出流中各字段的上限，超出即视为流格式错误：根哈希为SHA-256（没有键时为空），键长度与C层MAX_KEY_SIZE一致，
+// 值长度和记录数不超过C层可以传递的长度或数量
+const (
+	maxExportKeyLen   = 0xFFFF
+	maxExportValueLen = maxCLength
+	maxExportRecords  = maxCLength
+)
+
+// readExportStream 读取导出流在流头之后的部分并校验根哈希，返回存储形式的键值
+func readExportStream(br *bufio.Reader) (map[string][]byte, error) {
+	root, err := readLengthPrefixedMax(br, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	if len(root) != 0 && len(root) != sha256.Size {
+		return nil, ErrBadImportRecord
+	}
+	var count uint32
+	if binary.Read(br, wireOrder, &count) != nil || count > maxExportRecords {
+		return nil, ErrBadImportRecord
+	}
+	batch := make(map[string][]byte)
+	var items []kv
+	for i := uint32(0); i < count; i++ {
+		key, err := readLengthPrefixedMax(br, maxExportKeyLen)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readLengthPrefixedMax(br, maxExportValueLen)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := batch[string(key)]; dup || len(key) == 0 {
+			return nil, ErrBadImportRecord
+		}
+		batch[string(key)] = value
+		items = append(items, kv{key: key, value: value})
+	}
+	if _, err := br.ReadByte(); err != io.EOF {
+		return nil, ErrBadImportRecord
+	}
+	got, err := subtreeRoot(items)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(got, root) {
+		return nil, ErrExportRootMismatch
+	}
+	return batch, nil
+}
@@ -0,0 +1,193 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// tenantDB 返回写入了租户a的n个键、租户b的若干键和租户a的一个已删除键的数据库
+func tenantDB(t *testing.T, n int) *Database {
+	t.Helper()
+	db := openTestDB(t, nil)
+	items := make(map[string][]byte)
+	for i := 0; i < n; i++ {
+		items[fmt.Sprintf("a/%05d", i)] = []byte(fmt.Sprintf("va%d", i))
+	}
+	for i := 0; i < 50; i++ {
+		items[fmt.Sprintf("b/%05d", i)] = []byte("vb")
+	}
+	items["a/gone"] = []byte("x")
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete([]byte("a/gone")); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func tenantA(key []byte) bool { return strings.HasPrefix(string(key), "a/") }
+
+func TestExportFilteredReimportsToSubsetRoot(t *testing.T) {
+	const n = 3200
+	src := tenantDB(t, n)
+	var buf bytes.Buffer
+	if err := src.ExportFiltered(&buf, 0, tenantA); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+	// 流头：[格式版本][4字节长度][子集根哈希]
+	subsetRoot := stream[5 : 5+wireOrder.Uint32(stream[1:5])]
+	if bytes.Equal(subsetRoot, rootOf(t, src)) {
+		t.Fatal("subset root equals the full root")
+	}
+
+	dst := openTestDB(t, nil)
+	root, err := dst.ImportFiltered(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, subsetRoot) || !bytes.Equal(stateRoot(t, dst), subsetRoot) {
+		t.Fatal("imported root differs from the subset root")
+	}
+
+	// 全部键作为一次批量写入提交，只产生一个版本
+	history, err := dst.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].KeysChanged != n {
+		t.Fatalf("import produced %d versions: %+v", len(history), history)
+	}
+	if mustGet(t, dst, "a/03199", 0) != "va3199" {
+		t.Fatal("exported key missing")
+	}
+	for _, key := range []string{"b/00000", "a/gone"} {
+		if _, err := dst.Get([]byte(key), 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s imported: %v", key, err)
+		}
+	}
+
+	if err := src.ExportFiltered(&buf, 0, nil); err != ErrInvalidArg {
+		t.Fatalf("nil predicate: %v", err)
+	}
+}
+
+func TestImportFilteredRejectsBadStreams(t *testing.T) {
+	src := tenantDB(t, 10)
+	var buf bytes.Buffer
+	if err := src.ExportFiltered(&buf, 0, tenantA); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+	header := 1 + 4 + int(wireOrder.Uint32(stream[1:5])) + 4
+
+	dst := openTestDB(t, nil)
+	tampered := bytes.Clone(stream)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := dst.ImportFiltered(bytes.NewReader(tampered)); !errors.Is(err, ErrExportRootMismatch) {
+		t.Fatalf("tampered value: %v", err)
+	}
+	if v, err := dst.CurrentVersion(); err != nil || v != 0 {
+		t.Fatalf("tampered stream was written: version %d, %v", v, err)
+	}
+
+	huge := func(at int) []byte {
+		b := bytes.Clone(stream)
+		wireOrder.PutUint32(b[at:], 0xFFFFFFFF)
+		return b
+	}
+	for name, bad := range map[string][]byte{
+		"root length":  huge(1),
+		"record count": huge(header - 4),
+		"key length":   huge(header),
+		"format":       append([]byte{0xFF}, stream[1:]...),
+		"trailing":     append(bytes.Clone(stream), 0),
+		"truncated":    stream[:len(stream)-1],
+	} {
+		if _, err := dst.ImportFiltered(bytes.NewReader(bad)); !errors.Is(err, ErrBadImportRecord) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestExportVerifiableRoundTrip(t *testing.T) {
+	const n = 3200
+	src := tenantDB(t, n)
+	var buf bytes.Buffer
+	if err := src.ExportVerifiable(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+	want := stateRoot(t, src)
+
+	dst := openTestDB(t, nil)
+	root, err := dst.ImportVerifiable(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, want) || !bytes.Equal(stateRoot(t, dst), want) {
+		t.Fatal("imported root differs from the exported root")
+	}
+	// 全部键（含删除标记）作为一次批量写入提交
+	history, err := dst.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].KeysChanged != n+51 {
+		t.Fatalf("import produced %d versions: %+v", len(history), history)
+	}
+	if mustGet(t, dst, "b/00049", 0) != "vb" {
+		t.Fatal("exported key missing")
+	}
+	if _, err := dst.Get([]byte("a/gone"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted key: %v", err)
+	}
+
+	// 已有数据的库拒绝导入
+	if _, err := dst.ImportVerifiable(bytes.NewReader(stream)); err != ErrInvalidArg {
+		t.Fatalf("import into a non-empty database: %v", err)
+	}
+	// 导出较早的版本
+	buf.Reset()
+	if err := src.ExportVerifiable(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	first, err := openTestDB(t, nil).ImportVerifiable(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old, err := src.RootHashAtVersion(1); err != nil || !bytes.Equal(first, old) {
+		t.Fatalf("version 1 export root differs: %v", err)
+	}
+}
+
+func TestImportVerifiableRejectsTampering(t *testing.T) {
+	src := tenantDB(t, 10)
+	var buf bytes.Buffer
+	if err := src.ExportVerifiable(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+	header := 1 + 4 + int(wireOrder.Uint32(stream[1:5])) + 4
+
+	// 翻转根哈希、第一个键或最后一个值中的一个字节都导致根哈希不一致
+	for _, at := range []int{6, header + 4, len(stream) - 1} {
+		tampered := bytes.Clone(stream)
+		tampered[at] ^= 1
+		dst := openTestDB(t, nil)
+		if _, err := dst.ImportVerifiable(bytes.NewReader(tampered)); !errors.Is(err, ErrExportRootMismatch) {
+			t.Fatalf("byte %d flipped: %v", at, err)
+		}
+		if v, err := dst.CurrentVersion(); err != nil || v != 0 {
+			t.Fatalf("tampered stream was written: version %d, %v", v, err)
+		}
+	}
+
+	if _, err := openTestDB(t, nil).ImportVerifiable(bytes.NewReader(append([]byte{filteredExportFormatV1}, stream[1:]...))); !errors.Is(err, ErrBadImportRecord) {
+		t.Fatalf("filtered stream accepted: %v", err)
+	}
+}
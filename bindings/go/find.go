@@ -0,0 +1,39 @@
+package amdb
+
+import "context"
+
+// findCheckInterval FindKeys每检查多少个键检查一次ctx
+const findCheckInterval = 1024
+
+// FindKeys 全量扫描最新版本的存活键，按字典序返回predicate判定为true的键
+// 用于前缀迭代器无法覆盖的后缀、子串等匹配，复杂度为O(n)；只读取键，不读取值。
+// ctx取消或超时时停止扫描并返回ctx.Err()
+func (db *Database) FindKeys(ctx context.Context, predicate func(key []byte) bool) ([][]byte, error) {
+	if predicate == nil {
+		return nil, ErrInvalidArg
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	it, err := db.NewKeyIterator(nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var keys [][]byte
+	for n := 0; it.Next(); n++ {
+		if n%findCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if predicate(it.Key()) {
+			keys = append(keys, it.Key())
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
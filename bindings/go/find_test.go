@@ -0,0 +1,75 @@
+package amdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFindKeys(t *testing.T) {
+	db := openTestDB(t, nil)
+	items := make(map[string][]byte)
+	var want []string
+	for i := 0; i < 50; i++ {
+		k := fmt.Sprintf("user-%02d", i)
+		if i%7 == 0 {
+			k += "-admin"
+			want = append(want, k)
+		}
+		items[k] = []byte("v")
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete([]byte(want[0])); err != nil {
+		t.Fatal(err)
+	}
+	want = want[1:]
+
+	keys, err := db.FindKeys(context.Background(), func(key []byte) bool {
+		return bytes.HasSuffix(key, []byte("-admin"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, k := range keys {
+		got = append(got, string(k))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindKeys = %q, want %q", got, want)
+	}
+
+	if _, err := db.FindKeys(context.Background(), nil); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("nil predicate: %v", err)
+	}
+}
+
+func TestFindKeysCancelled(t *testing.T) {
+	db := openTestDB(t, nil)
+	items := make(map[string][]byte, 3*findCheckInterval)
+	for i := 0; i < 3*findCheckInterval; i++ {
+		items[fmt.Sprintf("k%05d", i)] = []byte("v")
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	seen := 0
+	_, err := db.FindKeys(ctx, func([]byte) bool {
+		if seen++; seen == 10 {
+			cancel()
+		}
+		return true
+	})
+	if !errors.Is(err, context.Canceled) || seen > findCheckInterval+1 {
+		t.Fatalf("err %v after %d keys", err, seen)
+	}
+	if _, err := db.FindKeys(ctx, func([]byte) bool { return true }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("already cancelled: %v", err)
+	}
+}
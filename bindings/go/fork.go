@@ -0,0 +1,126 @@
+package amdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Fork 以数据库版本fromVersion（0表示当前版本）的状态在destDir创建一个独立、可写的副本
+// 副本为物理复制：该版本的全部键值（包括删除标记，以保证根哈希一致）被写入新目录，
+// 两个数据库之后的写入互不影响。副本不包含源数据库的历史版本：键值每chunkKeys个一批写入，
+// 版本号从1重新开始，副本的当前版本即源数据库fromVersion的状态，根哈希与之相同。
+// destDir必须不存在或为空目录
+func (db *Database) Fork(destDir string, fromVersion uint32) (*Database, error) {
+	if entries, err := os.ReadDir(destDir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("fork destination %s is not empty", destDir)
+	}
+
+	state, err := db.stateAt(fromVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	fork, err := NewDatabaseWithOptions(destDir, &Options{HashKeys: db.hashKeys, KeySalt: db.keySalt})
+	if err != nil {
+		return nil, err
+	}
+	// 状态已是存储形式，直接写入；副本尚未返回给调用方，无需加锁
+	for i := 0; i < len(state); i += chunkKeys {
+		end := i + chunkKeys
+		if end > len(state) {
+			end = len(state)
+		}
+		items := make(map[string][]byte, end-i)
+		for _, item := range state[i:end] {
+			items[string(item.key)] = item.value
+		}
+		if _, err := fork.batchPut(items); err != nil {
+			fork.Close()
+			return nil, err
+		}
+	}
+	return fork, nil
+}
+
+// Rehash 将当前版本的全部存活键值重建到destDir中一个以newAlgo构建Merkle树的新数据库，返回新数据库的根哈希
+// 用于哈希算法迁移的一次性工具，新库只保留存活键值：历史版本与删除标记都不迁移，版本号从1重新开始，
+// 因此即使算法相同，根哈希通常也与源库不同。引擎目前只实现SHA-256，
+// 其他算法返回错误且不创建新库；destDir必须不存在或为空目录，源库保持不变。
+// 新库先在Options.TempDir下的临时目录中构建，成功后再移动到destDir，失败时不留下临时文件
+func (db *Database) Rehash(destDir string, newAlgo HashAlgorithm) ([]byte, error) {
+	return db.RehashContext(context.Background(), destDir, newAlgo)
+}
+
+// rehashBatchSize RehashContext每次批量写入新库的键数，两批之间检查ctx
+const rehashBatchSize = chunkKeys
+
+// RehashContext 与Rehash相同，但在读取状态、计算根哈希和每写入rehashBatchSize个键之后检查ctx，
+// 已取消时返回ctx.Err()：临时目录连同已写入的部分被删除，destDir不被创建，源库不受影响
+func (db *Database) RehashContext(ctx context.Context, destDir string, newAlgo HashAlgorithm) ([]byte, error) {
+	if newAlgo != HashSHA256 {
+		return nil, fmt.Errorf("engine does not support hash algorithm %v", newAlgo)
+	}
+	if entries, err := os.ReadDir(destDir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("rehash destination %s is not empty", destDir)
+	}
+
+	state, err := db.stateAt(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	live := make([]kv, 0, len(state))
+	for _, item := range state {
+		if !isDeleted(item.value) {
+			live = append(live, item)
+		}
+	}
+	root, err := buildTrie(live, newAlgo)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	scratch, err := db.scratchDir("amdb-rehash-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+	dest, err := NewDatabaseWithOptions(scratch, &Options{HashKeys: db.hashKeys, KeySalt: db.keySalt})
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(live); i += rehashBatchSize {
+		end := i + rehashBatchSize
+		if end > len(live) {
+			end = len(live)
+		}
+		items := make(map[string][]byte, end-i)
+		for _, item := range live[i:end] {
+			items[string(item.key)] = item.value
+		}
+		if _, err := dest.batchPut(items); err != nil {
+			dest.Close()
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			dest.Close()
+			return nil, err
+		}
+	}
+	if err := dest.Close(); err != nil {
+		return nil, err
+	}
+	if err := moveDir(scratch, destDir); err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return []byte{}, nil
+	}
+	return root.hash, nil
+}
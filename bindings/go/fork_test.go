@@ -0,0 +1,211 @@
+package amdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// rootOf 返回db当前的根哈希
+func rootOf(t *testing.T, db *Database) []byte {
+	t.Helper()
+	root, err := db.GetRootHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestForkIndependent(t *testing.T) {
+	src := openTestDB(t, nil)
+	const n = 3*chunkKeys + 50
+	items := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		items[fmt.Sprintf("k%05d", i)] = []byte("v1")
+	}
+	if _, err := src.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Delete([]byte("k00001")); err != nil {
+		t.Fatal(err)
+	}
+	version, err := src.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, src, "k00002", "later")
+
+	fork, err := src.Fork(filepath.Join(t.TempDir(), "fork"), version)
+	if err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	defer fork.Close()
+
+	// 副本的当前状态即源库fromVersion的状态，删除标记同样复制
+	srcAt, err := src.RootHashAtVersion(version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rootOf(t, fork); !bytes.Equal(got, srcAt) {
+		t.Fatalf("fork root %x, source root at %d %x", got, version, srcAt)
+	}
+	if got := mustGet(t, fork, "k00002", 0); got != "v1" {
+		t.Fatalf("fork k00002: %q", got)
+	}
+	if _, err := fork.Get([]byte("k00001"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted key in fork: %v", err)
+	}
+
+	srcRoot := rootOf(t, src)
+	mustPut(t, fork, "k00003", "fork only")
+	if got := rootOf(t, src); !bytes.Equal(got, srcRoot) {
+		t.Fatal("write to the fork changed the source root")
+	}
+	if got := mustGet(t, src, "k00003", 0); got != "v1" {
+		t.Fatalf("source sees fork write: %q", got)
+	}
+	forkRoot := rootOf(t, fork)
+	mustPut(t, src, "k00004", "source only")
+	if got := rootOf(t, fork); !bytes.Equal(got, forkRoot) {
+		t.Fatal("write to the source changed the fork root")
+	}
+	if got := mustGet(t, fork, "k00004", 0); got != "v1" {
+		t.Fatalf("fork sees source write: %q", got)
+	}
+}
+
+func TestForkRejectsNonEmptyDir(t *testing.T) {
+	src := openTestDB(t, nil)
+	mustPut(t, src, "k", "v")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Fork(dir, 0); err == nil {
+		t.Fatal("fork into a non-empty directory succeeded")
+	}
+}
+
+func TestRehashPreservesLiveState(t *testing.T) {
+	src := openTestDB(t, nil)
+	const n = 2*chunkKeys + 10
+	items := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		items[fmt.Sprintf("k%05d", i)] = []byte(fmt.Sprint("v", i))
+	}
+	if _, err := src.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, src, "k00000", "updated")
+	items["k00000"] = []byte("updated")
+	if err := src.Delete([]byte("k00001")); err != nil {
+		t.Fatal(err)
+	}
+	delete(items, "k00001")
+	srcRoot := rootOf(t, src)
+
+	dir := filepath.Join(t.TempDir(), "rehashed")
+	root, err := src.Rehash(dir, HashSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 删除标记不迁移，根哈希与源库不同
+	if bytes.Equal(root, srcRoot) {
+		t.Fatal("rehashed root equals source root despite dropped tombstones")
+	}
+	if !bytes.Equal(rootOf(t, src), srcRoot) {
+		t.Fatal("source changed")
+	}
+
+	dest, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dest.Close()
+	if !bytes.Equal(stateRoot(t, dest), root) {
+		t.Fatalf("returned root %x, rehashed db root %x", root, stateRoot(t, dest))
+	}
+	state, err := dest.stateAt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state) != len(items) {
+		t.Fatalf("%d keys in rehashed db, want %d", len(state), len(items))
+	}
+	for _, item := range state {
+		if want, ok := items[string(item.key)]; !ok || !bytes.Equal(item.value, want) {
+			t.Fatalf("%s = %q, want %q", item.key, item.value, want)
+		}
+	}
+}
+
+func TestRehashRejectsUnsupported(t *testing.T) {
+	src := openTestDB(t, nil)
+	mustPut(t, src, "k", "v")
+	dir := filepath.Join(t.TempDir(), "rehashed")
+	if _, err := src.Rehash(dir, HashSHA256+1); err == nil {
+		t.Fatal("unsupported algorithm accepted")
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("destination created: %v", err)
+	}
+
+	full := t.TempDir()
+	if err := os.WriteFile(filepath.Join(full, "x"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Rehash(full, HashSHA256); err == nil {
+		t.Fatal("non-empty destination accepted")
+	}
+}
+
+// canceledAfter 在前n次Err调用返回nil，之后返回context.Canceled，用于在长任务的中途取消
+type canceledAfter struct {
+	context.Context
+	n atomic.Int32
+}
+
+func (c *canceledAfter) Err() error {
+	if c.n.Add(-1) < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestRehashContextCanceledMidway(t *testing.T) {
+	scratch := t.TempDir()
+	src := openTestDB(t, &Options{TempDir: scratch})
+	items := make(map[string][]byte)
+	for i := 0; i < 3*rehashBatchSize; i++ {
+		items[fmt.Sprintf("k%05d", i)] = []byte("v")
+	}
+	if _, err := src.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	want := rootOf(t, src)
+
+	// 读取状态、计算根哈希和第一批写入之后放行，第二批写入之后取消
+	ctx := &canceledAfter{Context: context.Background()}
+	ctx.n.Store(3)
+	dest := filepath.Join(t.TempDir(), "rehashed")
+	if _, err := src.RehashContext(ctx, dest, HashSHA256); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled rehash: %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("destination created by a canceled rehash: %v", err)
+	}
+	assertEmptyDir(t, scratch)
+	if !bytes.Equal(rootOf(t, src), want) {
+		t.Fatal("source changed by a canceled rehash")
+	}
+
+	root, err := src.RehashContext(context.Background(), dest, HashSHA256)
+	if err != nil || !bytes.Equal(root, want) {
+		t.Fatalf("rehash after cancellation: %x, %v", root, err)
+	}
+}
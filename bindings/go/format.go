@@ -0,0 +1,118 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// 本绑定所支持的引擎数据目录格式版本（即元数据文件头中的格式版本）
+const (
+	// MinFormatVersion 可直接打开的最低格式版本
+	MinFormatVersion = 1
+	// SupportedFormatVersion 当前引擎写入的格式版本
+	SupportedFormatVersion = 1
+)
+
+var (
+	// ErrFormatTooOld 数据目录的格式版本低于MinFormatVersion，需要先Migrate
+	ErrFormatTooOld = errors.New("data format too old")
+	// ErrFormatTooNew 数据目录的格式版本高于SupportedFormatVersion，由更新的版本创建
+	ErrFormatTooNew = errors.New("data format too new")
+)
+
+// FormatError 数据目录格式版本不受支持，携带检测到的版本与支持的范围
+// 可用errors.Is匹配ErrFormatTooOld或ErrFormatTooNew
+type FormatError struct {
+	Detected  uint16
+	Min       uint16
+	Supported uint16
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("%v: detected format %d, supported %d-%d", e.sentinel(), e.Detected, e.Min, e.Supported)
+}
+
+// Is 使errors.Is(err, ErrFormatTooOld)或errors.Is(err, ErrFormatTooNew)成立
+func (e *FormatError) Is(target error) bool {
+	return target == e.sentinel()
+}
+
+func (e *FormatError) sentinel() error {
+	if e.Detected < e.Min {
+		return ErrFormatTooOld
+	}
+	return ErrFormatTooNew
+}
+
+// formatMigrations 格式迁移步骤：formatMigrations[v]将格式v的目录原地升级为v+1
+// 引擎至今只有格式1，尚无迁移步骤；格式变化时在此登记
+var formatMigrations = map[uint16]func(dataDir string) error{}
+
+// detectFormat 读取元数据文件头中的格式版本，目录尚无元数据文件（新目录）时返回false
+func detectFormat(dataDir string) (uint16, bool, error) {
+	f, err := os.Open(filepath.Join(dataDir, metadataFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+	var header [6]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil || !bytes.Equal(header[:4], metadataMagic) {
+		return 0, false, ErrCorrupted
+	}
+	return engineFileOrder.Uint16(header[4:]), true, nil
+}
+
+// checkFormat 检查数据目录的格式版本能否直接打开，新目录视为可打开
+func checkFormat(dataDir string) error {
+	format, ok, err := detectFormat(dataDir)
+	if err != nil || !ok {
+		return err
+	}
+	if format < MinFormatVersion || format > SupportedFormatVersion {
+		return &FormatError{Detected: format, Min: MinFormatVersion, Supported: SupportedFormatVersion}
+	}
+	return nil
+}
+
+// Migrate 将数据目录原地升级到格式版本targetFormat，目录已是该版本时不做任何修改
+// 迁移期间持有目录锁，数据库不能同时被打开。targetFormat高于SupportedFormatVersion时返回ErrFormatTooNew；
+// 低于当前格式（降级）或缺少所需的迁移步骤时返回错误且不修改目录
+func Migrate(dataDir string, targetFormat int) error {
+	if targetFormat > SupportedFormatVersion {
+		return fmt.Errorf("amdb: target format %d exceeds supported %d: %w", targetFormat, SupportedFormatVersion, ErrFormatTooNew)
+	}
+	lock, err := lockDir(dataDir)
+	if err != nil {
+		return err
+	}
+	defer unlockDir(lock)
+
+	format, ok, err := detectFormat(dataDir)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return os.ErrNotExist
+	}
+	if int(format) > targetFormat {
+		return fmt.Errorf("amdb: cannot downgrade format %d to %d: %w", format, targetFormat, ErrInvalidArg)
+	}
+	for v := format; int(v) < targetFormat; v++ {
+		if _, ok := formatMigrations[v]; !ok {
+			return fmt.Errorf("amdb: no migration from format %d to %d", v, v+1)
+		}
+	}
+	for v := format; int(v) < targetFormat; v++ {
+		if err := formatMigrations[v](dataDir); err != nil {
+			return fmt.Errorf("amdb: migrating format %d to %d: %w", v, v+1, err)
+		}
+	}
+	return nil
+}
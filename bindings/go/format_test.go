@@ -0,0 +1,92 @@
+package amdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stampFormat 改写dir中元数据文件头的格式版本，返回改写前的文件内容
+func stampFormat(t *testing.T, dir string, format uint16) []byte {
+	t.Helper()
+	path := filepath.Join(dir, metadataFileName)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamped := append([]byte{}, original...)
+	engineFileOrder.PutUint16(stamped[4:6], format)
+	if err := os.WriteFile(path, stamped, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return original
+}
+
+// formatTestDir 创建写入了k=v并已关闭的数据库目录
+func formatTestDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "k", "v")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestOpenRejectsUnsupportedFormat(t *testing.T) {
+	dir := formatTestDir(t)
+	stampFormat(t, dir, SupportedFormatVersion+1)
+	_, err := NewDatabase(dir)
+	var fe *FormatError
+	if !errors.Is(err, ErrFormatTooNew) || !errors.As(err, &fe) || fe.Detected != SupportedFormatVersion+1 {
+		t.Fatalf("newer format: %v", err)
+	}
+	if err := Migrate(dir, SupportedFormatVersion+1); !errors.Is(err, ErrFormatTooNew) {
+		t.Fatalf("Migrate past supported: %v", err)
+	}
+	if err := Migrate(dir, SupportedFormatVersion); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("downgrade: %v", err)
+	}
+}
+
+func TestMigrateOlderFormat(t *testing.T) {
+	dir := formatTestDir(t)
+	original := stampFormat(t, dir, MinFormatVersion-1)
+	_, err := NewDatabase(dir)
+	var fe *FormatError
+	if !errors.Is(err, ErrFormatTooOld) || !errors.As(err, &fe) || fe.Detected != MinFormatVersion-1 || fe.Supported != SupportedFormatVersion {
+		t.Fatalf("older format: %v", err)
+	}
+
+	// 没有登记迁移步骤时拒绝迁移且不修改目录
+	if err := Migrate(dir, SupportedFormatVersion); err == nil {
+		t.Fatal("Migrate without a registered step succeeded")
+	}
+	if format, _, _ := detectFormat(dir); format != MinFormatVersion-1 {
+		t.Fatalf("failed Migrate changed the format to %d", format)
+	}
+
+	formatMigrations[MinFormatVersion-1] = func(dataDir string) error {
+		return os.WriteFile(filepath.Join(dataDir, metadataFileName), original, 0o644)
+	}
+	defer delete(formatMigrations, MinFormatVersion-1)
+	if err := Migrate(dir, SupportedFormatVersion); err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("open after Migrate: %v", err)
+	}
+	defer db.Close()
+	if got := mustGet(t, db, "k", 0); got != "v" {
+		t.Fatalf("k = %q after Migrate", got)
+	}
+	if err := Migrate(t.TempDir(), SupportedFormatVersion); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Migrate of a non-database directory: %v", err)
+	}
+}
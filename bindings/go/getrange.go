@@ -0,0 +1,28 @@
+package amdb
+
+// valueRange 值的读取区间
+type valueRange struct {
+	offset, length int
+}
+
+// slice 返回raw中的区间，长度超出末尾的部分被截去
+func (r *valueRange) slice(raw []byte) ([]byte, error) {
+	if r.offset < 0 || r.length < 0 {
+		return nil, ErrInvalidArg
+	}
+	if r.offset > len(raw) {
+		return nil, ErrRangeOutOfBounds
+	}
+	end := len(raw)
+	if r.length < end-r.offset {
+		end = r.offset + r.length
+	}
+	return raw[r.offset:end], nil
+}
+
+// GetRange 读取键在数据库版本version（0表示最新版本）时的值中从offset开始的至多length个字节
+// 只把该区间复制到Go内存。offset超过值的长度时返回ErrRangeOutOfBounds（offset等于长度时返回空切片）；
+// offset+length超过值的末尾时截断到末尾，不视为错误；offset或length为负时返回ErrInvalidArg
+func (db *Database) GetRange(key []byte, version uint32, offset, length int) ([]byte, error) {
+	return db.get(key, ReadOptions{Version: version, AllowStale: true}, &valueRange{offset: offset, length: length})
+}
@@ -0,0 +1,43 @@
+package amdb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetRange(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "k", "0123456789")
+	mustPut(t, db, "k", "abc")
+
+	for _, tc := range []struct {
+		version        uint32
+		offset, length int
+		want           string
+		err            error
+	}{
+		{version: 1, offset: 3, length: 4, want: "3456"},
+		{version: 1, offset: 8, length: 10, want: "89"},
+		{version: 1, offset: 10, length: 1, want: ""},
+		{version: 1, offset: 11, length: 1, err: ErrRangeOutOfBounds},
+		{version: 0, offset: 1, length: 100, want: "bc"},
+		{version: 0, offset: 4, length: 0, err: ErrRangeOutOfBounds},
+		{version: 0, offset: -1, length: 1, err: ErrInvalidArg},
+		{version: 0, offset: 0, length: -1, err: ErrInvalidArg},
+	} {
+		got, err := db.GetRange([]byte("k"), tc.version, tc.offset, tc.length)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("v%d [%d,+%d): err %v, want %v", tc.version, tc.offset, tc.length, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || string(got) != tc.want {
+			t.Errorf("v%d [%d,+%d) = %q, %v; want %q", tc.version, tc.offset, tc.length, got, err, tc.want)
+		}
+	}
+
+	if _, err := db.GetRange([]byte("missing"), 0, 0, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing key: %v", err)
+	}
+}
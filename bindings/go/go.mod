@@ -0,0 +1,3 @@
+module github.com/coretrusts/amdb-bindings/go
+
+go 1.21
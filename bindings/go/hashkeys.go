@@ -0,0 +1,91 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// 哈希键模式（Options.HashKeys，即“安全树”）下，键以SHA-256(key)存入引擎，
+// 原始键与值一起存储：[4字节大端键长度][原始键][值]。
+// 树中所有键等长，深度不超过2*32+1，且不受调用方构造的键影响。
+// 配置Options.KeySalt时以HMAC-SHA256(salt, key)代替SHA-256(key)，存储格式不变。
+
+// StoredKey 返回哈希键模式下key在树中的存储形式：salt为空时为SHA-256(key)，否则为HMAC-SHA256(salt, key)
+// 校验方据此核对哈希键模式下证明中的Key，例如VerifyProof(root, StoredKey(key, salt), proof.Value, proof)
+func StoredKey(key, salt []byte) []byte {
+	if len(salt) == 0 {
+		h := sha256.Sum256(key)
+		return h[:]
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(key)
+	return mac.Sum(nil)
+}
+
+// storedKey 返回键在引擎中的存储形式
+func (db *Database) storedKey(key []byte) []byte {
+	if !db.hashKeys {
+		return key
+	}
+	return StoredKey(key, db.keySalt)
+}
+
+// storedEntry 返回键值对在引擎中的存储形式
+func (db *Database) storedEntry(key, value []byte) ([]byte, []byte) {
+	if !db.hashKeys {
+		return key, value
+	}
+	stored := make([]byte, 0, 4+len(key)+len(value))
+	stored = appendBytes32(stored, key)
+	stored = append(stored, value...)
+	return db.storedKey(key), stored
+}
+
+// storedBatch 返回批量写入在引擎中的存储形式
+func (db *Database) storedBatch(items map[string][]byte) map[string][]byte {
+	if !db.hashKeys {
+		return items
+	}
+	stored := make(map[string][]byte, len(items))
+	for k, v := range items {
+		key, value := db.storedEntry(stringBytes(k), v)
+		stored[string(key)] = value
+	}
+	return stored
+}
+
+// userEntry 将引擎中的存储形式还原为原始键值对，删除标记原样返回
+func (db *Database) userEntry(item kv) (kv, error) {
+	if !db.hashKeys || isDeleted(item.value) {
+		return item, nil
+	}
+	if len(item.value) < 4 {
+		return kv{}, ErrCorrupted
+	}
+	n := wireOrder.Uint32(item.value)
+	if uint64(n) > uint64(len(item.value)-4) {
+		return kv{}, ErrCorrupted
+	}
+	key := item.value[4 : 4+n]
+	if !bytes.Equal(db.storedKey(key), item.key) {
+		return kv{}, ErrCorrupted
+	}
+	return kv{key: key, value: item.value[4+n:]}, nil
+}
+
+// userEntries 逐项还原存储形式，保持原有顺序
+func (db *Database) userEntries(items []kv) ([]kv, error) {
+	if !db.hashKeys {
+		return items, nil
+	}
+	out := make([]kv, len(items))
+	for i, item := range items {
+		entry, err := db.userEntry(item)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = entry
+	}
+	return out, nil
+}
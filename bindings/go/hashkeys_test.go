@@ -0,0 +1,154 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHashKeysLookupAndDepth(t *testing.T) {
+	db := openTestDB(t, &Options{HashKeys: true})
+	// 共享超长前缀的键在普通模式下超过默认深度上限
+	prefix := strings.Repeat("p", 2000)
+	var keys []string
+	items := make(map[string][]byte)
+	for i := 0; i < 50; i++ {
+		k := fmt.Sprintf("%s%03d", prefix, i)
+		keys = append(keys, k)
+		items[k] = []byte("v" + k[len(prefix):])
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "short", "s")
+	keys = append(keys, "short")
+	items["short"] = []byte("s")
+
+	root := rootOf(t, db)
+	for _, k := range keys {
+		if got := mustGet(t, db, k, 0); got != string(items[k]) {
+			t.Fatalf("%.10s…: %q", k, got)
+		}
+		proof, err := db.GetWithProof([]byte(k), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(proof.Steps) > 2*32 {
+			t.Fatalf("depth %d exceeds the bound for 32-byte keys", len(proof.Steps))
+		}
+		if !bytes.Equal(proof.Key, StoredKey([]byte(k), nil)) || !VerifyProof(root, proof.Key, proof.Value, proof) {
+			t.Fatalf("%.10s…: proof is not over the hashed key", k)
+		}
+	}
+
+	// 迭代器返回原始键，顺序按哈希后的键
+	it, err := db.NewIterator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	var got []string
+	var stored [][]byte
+	for it.Next() {
+		got = append(got, string(it.Key()))
+		stored = append(stored, StoredKey(it.Key(), nil))
+		if !bytes.Equal(it.Value(), items[string(it.Key())]) {
+			t.Fatalf("value of %.10s…: %q", it.Key(), it.Value())
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(keys) {
+		t.Fatalf("iterated %d keys, want %d", len(got), len(keys))
+	}
+	if !sort.SliceIsSorted(stored, func(i, j int) bool { return bytes.Compare(stored[i], stored[j]) < 0 }) {
+		t.Fatal("iteration is not in hashed-key order")
+	}
+}
+
+func TestHashKeysReopen(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabaseWithOptions(dir, &Options{HashKeys: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+	if err := db.Delete([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "b", "2")
+	db.Close()
+
+	db, err = NewDatabaseWithOptions(dir, &Options{HashKeys: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if ok, err := db.Has([]byte("a")); err != nil || ok {
+		t.Fatalf("deleted key: %v, %v", ok, err)
+	}
+	if got := mustGet(t, db, "b", 0); got != "2" {
+		t.Fatalf("b: %q", got)
+	}
+	if got := mustGet(t, db, "a", 1); got != "1" {
+		t.Fatalf("a@1: %q", got)
+	}
+}
+
+func TestKeySalt(t *testing.T) {
+	salt := []byte("secret-salt")
+	dir := t.TempDir()
+	db, err := NewDatabaseWithOptions(dir, &Options{KeySalt: salt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "alice", "1")
+	mustPut(t, db, "bob", "2")
+
+	// 相同的盐与键总是映射到相同的存储形式，与盐不同或不加盐时不同
+	stored := StoredKey([]byte("alice"), salt)
+	if !bytes.Equal(stored, StoredKey([]byte("alice"), bytes.Clone(salt))) {
+		t.Fatal("salted key is not deterministic")
+	}
+	if bytes.Equal(stored, StoredKey([]byte("alice"), nil)) || bytes.Equal(stored, StoredKey([]byte("alice"), []byte("other"))) {
+		t.Fatal("salt does not change the stored key")
+	}
+
+	root := rootOf(t, db)
+	proof, err := db.GetWithProof([]byte("alice"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(proof.Key, stored) || !VerifyProof(root, stored, proof.Value, proof) {
+		t.Fatal("proof does not verify for a caller who knows the salt")
+	}
+	if VerifyProof(root, StoredKey([]byte("alice"), nil), proof.Value, proof) {
+		t.Fatal("proof verified for the unsalted key")
+	}
+
+	// 另一个使用相同盐的库写入相同内容得到相同的根哈希，不同的盐得到不同的根哈希
+	same := openTestDB(t, &Options{KeySalt: salt})
+	other := openTestDB(t, &Options{KeySalt: []byte("other")})
+	for _, d := range []*Database{same, other} {
+		mustPut(t, d, "alice", "1")
+		mustPut(t, d, "bob", "2")
+	}
+	if !bytes.Equal(rootOf(t, same), root) || bytes.Equal(rootOf(t, other), root) {
+		t.Fatal("root does not depend on the salt alone")
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err = NewDatabaseWithOptions(dir, &Options{KeySalt: salt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := mustGet(t, db, "alice", 0); got != "1" {
+		t.Fatalf("after reopen: %q", got)
+	}
+}
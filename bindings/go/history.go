@@ -0,0 +1,119 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"time"
+)
+
+// KeyVersionEntry 键在某个数据库版本上的一次变更
+type KeyVersionEntry struct {
+	// Version 写入该值的数据库版本
+	Version uint32
+	// Value 该版本写入的值，删除时为nil
+	Value []byte
+	// Deleted 该版本删除了键
+	Deleted bool
+	// Timestamp 引擎记录的提交时间
+	Timestamp time.Time
+}
+
+// KeyHistory 按版本顺序返回键的每一次变更（写入或删除）及当时的值，用于分析频繁改写的热点键
+// 只读取该键自身的版本链：版本号取自缓存的时间线，每个版本的值单独读取一次，开销与该键的变更次数成正比，
+// 与数据库中的键数量无关。引擎不裁剪历史版本，结果包含该键的全部变更。键从未写入时返回ErrNotFound
+func (db *Database) KeyHistory(key []byte) ([]KeyVersionEntry, error) {
+	if len(key) == 0 {
+		return nil, ErrInvalidArg
+	}
+	if !db.inKeyRange(key) {
+		return nil, ErrNotFound
+	}
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	stored := db.storedKey(key)
+	history := tl.keys[string(stored)]
+	if len(history) == 0 {
+		return nil, ErrNotFound
+	}
+
+	entries := make([]KeyVersionEntry, 0, len(history))
+	for _, h := range history {
+		entry := KeyVersionEntry{Version: h.dbVersion, Timestamp: stampTime(tl.stamps[h.dbVersion-1])}
+		value, err := db.storedValueAt(stored, h.dbVersion)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			entry.Deleted = true
+		case err != nil:
+			return nil, err
+		default:
+			item, err := db.userEntry(kv{key: stored, value: value})
+			if err != nil {
+				return nil, err
+			}
+			entry.Value = item.value
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// GetVersions 返回键在versions中每个数据库版本（0表示最新版本）时的值，结果与versions一一对应，
+// 该版本时键尚未写入或已被删除的位置为nil。版本按缓存的时间线映射到键的版本链，
+// 多个版本落在同一次变更上时该变更的值只读取一次，开销与不同变更的个数成正比。
+// 引擎不裁剪历史版本，不存在被裁剪的版本：versions中有超出当前版本的版本号时整个调用返回ErrVersionNotFound
+func (db *Database) GetVersions(key []byte, versions []uint32) ([][]byte, error) {
+	if len(key) == 0 {
+		return nil, ErrInvalidArg
+	}
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if v > tl.current() {
+			return nil, ErrVersionNotFound
+		}
+	}
+	values := make([][]byte, len(versions))
+	if !db.inKeyRange(key) {
+		return values, nil
+	}
+	stored := db.storedKey(key)
+	history := tl.keys[string(stored)]
+
+	read := make(map[uint32][]byte) // 写入该值的数据库版本 -> 用户形式的值（删除时为nil）
+	for i, v := range versions {
+		if v == 0 {
+			v = tl.current()
+		}
+		// 版本v时生效的变更是版本号不大于v的最后一次
+		n := sort.Search(len(history), func(j int) bool { return history[j].dbVersion > v })
+		if n == 0 {
+			continue
+		}
+		changed := history[n-1].dbVersion
+		if value, ok := read[changed]; ok {
+			values[i] = bytes.Clone(value)
+			continue
+		}
+		value, err := db.storedValueAt(stored, changed)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			value = nil
+		case err != nil:
+			return nil, err
+		default:
+			item, err := db.userEntry(kv{key: stored, value: value})
+			if err != nil {
+				return nil, err
+			}
+			value = item.value
+		}
+		read[changed] = value
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,101 @@
+package amdb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeyHistory(t *testing.T) {
+	for name, opts := range map[string]*Options{"plain keys": nil, "hashed keys": {HashKeys: true}} {
+		t.Run(name, func(t *testing.T) {
+			db := openTestDB(t, opts)
+			start := time.Now().Add(-time.Second)
+			mustPut(t, db, "k", "one")
+			mustPut(t, db, "other", "x")
+			mustPut(t, db, "k", "two")
+			if _, err := db.BatchPut(map[string][]byte{"k": []byte("three"), "other": []byte("y")}); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := db.KeyHistory([]byte("k"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []struct {
+				version uint32
+				value   string
+			}{{1, "one"}, {3, "two"}, {4, "three"}}
+			if len(entries) != len(want) {
+				t.Fatalf("%d entries, want %d", len(entries), len(want))
+			}
+			for i, e := range entries {
+				if e.Version != want[i].version || string(e.Value) != want[i].value || e.Deleted {
+					t.Fatalf("entry %d: version %d value %q deleted %v", i, e.Version, e.Value, e.Deleted)
+				}
+				if e.Timestamp.Before(start) || (i > 0 && e.Timestamp.Before(entries[i-1].Timestamp)) {
+					t.Fatalf("entry %d timestamp %v", i, e.Timestamp)
+				}
+			}
+
+			if err := db.Delete([]byte("k")); err != nil {
+				t.Fatal(err)
+			}
+			entries, err = db.KeyHistory([]byte("k"))
+			if err != nil || len(entries) != 4 {
+				t.Fatalf("after delete: %d entries, %v", len(entries), err)
+			}
+			if last := entries[3]; !last.Deleted || last.Value != nil || last.Version != 5 {
+				t.Fatalf("delete entry %+v", last)
+			}
+
+			if _, err := db.KeyHistory([]byte("never")); err != ErrNotFound {
+				t.Fatalf("unwritten key: %v", err)
+			}
+			if _, err := db.KeyHistory(nil); err != ErrInvalidArg {
+				t.Fatalf("empty key: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetVersions(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "k", "one")
+	if err := db.Delete([]byte("k")); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "k", "three")
+	mustPut(t, db, "other", "x")
+
+	got, err := db.GetVersions([]byte("k"), []uint32{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]byte{[]byte("one"), nil, []byte("three")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("versions 1-3: %q, want %q", got, want)
+	}
+	// 0表示最新版本，重复的版本各自返回独立的副本
+	got, err = db.GetVersions([]byte("k"), []uint32{0, 4, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got[0]) != "three" || string(got[1]) != "three" || string(got[2]) != "three" {
+		t.Fatalf("latest versions: %q", got)
+	}
+	got[1][0] = 'X'
+	if string(got[2]) != "three" {
+		t.Fatal("values for repeated versions share memory")
+	}
+
+	got, err = db.GetVersions([]byte("never"), []uint32{1, 4})
+	if err != nil || got[0] != nil || got[1] != nil {
+		t.Fatalf("unwritten key: %q, %v", got, err)
+	}
+	if _, err := db.GetVersions([]byte("k"), []uint32{1, 5}); err != ErrVersionNotFound {
+		t.Fatalf("version past the current one: %v", err)
+	}
+	if _, err := db.GetVersions(nil, []uint32{1}); err != ErrInvalidArg {
+		t.Fatalf("empty key: %v", err)
+	}
+}
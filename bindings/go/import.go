@@ -0,0 +1,170 @@
+package amdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// 导入流格式：连续的记录，每条记录为
+//
+//	[4字节大端键长度][键][4字节大端值长度][值]
+//
+// 重复的键以后出现的记录为准
+
+const (
+	// importChunkRecords 每次提交的记录数，每提交一批记录一次检查点
+	importChunkRecords = chunkKeys
+	// importCheckpointFile 检查点文件名（位于数据目录下）
+	importCheckpointFile = "import.checkpoint"
+)
+
+// ErrBadImportRecord 导入流中的记录格式错误
+var ErrBadImportRecord = errors.New("malformed import record")
+
+// ImportResumable 从r导入键值记录，支持中断后续传
+// 每提交一批记录都会在数据目录中记录检查点（已提交的偏移量和流长度），
+// 再次对同一数据流调用时从检查点继续，不会重复导入已提交的批次；
+// 流长度与检查点不一致时视为新的数据流并从头导入。导入完成后删除检查点。
+// 与本库已有的键冲突时以导入的值覆盖，配置了Options.ResolveConflict时由其决定。
+// progress在每批提交后以已处理的字节数回调（可为nil）
+func (db *Database) ImportResumable(r io.ReadSeeker, progress func(bytesDone int64)) (root []byte, err error) {
+	if db.dataDir == "" {
+		// FromHandle包装的句柄没有数据目录，无处保存检查点
+		return nil, ErrInvalidArg
+	}
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	ckptPath := filepath.Join(db.dataDir, importCheckpointFile)
+	offset := readImportCheckpoint(ckptPath, size)
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if progress != nil && offset > 0 {
+		progress(offset)
+	}
+
+	br := bufio.NewReader(r)
+	batch := make(map[string][]byte, importChunkRecords)
+	for offset < size {
+		key, value, n, err := readImportRecord(br)
+		if err != nil {
+			return nil, fmt.Errorf("import at offset %d: %w", offset, err)
+		}
+		batch[string(key)] = value
+		offset += n
+
+		if len(batch) >= importChunkRecords || offset == size {
+			if err := db.importChunk(batch); err != nil {
+				return nil, err
+			}
+			if err := writeImportCheckpoint(ckptPath, offset, size); err != nil {
+				return nil, err
+			}
+			if progress != nil {
+				progress(offset)
+			}
+			batch = make(map[string][]byte, importChunkRecords)
+		}
+	}
+
+	os.Remove(ckptPath)
+	return db.rootHash()
+}
+
+// importChunk 提交一批导入记录，配置了Options.ResolveConflict时先解析与已有键的冲突
+func (db *Database) importChunk(batch map[string][]byte) error {
+	if db.resolveConflict == nil {
+		_, err := db.BatchPut(batch)
+		return err
+	}
+	stored, err := db.prepareBatch(context.Background(), batch)
+	if err != nil {
+		return err
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	_, err = db.importBatch(stored)
+	return err
+}
+
+// readImportRecord 读取一条记录，返回键、值及记录占用的字节数
+func readImportRecord(r io.Reader) (key, value []byte, n int64, err error) {
+	if key, err = readLengthPrefixed(r); err != nil {
+		return nil, nil, 0, err
+	}
+	if value, err = readLengthPrefixed(r); err != nil {
+		return nil, nil, 0, err
+	}
+	return key, value, int64(8 + len(key) + len(value)), nil
+}
+
+// readLengthPrefixed 读取[4字节大端长度][数据]
+func readLengthPrefixed(r io.Reader) ([]byte, error) {
+	return readLengthPrefixedMax(r, math.MaxUint32)
+}
+
+// lengthPrefixedChunk 长度超过该值时数据分块读取，内存随实际读到的数据增长，
+// 不会因为损坏或恶意的长度字段一次性分配
+const lengthPrefixedChunk = 1 << 20
+
+// readLengthPrefixedMax 读取[4字节大端长度][数据]，长度超过max时返回ErrBadImportRecord
+func readLengthPrefixedMax(r io.Reader, max uint32) ([]byte, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, ErrBadImportRecord
+	}
+	n := wireOrder.Uint32(lenBuf[:])
+	if n > max {
+		return nil, ErrBadImportRecord
+	}
+	if lr, ok := r.(interface{ Len() int }); ok && int64(n) > int64(lr.Len()) {
+		return nil, ErrBadImportRecord
+	}
+	if n <= lengthPrefixedChunk {
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, ErrBadImportRecord
+		}
+		return data, nil
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, ErrBadImportRecord
+	}
+	return buf.Bytes(), nil
+}
+
+// readImportCheckpoint 读取检查点偏移量，检查点不存在或与流长度不符时返回0
+func readImportCheckpoint(path string, size int64) int64 {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) != 16 {
+		return 0
+	}
+	offset := int64(wireOrder.Uint64(data[0:8]))
+	if int64(wireOrder.Uint64(data[8:16])) != size || offset > size {
+		return 0
+	}
+	return offset
+}
+
+// writeImportCheckpoint 原子写入检查点（先写临时文件再重命名）
+func writeImportCheckpoint(path string, offset, size int64) error {
+	var data [16]byte
+	wireOrder.PutUint64(data[0:8], uint64(offset))
+	wireOrder.PutUint64(data[8:16], uint64(size))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data[:], 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
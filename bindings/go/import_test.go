@@ -0,0 +1,139 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// importStream 编码n条记录key%05d=value%05d
+func importStream(n int) []byte {
+	var buf []byte
+	for i := 0; i < n; i++ {
+		buf = appendBytes32(buf, []byte(fmt.Sprintf("key%05d", i)))
+		buf = appendBytes32(buf, []byte(fmt.Sprintf("value%05d", i)))
+	}
+	return buf
+}
+
+// failingReader 读到limit字节处返回错误，模拟导入中途中断
+type failingReader struct {
+	*bytes.Reader
+	limit int64
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	pos := r.Size() - int64(r.Len())
+	if pos >= r.limit {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if rest := r.limit - pos; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	return r.Reader.Read(p)
+}
+
+// keysChanged 返回各版本变更键数量之和
+func keysChanged(t *testing.T, db *Database) int {
+	t.Helper()
+	infos, err := db.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, info := range infos {
+		total += info.KeysChanged
+	}
+	return total
+}
+
+func TestImportResumableAfterInterrupt(t *testing.T) {
+	const n = 3*importChunkRecords + 100
+	stream := importStream(n)
+
+	want := openTestDB(t, nil)
+	if _, err := want.ImportResumable(bytes.NewReader(stream), nil); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	wantRoot, err := want.RootHashAtVersion(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := openTestDB(t, nil)
+	var done []int64
+	_, err = db.ImportResumable(&failingReader{bytes.NewReader(stream), int64(len(stream)) / 2}, func(n int64) {
+		done = append(done, n)
+	})
+	if err == nil {
+		t.Fatal("interrupted import succeeded")
+	}
+	if len(done) == 0 {
+		t.Fatal("no chunk committed before the interrupt")
+	}
+	checkpoint := done[len(done)-1]
+	if _, err := os.Stat(filepath.Join(db.dataDir, importCheckpointFile)); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+
+	var resumed []int64
+	if _, err := db.ImportResumable(bytes.NewReader(stream), func(n int64) {
+		resumed = append(resumed, n)
+	}); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if resumed[0] != checkpoint || resumed[len(resumed)-1] != int64(len(stream)) {
+		t.Fatalf("progress %v, want from %d to %d", resumed, checkpoint, len(stream))
+	}
+	for i := 1; i < len(resumed); i++ {
+		if resumed[i] <= resumed[i-1] {
+			t.Fatalf("progress not increasing: %v", resumed)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(db.dataDir, importCheckpointFile)); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint left after import: %v", err)
+	}
+
+	root, err := db.RootHashAtVersion(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, wantRoot) {
+		t.Fatalf("root %x, want %x", root, wantRoot)
+	}
+	// 已提交的批次不会再次导入
+	if got := keysChanged(t, db); got != n {
+		t.Fatalf("%d keys written, want %d", got, n)
+	}
+	if got := mustGet(t, db, "key00000", 0); got != "value00000" {
+		t.Fatalf("get: %q", got)
+	}
+}
+
+func TestImportResumableNewStreamStartsOver(t *testing.T) {
+	db := openTestDB(t, nil)
+	stream := importStream(importChunkRecords + 10)
+	if _, err := db.ImportResumable(&failingReader{bytes.NewReader(stream), int64(len(stream)) - 10}, nil); err == nil {
+		t.Fatal("interrupted import succeeded")
+	}
+	// 长度不同的流不沿用检查点
+	other := importStream(20)
+	if _, err := db.ImportResumable(bytes.NewReader(other), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, db, "key00019", 0); got != "value00019" {
+		t.Fatalf("get: %q", got)
+	}
+}
+
+func TestImportResumableBadRecord(t *testing.T) {
+	db := openTestDB(t, nil)
+	stream := append(importStream(1), 0, 0, 0xFF, 0xFF)
+	if _, err := db.ImportResumable(bytes.NewReader(stream), nil); !errors.Is(err, ErrBadImportRecord) {
+		t.Fatalf("got %v, want ErrBadImportRecord", err)
+	}
+}
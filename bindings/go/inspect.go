@@ -0,0 +1,180 @@
+package amdb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// engineFileOrder 引擎数据文件的整数字节序
+// 引擎用Python struct的本机字节序写文件，所支持的平台均为小端；与绑定层自身格式的wireOrder无关
+var engineFileOrder = binary.LittleEndian
+
+// 引擎数据目录中的文件
+const (
+	metadataFileName = "database.amdb"
+	versionsFileName = "versions/versions.ver"
+)
+
+// 引擎文件魔数
+var (
+	metadataMagic = []byte("AMDB")
+	versionsMagic = []byte("VER\x00")
+)
+
+// mptArity 引擎MPT的分支数（每个nibble 16路）
+const mptArity = 16
+
+// DirInfo 不打开数据库即可读取的数据目录信息
+type DirInfo struct {
+	// FormatVersion 元数据文件格式版本
+	FormatVersion uint16
+	// HashAlgorithm Merkle树使用的哈希算法（引擎只实现SHA-256）
+	HashAlgorithm HashAlgorithm
+	// Arity Merkle树的分支数
+	Arity int
+	// CurrentVersion 数据库版本，与打开后的CurrentVersion含义相同
+	CurrentVersion uint32
+	// Root 引擎最近一次刷盘时记录的根哈希（空数据库为空）
+	Root []byte
+	// KeyCount 最近一次刷盘时记录的键数量（包含已删除的键）
+	KeyCount uint64
+}
+
+// InspectDir 读取数据目录的元数据，不打开数据库、不加锁、不修改任何文件
+// 信息来自引擎最近一次刷盘写入的元数据文件和版本文件，与另一个句柄尚未刷盘的写入可能不一致。
+// 目录不是amdb数据库时返回fs.ErrNotExist，文件格式或校验和不符时返回ErrCorrupted
+func InspectDir(dataDir string) (DirInfo, error) {
+	info, err := readMetadataFile(filepath.Join(dataDir, metadataFileName))
+	if err != nil {
+		return DirInfo{}, err
+	}
+	version, err := readVersionCount(filepath.Join(dataDir, filepath.FromSlash(versionsFileName)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return DirInfo{}, err
+	}
+	info.CurrentVersion = version
+	return info, nil
+}
+
+// readMetadataFile 解析元数据文件：
+//
+//	[4字节魔数"AMDB"][2字节格式版本][8字节长度][JSON元数据][32字节SHA-256校验和]
+func readMetadataFile(path string) (DirInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DirInfo{}, err
+	}
+	if len(data) < 4+2+8+sha256.Size || !bytes.Equal(data[:4], metadataMagic) {
+		return DirInfo{}, ErrCorrupted
+	}
+	body, sum := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	if h := sha256.Sum256(body); !bytes.Equal(h[:], sum) {
+		return DirInfo{}, ErrCorrupted
+	}
+	n := engineFileOrder.Uint64(body[6:14])
+	if n != uint64(len(body)-14) {
+		return DirInfo{}, ErrCorrupted
+	}
+
+	var meta struct {
+		TotalKeys  uint64 `json:"total_keys"`
+		MerkleRoot string `json:"merkle_root"`
+	}
+	if err := json.Unmarshal(body[14:], &meta); err != nil {
+		return DirInfo{}, ErrCorrupted
+	}
+	root, err := hex.DecodeString(meta.MerkleRoot)
+	if err != nil {
+		return DirInfo{}, ErrCorrupted
+	}
+	if bytes.Count(root, []byte{0}) == len(root) {
+		// 引擎以全零表示空树
+		root = []byte{}
+	}
+	return DirInfo{
+		FormatVersion: engineFileOrder.Uint16(body[4:6]),
+		HashAlgorithm: HashSHA256,
+		Arity:         mptArity,
+		Root:          root,
+		KeyCount:      meta.TotalKeys,
+	}, nil
+}
+
+// readVersionCount 扫描版本文件，返回不同提交时间的数量（即数据库版本）
+// 版本文件格式：[4字节魔数][2字节格式版本][8字节键数量]，每个键：
+// [4字节长度][键][4字节当前版本][4字节历史数量]，每条历史：
+// [4字节版本][8字节时间戳][4字节长度][值][4字节长度][前一版本哈希]；值只跳过不读取
+func readVersionCount(path string) (uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	var header [14]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil || !bytes.Equal(header[:4], versionsMagic) {
+		return 0, ErrCorrupted
+	}
+	keys := engineFileOrder.Uint64(header[6:])
+
+	var buf [8]byte
+	readUint32 := func() (uint32, error) {
+		if _, err := io.ReadFull(r, buf[:4]); err != nil {
+			return 0, ErrCorrupted
+		}
+		return engineFileOrder.Uint32(buf[:4]), nil
+	}
+	skip := func() error {
+		n, err := readUint32()
+		if err != nil {
+			return err
+		}
+		if _, err := r.Discard(int(n)); err != nil {
+			return ErrCorrupted
+		}
+		return nil
+	}
+
+	stamps := make(map[float64]struct{})
+	for i := uint64(0); i < keys; i++ {
+		if err := skip(); err != nil { // 键
+			return 0, err
+		}
+		if _, err := readUint32(); err != nil { // 当前版本
+			return 0, err
+		}
+		count, err := readUint32()
+		if err != nil {
+			return 0, err
+		}
+		for j := uint32(0); j < count; j++ {
+			if _, err := readUint32(); err != nil { // 版本号
+				return 0, err
+			}
+			if _, err := io.ReadFull(r, buf[:]); err != nil {
+				return 0, ErrCorrupted
+			}
+			stamps[math.Float64frombits(engineFileOrder.Uint64(buf[:]))] = struct{}{}
+			if err := skip(); err != nil { // 值
+				return 0, err
+			}
+			if err := skip(); err != nil { // 前一版本哈希
+				return 0, err
+			}
+		}
+	}
+	if uint64(len(stamps)) > math.MaxUint32 {
+		return 0, ErrCorrupted
+	}
+	return uint32(len(stamps)), nil
+}
@@ -0,0 +1,69 @@
+package amdb
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspectDir(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+	mustPut(t, db, "a", "3")
+	version, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := InspectDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CurrentVersion != version {
+		t.Errorf("CurrentVersion = %d, want %d", info.CurrentVersion, version)
+	}
+	if info.HashAlgorithm != HashSHA256 || info.Arity != mptArity {
+		t.Errorf("algorithm %v arity %d", info.HashAlgorithm, info.Arity)
+	}
+	if len(info.Root) == 0 || info.KeyCount == 0 {
+		t.Errorf("root %x keys %d", info.Root, info.KeyCount)
+	}
+}
+
+func TestInspectDirErrors(t *testing.T) {
+	if _, err := InspectDir(t.TempDir()); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("empty dir: %v", err)
+	}
+
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "k", "v")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, metadataFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InspectDir(dir); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("tampered metadata: %v", err)
+	}
+}
@@ -0,0 +1,476 @@
+package amdb
+
+import (
+	"bytes"
+	"context"
+	"sort"
+)
+
+// Iterator 按键的字典序遍历键值对
+// 迭代器在创建时固定数据库版本并读取该版本的稳定视图：
+// 迭代期间的写入不会影响遍历结果，不会出现遗漏或重复，因此迭代器打开时仍可经同一句柄Put或Delete，
+// 无论迭代器来自数据库还是快照，写入都不会被拒绝。迭代器从不遍历活动的树，
+// 所以不提供在迭代期间拒绝写入的保护（如ErrConcurrentIteration），保护只会阻塞合法的写入。
+// 视图在创建时一次性读入内存，开销与范围内的键数量成正比。
+// 与bufio.Scanner相同，Next在遍历结束和出错时都返回false，之后由Err区分两者
+type Iterator struct {
+	items    []kv
+	pos      int
+	version  uint32
+	keysOnly bool
+	ctx      context.Context // nil表示不可取消
+	err      error
+	// decode 将存储形式还原为原始键值（nil表示items已是原始形式），在Next到达该条目时调用
+	decode func(kv) (kv, error)
+	// prefix 非nil时跳过原始键不以其开头的条目，用于无法按存储形式的键确定范围的哈希键模式
+	prefix []byte
+}
+
+// newIterator 创建遍历items（存储形式，已按存储形式的键排序）的迭代器
+// 哈希键模式下每个条目在Next到达时才还原，存储的值损坏时由Err报告
+func (db *Database) newIterator(items []kv, version uint32) *Iterator {
+	it := &Iterator{items: items, pos: -1, version: version}
+	if db.hashKeys {
+		it.decode = db.userEntry
+	}
+	return it
+}
+
+// NewIterator 创建遍历当前数据库版本全部键值的迭代器
+func (db *Database) NewIterator() (*Iterator, error) {
+	return db.NewRangeIterator(nil, nil, 0)
+}
+
+// NewRangeIterator 创建遍历[start, end)范围的迭代器
+// start或end为nil表示不限制该方向；version为数据库版本（0表示当前版本）
+func (db *Database) NewRangeIterator(start, end []byte, version uint32) (*Iterator, error) {
+	return db.rangeIterator(context.Background(), start, end, version)
+}
+
+// rangeIterator NewRangeIterator的实现，在ctx中为读取视图创建span
+func (db *Database) rangeIterator(ctx context.Context, start, end []byte, version uint32) (it *Iterator, err error) {
+	if _, span := db.startSpan(ctx, "amdb.NewRangeIterator"); span != nil {
+		span.SetAttribute(attrVersion, version)
+		defer func() { endSpan(span, nil, err) }()
+	}
+	start, end = db.clampRange(start, end)
+	if version == 0 {
+		current, err := db.CurrentVersion()
+		if err != nil {
+			return nil, err
+		}
+		version = current
+	}
+
+	var items []kv
+	if version > 0 {
+		state, err := db.stateAt(version)
+		if err != nil {
+			return nil, err
+		}
+		items = liveRange(state, start, end, db.keyOrder())
+	}
+
+	return db.newIterator(items, version), nil
+}
+
+// NewIteratorContext 与NewIterator相同，但ctx取消后Next返回false，Err返回ctx.Err()
+func (db *Database) NewIteratorContext(ctx context.Context) (*Iterator, error) {
+	return db.NewRangeIteratorContext(ctx, nil, nil, 0)
+}
+
+// NewRangeIteratorContext 与NewRangeIterator相同，但ctx取消后Next返回false，Err返回ctx.Err()
+// 创建前ctx已取消时直接返回ctx.Err()。视图在创建时已全部读入，迭代不持有C层游标，
+// 取消时释放视图，调用方不必为此调用Close
+func (db *Database) NewRangeIteratorContext(ctx context.Context, start, end []byte, version uint32) (*Iterator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	it, err := db.rangeIterator(ctx, start, end, version)
+	if err != nil {
+		return nil, err
+	}
+	it.ctx = ctx
+	return it, nil
+}
+
+// NewPrefixIterator 创建遍历版本version（0表示当前版本）中键前缀为prefix的键值对的迭代器
+// 哈希键模式或设置了Options.Comparator时prefix匹配原始键，需要读取全部存活键值后再筛选，遍历顺序同NewRangeIterator
+func (db *Database) NewPrefixIterator(prefix []byte, version uint32) (*Iterator, error) {
+	return db.prefixIterator(context.Background(), prefix, version)
+}
+
+// prefixIterator NewPrefixIterator的实现，读取视图的span在ctx中创建
+func (db *Database) prefixIterator(ctx context.Context, prefix []byte, version uint32) (*Iterator, error) {
+	if !db.hashKeys && db.compare == nil {
+		return db.rangeIterator(ctx, prefix, prefixEnd(prefix), version)
+	}
+	it, err := db.rangeIterator(ctx, nil, nil, version)
+	if err != nil {
+		return nil, err
+	}
+	it.prefix = append([]byte{}, prefix...)
+	return it, nil
+}
+
+// NewPrefixIteratorContext 与NewPrefixIterator相同，取消语义同NewRangeIteratorContext
+func (db *Database) NewPrefixIteratorContext(ctx context.Context, prefix []byte, version uint32) (*Iterator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	it, err := db.prefixIterator(ctx, prefix, version)
+	if err != nil {
+		return nil, err
+	}
+	it.ctx = ctx
+	return it, nil
+}
+
+// prefixEnd 返回大于所有以prefix开头的键的最小键，prefix为空或全为0xff时返回nil（不限制）
+func prefixEnd(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			end := bytes.Clone(prefix[:i+1])
+			end[i]++
+			return end
+		}
+	}
+	return nil
+}
+
+// NewKeyIterator 创建只遍历[start, end)范围内键的迭代器，参数含义同NewRangeIterator
+// 只从引擎复制键而不复制值，值较大时远比NewRangeIterator开销小；Value始终返回nil。
+// HashKeys模式下原始键保存在值中，仍需读取完整值
+func (db *Database) NewKeyIterator(start, end []byte, version uint32) (*Iterator, error) {
+	if db.hashKeys {
+		it, err := db.NewRangeIterator(start, end, version)
+		if err != nil {
+			return nil, err
+		}
+		it.keysOnly = true
+		return it, nil
+	}
+
+	if version == 0 {
+		current, err := db.CurrentVersion()
+		if err != nil {
+			return nil, err
+		}
+		version = current
+	}
+
+	start, end = db.clampRange(start, end)
+	var items []kv
+	if version > 0 {
+		keys, err := db.liveKeysAt(version)
+		if err != nil {
+			return nil, err
+		}
+		compare := db.keyOrder()
+		for _, key := range keys {
+			if inRange(key, start, end, compare) {
+				items = append(items, kv{key: key})
+			}
+		}
+		sort.Slice(items, func(i, j int) bool { return compare(items[i].key, items[j].key) < 0 })
+	}
+
+	return &Iterator{items: items, pos: -1, version: version, keysOnly: true}, nil
+}
+
+// Next 前进到下一个键值对，没有更多数据、ctx已取消或读取出错时返回false
+func (it *Iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.ctx != nil {
+		if err := it.ctx.Err(); err != nil {
+			it.fail(err)
+			return false
+		}
+	}
+	for {
+		if it.pos < len(it.items) {
+			it.pos++
+		}
+		if it.pos >= len(it.items) {
+			return false
+		}
+		if it.decode != nil {
+			item, err := it.decode(it.items[it.pos])
+			if err != nil {
+				it.fail(err)
+				return false
+			}
+			if it.keysOnly {
+				item.value = nil
+			}
+			it.items[it.pos] = item
+		}
+		if it.prefix == nil || bytes.HasPrefix(it.items[it.pos].key, it.prefix) {
+			return true
+		}
+	}
+}
+
+// fail 记录迭代出错的原因并释放视图
+func (it *Iterator) fail(err error) {
+	it.err = err
+	it.Close()
+}
+
+// Key 返回当前键
+func (it *Iterator) Key() []byte {
+	if it.pos < 0 || it.pos >= len(it.items) {
+		return nil
+	}
+	return it.items[it.pos].key
+}
+
+// Value 返回当前值（NewKeyIterator创建的迭代器返回nil）
+func (it *Iterator) Value() []byte {
+	if it.keysOnly || it.pos < 0 || it.pos >= len(it.items) {
+		return nil
+	}
+	return it.items[it.pos].value
+}
+
+// Err 返回迭代提前结束的原因（如存储的数据损坏时为ErrCorrupted，ctx取消时为ctx.Err()），
+// 正常遍历结束时为nil
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Version 返回迭代器固定的数据库版本
+func (it *Iterator) Version() uint32 {
+	return it.version
+}
+
+// Close 释放迭代器持有的视图
+func (it *Iterator) Close() error {
+	it.items = nil
+	it.pos = 0
+	return nil
+}
+
+// liveRange 返回state中按compare的顺序位于[start, end)的存活键值，按该顺序排序
+func liveRange(state []kv, start, end []byte, compare func(a, b []byte) int) []kv {
+	var items []kv
+	for _, item := range state {
+		if !isDeleted(item.value) && inRange(item.key, start, end, compare) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return compare(items[i].key, items[j].key) < 0 })
+	return items
+}
+
+// EstimateRangeCount 估算版本version（0表示当前版本）中[start, end)范围内的存活键数量，用于查询规划
+// 结果只应作为估计值使用。引擎的树节点不记录子树大小，目前通过一次只取键的C调用计数，
+// 不读取值，开销远低于遍历；范围边界的含义与NewRangeIterator相同
+func (db *Database) EstimateRangeCount(start, end []byte, version uint32) (uint64, error) {
+	keys, err := db.liveKeysAt(version)
+	if err != nil {
+		return 0, err
+	}
+	start, end = db.clampRange(start, end)
+	var n uint64
+	compare := db.keyOrder()
+	for _, key := range keys {
+		if inRange(key, start, end, compare) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// inRange 判断key按compare的顺序是否位于[start, end)，nil表示不限制该方向
+func inRange(key, start, end []byte, compare func(a, b []byte) int) bool {
+	return (start == nil || compare(key, start) >= 0) && (end == nil || compare(key, end) < 0)
+}
+
+// Scan 按键的字典序对版本version（0表示当前版本）中[start, end)范围内的每个键值调用fn，范围含义同NewRangeIterator
+// fn返回stop为true时提前结束且Scan返回nil；返回非nil错误时结束并原样返回该错误。
+// 传给fn的key和value只在本次回调期间有效，需要保留时应自行复制
+func (db *Database) Scan(start, end []byte, version uint32, fn func(key, value []byte) (stop bool, err error)) error {
+	it, err := db.NewRangeIterator(start, end, version)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for it.Next() {
+		stop, err := fn(it.Key(), it.Value())
+		if err != nil {
+			return err
+		}
+		if stop {
+			return nil
+		}
+	}
+	return it.Err()
+}
+
+// NextKey 返回版本version（0表示当前版本）中严格大于key的最小存活键，不存在时返回ErrNotFound
+// key本身不必存在。顺序与迭代器一致：哈希键模式下按哈希后的键排序。
+// 同一版本的首次调用读取并排序该版本的全部键，之后二分查找；哈希键模式下每次调用都遍历全部存活键值
+func (db *Database) NextKey(key []byte, version uint32) ([]byte, error) {
+	return db.neighborKey(key, version, true)
+}
+
+// PrevKey 返回版本version（0表示当前版本）中严格小于key的最大存活键，不存在时返回ErrNotFound
+// key本身不必存在。顺序与迭代器一致：哈希键模式下按哈希后的键排序，开销同NextKey
+func (db *Database) PrevKey(key []byte, version uint32) ([]byte, error) {
+	return db.neighborKey(key, version, false)
+}
+
+// neighborKey NextKey与PrevKey的实现，只读取键
+func (db *Database) neighborKey(key []byte, version uint32, next bool) ([]byte, error) {
+	if db.hashKeys {
+		return db.neighborKeyHashed(key, version, next)
+	}
+	keys, err := db.sortedLiveKeys(version)
+	if err != nil {
+		return nil, err
+	}
+	compare := db.keyOrder()
+	if next {
+		i := sort.Search(len(keys), func(i int) bool { return compare(keys[i], key) > 0 })
+		if i == len(keys) {
+			return nil, ErrNotFound
+		}
+		return bytes.Clone(keys[i]), nil
+	}
+	i := sort.Search(len(keys), func(i int) bool { return compare(keys[i], key) >= 0 })
+	if i == 0 {
+		return nil, ErrNotFound
+	}
+	return bytes.Clone(keys[i-1]), nil
+}
+
+// neighborKeyHashed 哈希键模式下的neighborKey：原始键保存在值中，需经迭代器逐个还原
+func (db *Database) neighborKeyHashed(key []byte, version uint32, next bool) ([]byte, error) {
+	it, err := db.NewKeyIterator(nil, nil, version)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	target := db.storedKey(key)
+	compare := db.keyOrder()
+	var found []byte
+	for it.Next() {
+		c := compare(db.storedKey(it.Key()), target)
+		if next && c > 0 {
+			return it.Key(), nil
+		}
+		if !next {
+			if c >= 0 {
+				break
+			}
+			found = it.Key()
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// liveKeyCacheVersions 时间线中最多缓存有序存活键的版本数，超出时清空重建
+const liveKeyCacheVersions = 4
+
+// sortedLiveKeys 返回版本version（0表示当前版本）中位于键范围内的全部存活键，按keyOrder排序（仅非哈希键模式）
+// 结果缓存在时间线中，随时间线在写入后失效；具体版本的内容不可变，缓存期间不会过时。
+// 返回的切片与其中的键由缓存共享，调用方不得修改
+func (db *Database) sortedLiveKeys(version uint32) ([][]byte, error) {
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		if version = tl.current(); version == 0 {
+			return nil, nil
+		}
+	}
+	tl.liveMu.Lock()
+	keys, ok := tl.live[version]
+	tl.liveMu.Unlock()
+	if ok {
+		return keys, nil
+	}
+
+	all, err := db.liveKeysAt(version)
+	if err != nil {
+		return nil, err
+	}
+	keys = all[:0]
+	for _, key := range all {
+		if db.inKeyRange(key) {
+			keys = append(keys, key)
+		}
+	}
+	compare := db.keyOrder()
+	sort.Slice(keys, func(i, j int) bool { return compare(keys[i], keys[j]) < 0 })
+
+	tl.liveMu.Lock()
+	if tl.live == nil || len(tl.live) >= liveKeyCacheVersions {
+		tl.live = make(map[uint32][][]byte)
+	}
+	tl.live[version] = keys
+	tl.liveMu.Unlock()
+	return keys, nil
+}
+
+// FirstKey 返回版本version（0表示当前版本）中最小的存活键，数据库为空时返回ErrNotFound
+// 只从引擎复制键而不复制值；顺序与迭代器一致，哈希键模式下按哈希后的键排序，且需读取值以还原原始键。
+// 与NextKey共用按版本缓存的有序键，同一版本只在首次调用时读取全部键
+func (db *Database) FirstKey(version uint32) ([]byte, error) {
+	return db.edgeKey(version, true)
+}
+
+// LastKey 返回版本version（0表示当前版本）中最大的存活键，数据库为空时返回ErrNotFound，开销同FirstKey
+func (db *Database) LastKey(version uint32) ([]byte, error) {
+	return db.edgeKey(version, false)
+}
+
+// edgeKey FirstKey与LastKey的实现
+// 引擎的树包含删除标记，沿最左（最右）路径下行可能到达已删除的键，因此对只含键的存活集合取最值
+func (db *Database) edgeKey(version uint32, first bool) ([]byte, error) {
+	if db.hashKeys {
+		// 原始键保存在值中，需经迭代器还原
+		it, err := db.NewKeyIterator(nil, nil, version)
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		var edge []byte
+		for it.Next() {
+			edge = it.Key()
+			if first {
+				break
+			}
+		}
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+		if edge == nil {
+			return nil, ErrNotFound
+		}
+		return edge, nil
+	}
+
+	keys, err := db.sortedLiveKeys(version)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrNotFound
+	}
+	if first {
+		return bytes.Clone(keys[0]), nil
+	}
+	return bytes.Clone(keys[len(keys)-1]), nil
+}
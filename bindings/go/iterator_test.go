@@ -0,0 +1,514 @@
+package amdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// collect 遍历it并返回key=value列表
+func collect(t *testing.T, it *Iterator) []string {
+	t.Helper()
+	var got []string
+	for it.Next() {
+		got = append(got, string(it.Key())+"="+string(it.Value()))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestIteratorStableUnderWrites(t *testing.T) {
+	db := openTestDB(t, nil)
+	var want []string
+	for i := 0; i < 10; i++ {
+		k := fmt.Sprintf("k%d", i)
+		mustPut(t, db, k, "old")
+		want = append(want, k+"=old")
+	}
+	it, err := db.NewIterator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	var got []string
+	for i := 0; it.Next(); i++ {
+		got = append(got, string(it.Key())+"="+string(it.Value()))
+		// 迭代中覆盖、删除已遍历和未遍历的键，并写入新键
+		mustPut(t, db, "k9", "new")
+		if err := db.Delete([]byte("k5")); err != nil {
+			t.Fatal(err)
+		}
+		mustPut(t, db, fmt.Sprintf("k%da", i), "inserted")
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("iteration saw writes:\n got %v\nwant %v", got, want)
+	}
+
+	// 新的迭代器看到写入
+	rest, err := db.NewRangeIterator([]byte("k5"), []byte("k6"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, rest); !reflect.DeepEqual(got, []string{"k5a=inserted"}) {
+		t.Fatalf("after writes: %v", got)
+	}
+}
+
+func TestRangeIteratorAtVersion(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+	mustPut(t, db, "c", "3")
+	mustPut(t, db, "b", "22")
+
+	it, err := db.NewRangeIterator([]byte("b"), nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.Version() != 3 {
+		t.Fatalf("version %d", it.Version())
+	}
+	if got := collect(t, it); !reflect.DeepEqual(got, []string{"b=2", "c=3"}) {
+		t.Fatalf("got %v", got)
+	}
+	it, err = db.NewRangeIterator(nil, []byte("c"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, it); !reflect.DeepEqual(got, []string{"a=1", "b=22"}) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+// largeValueDB 返回写入了n个值大小为size字节的键的数据库
+func largeValueDB(t testing.TB, n, size int) *Database {
+	t.Helper()
+	db := openTestDB(t, nil)
+	items := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		items[fmt.Sprintf("k%04d", i)] = make([]byte, size)
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestKeyIterator(t *testing.T) {
+	db := openTestDB(t, nil)
+	for _, k := range []string{"a", "b", "c", "d"} {
+		mustPut(t, db, k, "v"+k)
+	}
+	if err := db.Delete([]byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	it, err := db.NewKeyIterator([]byte("b"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	var keys []string
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+		if it.Value() != nil {
+			t.Fatalf("%s: keys-only iterator returned value %q", it.Key(), it.Value())
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b", "d"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys %q, want %q", keys, want)
+	}
+
+	// 历史版本中c仍存在
+	it, err = db.NewKeyIterator(nil, nil, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	if n := len(collect(t, it)); n != 4 {
+		t.Fatalf("%d keys at version 4", n)
+	}
+}
+
+func BenchmarkIterateLargeValues(b *testing.B) {
+	db := largeValueDB(b, 200, 64<<10)
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			it, err := db.NewRangeIterator(nil, nil, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for it.Next() {
+			}
+			it.Close()
+		}
+	})
+	b.Run("keys-only", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			it, err := db.NewKeyIterator(nil, nil, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for it.Next() {
+			}
+			it.Close()
+		}
+	})
+}
+
+func TestEstimateRangeCount(t *testing.T) {
+	db := openTestDB(t, nil)
+	items := make(map[string][]byte, 1000)
+	for i := 0; i < 1000; i++ {
+		items[fmt.Sprintf("k%04d", i)] = []byte("v")
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		start, end string
+		count      uint64
+	}{
+		{"", "", 1000},
+		{"k0100", "k0300", 200},
+		{"k0990", "", 10},
+		{"", "k0005", 5},
+		{"x", "", 0},
+	} {
+		var start, end []byte
+		if tc.start != "" {
+			start = []byte(tc.start)
+		}
+		if tc.end != "" {
+			end = []byte(tc.end)
+		}
+		got, err := db.EstimateRangeCount(start, end, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 估计值应在真实值的2倍以内
+		if got > 2*tc.count || 2*got < tc.count {
+			t.Fatalf("[%q, %q): estimate %d, true count %d", tc.start, tc.end, got, tc.count)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	db := openTestDB(t, nil)
+	for _, k := range []string{"a", "b", "c", "d"} {
+		mustPut(t, db, k, k+"1")
+	}
+
+	var got []string
+	err := db.Scan([]byte("b"), nil, 0, func(key, value []byte) (bool, error) {
+		got = append(got, string(key)+"="+string(value))
+		return len(got) == 2, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b=b1", "c=c1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("early stop visited %v, want %v", got, want)
+	}
+
+	boom := errors.New("boom")
+	calls := 0
+	err = db.Scan(nil, nil, 0, func(key, value []byte) (bool, error) {
+		calls++
+		return false, boom
+	})
+	if err != boom || calls != 1 {
+		t.Fatalf("callback error: %v after %d calls", err, calls)
+	}
+
+	got = nil
+	err = db.Scan(nil, []byte("c"), 2, func(key, value []byte) (bool, error) {
+		got = append(got, string(key))
+		return false, nil
+	})
+	if err != nil || !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("version 2 scan: %v, %v", got, err)
+	}
+}
+
+func TestNextPrevKey(t *testing.T) {
+	for _, hashKeys := range []bool{false, true} {
+		db := openTestDB(t, &Options{HashKeys: hashKeys})
+		for _, k := range []string{"b", "d", "f"} {
+			mustPut(t, db, k, "v")
+		}
+		// 按迭代器顺序得到键的排列，哈希键模式下与字典序不同
+		var order []string
+		it, err := db.NewKeyIterator(nil, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for it.Next() {
+			order = append(order, string(it.Key()))
+		}
+		first, last := order[0], order[len(order)-1]
+
+		for i, k := range order {
+			next, err := db.NextKey([]byte(k), 0)
+			if i == len(order)-1 {
+				if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("hashKeys=%v: NextKey(last) = %q, %v", hashKeys, next, err)
+				}
+			} else if err != nil || string(next) != order[i+1] {
+				t.Fatalf("hashKeys=%v: NextKey(%q) = %q, %v; want %q", hashKeys, k, next, err, order[i+1])
+			}
+			prev, err := db.PrevKey([]byte(k), 0)
+			if i == 0 {
+				if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("hashKeys=%v: PrevKey(first) = %q, %v", hashKeys, prev, err)
+				}
+			} else if err != nil || string(prev) != order[i-1] {
+				t.Fatalf("hashKeys=%v: PrevKey(%q) = %q, %v; want %q", hashKeys, k, prev, err, order[i-1])
+			}
+		}
+		if hashKeys {
+			continue
+		}
+
+		// key本身不必存在
+		if next, err := db.NextKey([]byte("c"), 0); err != nil || string(next) != "d" {
+			t.Fatalf("NextKey(c) = %q, %v", next, err)
+		}
+		if prev, err := db.PrevKey([]byte("a"), 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("PrevKey before %q = %q, %v", first, prev, err)
+		}
+		// 写入使缓存的有序键失效，历史版本不受影响
+		mustPut(t, db, "g", "v")
+		if err := db.Delete([]byte("d")); err != nil {
+			t.Fatal(err)
+		}
+		if next, err := db.NextKey([]byte(last), 0); err != nil || string(next) != "g" {
+			t.Fatalf("NextKey(%q) after put = %q, %v", last, next, err)
+		}
+		if next, err := db.NextKey([]byte("b"), 0); err != nil || string(next) != "f" {
+			t.Fatalf("NextKey(b) after delete = %q, %v", next, err)
+		}
+		if next, err := db.NextKey([]byte("b"), 3); err != nil || string(next) != "d" {
+			t.Fatalf("NextKey(b) at version 3 = %q, %v", next, err)
+		}
+	}
+	if _, err := openTestDB(t, nil).NextKey([]byte("a"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("empty database: %v", err)
+	}
+}
+
+func TestSortedLiveKeysCached(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "b", "v")
+	mustPut(t, db, "a", "v")
+	keys, err := db.sortedLiveKeys(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := db.sortedLiveKeys(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || string(keys[0]) != "a" || &again[0] != &keys[0] {
+		t.Fatalf("second lookup of version 2 was not served from the cache: %q", again)
+	}
+	mustPut(t, db, "c", "v")
+	keys, err = db.sortedLiveKeys(0)
+	if err != nil || len(keys) != 3 {
+		t.Fatalf("after write: %q, %v", keys, err)
+	}
+	tl, err := db.timeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for v := uint32(1); v <= liveKeyCacheVersions+1; v++ {
+		if _, err := db.sortedLiveKeys(v % 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(tl.live); n > liveKeyCacheVersions {
+		t.Fatalf("cache holds %d versions", n)
+	}
+}
+
+func TestFirstLastKey(t *testing.T) {
+	db := openTestDB(t, nil)
+	if _, err := db.FirstKey(0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FirstKey on empty database: %v", err)
+	}
+	if _, err := db.LastKey(0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LastKey on empty database: %v", err)
+	}
+	if _, err := db.BatchPut(map[string][]byte{"m": {}, "c": {}, "x": {}, "a\x00": {}, "zz": {}}); err != nil {
+		t.Fatal(err)
+	}
+	check := func(version uint32, first, last string) {
+		t.Helper()
+		if got, err := db.FirstKey(version); err != nil || string(got) != first {
+			t.Fatalf("FirstKey(%d) = %q, %v; want %q", version, got, err, first)
+		}
+		if got, err := db.LastKey(version); err != nil || string(got) != last {
+			t.Fatalf("LastKey(%d) = %q, %v; want %q", version, got, err, last)
+		}
+	}
+	check(0, "a\x00", "zz")
+
+	// 删除最值后由删除标记之外的存活键决定；历史版本保持原值
+	if err := db.Delete([]byte("zz")); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "b", "v")
+	check(0, "a\x00", "x")
+	check(1, "a\x00", "zz")
+
+	hashed := openTestDB(t, &Options{HashKeys: true})
+	mustPut(t, hashed, "k", "v")
+	if got, err := hashed.FirstKey(0); err != nil || string(got) != "k" {
+		t.Fatalf("hashed FirstKey = %q, %v", got, err)
+	}
+}
+
+func TestIteratorContextCancel(t *testing.T) {
+	db := openTestDB(t, nil)
+	items := make(map[string][]byte)
+	for i := 0; i < 20; i++ {
+		items[fmt.Sprintf("p%02d", i)] = []byte("v")
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, open := range map[string]func(ctx context.Context) (*Iterator, error){
+		"all": func(ctx context.Context) (*Iterator, error) { return db.NewIteratorContext(ctx) },
+		"range": func(ctx context.Context) (*Iterator, error) {
+			return db.NewRangeIteratorContext(ctx, []byte("p05"), nil, 0)
+		},
+		"prefix": func(ctx context.Context) (*Iterator, error) { return db.NewPrefixIteratorContext(ctx, []byte("p1"), 0) },
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		it, err := open(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := 0
+		for it.Next() {
+			if seen++; seen == 3 {
+				cancel()
+			}
+		}
+		if seen != 3 || !errors.Is(it.Err(), context.Canceled) {
+			t.Fatalf("%s: stopped after %d entries, Err %v", name, seen, it.Err())
+		}
+		if it.Key() != nil || it.items != nil {
+			t.Fatalf("%s: view not released after cancellation", name)
+		}
+		if it.Next() {
+			t.Fatalf("%s: Next after cancellation returned true", name)
+		}
+
+		if _, err := open(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("%s: open with cancelled ctx: %v", name, err)
+		}
+	}
+
+	// 未取消时与不带ctx的迭代器结果相同
+	it, err := db.NewPrefixIteratorContext(context.Background(), []byte("p1"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, it); len(got) != 10 || it.Err() != nil {
+		t.Fatalf("uncancelled prefix iteration: %v, %v", got, it.Err())
+	}
+}
+
+func TestIteratorErrSurfacesCorruption(t *testing.T) {
+	db := openTestDB(t, &Options{HashKeys: true})
+	good := func(key, value string) kv {
+		k, v := db.storedEntry([]byte(key), []byte(value))
+		return kv{key: k, value: v}
+	}
+	// 第二个条目的存储值被截断，模拟读取到损坏的数据
+	items := []kv{good("a", "1"), {key: db.storedKey([]byte("b")), value: []byte{0, 0}}, good("c", "3")}
+
+	it := db.newIterator(items, 1)
+	if !it.Next() || string(it.Key()) != "a" || string(it.Value()) != "1" {
+		t.Fatal("entry before the corruption not delivered")
+	}
+	if it.Next() {
+		t.Fatalf("Next past corrupted entry returned %q", it.Key())
+	}
+	if !errors.Is(it.Err(), ErrCorrupted) {
+		t.Fatalf("Err = %v, want ErrCorrupted", it.Err())
+	}
+	if it.Next() || !errors.Is(it.Err(), ErrCorrupted) {
+		t.Fatal("iterator resumed after error")
+	}
+
+	// 正常结束时Err为nil
+	it = db.newIterator([]kv{good("a", "1"), good("c", "3")}, 1)
+	if got := collect(t, it); len(got) != 2 {
+		t.Fatalf("clean iteration: %v", got)
+	}
+}
+
+func TestWritesNotRejectedDuringIteration(t *testing.T) {
+	db := openTestDB(t, nil)
+	for i := 0; i < 6; i++ {
+		mustPut(t, db, fmt.Sprintf("k%d", i), "old")
+	}
+	snap, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Release()
+
+	// 迭代器读取的是创建时的稳定视图，不存在需要保护的活动树遍历：
+	// 数据库、前缀、仅键与快照迭代器打开时写入都照常提交
+	for name, open := range map[string]func() (*Iterator, error){
+		"live":     db.NewIterator,
+		"prefix":   func() (*Iterator, error) { return db.NewPrefixIterator([]byte("k"), 0) },
+		"keys":     func() (*Iterator, error) { return db.NewKeyIterator(nil, nil, 0) },
+		"snapshot": func() (*Iterator, error) { return snap.NewRangeIterator(nil, nil) },
+	} {
+		it, err := open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		before, err := db.CurrentVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var seen []string
+		for it.Next() {
+			seen = append(seen, string(it.Key()))
+			if _, err := db.Put([]byte(string(it.Key())+"x"), []byte("new")); err != nil {
+				t.Fatalf("%s: Put during iteration: %v", name, err)
+			}
+			if err := db.Delete([]byte("k5")); err != nil {
+				t.Fatalf("%s: Delete during iteration: %v", name, err)
+			}
+		}
+		if err := it.Err(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		it.Close()
+		if v, err := db.CurrentVersion(); err != nil || v != before+uint32(2*len(seen)) {
+			t.Fatalf("%s: writes during iteration not committed: version %d, %v", name, v, err)
+		}
+		if len(seen) == 0 || seen[0] != "k0" {
+			t.Fatalf("%s: iterated %v", name, seen)
+		}
+		mustPut(t, db, "k5", "old")
+	}
+}
@@ -0,0 +1,241 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// 批量写入日志：引擎的批量写入不经过其WAL，数据在刷盘（Compact、Seal、Close）时才落盘，而刷盘逐个分片写出，
+// 刷盘中途崩溃时一次批量写入可能只有部分键落盘。每次批量写入调用C层之前，先把各键及其值的SHA-256追加到
+// 数据目录的批量写入日志并刷入磁盘；刷盘成功后日志中的批量写入都已完整落盘，清空日志。
+// 日志达到batchJournalCheckpoint字节后，下一次批量写入提交后随即刷盘并清空日志，长时间运行的进程中日志大小有上限，
+// 打开时的检查也只涉及最近的批量写入。
+// 打开时日志非空说明上次会话在刷盘之前结束：逐个检查日志中的批量写入，只有部分键落盘的批量写入连同其后的全部版本
+// 经Rewind回退，使数据库回到该批量写入之前的状态，并由OpenInfo.RolledBackBatch报告。
+//
+// 日志格式（整数均为大端）：每条记录[4字节长度][记录]，记录为[8字节开始时间（float64位模式）][4字节键数]
+// 之后每键[4字节键长度][存储形式的键][32字节值的SHA-256]。开始时间取自调用C层之前，
+// 该批量写入产生的版本的提交时间都不早于它
+const batchJournalName = "BATCH_JOURNAL"
+
+// batchJournalCheckpoint 日志达到该字节数后，批量写入提交后随即刷盘并清空日志
+// 日志大小因此不超过该值加一次批量写入的记录
+const batchJournalCheckpoint = 256 << 10
+
+// batchJournal 数据目录中的批量写入日志
+type batchJournal struct {
+	path string
+	// size 日志文件的字节数
+	size int64
+}
+
+// newBatchJournal 返回dataDir的批量写入日志，日志文件在首次记录时创建
+func newBatchJournal(dataDir string) *batchJournal {
+	j := &batchJournal{path: filepath.Join(dataDir, batchJournalName)}
+	if info, err := os.Stat(j.path); err == nil {
+		j.size = info.Size()
+	}
+	return j
+}
+
+// journaledBatch 日志中的一次批量写入
+type journaledBatch struct {
+	start  float64
+	keys   [][]byte
+	hashes [][]byte
+}
+
+// record 将一次批量写入（存储形式的键值）追加到日志并刷入磁盘，返回前日志已落盘
+func (j *batchJournal) record(keys, values [][]byte) error {
+	body := wireOrder.AppendUint64(nil, math.Float64bits(float64(time.Now().UnixNano())/1e9))
+	body = wireOrder.AppendUint32(body, uint32(len(keys)))
+	for i, key := range keys {
+		sum := sha256.Sum256(values[i])
+		body = append(appendBytes32(body, key), sum[:]...)
+	}
+
+	_, statErr := os.Stat(j.path)
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	n, err := f.Write(appendBytes32(nil, body))
+	j.size += int64(n)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && errors.Is(statErr, fs.ErrNotExist) {
+		err = syncDir(filepath.Dir(j.path))
+	}
+	return err
+}
+
+// reset 清空日志（刷盘成功后调用）
+func (j *batchJournal) reset() error {
+	err := os.Remove(j.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		j.size = 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	j.size = 0
+	return syncDir(filepath.Dir(j.path))
+}
+
+// load 读取日志中的全部批量写入，日志不存在时返回nil
+// 末尾不完整的记录来自追加时崩溃，对应的批量写入尚未调用C层，忽略它
+func (j *batchJournal) load() ([]journaledBatch, error) {
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var batches []journaledBatch
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		body, err := readLengthPrefixed(r)
+		if err != nil {
+			break
+		}
+		batch, err := parseJournaledBatch(body)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+// parseJournaledBatch 解析一条完整的日志记录，格式错误时返回ErrCorrupted
+func parseJournaledBatch(body []byte) (journaledBatch, error) {
+	if len(body) < 12 {
+		return journaledBatch{}, ErrCorrupted
+	}
+	batch := journaledBatch{start: math.Float64frombits(wireOrder.Uint64(body))}
+	n := wireOrder.Uint32(body[8:])
+	r := bytes.NewReader(body[12:])
+	for i := uint32(0); i < n; i++ {
+		key, err := readLengthPrefixed(r)
+		if err != nil || r.Len() < sha256.Size {
+			return journaledBatch{}, ErrCorrupted
+		}
+		sum := make([]byte, sha256.Size)
+		r.Read(sum)
+		batch.keys = append(batch.keys, key)
+		batch.hashes = append(batch.hashes, sum)
+	}
+	if r.Len() != 0 {
+		return journaledBatch{}, ErrCorrupted
+	}
+	return batch, nil
+}
+
+// journalBatch 在批量写入调用C层之前记录它（未启用日志时不做任何事）
+func (db *Database) journalBatch(keys, values [][]byte) error {
+	if db.journal == nil {
+		return nil
+	}
+	return db.journal.record(keys, values)
+}
+
+// checkpointBatches 批量写入提交后调用：日志达到batchJournalCheckpoint时刷盘并清空日志（调用方需持有wmu并已enter）
+// 批量写入此时已经提交，刷盘失败时保留日志，下一次批量写入后重试，其中的批量写入仍受日志保护
+func (db *Database) checkpointBatches() {
+	if db.journal == nil || db.journal.size < batchJournalCheckpoint {
+		return
+	}
+	db.flush()
+}
+
+// batchesDurable 刷盘成功后清空批量写入日志
+func (db *Database) batchesDurable() error {
+	if db.journal == nil {
+		return nil
+	}
+	return db.journal.reset()
+}
+
+// recoverBatches 打开时检查批量写入日志，回退只有部分键落盘的批量写入，返回是否回退
+// 同一个键被日志中的多次批量写入写过时，只按最后一次判断：之前的写入本就会被它覆盖。
+// 键落盘指其最新版本提交于该批量写入开始之后且值与日志一致；所有键都已落盘或都未落盘的批量写入保持不变
+func (db *Database) recoverBatches() (bool, error) {
+	batches, err := db.journal.load()
+	if err != nil || len(batches) == 0 {
+		return false, err
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	tl, err := db.timeline()
+	if err != nil {
+		return false, err
+	}
+
+	judged := make(map[string]bool)
+	rollback := false
+	var before float64
+	for i := len(batches) - 1; i >= 0; i-- {
+		batch := batches[i]
+		landed, checked := 0, 0
+		for k, key := range batch.keys {
+			if judged[string(key)] {
+				continue
+			}
+			judged[string(key)] = true
+			checked++
+			ok, err := db.journaledKeyLanded(tl, key, batch.hashes[k], batch.start)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				landed++
+			}
+		}
+		if landed > 0 && landed < checked {
+			rollback, before = true, batch.start
+		}
+	}
+
+	if rollback {
+		// 回退到该批量写入开始之前的最后一个版本
+		target := sort.Search(len(tl.stamps), func(i int) bool { return tl.stamps[i] >= before })
+		if _, err := db.rewindTo(tl, uint32(target)); err != nil {
+			return false, err
+		}
+	}
+	return rollback, db.journal.reset()
+}
+
+// journaledKeyLanded 报告日志中的键是否已以记录的值落盘
+func (db *Database) journaledKeyLanded(tl *timeline, key, hash []byte, start float64) (bool, error) {
+	history := tl.keys[string(key)]
+	if len(history) == 0 {
+		return false, nil
+	}
+	latest := history[len(history)-1].dbVersion
+	if tl.stamps[latest-1] < start {
+		return false, nil
+	}
+	value, err := db.storedValueAt(key, latest)
+	if errors.Is(err, ErrNotFound) {
+		value, err = deletedValue, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(value)
+	return bytes.Equal(sum[:], hash), nil
+}
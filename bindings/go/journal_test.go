@@ -0,0 +1,178 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// partialBatchDir 返回一个数据库目录，其批量写入日志记录了对a、b的一次批量写入，但只有a落盘，
+// 模拟刷盘中途崩溃；同时返回该批量写入之前的根哈希
+func partialBatchDir(t *testing.T, landed map[string]string) (string, []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "base", "0")
+	before := rootOf(t, db)
+
+	// 日志写到别处：Close刷盘成功后会清空数据目录中的日志
+	journal := newBatchJournal(t.TempDir())
+	var keys, values [][]byte
+	for _, kv := range [][2]string{{"a", "1"}, {"b", "2"}} {
+		k, v := db.storedEntry([]byte(kv[0]), []byte(kv[1]))
+		keys, values = append(keys, k), append(values, v)
+	}
+	if err := journal.record(keys, values); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	for k, v := range landed {
+		mustPut(t, db, k, v)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(journal.path, filepath.Join(dir, batchJournalName)); err != nil {
+		t.Fatal(err)
+	}
+	return dir, before
+}
+
+func TestPartialBatchRolledBackOnOpen(t *testing.T) {
+	dir, before := partialBatchDir(t, map[string]string{"a": "1"})
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if !db.OpenInfo().RolledBackBatch {
+		t.Fatal("partial batch not reported as rolled back")
+	}
+	if root := rootOf(t, db); !bytes.Equal(root, before) {
+		t.Fatalf("root %x after rollback, want %x", root, before)
+	}
+	if v, err := db.CurrentVersion(); err != nil || v != 1 {
+		t.Fatalf("version %d, %v", v, err)
+	}
+	if _, err := db.Get([]byte("a"), 0); err != ErrNotFound {
+		t.Fatalf("a after rollback: %v", err)
+	}
+	if got := mustGet(t, db, "base", 0); got != "0" {
+		t.Fatalf("base: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, batchJournalName)); !os.IsNotExist(err) {
+		t.Fatalf("journal kept after recovery: %v", err)
+	}
+}
+
+func TestCompleteOrAbsentBatchKept(t *testing.T) {
+	for name, landed := range map[string]map[string]string{
+		"all":  {"a": "1", "b": "2"},
+		"none": nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir, _ := partialBatchDir(t, landed)
+			db, err := NewDatabase(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if db.OpenInfo().RolledBackBatch {
+				t.Fatal("batch rolled back")
+			}
+			if v, err := db.CurrentVersion(); err != nil || v != uint32(1+len(landed)) {
+				t.Fatalf("version %d, %v", v, err)
+			}
+		})
+	}
+}
+
+func TestJournalIgnoresTruncatedTail(t *testing.T) {
+	j := newBatchJournal(t.TempDir())
+	if err := j.record([][]byte{[]byte("k")}, [][]byte{[]byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 40, 1, 2})
+	f.Close()
+
+	batches, err := j.load()
+	if err != nil || len(batches) != 1 || string(batches[0].keys[0]) != "k" {
+		t.Fatalf("load: %v, %v", batches, err)
+	}
+	if _, err := parseJournaledBatch([]byte{1, 2, 3}); err != ErrCorrupted {
+		t.Fatalf("short record: %v", err)
+	}
+	if err := j.reset(); err != nil {
+		t.Fatal(err)
+	}
+	if batches, err := j.load(); err != nil || batches != nil {
+		t.Fatalf("after reset: %v, %v", batches, err)
+	}
+}
+
+func TestJournalBoundedAcrossBatches(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, batchJournalName)
+	var largest int64
+	checkpoints := 0
+	for b := 0; b < 80; b++ {
+		items := make(map[string][]byte)
+		for i := 0; i < 50; i++ {
+			items[fmt.Sprintf("%s-%03d-%02d", strings.Repeat("k", 48), b, i)] = []byte("v")
+		}
+		before := db.journal.size
+		if _, err := db.BatchPut(items); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		size := int64(0)
+		if err == nil {
+			size = info.Size()
+		} else if !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		if size != db.journal.size {
+			t.Fatalf("batch %d: journal of %d bytes, tracked %d", b, size, db.journal.size)
+		}
+		if size < before {
+			checkpoints++
+		}
+		if size > largest {
+			largest = size
+		}
+	}
+	// 每条记录约5KB，80次批量写入共约400KB
+	if checkpoints == 0 || largest >= batchJournalCheckpoint+8<<10 {
+		t.Fatalf("%d checkpoints, largest journal %d bytes", checkpoints, largest)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if db.OpenInfo().RolledBackBatch {
+		t.Fatal("checkpointed batches rolled back")
+	}
+	if v, err := db.CurrentVersion(); err != nil || v != 80 {
+		t.Fatalf("version %d, %v", v, err)
+	}
+}
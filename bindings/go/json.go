@@ -0,0 +1,163 @@
+package amdb
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// JSON编码约定：所有字节字段编码为小写十六进制字符串（不带0x前缀），
+// 解码时同样只接受十六进制字符串。
+//
+// MerkleProof：
+//
+//	{"key":"…","value":"…","root":"…","leafHash":"…","version":n,"generatedAt":"…","steps":[…]}
+//
+// value在不含值的证明（GetProofOnly）中省略，leafHash仅在此时出现。
+// version和generatedAt（RFC 3339格式）为零值时省略。
+// steps中扩展节点为{"type":"ext","nibble":n}，分支节点为
+// {"type":"branch","nibble":n,"siblings":[16个哈希]}，空子节点及自身所在下标为""。
+//
+// VersionRoot：
+//
+//	{"version":n,"root":"…"}
+
+// hexBytes 编码为十六进制字符串的字节串
+type hexBytes []byte
+
+// MarshalJSON 实现json.Marshaler
+func (b hexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(b))
+}
+
+// UnmarshalJSON 实现json.Unmarshaler
+func (b *hexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// proofStepJSON ProofStep的JSON形式
+type proofStepJSON struct {
+	Type     string     `json:"type"`
+	Nibble   byte       `json:"nibble"`
+	Siblings []hexBytes `json:"siblings,omitempty"`
+}
+
+// merkleProofJSON MerkleProof的JSON形式
+type merkleProofJSON struct {
+	Key         hexBytes    `json:"key"`
+	Value       *hexBytes   `json:"value,omitempty"`
+	Root        hexBytes    `json:"root"`
+	LeafHash    hexBytes    `json:"leafHash,omitempty"`
+	Version     uint32      `json:"version,omitempty"`
+	GeneratedAt *time.Time  `json:"generatedAt,omitempty"`
+	Steps       []ProofStep `json:"steps"`
+}
+
+// versionRootJSON VersionRoot的JSON形式
+type versionRootJSON struct {
+	Version uint32   `json:"version"`
+	Root    hexBytes `json:"root"`
+}
+
+// MarshalJSON 实现json.Marshaler
+func (s ProofStep) MarshalJSON() ([]byte, error) {
+	if !s.Branch {
+		return json.Marshal(proofStepJSON{Type: "ext", Nibble: s.Nibble})
+	}
+	step := proofStepJSON{Type: "branch", Nibble: s.Nibble, Siblings: make([]hexBytes, 16)}
+	for i, h := range s.Siblings {
+		step.Siblings[i] = h
+	}
+	return json.Marshal(step)
+}
+
+// UnmarshalJSON 实现json.Unmarshaler
+func (s *ProofStep) UnmarshalJSON(data []byte) error {
+	var step proofStepJSON
+	if err := json.Unmarshal(data, &step); err != nil {
+		return err
+	}
+	if step.Nibble > 0x0F {
+		return ErrBadProof
+	}
+	result := ProofStep{Nibble: step.Nibble}
+	switch step.Type {
+	case "ext":
+		if len(step.Siblings) != 0 {
+			return ErrBadProof
+		}
+	case "branch":
+		if len(step.Siblings) != 16 {
+			return ErrBadProof
+		}
+		result.Branch = true
+		for i, h := range step.Siblings {
+			if len(h) > 0 {
+				result.Siblings[i] = h
+			}
+		}
+	default:
+		return ErrBadProof
+	}
+	*s = result
+	return nil
+}
+
+// MarshalJSON 实现json.Marshaler
+func (p MerkleProof) MarshalJSON() ([]byte, error) {
+	out := merkleProofJSON{Key: p.Key, Root: p.Root, LeafHash: p.LeafHash, Version: p.Version, Steps: p.Steps}
+	if !p.GeneratedAt.IsZero() {
+		out.GeneratedAt = &p.GeneratedAt
+	}
+	if p.Value != nil || len(p.LeafHash) == 0 {
+		value := hexBytes(p.Value)
+		out.Value = &value
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 实现json.Unmarshaler
+func (p *MerkleProof) UnmarshalJSON(data []byte) error {
+	var in merkleProofJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	proof := MerkleProof{Key: in.Key, Root: in.Root, Steps: in.Steps, Version: in.Version}
+	if in.GeneratedAt != nil {
+		proof.GeneratedAt = *in.GeneratedAt
+	}
+	if len(in.LeafHash) > 0 {
+		proof.LeafHash = in.LeafHash
+	}
+	if in.Value != nil {
+		proof.Value = *in.Value
+	} else if proof.LeafHash == nil {
+		return ErrBadProof
+	}
+	*p = proof
+	return nil
+}
+
+// MarshalJSON 实现json.Marshaler
+func (v VersionRoot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(versionRootJSON{Version: v.Version, Root: v.Root})
+}
+
+// UnmarshalJSON 实现json.Unmarshaler
+func (v *VersionRoot) UnmarshalJSON(data []byte) error {
+	var in versionRootJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*v = VersionRoot{Version: in.Version, Root: in.Root}
+	return nil
+}
@@ -0,0 +1,101 @@
+package amdb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// jsonRoundTrip 编码v并解码到out，返回编码结果
+func jsonRoundTrip(t *testing.T, v, out interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("unmarshal %s: %v", data, err)
+	}
+	again, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Fatalf("re-encoded\n%s\nwant\n%s", again, data)
+	}
+	return data
+}
+
+func TestMerkleProofJSON(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	if _, err := db.Put([]byte("empty"), nil); err != nil {
+		t.Fatal(err)
+	}
+	root := rootOf(t, db)
+	for _, k := range append(keys, "empty") {
+		proof, err := db.GetWithProof([]byte(k), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded MerkleProof
+		data := jsonRoundTrip(t, proof, &decoded)
+		if !strings.Contains(string(data), `"key":"`+hex.EncodeToString([]byte(k))+`"`) ||
+			!strings.Contains(string(data), `"root":"`+hex.EncodeToString(root)+`"`) {
+			t.Fatalf("%s: fields not hex-encoded: %s", k, data)
+		}
+		if !decoded.Verify(root) || decoded.Version != proof.Version || !decoded.GeneratedAt.Equal(proof.GeneratedAt) {
+			t.Fatalf("%s: decoded proof %+v", k, decoded)
+		}
+		if k == "empty" && (decoded.Value == nil || len(decoded.Value) != 0) {
+			t.Fatalf("empty value decoded as %q", decoded.Value)
+		}
+
+		only, err := db.GetProofOnly([]byte(k), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decodedOnly MerkleProof
+		data = jsonRoundTrip(t, only, &decodedOnly)
+		if strings.Contains(string(data), `"value"`) || decodedOnly.Value != nil {
+			t.Fatalf("%s: value-less proof carries a value: %s", k, data)
+		}
+		if !VerifyLeafHash(root, []byte(k), decodedOnly.LeafHash, &decodedOnly) {
+			t.Fatalf("%s: decoded leaf hash proof does not verify", k)
+		}
+	}
+}
+
+func TestMerkleProofJSONRejectsMalformed(t *testing.T) {
+	for _, data := range []string{
+		`{"key":"6b","root":"","steps":[]}`,
+		`{"key":"zz","value":"","root":"","steps":[]}`,
+		`{"key":"6b","value":"","root":"","steps":[{"type":"leaf","nibble":1}]}`,
+		`{"key":"6b","value":"","root":"","steps":[{"type":"ext","nibble":16}]}`,
+		`{"key":"6b","value":"","root":"","steps":[{"type":"branch","nibble":1,"siblings":["00"]}]}`,
+		`{"key":"6b","value":"","root":"","steps":[{"type":"ext","nibble":1,"siblings":["00"]}]}`,
+	} {
+		var p MerkleProof
+		if err := json.Unmarshal([]byte(data), &p); err == nil {
+			t.Fatalf("accepted %s", data)
+		}
+	}
+}
+
+func TestVersionRootJSON(t *testing.T) {
+	for _, vr := range []VersionRoot{
+		{Version: 3, Root: bytes.Repeat([]byte{0xAB}, 32)},
+		{Version: 0, Root: []byte{}},
+	} {
+		var decoded VersionRoot
+		data := jsonRoundTrip(t, vr, &decoded)
+		if decoded.Version != vr.Version || !bytes.Equal(decoded.Root, vr.Root) {
+			t.Fatalf("%s decoded as %+v", data, decoded)
+		}
+	}
+	var vr VersionRoot
+	if err := json.Unmarshal([]byte(`{"version":1,"root":"ab"}`), &vr); err != nil || !bytes.Equal(vr.Root, []byte{0xAB}) {
+		t.Fatalf("decode: %+v, %v", vr, err)
+	}
+}
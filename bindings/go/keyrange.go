@@ -0,0 +1,54 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrOutOfRange 键不在OpenRangeView限定的键范围内
+var ErrOutOfRange = errors.New("key out of range")
+
+// keyRange OpenRangeView限定的键范围[start, end)，nil表示不限制该方向
+type keyRange struct {
+	start, end []byte
+}
+
+// OpenRangeView 以默认选项打开dataDir，返回限定在键范围[start, end)内（nil表示不限制该方向）的视图句柄
+// 这只是对NewDatabase返回句柄的过滤，不是部分加载：引擎没有按键范围加载的接口，打开时仍加载整个数据目录，
+// 内存占用与NewDatabase相同。范围外的键视为不存在：Get、Has、GetWithProof返回ErrNotFound，
+// 迭代、Scan、NextKey/PrevKey、FirstKey/LastKey只看到范围内的键；写入（Put、Delete、批量写入、事务与导入）
+// 范围外的键返回ErrOutOfRange且不写入。根哈希、证明的根、导出、校验和与差异仍覆盖整个数据库，
+// 证明因此可以针对完整数据库的根校验。start不小于end时返回ErrInvalidArg
+func OpenRangeView(dataDir string, start, end []byte) (*Database, error) {
+	if start != nil && end != nil && bytes.Compare(start, end) >= 0 {
+		return nil, ErrInvalidArg
+	}
+	db, err := NewDatabase(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	db.keyRange = &keyRange{start: bytes.Clone(start), end: bytes.Clone(end)}
+	return db, nil
+}
+
+// inKeyRange 报告原始形式的键是否位于OpenRangeView限定的范围内（未限定时总为true）
+func (db *Database) inKeyRange(key []byte) bool {
+	r := db.keyRange
+	return r == nil || inRange(key, r.start, r.end, db.keyOrder())
+}
+
+// clampRange 返回[start, end)与OpenRangeView限定的范围的交集，nil表示不限制该方向
+func (db *Database) clampRange(start, end []byte) ([]byte, []byte) {
+	r := db.keyRange
+	if r == nil {
+		return start, end
+	}
+	compare := db.keyOrder()
+	if r.start != nil && (start == nil || compare(start, r.start) < 0) {
+		start = r.start
+	}
+	if r.end != nil && (end == nil || compare(end, r.end) > 0) {
+		end = r.end
+	}
+	return start, end
+}
@@ -0,0 +1,78 @@
+package amdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestOpenRangeView(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		mustPut(t, db, k, "v"+k)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenRangeView(dir, []byte("d"), []byte("b")); err != ErrInvalidArg {
+		t.Fatalf("inverted range: %v", err)
+	}
+	db, err = OpenRangeView(dir, []byte("b"), []byte("d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, k := range []string{"b", "c"} {
+		if got := mustGet(t, db, k, 0); got != "v"+k {
+			t.Fatalf("%s: %q", k, got)
+		}
+	}
+	for _, k := range []string{"a", "d", "e"} {
+		if _, err := db.Get([]byte(k), 0); err != ErrNotFound {
+			t.Fatalf("get %s outside the range: %v", k, err)
+		}
+		if ok, err := db.Has([]byte(k)); err != nil || ok {
+			t.Fatalf("has %s outside the range: %v, %v", k, ok, err)
+		}
+	}
+	it, err := db.NewIterator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := collect(t, it); !reflect.DeepEqual(keys, []string{"b=vb", "c=vc"}) {
+		t.Fatalf("iterated %v", keys)
+	}
+	if first, err := db.FirstKey(0); err != nil || string(first) != "b" {
+		t.Fatalf("first key %q, %v", first, err)
+	}
+
+	before, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Put([]byte("e"), []byte("x")); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("put outside the range: %v", err)
+	}
+	if err := db.Delete([]byte("a")); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("delete outside the range: %v", err)
+	}
+	if _, err := db.BatchPut(map[string][]byte{"c": []byte("x"), "z": []byte("x")}); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("batch put outside the range: %v", err)
+	}
+	if v, err := db.CurrentVersion(); err != nil || v != before {
+		t.Fatalf("rejected writes committed: version %d, %v", v, err)
+	}
+	if got := mustGet(t, db, "c", 0); got != "vc" {
+		t.Fatalf("c after rejected batch: %q", got)
+	}
+	mustPut(t, db, "bb", "in")
+	if got := mustGet(t, db, "bb", 0); got != "in" {
+		t.Fatalf("bb: %q", got)
+	}
+}
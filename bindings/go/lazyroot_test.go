@@ -0,0 +1,97 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// lazyWrites 对db执行同一组Put和Delete，返回Put报告的根哈希
+func lazyWrites(t *testing.T, db *Database) [][]byte {
+	t.Helper()
+	var roots [][]byte
+	for i := 0; i < 200; i++ {
+		root, err := db.Put([]byte(fmt.Sprintf("key-%03d", i%150)), []byte(fmt.Sprintf("value-%d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+	for i := 0; i < 150; i += 7 {
+		if err := db.Delete([]byte(fmt.Sprintf("key-%03d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return roots
+}
+
+func TestLazyRootMatchesEager(t *testing.T) {
+	eager := openTestDB(t, nil)
+	for _, root := range lazyWrites(t, eager) {
+		if root == nil {
+			t.Fatal("eager Put returned a nil root")
+		}
+	}
+	want := rootOf(t, eager)
+
+	dir := t.TempDir()
+	lazy, err := NewDatabaseWithOptions(dir, &Options{LazyRoot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, root := range lazyWrites(t, lazy) {
+		if root != nil {
+			t.Fatalf("lazy Put %d returned root %x", i, root)
+		}
+	}
+	if got := rootOf(t, lazy); !bytes.Equal(got, want) {
+		t.Fatalf("lazy root %x, eager root %x", got, want)
+	}
+	for _, v := range []uint32{1, 100, 200} {
+		a, err := eager.RootHashAtVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := lazy.RootHashAtVersion(v)
+		if err != nil || !bytes.Equal(a, b) {
+			t.Fatalf("version %d: lazy root %x, eager root %x (%v)", v, b, a, err)
+		}
+	}
+	if err := lazy.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 不带LazyRoot重新打开，根哈希包含延迟期间的写入
+	reopened, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if got := rootOf(t, reopened); !bytes.Equal(got, want) {
+		t.Fatalf("reopened root %x, eager root %x", got, want)
+	}
+	mustPut(t, reopened, "after", "x")
+	mustPut(t, eager, "after", "x")
+	if !bytes.Equal(rootOf(t, reopened), rootOf(t, eager)) {
+		t.Fatal("roots differ after an eager write on the reopened database")
+	}
+}
+
+// BenchmarkLazyRootPut 比较每次Put都更新根哈希与延迟到最后一次GetRootHash的开销
+func BenchmarkLazyRootPut(b *testing.B) {
+	for _, lazy := range []bool{false, true} {
+		b.Run(fmt.Sprintf("lazy=%v", lazy), func(b *testing.B) {
+			db := openTestDB(b, &Options{LazyRoot: lazy})
+			value := bytes.Repeat([]byte{'v'}, 64)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := db.Put([]byte(fmt.Sprintf("key-%08d", i)), value); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if _, err := db.GetRootHash(); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package amdb
+
+/*
+#include "amdb.h"
+*/
+import "C"
+import "sync"
+
+// LibInfo 链接的C库及引擎的版本与能力
+type LibInfo struct {
+	// Version 引擎版本号，查询失败时为空
+	Version string
+	// SupportedHashes 引擎Merkle树支持的哈希算法
+	SupportedHashes []HashAlgorithm
+	// MaxKeySize、MaxValueSize 存储格式允许的最大键、值长度（字节）
+	// 设置了Options.MaxTreeDepth时写入的键还受其限制
+	MaxKeySize   uint64
+	MaxValueSize uint64
+	// Compression 引擎的压缩模块可用
+	Compression bool
+	// InMemory 支持Options.InMemory（由绑定层以临时目录实现，不依赖C库）
+	InMemory bool
+}
+
+var (
+	libInfoOnce sync.Once
+	libInfo     LibInfo
+)
+
+// LibraryInfo 查询链接的C库的版本与能力，用于启动时检查所需功能是否可用
+// 结果在首次调用时从C层取得并缓存；与打开数据库相同，首次调用会初始化引擎的Python环境。
+// 查询失败时只有InMemory有效，Version为空、SupportedHashes为nil
+func LibraryInfo() LibInfo {
+	libInfoOnce.Do(func() {
+		libInfo.InMemory = true
+		var info C.amdb_library_info_t
+		if C.amdb_library_info(&info) != C.AMDB_OK {
+			return
+		}
+		libInfo.Version = C.GoString(&info.version[0])
+		for _, algo := range []HashAlgorithm{HashSHA256} {
+			if info.hash_algorithms&(1<<uint(algo)) != 0 {
+				libInfo.SupportedHashes = append(libInfo.SupportedHashes, algo)
+			}
+		}
+		libInfo.MaxKeySize = uint64(info.max_key_size)
+		libInfo.MaxValueSize = uint64(info.max_value_size)
+		libInfo.Compression = bool(info.compression)
+	})
+	info := libInfo
+	info.SupportedHashes = append([]HashAlgorithm(nil), libInfo.SupportedHashes...)
+	return info
+}
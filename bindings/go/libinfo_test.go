@@ -0,0 +1,26 @@
+package amdb
+
+import "testing"
+
+func TestLibraryInfo(t *testing.T) {
+	info := LibraryInfo()
+	if info.Version == "" {
+		t.Fatal("empty library version")
+	}
+	found := false
+	for _, algo := range info.SupportedHashes {
+		found = found || algo == HashSHA256
+	}
+	if !found {
+		t.Fatalf("supported hashes %v do not include the default %v", info.SupportedHashes, HashSHA256)
+	}
+	if info.MaxKeySize == 0 || info.MaxValueSize < info.MaxKeySize || !info.InMemory {
+		t.Fatalf("capabilities %+v", info)
+	}
+
+	// 返回的切片是副本，修改不影响之后的结果
+	info.SupportedHashes[0] = HashAlgorithm(99)
+	if again := LibraryInfo(); again.SupportedHashes[0] != HashSHA256 {
+		t.Fatalf("cached info modified through the returned slice: %v", again.SupportedHashes)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package amdb
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockDir 对数据目录加排他锁，锁被占用时返回ErrLocked
+func lockDir(dataDir string) (*os.File, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dataDir, "LOCK"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// unlockDir 释放数据目录锁
+func unlockDir(f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return f.Close()
+}
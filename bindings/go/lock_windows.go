@@ -0,0 +1,15 @@
+//go:build windows
+
+package amdb
+
+import "os"
+
+// lockDir Windows下暂不加锁
+func lockDir(dataDir string) (*os.File, error) {
+	return nil, nil
+}
+
+// unlockDir Windows下暂不加锁
+func unlockDir(f *os.File) error {
+	return nil
+}
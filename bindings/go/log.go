@@ -0,0 +1,111 @@
+package amdb
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+// logSeqSize 日志条目键中序号的长度
+const logSeqSize = 8
+
+// Log 建立在键值接口之上的只追加日志，条目的键为日志名加8字节大端序号，序号从0开始连续递增。
+// 条目与其他键一样进入Merkle树，Append返回的根哈希和GetWithProof的证明因此可以验证日志的任一条目。
+// 同一进程中每个日志名只应打开一个Log，多个Log并发追加同一日志会分配重复的序号；经由db直接写入或删除
+// 日志的键同样会破坏序号的连续性
+type Log struct {
+	db   *Database
+	name []byte
+
+	mu   sync.Mutex
+	next uint64 // 下一个条目的序号，即日志长度
+}
+
+// OpenLog 打开名为name的日志，从数据库中已有的条目恢复序号，重新打开后的Append紧接最后一个条目继续编号
+// name不能为空，且不应是其他键或其他日志名的前缀：以name开头但其后不是8字节序号的键不属于该日志，
+// 恢复和遍历时忽略，而恰好长8字节的后缀无法与条目区分。
+// 哈希键模式下键没有顺序，无法按序号遍历，返回ErrInvalidArg
+func (db *Database) OpenLog(name []byte) (*Log, error) {
+	if len(name) == 0 || db.hashKeys {
+		return nil, ErrInvalidArg
+	}
+	l := &Log{db: db, name: bytes.Clone(name)}
+
+	keys, err := db.liveKeysAt(0)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if seq, ok := l.seqOf(key); ok && seq >= l.next {
+			l.next = seq + 1
+		}
+	}
+	return l, nil
+}
+
+// Append 以下一个序号追加条目，返回条目的序号和写入后的根哈希
+// 追加失败时序号不被占用，下一次Append仍使用该序号
+func (l *Log) Append(value []byte) (seq uint64, root []byte, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.db.wmu.Lock()
+	defer l.db.wmu.Unlock()
+	root, err = l.db.put(l.key(l.next), value)
+	if err != nil {
+		return 0, nil, err
+	}
+	seq = l.next
+	l.next++
+	return seq, root, nil
+}
+
+// ReadFrom 创建按序号升序遍历当前版本中序号不小于seq的条目的迭代器
+// 迭代器的Key为条目的完整键，可用Seq取出序号；seq不小于Len时迭代器不产生条目
+func (l *Log) ReadFrom(seq uint64) (*Iterator, error) {
+	it, err := l.db.NewPrefixIterator(l.name, 0)
+	if err != nil {
+		return nil, err
+	}
+	// 前缀下可能有以日志名开头的其他键，只保留条目键；设置了Options.Comparator时迭代器不按字节序，
+	// 条目改按序号（即键的字节序）排列
+	entries := it.items[:0]
+	for _, item := range it.items {
+		if s, ok := l.seqOf(item.key); ok && s >= seq {
+			entries = append(entries, item)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+	it.items = entries
+	return it, nil
+}
+
+// Len 返回日志的条目数，即下一个条目的序号
+func (l *Log) Len() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next
+}
+
+// Seq 返回日志条目的键key中的序号，key不是该日志的条目键时返回ErrInvalidArg
+func (l *Log) Seq(key []byte) (uint64, error) {
+	seq, ok := l.seqOf(key)
+	if !ok {
+		return 0, ErrInvalidArg
+	}
+	return seq, nil
+}
+
+// key 返回序号为seq的条目的键
+func (l *Log) key(seq uint64) []byte {
+	key := make([]byte, 0, len(l.name)+logSeqSize)
+	return wireOrder.AppendUint64(append(key, l.name...), seq)
+}
+
+// seqOf 解析条目键中的序号
+func (l *Log) seqOf(key []byte) (uint64, bool) {
+	if len(key) != len(l.name)+logSeqSize || !bytes.HasPrefix(key, l.name) {
+		return 0, false
+	}
+	return wireOrder.Uint64(key[len(l.name):]), true
+}
@@ -0,0 +1,97 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestLogContinuesAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := db.OpenLog([]byte("events/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		seq, root, err := log.Append([]byte(fmt.Sprintf("e%d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != uint64(i) || !bytes.Equal(root, rootOf(t, db)) {
+			t.Fatalf("append %d: seq %d", i, seq)
+		}
+	}
+	// 以日志名开头但不是条目键的键不属于日志
+	mustPut(t, db, "events/meta", "x")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	log, err = db.OpenLog([]byte("events/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.Len() != 5 {
+		t.Fatalf("Len after reopen %d", log.Len())
+	}
+	seq, root, err := log.Append([]byte("e5"))
+	if err != nil || seq != 5 {
+		t.Fatalf("append after reopen: seq %d, %v", seq, err)
+	}
+
+	it, err := log.ReadFrom(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for it.Next() {
+		s, err := log.Seq(it.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d=%s", s, it.Value()))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[3=e3 4=e4 5=e5]" {
+		t.Fatalf("ReadFrom(3): %v", got)
+	}
+	it, err = log.ReadFrom(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.Next() {
+		t.Fatal("ReadFrom(Len) produced an entry")
+	}
+
+	// 条目与其他键一样可以证明
+	proof, err := db.GetWithProof(log.key(5), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyProof(root, log.key(5), []byte("e5"), proof) {
+		t.Fatal("log entry proof does not verify")
+	}
+	if _, err := log.Seq([]byte("events/meta")); err != ErrInvalidArg {
+		t.Fatalf("Seq of a non-entry key: %v", err)
+	}
+}
+
+func TestOpenLogInvalid(t *testing.T) {
+	if _, err := openTestDB(t, nil).OpenLog(nil); err != ErrInvalidArg {
+		t.Fatalf("empty name: %v", err)
+	}
+	if _, err := openTestDB(t, &Options{HashKeys: true}).OpenLog([]byte("l")); err != ErrInvalidArg {
+		t.Fatalf("hash-key mode: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package amdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain 测试需要嵌入的Python引擎能导入src.amdb，未设置PYTHONPATH时指向仓库根目录
+// 运行前需先构建bindings/c/libamdb.so（见bindings/README.md）
+func TestMain(m *testing.M) {
+	if os.Getenv("PYTHONPATH") == "" {
+		root, err := filepath.Abs(filepath.Join("..", ".."))
+		if err != nil {
+			panic(err)
+		}
+		os.Setenv("PYTHONPATH", root)
+	}
+	// 引擎会打印中文日志，避免在C locale下编码失败
+	if os.Getenv("PYTHONIOENCODING") == "" {
+		os.Setenv("PYTHONIOENCODING", "utf-8")
+	}
+	os.Exit(m.Run())
+}
+
+// openTestDB 在临时目录中打开数据库，测试结束时关闭
+func openTestDB(t testing.TB, opts *Options) *Database {
+	t.Helper()
+	db, err := NewDatabaseWithOptions(t.TempDir(), opts)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// mustPut 写入key=value，失败时终止测试
+func mustPut(t testing.TB, db *Database, key, value string) {
+	t.Helper()
+	if _, err := db.Put([]byte(key), []byte(value)); err != nil {
+		t.Fatalf("put %q: %v", key, err)
+	}
+}
+
+// mustGet 读取版本version中key的值，失败时终止测试
+func mustGet(t testing.TB, db *Database, key string, version uint32) string {
+	t.Helper()
+	value, err := db.Get([]byte(key), version)
+	if err != nil {
+		t.Fatalf("get %q@%d: %v", key, version, err)
+	}
+	return string(value)
+}
@@ -0,0 +1,134 @@
+package amdb
+
+/*
+#include "amdb.h"
+*/
+import "C"
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// MaintenanceType 维护操作类型
+type MaintenanceType int
+
+const (
+	// MaintenanceCompact 压缩（Compact）
+	MaintenanceCompact MaintenanceType = iota
+)
+
+// String 返回操作名称
+func (t MaintenanceType) String() string {
+	switch t {
+	case MaintenanceCompact:
+		return "compact"
+	}
+	return fmt.Sprintf("MaintenanceType(%d)", int(t))
+}
+
+// MaintenancePhase 维护事件所处阶段
+type MaintenancePhase int
+
+const (
+	// MaintenanceStarted 操作开始
+	MaintenanceStarted MaintenancePhase = iota
+	// MaintenanceFinished 操作结束（无论成功与否）
+	MaintenanceFinished
+)
+
+// MaintenanceEvent 维护操作的开始或结束事件，传给Options.OnMaintenance
+type MaintenanceEvent struct {
+	Type  MaintenanceType
+	Phase MaintenancePhase
+	// Versions 受影响的数据库版本数；压缩不删除任何版本，始终为0
+	Versions uint32
+	// BytesReclaimed 仅结束事件：数据目录占用空间的减少量，刷盘写入新文件时可能为负
+	BytesReclaimed int64
+	// Duration 仅结束事件：操作耗时
+	Duration time.Duration
+	// Err 仅结束事件：操作失败的原因，成功时为nil
+	Err error
+}
+
+// Compact 将引擎内存中的数据同步刷盘，由LSM树按需合并SSTable，回收被覆盖记录占用的空间
+// 压缩期间阻塞经由本句柄的写入。引擎保留全部历史版本，压缩不改变任何版本的内容和根哈希。
+// 配置了Options.OnMaintenance时在开始和结束时各回调一次，回调在不持有写锁时调用
+func (db *Database) Compact() error {
+	return db.CompactContext(context.Background())
+}
+
+// CompactContext 与Compact相同，但在等待写锁之后、刷盘开始之前检查ctx，已取消时返回ctx.Err()且不刷盘。
+// 刷盘是引擎的一次调用，开始后无法中断，会执行完毕；压缩不创建临时文件，取消时数据库保持压缩前的状态
+func (db *Database) CompactContext(ctx context.Context) (err error) {
+	ctx, span := db.startSpan(ctx, "amdb.Compact")
+	if span != nil {
+		defer func() { endSpan(span, nil, err) }()
+	}
+	db.maintenance(MaintenanceEvent{Type: MaintenanceCompact, Phase: MaintenanceStarted})
+	start := time.Now()
+	before := dirSize(db.dataDir)
+	defer func() {
+		db.maintenance(MaintenanceEvent{
+			Type:           MaintenanceCompact,
+			Phase:          MaintenanceFinished,
+			BytesReclaimed: before - dirSize(db.dataDir),
+			Duration:       time.Since(start),
+			Err:            err,
+		})
+	}()
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	if err := db.enter(); err != nil {
+		return err
+	}
+	defer db.leave()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return db.flush()
+}
+
+// flush 将引擎内存中的数据同步刷盘，成功后清空批量写入日志（调用方需持有wmu并已enter）
+func (db *Database) flush() error {
+	written := db.writeAmpStart()
+	cgoStart := db.cgoStart()
+	status := C.amdb_flush(db.handle)
+	db.cgoEnd(cgoStart)
+	if status != C.AMDB_OK {
+		return statusError(status)
+	}
+	db.writeAmpEnd(written, 0)
+	return db.batchesDurable()
+}
+
+// maintenance 调用维护事件回调（未配置时不做任何事）
+func (db *Database) maintenance(event MaintenanceEvent) {
+	if db.onMaintenance != nil {
+		db.onMaintenance(event)
+	}
+}
+
+// dirSize 返回目录下全部普通文件的总字节数（目录为空串或不可读时为0）
+func dirSize(dir string) int64 {
+	if dir == "" {
+		return 0
+	}
+	var total int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
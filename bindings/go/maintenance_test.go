@@ -0,0 +1,94 @@
+package amdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCompactMaintenanceEvents(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []MaintenanceEvent
+	)
+	db := openTestDB(t, &Options{OnMaintenance: func(e MaintenanceEvent) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}})
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "a", "2")
+	before := rootOf(t, db)
+
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].Type != MaintenanceCompact || events[0].Phase != MaintenanceStarted {
+		t.Errorf("first event %+v", events[0])
+	}
+	if end := events[1]; end.Type != MaintenanceCompact || end.Phase != MaintenanceFinished || end.Err != nil || end.Duration <= 0 {
+		t.Errorf("second event %+v", end)
+	}
+	if !bytes.Equal(rootOf(t, db), before) || mustGet(t, db, "a", 1) != "1" {
+		t.Fatal("compaction changed contents")
+	}
+}
+
+func TestCompactFinishedReportsError(t *testing.T) {
+	var last MaintenanceEvent
+	db, err := NewDatabaseWithOptions(t.TempDir(), &Options{OnMaintenance: func(e MaintenanceEvent) { last = e }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if err := db.Compact(); err == nil {
+		t.Fatal("Compact on closed database succeeded")
+	}
+	if last.Phase != MaintenanceFinished || last.Err == nil {
+		t.Fatalf("finished event %+v", last)
+	}
+}
+
+func TestCompactContextCanceled(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+	if _, err := db.BatchPut(map[string][]byte{"b": []byte("2"), "c": []byte("3")}); err != nil {
+		t.Fatal(err)
+	}
+	want := rootOf(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := db.CompactContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled compaction: %v", err)
+	}
+	if !bytes.Equal(rootOf(t, db), want) {
+		t.Fatal("root changed by a canceled compaction")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("reopen after canceled compaction: %v", err)
+	}
+	defer db.Close()
+	if !bytes.Equal(rootOf(t, db), want) || mustGet(t, db, "c", 0) != "3" {
+		t.Fatal("pre-compaction state lost")
+	}
+	if err := db.CompactContext(context.Background()); err != nil || !bytes.Equal(rootOf(t, db), want) {
+		t.Fatalf("compaction: %v", err)
+	}
+}
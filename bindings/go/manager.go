@@ -0,0 +1,173 @@
+package amdb
+
+import (
+	"container/list"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Manager 按数据目录缓存打开的数据库句柄，适用于每个租户一个数据库的服务
+// 句柄在首次Get时打开，超过maxOpen个时关闭最久未使用的句柄，空闲超过idleTimeout的句柄也会被关闭。
+// 关闭经由Close完成，会等待该句柄上正在执行的调用结束；被淘汰句柄上之后的调用返回ErrClosed，
+// 因此调用方应在每次使用前通过Get取得句柄，而不是长期持有
+type Manager struct {
+	opts        *Options
+	maxOpen     int
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	lru      *list.List // 元素为*managedDB，表头为最近使用
+	entries  map[string]*list.Element
+	evicting map[string]chan struct{} // 正在关闭的目录，关闭完成时关闭通道
+	closed   bool
+	stop     chan struct{}
+}
+
+// managedDB Manager缓存的一个句柄
+type managedDB struct {
+	dir      string
+	db       *Database
+	lastUsed time.Time
+}
+
+// NewManager 创建句柄管理器，opts用于打开每个数据库（可为nil）
+// maxOpen为同时打开的句柄上限（0表示不限制）；idleTimeout为空闲关闭时间（0表示不按空闲关闭）
+func NewManager(maxOpen int, idleTimeout time.Duration, opts *Options) *Manager {
+	m := &Manager{
+		opts:        opts,
+		maxOpen:     maxOpen,
+		idleTimeout: idleTimeout,
+		lru:         list.New(),
+		entries:     make(map[string]*list.Element),
+		evicting:    make(map[string]chan struct{}),
+		stop:        make(chan struct{}),
+	}
+	if idleTimeout > 0 {
+		go m.reapIdle()
+	}
+	return m
+}
+
+// Get 返回dataDir的数据库句柄，尚未打开（或已被淘汰）时打开
+// 同一目录总是返回同一个句柄，直到它被淘汰；管理器关闭后返回ErrClosed
+func (m *Manager) Get(dataDir string) (*Database, error) {
+	dir := filepath.Clean(dataDir)
+	for {
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return nil, ErrClosed
+		}
+		if elem, ok := m.entries[dir]; ok {
+			entry := elem.Value.(*managedDB)
+			entry.lastUsed = time.Now()
+			m.lru.MoveToFront(elem)
+			m.mu.Unlock()
+			return entry.db, nil
+		}
+		// 同一目录的旧句柄尚未关闭完成时，等待其释放目录锁
+		if done, ok := m.evicting[dir]; ok {
+			m.mu.Unlock()
+			<-done
+			continue
+		}
+		break
+	}
+	defer m.mu.Unlock()
+
+	// 持有锁打开，保证同一目录只打开一次
+	db, err := NewDatabaseWithOptions(dir, m.opts)
+	if err != nil {
+		return nil, err
+	}
+	m.entries[dir] = m.lru.PushFront(&managedDB{dir: dir, db: db, lastUsed: time.Now()})
+	if m.maxOpen > 0 {
+		for m.lru.Len() > m.maxOpen {
+			m.evictLocked(m.lru.Back())
+		}
+	}
+	return db, nil
+}
+
+// Len 返回当前打开的句柄数
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// Close 关闭全部句柄并停止空闲回收，等待每个句柄上正在执行的调用完成
+// 返回第一个关闭错误；之后的Get返回ErrClosed
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	m.closed = true
+	close(m.stop)
+	var dbs []*Database
+	for elem := m.lru.Front(); elem != nil; elem = elem.Next() {
+		dbs = append(dbs, elem.Value.(*managedDB).db)
+	}
+	m.lru.Init()
+	m.entries = make(map[string]*list.Element)
+	pending := make([]chan struct{}, 0, len(m.evicting))
+	for _, done := range m.evicting {
+		pending = append(pending, done)
+	}
+	m.mu.Unlock()
+
+	var first error
+	for _, db := range dbs {
+		if err := db.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	for _, done := range pending {
+		<-done
+	}
+	return first
+}
+
+// evictLocked 从缓存中移除句柄并在后台关闭（调用方需持有mu）
+func (m *Manager) evictLocked(elem *list.Element) {
+	entry := m.lru.Remove(elem).(*managedDB)
+	delete(m.entries, entry.dir)
+	done := make(chan struct{})
+	m.evicting[entry.dir] = done
+	go func() {
+		entry.db.Close()
+		m.mu.Lock()
+		delete(m.evicting, entry.dir)
+		m.mu.Unlock()
+		close(done)
+	}()
+}
+
+// reapIdle 定期关闭空闲超时的句柄，直到管理器关闭
+func (m *Manager) reapIdle() {
+	interval := m.idleTimeout / 2
+	if interval <= 0 {
+		interval = m.idleTimeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for elem := m.lru.Back(); elem != nil; {
+				prev := elem.Prev()
+				if now.Sub(elem.Value.(*managedDB).lastUsed) >= m.idleTimeout {
+					m.evictLocked(elem)
+				}
+				elem = prev
+			}
+			m.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package amdb
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManagerCachesHandles(t *testing.T) {
+	base := t.TempDir()
+	m := NewManager(2, 0, nil)
+	defer m.Close()
+
+	a, err := m.Get(filepath.Join(base, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := m.Get(filepath.Join(base, "a", "."))
+	if err != nil || again != a {
+		t.Fatalf("second Get returned %p (%v), want %p", again, err, a)
+	}
+	mustPut(t, a, "k", "v")
+
+	if _, err := m.Get(filepath.Join(base, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(filepath.Join(base, "c")); err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", m.Len())
+	}
+	// a最久未使用，已被淘汰；再次Get会等待旧句柄关闭完成后重新打开
+	reopened, err := m.Get(filepath.Join(base, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Ping(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("evicted handle: %v", err)
+	}
+	if reopened == a || mustGet(t, reopened, "k", 0) != "v" {
+		t.Fatal("evicted directory not reopened with its data")
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(filepath.Join(base, "a")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Get after Close: %v", err)
+	}
+}
+
+func TestManagerIdleEviction(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(0, 50*time.Millisecond, nil)
+	defer m.Close()
+
+	db, err := m.Get(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle handle not evicted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	reopened, err := m.Get(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened == db {
+		t.Fatal("idle handle returned after eviction")
+	}
+	mustPut(t, reopened, "k", "v")
+}
+
+func TestManagerConcurrent(t *testing.T) {
+	base := t.TempDir()
+	m := NewManager(2, 0, nil)
+	defer m.Close()
+
+	const dirs, goroutines, rounds = 4, 8, 20
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		written = make(map[string]string)
+	)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				dir := filepath.Join(base, fmt.Sprint((g+i)%dirs))
+				db, err := m.Get(dir)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				key := fmt.Sprintf("g%d-%d", g, i)
+				// 句柄可能在Get之后被其他goroutine的Get淘汰，此时写入以ErrClosed失败
+				if _, err := db.Put([]byte(key), []byte(dir)); err != nil {
+					if !errors.Is(err, ErrClosed) {
+						t.Error(err)
+					}
+					continue
+				}
+				mu.Lock()
+				written[key] = dir
+				mu.Unlock()
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := m.Len(); n > 2 {
+		t.Fatalf("Len = %d exceeds maxOpen", n)
+	}
+
+	for key, dir := range written {
+		db, err := m.Get(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mustGet(t, db, key, 0); got != dir {
+			t.Fatalf("%s in %s = %q", key, dir, got)
+		}
+	}
+}
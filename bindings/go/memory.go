@@ -0,0 +1,28 @@
+package amdb
+
+import "os"
+
+// openTemp 在专属临时目录中打开一次性数据库，Close时删除该目录
+// 临时目录不会被其他句柄共享，因此不需要打开重试，也不需要同步新建目录
+func openTemp(opts *Options) (*Database, error) {
+	dir, err := os.MkdirTemp(opts.TempDir, "amdb-mem-")
+	if err != nil {
+		return nil, err
+	}
+	tempOpts := *opts
+	tempOpts.InMemory = false
+	tempOpts.OpenRetry = nil
+	noSync := false
+	tempOpts.SyncOnCreate = &noSync
+
+	db, err := NewDatabaseWithOptions(dir, &tempOpts)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	db.tempDir = dir
+	// 临时目录随关闭删除，不需要崩溃恢复
+	db.journal = nil
+	db.opts.InMemory = true
+	return db, nil
+}
@@ -0,0 +1,51 @@
+package amdb
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInMemory(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		dataDir string
+		opts    *Options
+	}{
+		{"option", filepath.Join(t.TempDir(), "ignored"), &Options{InMemory: true}},
+		{"memory dir", MemoryDataDir, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := NewDatabaseWithOptions(tc.dataDir, tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mustPut(t, db, "k", "v")
+			if _, err := db.BatchPut(map[string][]byte{"a": []byte("1"), "b": []byte("2")}); err != nil {
+				t.Fatal(err)
+			}
+			if got := mustGet(t, db, "k", 0); got != "v" {
+				t.Fatalf("k: %q", got)
+			}
+			if got := mustGet(t, db, "b", 0); got != "2" {
+				t.Fatalf("b: %q", got)
+			}
+			temp := db.tempDir
+			if temp == "" {
+				t.Fatal("not opened as a temporary database")
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// 给定的目录从未创建，临时目录随关闭删除
+			for _, dir := range []string{tc.dataDir, temp} {
+				if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("%s exists after Close: %v", dir, err)
+				}
+			}
+		})
+	}
+}
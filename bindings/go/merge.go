@@ -0,0 +1,151 @@
+package amdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ConflictPolicy Merge遇到两库都存在的键时的处理方式
+type ConflictPolicy int
+
+const (
+	// ConflictKeepExisting 保留本库的值，跳过src中的该键
+	ConflictKeepExisting ConflictPolicy = iota
+	// ConflictOverwrite 以src的值覆盖本库的值
+	ConflictOverwrite
+	// ConflictFail 存在任何冲突键时返回*MergeConflictError且不写入
+	ConflictFail
+	// ConflictResolve 由Options.ResolveConflict决定冲突键的取值，未配置时返回ErrInvalidArg
+	ConflictResolve
+)
+
+// String 返回策略名称
+func (p ConflictPolicy) String() string {
+	switch p {
+	case ConflictKeepExisting:
+		return "keep-existing"
+	case ConflictOverwrite:
+		return "overwrite"
+	case ConflictFail:
+		return "fail"
+	case ConflictResolve:
+		return "resolve"
+	}
+	return fmt.Sprintf("ConflictPolicy(%d)", int(p))
+}
+
+// ErrMergeConflict Merge在ConflictFail策略下遇到两库都存在的键
+var ErrMergeConflict = errors.New("merge conflict")
+
+// MergeConflictError ConflictFail策略下按键排序的第一个冲突键
+type MergeConflictError struct {
+	// Key 冲突的键（哈希键模式下为存储形式）
+	Key []byte
+}
+
+func (e *MergeConflictError) Error() string {
+	return fmt.Sprintf("%v: key %q exists in both databases", ErrMergeConflict, e.Key)
+}
+
+// Is 使errors.Is(err, ErrMergeConflict)成立
+func (e *MergeConflictError) Is(target error) bool {
+	return target == ErrMergeConflict
+}
+
+// mergeBatchSize Merge每次从src读取并提交的键数
+const mergeBatchSize = chunkKeys
+
+// Merge 将src最新版本的全部存活键值并入本数据库，两库都存在的键按onConflict处理，返回合并后的根哈希
+// 合并在整个过程中持有本库的写锁：先只读取两库的键确定冲突，再按键排序每次从src读取mergeBatchSize个值
+// 并作为一次批量写入提交，不会把src整体读入内存。因此合并产生多个版本，中间版本只含部分合并结果；
+// 需要一致视图的读取方应固定合并前或合并后的版本读取。ConflictFail策略在写入任何数据之前检测冲突，
+// ConflictResolve策略在提交每批时对其中的冲突键调用Options.ResolveConflict。
+// 本库已删除的键不视为冲突。src必须使用与本库相同的HashKeys和KeySalt设置，否则返回ErrInvalidArg；
+// src为本库自身时不做任何修改。src在合并期间的写入不影响合并内容
+func (db *Database) Merge(src *Database, onConflict ConflictPolicy) (root []byte, err error) {
+	if src == nil || src.hashKeys != db.hashKeys || !bytes.Equal(src.keySalt, db.keySalt) {
+		return nil, ErrInvalidArg
+	}
+	if onConflict < ConflictKeepExisting || onConflict > ConflictResolve {
+		return nil, ErrInvalidArg
+	}
+	if onConflict == ConflictResolve && db.resolveConflict == nil {
+		return nil, ErrInvalidArg
+	}
+	if src == db {
+		return db.rootHash()
+	}
+	if _, span := db.startSpan(context.Background(), "amdb.Merge"); span != nil {
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	version, err := src.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	if version > 0 {
+		if keys, err = src.liveKeysAt(version); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	if onConflict == ConflictKeepExisting || onConflict == ConflictFail {
+		existing, err := db.liveKeysAt(0)
+		if err != nil {
+			return nil, err
+		}
+		live := make(map[string]struct{}, len(existing))
+		for _, key := range existing {
+			live[string(key)] = struct{}{}
+		}
+		kept := keys[:0]
+		for _, key := range keys {
+			if _, ok := live[string(key)]; !ok {
+				kept = append(kept, key)
+				continue
+			}
+			if onConflict == ConflictFail {
+				return nil, &MergeConflictError{Key: key}
+			}
+		}
+		keys = kept
+	}
+
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > mergeBatchSize {
+			n = mergeBatchSize
+		}
+		values := make([][]byte, n)
+		for i, key := range keys[:n] {
+			if values[i], err = src.storedValueAt(key, version); err != nil {
+				return nil, err
+			}
+		}
+		chunk := keys[:n]
+		keys = keys[n:]
+		if onConflict == ConflictResolve {
+			if chunk, values, err = db.resolveConflicts(chunk, values); err != nil {
+				return nil, err
+			}
+			if len(chunk) == 0 {
+				continue
+			}
+		}
+		if root, err = db.batchPutSlices(chunk, values); err != nil {
+			return nil, err
+		}
+	}
+	if root == nil {
+		return db.rootHash()
+	}
+	return root, nil
+}
@@ -0,0 +1,127 @@
+package amdb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// mergeDBs 返回分别写入了dst与src键值的两个数据库
+func mergeDBs(t *testing.T, dst, src map[string]string) (*Database, *Database) {
+	t.Helper()
+	a, b := openTestDB(t, nil), openTestDB(t, nil)
+	for k, v := range dst {
+		mustPut(t, a, k, v)
+	}
+	for k, v := range src {
+		mustPut(t, b, k, v)
+	}
+	return a, b
+}
+
+func TestMergeDisjointIsUnion(t *testing.T) {
+	dst, src := openTestDB(t, nil), openTestDB(t, nil)
+	union := openTestDB(t, nil)
+	left, right := make(map[string][]byte), make(map[string][]byte)
+	const n = chunkKeys + 200
+	for i := 0; i < n; i++ {
+		left[fmt.Sprintf("a%04d", i)] = []byte("l")
+		right[fmt.Sprintf("b%04d", i)] = []byte("r")
+	}
+	for _, w := range []struct {
+		db    *Database
+		items map[string][]byte
+	}{{dst, left}, {src, right}, {union, left}, {union, right}} {
+		if _, err := w.db.BatchPut(w.items); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := dst.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dst.Merge(src, ConflictFail); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a0000", fmt.Sprintf("a%04d", n-1), "b0000", fmt.Sprintf("b%04d", n-1)} {
+		if got := mustGet(t, dst, key, 0); got != string(append(left[key], right[key]...)) {
+			t.Fatalf("%s = %q", key, got)
+		}
+	}
+	if got, want := stateRoot(t, dst), stateRoot(t, union); string(got) != string(want) {
+		t.Fatal("merged state differs from the union")
+	}
+
+	// 每批提交不超过chunkKeys个键
+	history, err := dst.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := 0
+	for _, h := range history {
+		if h.Version <= before {
+			continue
+		}
+		if h.KeysChanged > chunkKeys {
+			t.Fatalf("version %d committed %d keys", h.Version, h.KeysChanged)
+		}
+		merged += h.KeysChanged
+	}
+	if merged != n {
+		t.Fatalf("merge committed %d keys, want %d", merged, n)
+	}
+}
+
+func TestMergeConflictPolicies(t *testing.T) {
+	dst := map[string]string{"a": "dst", "shared": "dst"}
+	src := map[string]string{"b": "src", "shared": "src"}
+
+	db, other := mergeDBs(t, dst, src)
+	if _, err := db.Merge(other, ConflictKeepExisting); err != nil {
+		t.Fatal(err)
+	}
+	if mustGet(t, db, "shared", 0) != "dst" || mustGet(t, db, "b", 0) != "src" {
+		t.Fatal("keep-existing did not keep the existing value")
+	}
+
+	db, other = mergeDBs(t, dst, src)
+	if _, err := db.Merge(other, ConflictOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	if mustGet(t, db, "shared", 0) != "src" || mustGet(t, db, "a", 0) != "dst" {
+		t.Fatal("overwrite did not take the source value")
+	}
+
+	db, other = mergeDBs(t, dst, src)
+	root := rootOf(t, db)
+	_, err := db.Merge(other, ConflictFail)
+	var conflict *MergeConflictError
+	if !errors.Is(err, ErrMergeConflict) || !errors.As(err, &conflict) || string(conflict.Key) != "shared" {
+		t.Fatalf("fail policy: %v", err)
+	}
+	if string(rootOf(t, db)) != string(root) {
+		t.Fatal("fail policy wrote data before detecting the conflict")
+	}
+	if _, err := db.Get([]byte("b"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("fail policy merged a non-conflicting key: %v", err)
+	}
+
+	// 本库已删除的键不视为冲突
+	if err := db.Delete([]byte("shared")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Merge(other, ConflictFail); err != nil {
+		t.Fatalf("deleted key treated as a conflict: %v", err)
+	}
+	if mustGet(t, db, "shared", 0) != "src" {
+		t.Fatal("deleted key not merged")
+	}
+
+	if _, err := db.Merge(nil, ConflictOverwrite); err != ErrInvalidArg {
+		t.Fatalf("nil src: %v", err)
+	}
+	if _, err := db.Merge(other, ConflictPolicy(-1)); err != ErrInvalidArg {
+		t.Fatalf("unknown policy: %v", err)
+	}
+}
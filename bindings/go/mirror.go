@@ -0,0 +1,68 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrMirrorDivergence 镜像目录的根哈希与主数据目录不一致
+var ErrMirrorDivergence = errors.New("mirror root diverged from primary")
+
+// openMirror 打开Options.MirrorDir并确认其与主数据库的根哈希一致（调用方为NewDatabaseWithOptions，主数据库已完成恢复）
+// 镜像以存储形式接收写入，因此不设置HashKeys；块存储、前缀压缩和键顺序只影响镜像自身的存储，与主库使用相同的选项
+func (db *Database) openMirror(opts *Options) error {
+	mirror, err := NewDatabaseWithOptions(opts.MirrorDir, &Options{
+		PlainMode:         opts.PlainMode,
+		ChunkLargeValues:  opts.ChunkLargeValues,
+		ChunkThreshold:    opts.ChunkThreshold,
+		PrefixCompression: opts.PrefixCompression,
+		Comparator:        opts.Comparator,
+		ComparatorName:    opts.ComparatorName,
+		TempDir:           opts.TempDir,
+	})
+	if err != nil {
+		return fmt.Errorf("mirror: %w", err)
+	}
+	db.mirror = mirror
+	return db.checkMirror()
+}
+
+// mirrorWrite 将已提交到主数据库的一次写入（存储形式，删除以删除标记表示）作为一次批量写入应用到镜像，
+// 并比较两者的根哈希（调用方需持有wmu）。未配置镜像时不做任何事
+func (db *Database) mirrorWrite(keys, values [][]byte) error {
+	if db.mirror == nil {
+		return nil
+	}
+	db.mirror.wmu.Lock()
+	_, err := db.mirror.batchPutSlices(keys, values)
+	db.mirror.wmu.Unlock()
+	if err != nil {
+		return fmt.Errorf("mirror: %w", err)
+	}
+	db.invalidateTimeline()
+	return db.checkMirror()
+}
+
+// checkMirror 比较主数据库与镜像的最新根哈希，PlainMode下没有根哈希，不做比较
+func (db *Database) checkMirror() error {
+	if db.plain {
+		return nil
+	}
+	primary, err := db.RootHashAtVersion(0)
+	if err != nil {
+		return err
+	}
+	mirrored, err := db.mirror.RootHashAtVersion(0)
+	if err != nil {
+		return fmt.Errorf("mirror: %w", err)
+	}
+	if !bytes.Equal(primary, mirrored) {
+		current, err := db.CurrentVersion()
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w at version %d", ErrMirrorDivergence, current)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestMirrorMatchesPrimary(t *testing.T) {
+	base := t.TempDir()
+	primaryDir, mirrorDir := filepath.Join(base, "primary"), filepath.Join(base, "mirror")
+	db, err := NewDatabaseWithOptions(primaryDir, &Options{MirrorDir: mirrorDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+	if err := db.Delete([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.BatchPut(map[string][]byte{"c": []byte("3"), "d": []byte("4")}); err != nil {
+		t.Fatal(err)
+	}
+	want := rootOf(t, db)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{primaryDir, mirrorDir} {
+		d, err := NewDatabase(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := rootOf(t, d)
+		v, err := d.CurrentVersion()
+		d.Close()
+		if err != nil || v != 4 || !bytes.Equal(root, want) {
+			t.Fatalf("%s: version %d root %x, want version 4 root %x (%v)", filepath.Base(dir), v, root, want, err)
+		}
+	}
+}
+
+func TestMirrorDivergenceDetected(t *testing.T) {
+	base := t.TempDir()
+	primaryDir, mirrorDir := filepath.Join(base, "primary"), filepath.Join(base, "mirror")
+	db, err := NewDatabaseWithOptions(primaryDir, &Options{MirrorDir: mirrorDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+
+	// 绕过主库直接写入镜像
+	if _, err := db.mirror.Put([]byte("stray"), []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Put([]byte("b"), []byte("2")); !errors.Is(err, ErrMirrorDivergence) {
+		t.Fatalf("write after divergence: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewDatabaseWithOptions(primaryDir, &Options{MirrorDir: mirrorDir}); !errors.Is(err, ErrMirrorDivergence) {
+		t.Fatalf("open with a diverged mirror: %v", err)
+	}
+	// 打开失败后主数据目录仍可单独打开
+	db, err = NewDatabase(primaryDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := mustGet(t, db, "b", 0); got != "2" {
+		t.Fatalf("b: %q", got)
+	}
+}
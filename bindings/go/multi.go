@@ -0,0 +1,110 @@
+package amdb
+
+import (
+	"errors"
+	"fmt"
+)
+
+// KeyVersion 键及其要读取的版本（0表示最新版本）
+type KeyVersion struct {
+	Key     []byte
+	Version uint32
+}
+
+// MultiGetError 批量读取的逐条错误，Errs与请求一一对应，成功的条目为nil
+type MultiGetError struct {
+	Errs []error
+}
+
+func (e *MultiGetError) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e.Errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("multi get: %d of %d entries failed: %v", failed, len(e.Errs), first)
+}
+
+// Unwrap 返回所有非nil的逐条错误，便于errors.Is/As判断
+func (e *MultiGetError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// MultiGetVersions 批量读取多个键，每个键按各自的版本独立解析
+// 返回值与reqs一一对应；键在该版本不存在时对应值为nil且不视为错误，值为空时为[]byte{}，
+// 其他失败记录在返回的*MultiGetError中
+func (db *Database) MultiGetVersions(reqs []KeyVersion) ([][]byte, error) {
+	values := make([][]byte, len(reqs))
+	var errs []error
+	for i, req := range reqs {
+		value, err := db.Get(req.Key, req.Version)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(reqs))
+			}
+			errs[i] = err
+			continue
+		}
+		values[i] = value
+	}
+	if errs != nil {
+		return values, &MultiGetError{Errs: errs}
+	}
+	return values, nil
+}
+
+// HasMulti 批量判断多个键在数据库版本version（0表示最新版本）中是否存在，返回值与keys一一对应
+// 通过一次C调用取得该版本的全部存活键，不传输任何值；已删除的键和空键视为不存在
+func (db *Database) HasMulti(keys [][]byte, version uint32) ([]bool, error) {
+	present := make([]bool, len(keys))
+	if err := db.membership(keys, version, func(i int) { present[i] = true }); err != nil {
+		return nil, err
+	}
+	return present, nil
+}
+
+// MembershipBitmap 与HasMulti相同，但以位图返回结果：第i位（第i/8字节中的1<<(i%8)位）为1表示keys[i]存在
+// 位图长度为(len(keys)+7)/8字节，末尾多余的位为0；除位图和存活键集合外不为每个键分配内存，适合数百万个键的成员判断
+func (db *Database) MembershipBitmap(keys [][]byte, version uint32) ([]byte, error) {
+	bitmap := make([]byte, (len(keys)+7)/8)
+	if err := db.membership(keys, version, func(i int) { bitmap[i/8] |= 1 << (i % 8) }); err != nil {
+		return nil, err
+	}
+	return bitmap, nil
+}
+
+// membership 以一次C调用取得版本version的存活键，对keys中存在的每个下标调用present
+func (db *Database) membership(keys [][]byte, version uint32, present func(i int)) error {
+	live, err := db.liveKeysAt(version)
+	if err != nil {
+		return err
+	}
+	set := make(map[string]struct{}, len(live))
+	for _, key := range live {
+		set[string(key)] = struct{}{}
+	}
+
+	for i, key := range keys {
+		if len(key) == 0 || !db.inKeyRange(key) {
+			continue
+		}
+		if _, ok := set[string(db.storedKey(key))]; ok {
+			present(i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package amdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMultiGetVersions(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "a1") // 版本1
+	mustPut(t, db, "b", "b1") // 版本2
+	mustPut(t, db, "a", "a2") // 版本3
+	mustPut(t, db, "e", "")   // 版本4
+
+	values, err := db.MultiGetVersions([]KeyVersion{
+		{Key: []byte("a"), Version: 0},
+		{Key: []byte("a"), Version: 1},
+		{Key: []byte("b"), Version: 1}, // 版本1时b尚未写入
+		{Key: []byte("b"), Version: 3},
+		{Key: []byte("missing")},
+		{Key: []byte("e")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a2", "a1", "", "b1", "", ""}
+	for i, v := range values {
+		if string(v) != want[i] {
+			t.Fatalf("entry %d: %q, want %q", i, v, want[i])
+		}
+	}
+	if values[2] != nil || values[4] != nil {
+		t.Fatal("missing entries must be nil")
+	}
+	if values[5] == nil {
+		t.Fatal("empty value must be non-nil")
+	}
+}
+
+func TestMultiGetVersionsErrors(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+
+	values, err := db.MultiGetVersions([]KeyVersion{
+		{Key: []byte("a")},
+		{Key: []byte("a"), Version: 99},
+		{Key: nil},
+	})
+	var merr *MultiGetError
+	if !errors.As(err, &merr) {
+		t.Fatalf("got %v, want *MultiGetError", err)
+	}
+	if len(merr.Errs) != 3 || merr.Errs[0] != nil {
+		t.Fatalf("errs %v", merr.Errs)
+	}
+	if !errors.Is(merr.Errs[1], ErrVersionNotFound) || !errors.Is(merr.Errs[2], ErrInvalidArg) {
+		t.Fatalf("errs %v", merr.Errs)
+	}
+	if !errors.Is(err, ErrVersionNotFound) {
+		t.Fatal("errors.Is does not reach per-entry errors")
+	}
+	if string(values[0]) != "1" || values[1] != nil || values[2] != nil {
+		t.Fatalf("values %q", values)
+	}
+}
+
+func TestHasMulti(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "c", "")
+	mustPut(t, db, "d", "1")
+	if err := db.Delete([]byte("d")); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("a"), nil}
+	got, err := db.HasMulti(keys, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []bool{true, false, true, false, true, false}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("HasMulti = %v, want %v", got, want)
+	}
+	// 版本3时d尚未删除
+	got, err = db.HasMulti(keys[:4], 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []bool{true, false, true, true}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("HasMulti@3 = %v, want %v", got, want)
+	}
+	if got, err := db.HasMulti(nil, 0); err != nil || len(got) != 0 {
+		t.Fatalf("no keys: %v, %v", got, err)
+	}
+}
+
+func TestMembershipBitmap(t *testing.T) {
+	db := openTestDB(t, nil)
+	items := make(map[string][]byte)
+	for i := 0; i < 30; i += 3 {
+		items[fmt.Sprintf("k%02d", i)] = []byte("v")
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete([]byte("k06")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 30个键跨4字节，末字节只用到6位
+	keys := make([][]byte, 30)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("k%02d", i))
+	}
+	bitmap, err := db.MembershipBitmap(keys, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	present, err := db.HasMulti(keys, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bitmap) != 4 {
+		t.Fatalf("bitmap of %d bytes, want 4", len(bitmap))
+	}
+	for i := range keys {
+		set := bitmap[i/8]&(1<<(i%8)) != 0
+		want := i%3 == 0 && i != 6
+		if set != want || set != present[i] {
+			t.Fatalf("bit %d = %v, want %v", i, set, want)
+		}
+	}
+	if bitmap[3]>>6 != 0 {
+		t.Fatalf("padding bits set: %08b", bitmap[3])
+	}
+
+	// 版本1时k06尚未删除
+	bitmap, err = db.MembershipBitmap(keys[:8], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{1<<0 | 1<<3 | 1<<6}; !reflect.DeepEqual(bitmap, want) {
+		t.Fatalf("bitmap at version 1 = %08b, want %08b", bitmap, want)
+	}
+	if bitmap, err := db.MembershipBitmap(nil, 0); err != nil || len(bitmap) != 0 {
+		t.Fatalf("no keys: %v, %v", bitmap, err)
+	}
+}
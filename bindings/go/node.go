@@ -0,0 +1,56 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrNodeNotFound 当前版本的Merkle树中没有该哈希的节点
+var ErrNodeNotFound = errors.New("trie node not found")
+
+// 节点序列化格式：GetNode返回的字节即节点哈希的原像，SHA-256(节点字节)等于请求的哈希，调用方可据此校验内容。
+//   - 叶子节点："leaf:" + 键 + ":" + 值，键值为存储形式（哈希键模式下为哈希后的键，删除标记的值为"__DELETED__"）。
+//     键与值之间的":"不作转义，键本身含":"时无法仅凭节点字节切分，需由调用方已知的键确定边界
+//   - 扩展节点："ext:" + 1字节nibble（0-15） + ":" + 32字节子节点哈希
+//   - 分支节点："branch:" + 按nibble顺序拼接的非空子节点哈希，每个32字节。
+//     空子节点不占位，节点字节只给出子节点哈希及其顺序，不给出各自所在的nibble，
+//     按键查找时nibble需由MerkleProof的Siblings或逐个读取子节点确定
+
+// GetNode 返回当前版本Merkle树中哈希为hash的节点的序列化字节（格式见上），不存在时返回ErrNodeNotFound
+// 引擎不单独持久化节点，每次调用都由当前状态重建树，开销与状态大小成正比。
+// 根节点的哈希即RootHashAtVersion(0)；PlainMode下返回ErrNotAuthenticated
+func (db *Database) GetNode(hash []byte) ([]byte, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	root, err := db.trieAt(0)
+	if err != nil {
+		return nil, err
+	}
+	node := root.find(hash)
+	if node == nil {
+		return nil, ErrNodeNotFound
+	}
+	return node.content(), nil
+}
+
+// find 在以n为根的子树中查找哈希为hash的节点，不存在时返回nil
+func (n *trieNode) find(hash []byte) *trieNode {
+	if n == nil {
+		return nil
+	}
+	if bytes.Equal(n.hash, hash) {
+		return n
+	}
+	switch n.kind {
+	case extNode:
+		return n.child.find(hash)
+	case branchNode:
+		for _, child := range n.children {
+			if node := child.find(hash); node != nil {
+				return node
+			}
+		}
+	}
+	return nil
+}
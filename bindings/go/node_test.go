@@ -0,0 +1,69 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestGetNodeWalksTree(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	root := rootOf(t, db)
+
+	// 从根节点出发，按序列化格式解析出子节点哈希并逐个读取，直到全部叶子
+	leaves := make(map[string]string)
+	pending := [][]byte{root}
+	for len(pending) > 0 {
+		hash := pending[0]
+		pending = pending[1:]
+		node, err := db.GetNode(hash)
+		if err != nil {
+			t.Fatalf("node %x: %v", hash, err)
+		}
+		if sum := sha256.Sum256(node); !bytes.Equal(sum[:], hash) {
+			t.Fatalf("node %q does not hash to %x", node, hash)
+		}
+		switch {
+		case bytes.HasPrefix(node, []byte("leaf:")):
+			// 测试中的键都不含":"
+			key, value, ok := bytes.Cut(node[len("leaf:"):], []byte(":"))
+			if !ok {
+				t.Fatalf("leaf %q", node)
+			}
+			leaves[string(key)] = string(value)
+		case bytes.HasPrefix(node, []byte("ext:")):
+			body := node[len("ext:"):]
+			if len(body) != 2+sha256.Size || body[0] > 15 || body[1] != ':' {
+				t.Fatalf("extension %q", node)
+			}
+			pending = append(pending, body[2:])
+		case bytes.HasPrefix(node, []byte("branch:")):
+			body := node[len("branch:"):]
+			if len(body) == 0 || len(body)%sha256.Size != 0 {
+				t.Fatalf("branch of %d bytes", len(body))
+			}
+			for ; len(body) > 0; body = body[sha256.Size:] {
+				pending = append(pending, body[:sha256.Size])
+			}
+		default:
+			t.Fatalf("unknown node %q", node)
+		}
+	}
+	if len(leaves) != len(keys) {
+		t.Fatalf("walked %d leaves, want %d", len(leaves), len(keys))
+	}
+	for _, k := range keys {
+		if leaves[k] != "value of "+k {
+			t.Fatalf("leaf %s = %q", k, leaves[k])
+		}
+	}
+
+	if _, err := db.GetNode(bytes.Repeat([]byte{1}, sha256.Size)); err != ErrNodeNotFound {
+		t.Fatalf("unknown hash: %v", err)
+	}
+	plain := openTestDB(t, &Options{PlainMode: true})
+	mustPut(t, plain, "k", "v")
+	if _, err := plain.GetNode(root); err != ErrNotAuthenticated {
+		t.Fatalf("plain mode: %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package amdb
+
+import (
+	"sync"
+	"time"
+)
+
+// Options 数据库打开选项
+type Options struct {
+	// OpenRetry 打开时遇到锁竞争等临时错误的重试策略（nil表示不重试）
+	OpenRetry *RetryPolicy
+
+	// ReadRetry Get/Has等读取遇到临时错误时的重试策略（nil表示不重试）
+	// 只有isTransientRead判定的错误会重试，ErrNotFound、ErrCorrupted等立即返回
+	ReadRetry *RetryPolicy
+
+	// TracerProvider 追踪接口，设置后每次Put/Get/Delete/BatchPut、Compact及创建迭代器都会创建span（nil表示不追踪），
+	// 带context的方法把span挂在传入的ctx之下
+	TracerProvider TracerProvider
+
+	// BloomFilterBits 最新版本存活键布隆过滤器的每键位数（0表示不启用）
+	// 启用后Get(key, 0)和Has在调用C API前先查询过滤器，直接返回一定不存在的键。
+	// 误报率约为0.6185^BloomFilterBits，例如10位约1%，16位约0.05%；
+	// 每键占用BloomFilterBits/8字节内存，打开时需要读取全部存活键
+	BloomFilterBits int
+
+	// CollectCGOStats 是否统计C API调用次数与耗时，通过CGOStats读取
+	CollectCGOStats bool
+
+	// MaxTreeDepth Merkle树允许的最大深度（节点层数，0或负数表示不限制，默认不限制）
+	// 引擎递归构建树，共享超长前缀的键会使递归过深。由于深度不超过2*键长度+1，
+	// 设置为正数时写入拒绝2*len(key)+1超过该值的键并返回ErrTreeTooDeep，适用于键来自不可信输入的场景；
+	// 该检查按键长度估计深度，也会拒绝与其他键并不共享长前缀的长键
+	MaxTreeDepth int
+
+	// HashKeys 启用哈希键模式：键以SHA-256(key)存入树中，原始键与值一起存储，
+	// 使树保持平衡且深度有界。该模式必须在创建数据库时确定，之后每次打开都要使用相同设置。
+	// 启用后：
+	//   - Get/Put/Delete等按原始键读写，迭代器返回原始键，
+	//     但遍历顺序与范围边界[start, end)均基于哈希后的键，而非原始键的字典序
+	//   - 证明、子树导出与SubtreeRoot中的键和值为存储形式（哈希键、带原始键前缀的值）
+	HashKeys bool
+
+	// SyncOnCreate 新建数据目录时是否在打开后将初始文件及父目录项fsync到磁盘（nil表示true）
+	// 某些文件系统上，不同步父目录时新建的目录在崩溃后可能整体丢失
+	SyncOnCreate *bool
+
+	// ProofCacheEntries GetWithProof证明缓存的最大条目数（0表示不缓存），按LRU淘汰
+	ProofCacheEntries int
+
+	// CoalesceWindow 合并并发Put的时间窗口（0表示不合并）
+	// 启用后，窗口内来自多个goroutine的Put合并为一次C层批量写入，每个Put返回合并批次提交后的根哈希，
+	// 以单次写入延迟增加至多一个窗口为代价提升高并发写入吞吐。顺序保证见coalescer
+	CoalesceWindow time.Duration
+
+	// ValueBufferPool Get返回值所用缓冲区的来源（nil表示每次新分配），池中元素须为*[]byte
+	// 配置后调用方可在用完返回值时调用ReleaseValue将缓冲区归还池中复用，所有权约定见ReleaseValue
+	ValueBufferPool *sync.Pool
+
+	// InMemory 打开一次性的临时数据库（dataDir为MemoryDataDir时同样生效，此时忽略dataDir）
+	// 引擎没有纯内存后端，数据实际写入专属的临时目录，Close时连同目录一并删除；
+	// 其余API与普通数据库完全相同，只是不跨句柄持久化
+	InMemory bool
+
+	// OnMaintenance 维护操作（Compact）开始和结束时的回调（nil表示不回调）
+	// 回调在调用Compact的goroutine中同步执行，且不持有写锁；耗时操作应转交其他goroutine，
+	// 否则会推迟Compact的返回
+	OnMaintenance func(MaintenanceEvent)
+
+	// AuditLogPath 审计日志文件路径（空表示不记录）
+	// 启用后每次经由本句柄的变更都追加一条哈希链记录，格式见audit.go，可用VerifyAuditLog校验。
+	// 每次写入的记录在返回前fsync，并更新日志旁的头文件（路径加".head"）。打开时校验已有日志与头文件，
+	// 修复崩溃留下的不完整末行（见OpenInfo.RecoveredAuditLog），其他不一致时打开失败
+	AuditLogPath string
+
+	// ValueCacheEntries 最新版本值缓存的最大条目数（0表示不缓存），按LRU淘汰
+	// 只缓存AllowStale读取（Get、GetRange等）的最新版本值，经由本句柄的写入会使对应条目失效；
+	// 其他进程的写入不会使缓存失效。可用Warm预先填充，用Pin固定不被淘汰的键
+	ValueCacheEntries int
+
+	// WriteRateLimit Put、BatchPut和BatchPutSlices的写入限速（nil表示不限速）
+	// 超出配额的写入在获取写锁之前等待，PutContext和BatchPutContext的等待可由ctx取消
+	WriteRateLimit *WriteRateLimit
+
+	// SafeMode 写入时先复制调用方传入的键和值，之后修改或复用这些切片（包括在另一个goroutine中并发修改）
+	// 不会影响已写入的数据或正在进行的C调用。代价是每次写入多一次与键值大小成正比的分配和复制，
+	// 批量写入复制整个批次。WriteBatch在Put时只保存引用，提交前修改其中的切片仍会生效
+	SafeMode bool
+
+	// ValueHashEntries 叶子哈希缓存的最大条目数（0表示不缓存），按LRU淘汰
+	// 启用后按键及写入版本保存已计算的叶子哈希，GetWithProof等重建树时不再重新哈希未变化的值，
+	// ValueHash可直接返回缓存的哈希。哈希只保存在本句柄的内存中：引擎的值中没有可附加元数据而不改变
+	// Merkle树的位置，因此每次打开后按需重新计算
+	ValueHashEntries int
+
+	// TempDir 维护操作临时文件的父目录（空表示数据目录），适用于数据目录所在卷空间紧张的场景
+	// Rehash在其下构建新库后再移动到目标目录，InMemory数据库也创建在其下。
+	// Compact由引擎在数据目录内原地刷盘合并，不经过绑定层的临时目录
+	TempDir string
+
+	// KeySalt 加盐的哈希键模式：设置后同时启用HashKeys，键以HMAC-SHA256(KeySalt, key)代替SHA-256(key)存入树中
+	// 不知道盐的观察者无法由树的结构、根哈希或他人的证明推断出键，也无法通过猜测候选键并计算哈希来确认某个键是否存在，
+	// 适用于只公开承诺而不公开键集合的场景。知道盐的校验方用StoredKey计算存储形式的键后照常校验证明。
+	// 与HashKeys相同，盐必须在创建数据库时确定，之后每次打开都要使用相同的盐；绑定层无法检测盐是否正确，
+	// 用错的盐时按键读取返回ErrNotFound，迭代报告ErrCorrupted。盐一旦丢失，原始键就无法再由树的结构恢复，也无法再按键读取；
+	// 原始键仍与值一同保存在数据文件中（见HashKeys），因此盐保护的是树结构与证明，不能代替对数据文件的访问控制
+	KeySalt []byte
+
+	// TrackWriteAmplification 统计写入放大：调用方写入的逻辑字节数与写入期间实际写出的物理字节数，通过Stats读取
+	// 逻辑字节按写入引擎的键值计算（删除只计键，哈希键模式下为存储形式）；物理字节为Put、Delete、BatchPut及
+	// Compact刷盘的C调用期间本进程经write类系统调用写出的字节数（Linux的/proc/self/io中的wchar），
+	// 包含WAL、数据与索引文件以及Merkle树重写的祖先节点。该计数器是进程级的：同一时间其他goroutine或句柄的写出
+	// 也会计入，引擎在调用返回后异步写出的部分则不计入，因此结果是近似值。其他平台上物理字节始终为0。
+	// 每次写入额外读取两次/proc/self/io
+	TrackWriteAmplification bool
+
+	// PlainMode 纯键值模式：写入不维护引擎的Merkle树，用于不需要认证的数据
+	// Put和Delete改经引擎不更新Merkle树的批量写入路径提交，单键写入快数十到数百倍（差距随键数增长）；
+	// 写入方法返回nil根哈希，GetRootHash、RootHashAtVersion及各类证明、子树根和状态摘要接口返回ErrNotAuthenticated。
+	// 版本、历史读取、迭代与删除语义不变。该选项只作用于本句柄：PlainMode下写入的键不进入引擎的Merkle树，
+	// 之后不带PlainMode打开时GetRootHash与RootHashAtVersion一样由状态计算，包含这些键
+	PlainMode bool
+
+	// LazyRoot 延迟计算根哈希，用于只偶尔需要根哈希的突发写入：Put和Delete与PlainMode一样改经引擎不更新Merkle树的
+	// 批量写入路径提交，Put、BatchPut和BatchPutSlices返回nil根哈希，审计日志记录的根哈希同样为空。
+	// GetRootHash在调用时由当前状态一次计算涵盖之前全部写入的根哈希，结果与不启用时相同；
+	// RootHashAtVersion、证明及事务、导入等其他接口返回的根哈希不受影响。
+	// 与PlainMode相同，该选项只作用于本句柄：之后不带LazyRoot打开时GetRootHash同样由状态计算，包含期间写入的键
+	LazyRoot bool
+
+	// ResolveConflict 导入或合并遇到本库最新版本中已存在的键时调用，返回值即为写入的值
+	// 参数均为用户形式：key为键，existing为本库的现有值，incoming为导入或合并带来的值；返回nil表示删除该键，
+	// 返回与existing相同的内容时不写入该键。适用于ImportResumable、ImportSubtree、ImportFiltered
+	// 以及ConflictResolve策略的Merge；未设置时导入以传入的值覆盖，Merge按其策略参数处理。
+	// 本库已删除的键和传入的删除标记不视为冲突。调用时持有写锁，不得在其中写入本数据库
+	ResolveConflict func(key, existing, incoming []byte) []byte
+
+	// ChunkLargeValues 将不短于ChunkThreshold的值按内容定义的边界切分为块，按块的SHA-256存放在数据目录的chunks子目录中，
+	// 引擎只保存块清单。同一数据库中相同的块只存一份，局部修改的大值（如多个版本的大文档）共享未改动部分的块。
+	// Get、迭代和导出透明地重组原始的值，叶子哈希、根哈希与证明都按原始的值计算；由于引擎的Merkle树覆盖的是清单，
+	// 写入与GetRootHash报告的根哈希改由状态计算，开销与状态大小成正比。块不随版本裁剪而删除。
+	// 数据目录中已有块时，不带该选项打开也会重组已分块的值，但不再分块写入。以分块清单前缀开头的值总是分块存储
+	ChunkLargeValues bool
+	// ChunkThreshold 分块的最小值长度（字节），<=0表示默认的64KiB
+	ChunkThreshold int
+
+	// PrefixCompression 以前缀字典压缩引擎中存储的键：键中最后一个'/'之前（含）不短于16字节的部分记入数据目录的前缀字典，
+	// 引擎中以8字节的前缀ID代替，适合层级路径等共享长前缀的键。这只改变存储形式，Get、迭代、导出、根哈希与证明
+	// 都按原始的键进行，根哈希与不压缩时相同；由于引擎的Merkle树覆盖的是压缩后的键，写入与GetRootHash报告的根哈希
+	// 改由状态计算，开销与状态大小成正比。只能在创建数据库时启用，对已有数据的数据库返回ErrPrefixCompressionUnavailable，
+	// 与HashKeys同时设置时返回ErrInvalidArg；启用后数据目录中留有前缀字典，之后不带该选项打开仍按压缩格式读写
+	PrefixCompression bool
+
+	// Comparator 迭代器、范围查询、NextKey/PrevKey与FirstKey/LastKey使用的键顺序，返回负数、0、正数分别表示a小于、等于、大于b，
+	// nil表示按字节序。范围[start, end)按该顺序解释；前缀迭代器改为筛选全部键，因为前缀相同的键在自定义顺序下未必相邻。
+	// MPT的结构只取决于键本身，根哈希与单键证明不受顺序影响；范围证明、导出流、校验和与差异仍按字节序，
+	// 以便与其他数据库和验证方一致。比较器必须是全序且在进程之间保持不变。
+	// 只能在创建数据库时设置，须同时给出ComparatorName，名称记录在数据目录中；之后每次打开都须给出同名的比较器，
+	// 名称不一致、缺少比较器或对已有数据的数据库启用时返回ErrComparatorMismatch，与HashKeys同时设置时返回ErrInvalidArg
+	Comparator func(a, b []byte) int
+	// ComparatorName 标识Comparator的名称，比较器的顺序改变时应更换名称
+	ComparatorName string
+
+	// MirrorDir 非空时同步镜像到该数据目录：每次提交到主数据目录的写入（Put、Delete、批量写入及其上的事务、导入等）
+	// 随即以一次批量写入应用到镜像，之后比较两者的最新根哈希，不一致时该写入返回包装ErrMirrorDivergence的错误。
+	// 主数据目录中的写入此时已经提交，错误说明镜像不再可用作备用库；写入镜像失败时同样返回错误。
+	// Rewind同时回退镜像。打开时镜像的根哈希必须与主库一致，否则返回ErrMirrorDivergence：
+	// 镜像应从空目录开始与主库一同创建，或在两者都关闭时复制主数据目录得到。
+	// 每次写入都要在两侧由状态计算根哈希，开销与状态大小成正比，只适合写入不多的场景；
+	// PlainMode下没有根哈希，只镜像写入而不比较
+	MirrorDir string
+}
+
+// MemoryDataDir 作为dataDir传入时等同于设置Options.InMemory
+const MemoryDataDir = ":memory:"
+
+// EffectiveOptions 返回本句柄实际生效的选项：未设置的字段填入默认值
+// （SyncOnCreate非nil），InMemory反映句柄是否为临时数据库；
+// InMemory数据库的OpenRetry和SyncOnCreate为打开临时目录时实际使用的值。
+// 返回值是浅拷贝，指针字段与打开时传入的对象相同，修改它们不会改变已打开句柄的行为
+func (db *Database) EffectiveOptions() Options {
+	return db.opts
+}
+
+// effectiveOptions 将opts中未设置的字段填入默认值
+func effectiveOptions(opts Options) Options {
+	opts.HashKeys = opts.HashKeys || len(opts.KeySalt) > 0
+	if opts.SyncOnCreate == nil {
+		enabled := true
+		opts.SyncOnCreate = &enabled
+	}
+	return opts
+}
+
+// syncOnCreate 返回SyncOnCreate的实际取值
+func (o *Options) syncOnCreate() bool {
+	return o.SyncOnCreate == nil || *o.SyncOnCreate
+}
+
+// ReadOptions 单次读取的选项
+type ReadOptions struct {
+	// Version 读取的数据库版本（0表示最新版本）
+	// 引擎不裁剪历史版本，1到当前版本之间的任一版本都可读取，不存在读取已裁剪版本的情况：
+	// 键在该版本之前被删除或尚未写入时返回ErrNotFound，在该版本之后才删除时返回该版本时的值；
+	// 超过当前版本时返回ErrVersionNotFound
+	Version uint32
+	// AllowStale 允许使用本句柄缓存的版本时间线和布隆过滤器，不检查是否有更新的版本。
+	// 为false时先丢弃缓存的时间线并重新从引擎读取，且不经过布隆过滤器，
+	// 适用于数据文件可能被外部同步更新的只读副本
+	AllowStale bool
+	// MaxValueSize 值的最大字节数（0表示不限制），超过时返回*ValueTooLargeError
+	MaxValueSize int
+}
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	// MaxAttempts 最大尝试次数（包含首次尝试）
+	MaxAttempts int
+	// Backoff 首次重试前的等待时间，之后每次翻倍
+	Backoff time.Duration
+	// MaxBackoff 单次等待时间上限（0表示不限制）
+	MaxBackoff time.Duration
+}
+
+// do 按策略执行fn，仅当retryable判定错误可重试时才重试
+func (p *RetryPolicy) do(fn func() error, retryable func(error) bool) error {
+	attempts := 1
+	if p != nil && p.MaxAttempts > 1 {
+		attempts = p.MaxAttempts
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(p.backoff(i))
+		}
+		if err = fn(); err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// backoff 第n次重试前的等待时间
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < n && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,143 @@
+package amdb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestReadRetryClassification(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 4, Backoff: time.Millisecond}
+	for _, tc := range []struct {
+		err       error
+		transient bool
+	}{
+		{ErrIO, true},
+		{fmt.Errorf("read: %w", ErrIO), true},
+		{ErrNotFound, false},
+		{ErrCorrupted, false},
+		{ErrInternal, false},
+		{ErrClosed, false},
+		{ErrVersionNotFound, false},
+	} {
+		if got := isTransientRead(tc.err); got != tc.transient {
+			t.Fatalf("isTransientRead(%v) = %v", tc.err, got)
+		}
+
+		// 临时错误前两次失败后成功，其他错误只尝试一次
+		calls := 0
+		err := policy.do(func() error {
+			calls++
+			if calls <= 2 {
+				return tc.err
+			}
+			return nil
+		}, isTransientRead)
+		if tc.transient && (err != nil || calls != 3) {
+			t.Fatalf("%v: %v after %d calls", tc.err, err, calls)
+		}
+		if !tc.transient && (!errors.Is(err, tc.err) || calls != 1) {
+			t.Fatalf("%v retried: %v after %d calls", tc.err, err, calls)
+		}
+	}
+
+	calls := 0
+	err := policy.do(func() error { calls++; return ErrIO }, isTransientRead)
+	if !errors.Is(err, ErrIO) || calls != policy.MaxAttempts {
+		t.Fatalf("persistent error: %v after %d calls", err, calls)
+	}
+	var none *RetryPolicy
+	calls = 0
+	if err := none.do(func() error { calls++; return ErrIO }, isTransientRead); !errors.Is(err, ErrIO) || calls != 1 {
+		t.Fatalf("nil policy: %v after %d calls", err, calls)
+	}
+}
+
+func TestReadRetryNotFoundIsImmediate(t *testing.T) {
+	db := openTestDB(t, &Options{ReadRetry: &RetryPolicy{MaxAttempts: 3, Backoff: time.Second}})
+	mustPut(t, db, "k", "v")
+	start := time.Now()
+	if _, err := db.Get([]byte("missing"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	if ok, err := db.Has([]byte("missing")); err != nil || ok {
+		t.Fatal(ok, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("not-found reads took %v, were they retried?", elapsed)
+	}
+	if got := mustGet(t, db, "k", 0); got != "v" {
+		t.Fatalf("k: %q", got)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 30 * time.Millisecond}
+	for n, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 30 * time.Millisecond, 6: 30 * time.Millisecond} {
+		if got := p.backoff(n); got != want {
+			t.Fatalf("backoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestEffectiveOptions(t *testing.T) {
+	opts := &Options{HashKeys: true, ValueCacheEntries: 8}
+	got := openTestDB(t, opts).EffectiveOptions()
+	if !got.HashKeys || got.ValueCacheEntries != 8 {
+		t.Fatalf("explicit options lost: %+v", got)
+	}
+	if got.MaxTreeDepth != 0 {
+		t.Errorf("MaxTreeDepth = %d, want unlimited 0", got.MaxTreeDepth)
+	}
+	if got.SyncOnCreate == nil || !*got.SyncOnCreate {
+		t.Errorf("SyncOnCreate = %v, want default true", got.SyncOnCreate)
+	}
+	if got.InMemory {
+		t.Error("on-disk database reported InMemory")
+	}
+	if opts.MaxTreeDepth != 0 || opts.SyncOnCreate != nil {
+		t.Error("EffectiveOptions modified the caller's Options")
+	}
+
+	disabled := false
+	got = openTestDB(t, &Options{MaxTreeDepth: 100, SyncOnCreate: &disabled}).EffectiveOptions()
+	if got.MaxTreeDepth != 100 || *got.SyncOnCreate {
+		t.Fatalf("overrides not reported: depth %d sync %v", got.MaxTreeDepth, *got.SyncOnCreate)
+	}
+
+	mem, err := NewDatabaseWithOptions(MemoryDataDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	if !mem.EffectiveOptions().InMemory {
+		t.Fatal("in-memory database did not report InMemory")
+	}
+}
+
+func TestHistoricalVersionsStayReadable(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "k", "v1")
+	mustPut(t, db, "k", "v2")
+	if err := db.Delete([]byte("k")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 引擎不裁剪历史版本：压缩之后每个版本仍可读取，已删除键的旧值也是
+	for version, want := range map[uint32]string{1: "v1", 2: "v2"} {
+		got, err := db.GetWithOptions([]byte("k"), ReadOptions{Version: version})
+		if err != nil || string(got) != want {
+			t.Fatalf("version %d: %q, %v", version, got, err)
+		}
+	}
+	if _, err := db.GetWithOptions([]byte("k"), ReadOptions{Version: 3}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted key: %v", err)
+	}
+	if _, err := db.GetWithOptions([]byte("k"), ReadOptions{Version: 9}); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("future version: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package amdb
+
+import "errors"
+
+// Pin 将key的最新版本值固定在值缓存中：固定的键不占用Options.ValueCacheEntries的容量，无论其他读取造成多少淘汰都保留，
+// 经由本句柄写入该键时缓存值随即就地更新，之后的Get仍然命中缓存。固定时立即读取一次值，键尚不存在时也可固定，
+// 写入后开始缓存；删除或Rewind之后下一次读取重新缓存。
+// 与值缓存一样只作用于AllowStale的最新版本读取，其他进程的写入不会更新固定的值。
+// 未配置Options.ValueCacheEntries时返回ErrInvalidArg；重复固定同一个键不做任何事。
+// 本绑定不缓存Merkle树节点，固定只作用于值
+func (db *Database) Pin(key []byte) error {
+	if len(key) == 0 || db.values == nil {
+		return ErrInvalidArg
+	}
+	db.values.pin(db.storedKey(key))
+	value, err := db.Get(key, 0)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	db.ReleaseValue(value)
+	return nil
+}
+
+// Unpin 取消固定key，已缓存的值作为最近使用的条目回到LRU淘汰中，可能因此淘汰最久未使用的条目
+// 未配置Options.ValueCacheEntries时返回ErrInvalidArg；key未被固定时不做任何事
+func (db *Database) Unpin(key []byte) error {
+	if len(key) == 0 || db.values == nil {
+		return ErrInvalidArg
+	}
+	db.values.unpin(db.storedKey(key))
+	return nil
+}
+
+// updatePinned 写入成功后就地更新其中固定的键的缓存值（存储形式，调用方需持有wmu）
+// 删除标记不更新：invalidate已清除缓存值，下一次读取得到ErrNotFound且不缓存
+func (db *Database) updatePinned(keys, values [][]byte) {
+	if db.values.pinnedLen() == 0 {
+		return
+	}
+	for i, key := range keys {
+		if isDeleted(values[i]) {
+			continue
+		}
+		entry, err := db.userEntry(kv{key: key, value: values[i]})
+		if err != nil {
+			continue
+		}
+		db.values.update(key, entry.value)
+	}
+}
@@ -0,0 +1,71 @@
+package amdb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPinnedKeySurvivesEviction(t *testing.T) {
+	for _, hashKeys := range []bool{false, true} {
+		db := openTestDB(t, &Options{ValueCacheEntries: 4, HashKeys: hashKeys})
+		mustPut(t, db, "config", "v1")
+		for i := 0; i < 20; i++ {
+			mustPut(t, db, fmt.Sprintf("k%02d", i), "x")
+		}
+		if err := db.Pin([]byte("config")); err != nil {
+			t.Fatal(err)
+		}
+		if s := db.Stats(); s.ValueCachePinned != 1 || s.ValueCacheEntries != 0 {
+			t.Fatalf("hashKeys=%v: %d pinned, %d entries", hashKeys, s.ValueCachePinned, s.ValueCacheEntries)
+		}
+
+		// 读取远超容量的其他键，LRU中的条目全部被换出
+		for i := 0; i < 20; i++ {
+			mustGet(t, db, fmt.Sprintf("k%02d", i), 0)
+		}
+		hits := db.Stats().ValueCacheHits
+		if got := mustGet(t, db, "config", 0); got != "v1" || db.Stats().ValueCacheHits != hits+1 {
+			t.Fatalf("hashKeys=%v: pinned key %q missed the cache after eviction", hashKeys, got)
+		}
+		if n := db.Stats().ValueCacheEntries; n != 4 {
+			t.Fatalf("hashKeys=%v: %d LRU entries, want 4", hashKeys, n)
+		}
+
+		// 写入就地更新固定的值
+		mustPut(t, db, "config", "v2")
+		if _, err := db.BatchPut(map[string][]byte{"k00": []byte("y")}); err != nil {
+			t.Fatal(err)
+		}
+		hits = db.Stats().ValueCacheHits
+		if got := mustGet(t, db, "config", 0); got != "v2" || db.Stats().ValueCacheHits != hits+1 {
+			t.Fatalf("hashKeys=%v: pinned key after write %q", hashKeys, got)
+		}
+
+		// 删除后不再命中，重新写入后恢复
+		if err := db.Delete([]byte("config")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Get([]byte("config"), 0); err != ErrNotFound {
+			t.Fatalf("hashKeys=%v: pinned key after delete: %v", hashKeys, err)
+		}
+		mustPut(t, db, "config", "v3")
+		if got := mustGet(t, db, "config", 0); got != "v3" {
+			t.Fatalf("hashKeys=%v: pinned key after rewrite %q", hashKeys, got)
+		}
+
+		if err := db.Unpin([]byte("config")); err != nil {
+			t.Fatal(err)
+		}
+		if s := db.Stats(); s.ValueCachePinned != 0 || s.ValueCacheEntries != 4 {
+			t.Fatalf("hashKeys=%v: after Unpin %d pinned, %d entries", hashKeys, s.ValueCachePinned, s.ValueCacheEntries)
+		}
+	}
+
+	uncached := openTestDB(t, nil)
+	if err := uncached.Pin([]byte("k")); err != ErrInvalidArg {
+		t.Fatalf("Pin without a value cache: %v", err)
+	}
+	if err := uncached.Unpin([]byte("k")); err != ErrInvalidArg {
+		t.Fatalf("Unpin without a value cache: %v", err)
+	}
+}
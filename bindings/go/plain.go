@@ -0,0 +1,36 @@
+package amdb
+
+/*
+#include "amdb.h"
+*/
+import "C"
+import "runtime"
+
+// checkAuthenticated PlainMode下返回ErrNotAuthenticated
+func (db *Database) checkAuthenticated() error {
+	if db.plain {
+		return ErrNotAuthenticated
+	}
+	return nil
+}
+
+// plainWrite 以单条目的批量写入提交key和value，引擎的批量写入路径不更新Merkle树（调用方需持有wmu）
+func (db *Database) plainWrite(key, value []byte) C.amdb_status_t {
+	// 指针数组位于Go内存中，其指向的数据必须固定
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	keys := []*C.uint8_t{pinBytes(&pinner, key)}
+	keyLens := []C.size_t{C.size_t(len(key))}
+	values := []*C.uint8_t{pinBytes(&pinner, value)}
+	valueLens := []C.size_t{C.size_t(len(value))}
+	var rootHash [32]C.uint8_t
+	return C.amdb_batch_put(db.handle, &keys[0], &keyLens[0], &values[0], &valueLens[0], 1, &rootHash[0])
+}
+
+// versionRoot 返回写入操作报告的版本version的根哈希，PlainMode下为nil
+func (db *Database) versionRoot(version uint32) ([]byte, error) {
+	if db.plain {
+		return nil, nil
+	}
+	return db.RootHashAtVersion(version)
+}
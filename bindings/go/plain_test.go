@@ -0,0 +1,99 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestPlainModeRejectsProofs(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabaseWithOptions(dir, &Options{PlainMode: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := db.Put([]byte("a"), []byte("1"))
+	if err != nil || root != nil {
+		t.Fatalf("Put: root %x, %v", root, err)
+	}
+	if root, err = db.BatchPut(map[string][]byte{"b": []byte("2"), "c": []byte("3")}); err != nil || root != nil {
+		t.Fatalf("BatchPut: root %x, %v", root, err)
+	}
+	if err := db.Delete([]byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	if mustGet(t, db, "a", 0) != "1" || mustGet(t, db, "b", 2) != "2" || mustGet(t, db, "c", 2) != "3" {
+		t.Fatal("plain mode reads differ")
+	}
+	if _, err := db.Get([]byte("c"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted key: %v", err)
+	}
+	it, err := db.NewIterator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, it); fmt.Sprint(got) != "[a=1 b=2]" {
+		t.Fatalf("iteration: %v", got)
+	}
+
+	for name, call := range map[string]func() error{
+		"GetRootHash":       func() error { _, err := db.GetRootHash(); return err },
+		"RootHashAtVersion": func() error { _, err := db.RootHashAtVersion(1); return err },
+		"RootsSince":        func() error { _, err := db.RootsSince(0); return err },
+		"GetWithProof":      func() error { _, err := db.GetWithProof([]byte("a"), 0); return err },
+		"ProofSize":         func() error { _, err := db.ProofSize([]byte("a"), 0); return err },
+		"GetRangeWithProof": func() error { _, _, err := db.GetRangeWithProof(nil, nil, 0); return err },
+		"RangeProofSize":    func() error { _, err := db.RangeProofSize(nil, nil, 0); return err },
+		"GetRangePageWithProof": func() error {
+			_, _, _, err := db.GetRangePageWithProof(nil, 10, 0)
+			return err
+		},
+		"SubtreeRoot":    func() error { _, err := db.SubtreeRoot([]byte("a"), 0); return err },
+		"ExportSubtree":  func() error { _, err := db.ExportSubtree([]byte("a"), 0); return err },
+		"StateSummary":   func() error { _, err := db.StateSummary(); return err },
+		"ExportFiltered": func() error { return db.ExportFiltered(io.Discard, 0, func([]byte) bool { return true }) },
+		"DumpDOT":        func() error { return db.DumpDOT(io.Discard, 0) },
+	} {
+		if err := call(); !errors.Is(err, ErrNotAuthenticated) {
+			t.Errorf("%s: %v, want ErrNotAuthenticated", name, err)
+		}
+	}
+
+	// 不带PlainMode重新打开后根哈希由状态计算，与始终认证写入的结果相同
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	auth := openTestDB(t, nil)
+	mustPut(t, auth, "a", "1")
+	if _, err := auth.BatchPut(map[string][]byte{"b": []byte("2"), "c": []byte("3")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Delete([]byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stateRoot(t, db), stateRoot(t, auth); !bytes.Equal(got, want) {
+		t.Fatal("root over plain-mode writes differs from authenticated writes")
+	}
+}
+
+func BenchmarkPlainModePut(b *testing.B) {
+	for _, plain := range []bool{false, true} {
+		b.Run(fmt.Sprintf("plain=%v", plain), func(b *testing.B) {
+			db := openTestDB(b, &Options{PlainMode: plain})
+			value := bytes.Repeat([]byte{'v'}, 64)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := db.Put([]byte(fmt.Sprintf("key-%08d", i)), value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
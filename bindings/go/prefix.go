@@ -0,0 +1,195 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// 键前缀压缩：键在最后一个'/'处分为前缀和后缀，不短于minSharedPrefix的前缀记入数据目录中的前缀字典，
+// 引擎中的键以前缀的8字节ID代替前缀本身。引擎的数据文件、WAL、版本记录和Merkle树都按键存储，
+// 共享长前缀的键（如层级路径）因此占用更少的空间。与块存储相同，编码与解码位于与C层交换键的最底层，
+// 其上的读取、迭代、根哈希与证明都只看到原始的键。
+//
+// 引擎中的键：[1字节标记prefixTagLiteral][原始键] 或 [1字节标记prefixTagShared][8字节前缀ID][后缀]
+// 前缀ID为前缀SHA-256的前8字节；与字典中已有的不同前缀冲突时该前缀的键按原样存储，编码因此只取决于键本身和字典中
+// 已记录的前缀，而字典只追加不删除。前缀字典格式：每条记录[4字节长度][前缀]
+const prefixDictName = "PREFIXES"
+
+const (
+	prefixTagLiteral byte = 0
+	prefixTagShared  byte = 1
+	prefixIDSize          = 8
+	// minSharedPrefix 记入字典的最短前缀，更短的前缀节省的空间不足以抵消ID
+	minSharedPrefix = 16
+)
+
+// ErrPrefixCompressionUnavailable PrefixCompression只能在创建数据库时启用
+var ErrPrefixCompressionUnavailable = errors.New("prefix compression must be enabled when the database is created")
+
+// prefixDict 数据目录中的前缀字典
+type prefixDict struct {
+	path string
+
+	mu   sync.Mutex
+	byID map[string][]byte // 前缀ID到前缀
+}
+
+// openPrefixDict 打开dataDir的前缀字典：字典已存在时不论选项如何都加载，以便读取已压缩的键；
+// 不存在且启用PrefixCompression时为空数据库创建字典（empty报告数据库是否为空）；两者都不满足时返回nil
+func openPrefixDict(dataDir string, opts *Options, empty func() (bool, error)) (*prefixDict, error) {
+	path := filepath.Join(dataDir, prefixDictName)
+	d := &prefixDict{path: path, byID: make(map[string][]byte)}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return d, d.load(data)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if !opts.PrefixCompression {
+		return nil, nil
+	}
+	if opts.HashKeys || len(opts.KeySalt) > 0 {
+		// 哈希后的键没有共同前缀
+		return nil, ErrInvalidArg
+	}
+	ok, err := empty()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrPrefixCompressionUnavailable
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	err = f.Sync()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = syncDir(dataDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// load 解析字典文件的内容data
+// 末尾不完整的记录来自追加时崩溃，引用它的键尚未写入引擎，截掉它以免之后的追加错位
+func (d *prefixDict) load(data []byte) error {
+	off := 0
+	for len(data)-off >= 4 {
+		n := int(wireOrder.Uint32(data[off:]))
+		if n > len(data)-off-4 {
+			break
+		}
+		prefix := bytes.Clone(data[off+4 : off+4+n])
+		d.byID[prefixID(prefix)] = prefix
+		off += 4 + n
+	}
+	if off == len(data) {
+		return nil
+	}
+	return os.Truncate(d.path, int64(off))
+}
+
+// prefixID 返回前缀的ID
+func prefixID(prefix []byte) string {
+	sum := sha256.Sum256(prefix)
+	return string(sum[:prefixIDSize])
+}
+
+// encode 返回key在引擎中的形式；add为true时把字典中尚无的前缀记入字典（写入路径），
+// 否则（读取路径）只使用已记录的前缀：不在字典中的前缀从未被写入过，该键若存在必为原样存储
+func (d *prefixDict) encode(key []byte, add bool) ([]byte, error) {
+	i := bytes.LastIndexByte(key, '/') + 1
+	if i < minSharedPrefix {
+		return append([]byte{prefixTagLiteral}, key...), nil
+	}
+	prefix, id := key[:i], prefixID(key[:i])
+
+	d.mu.Lock()
+	known, ok := d.byID[id]
+	if !ok && add {
+		if err := d.append(prefix); err != nil {
+			d.mu.Unlock()
+			return nil, err
+		}
+		known, ok = bytes.Clone(prefix), true
+		d.byID[id] = known
+	}
+	d.mu.Unlock()
+
+	if !ok || !bytes.Equal(known, prefix) {
+		return append([]byte{prefixTagLiteral}, key...), nil
+	}
+	out := make([]byte, 0, 1+prefixIDSize+len(key)-i)
+	out = append(out, prefixTagShared)
+	out = append(out, id...)
+	return append(out, key[i:]...), nil
+}
+
+// decode 将引擎中的键还原为原始的键
+func (d *prefixDict) decode(stored []byte) ([]byte, error) {
+	if len(stored) > 0 && stored[0] == prefixTagLiteral {
+		return stored[1:], nil
+	}
+	if len(stored) < 1+prefixIDSize || stored[0] != prefixTagShared {
+		return nil, ErrCorrupted
+	}
+	d.mu.Lock()
+	prefix, ok := d.byID[string(stored[1:1+prefixIDSize])]
+	d.mu.Unlock()
+	if !ok {
+		return nil, ErrCorrupted
+	}
+	suffix := stored[1+prefixIDSize:]
+	return append(append(make([]byte, 0, len(prefix)+len(suffix)), prefix...), suffix...), nil
+}
+
+// append 将前缀追加到字典文件并刷入磁盘（调用方需持有mu）
+// 前缀须在引擎提交引用它的键之前落盘，否则崩溃后键无法还原
+func (d *prefixDict) append(prefix []byte) error {
+	f, err := os.OpenFile(d.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(appendBytes32(nil, prefix))
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// encodeKey 返回写入或查询C层时使用的键，未启用前缀压缩时原样返回；add含义同prefixDict.encode
+func (db *Database) encodeKey(key []byte, add bool) ([]byte, error) {
+	if db.prefixes == nil {
+		return key, nil
+	}
+	return db.prefixes.encode(key, add)
+}
+
+// decodeKey 将从C层读取的键还原为原始的键，未启用前缀压缩时原样返回
+func (db *Database) decodeKey(stored []byte) ([]byte, error) {
+	if db.prefixes == nil {
+		return stored, nil
+	}
+	return db.prefixes.decode(stored)
+}
+
+// rootFromState 报告根哈希是否须由状态计算：块存储和前缀压缩改变了引擎Merkle树所见的键值，
+// 引擎维护的根哈希不再是按原始键值计算的根哈希；LazyRoot下及有过批量写入之后引擎的Merkle树缺少部分写入
+func (db *Database) rootFromState() bool {
+	return db.chunks != nil || db.prefixes != nil || db.lazyRoot || db.stateRoot.Load()
+}
@@ -0,0 +1,96 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPrefixCompressionSavesSpaceKeepsRoot(t *testing.T) {
+	dir := t.TempDir()
+	compressed, err := NewDatabaseWithOptions(dir, &Options{PrefixCompression: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := openTestDB(t, nil)
+	prefix := strings.Repeat("tenants/acme/projects/", 4)
+	items := make(map[string][]byte)
+	for i := 0; i < 2000; i++ {
+		items[fmt.Sprintf("%sdir%d/file-%04d", prefix, i%4, i)] = []byte("v")
+	}
+	items["short"] = []byte("s")
+	for _, db := range []*Database{compressed, plain} {
+		if _, err := db.BatchPut(items); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Delete([]byte(prefix + "dir1/file-0001")); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Compact(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !bytes.Equal(rootOf(t, compressed), rootOf(t, plain)) {
+		t.Fatal("prefix compression changed the root")
+	}
+	small, err := compressed.DiskUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := plain.DiskUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small >= large {
+		t.Fatalf("compressed usage %d, uncompressed %d", small, large)
+	}
+
+	key := prefix + "dir2/file-0002"
+	if mustGet(t, compressed, key, 0) != "v" || mustGet(t, compressed, "short", 0) != "s" {
+		t.Fatal("compressed keys not readable")
+	}
+	it, err := compressed.NewPrefixIterator([]byte(prefix+"dir3/"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collect(t, it)
+	if len(got) != 500 || !strings.HasPrefix(got[0], prefix+"dir3/file-0003=") {
+		t.Fatalf("iterated %d keys, first %.60q", len(got), got[0])
+	}
+
+	// 之后不带选项打开仍按压缩格式读写
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	mustPut(t, reopened, prefix+"dir0/new", "n")
+	mustPut(t, plain, prefix+"dir0/new", "n")
+	if mustGet(t, reopened, key, 0) != "v" || !bytes.Equal(rootOf(t, reopened), rootOf(t, plain)) {
+		t.Fatal("reopened compressed database differs")
+	}
+}
+
+func TestPrefixCompressionOnlyAtCreation(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "k", "v")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDatabaseWithOptions(dir, &Options{PrefixCompression: true}); !errors.Is(err, ErrPrefixCompressionUnavailable) {
+		t.Fatalf("existing database: %v", err)
+	}
+	if _, err := NewDatabaseWithOptions(t.TempDir(), &Options{PrefixCompression: true, HashKeys: true}); err != ErrInvalidArg {
+		t.Fatalf("with HashKeys: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package amdb
+
+import (
+	"bytes"
+	"sort"
+)
+
+// ListPrefixes 返回最新版本中所有存活键按分隔符sep切分后的不同首段，按字典序排序
+// 例如键"users/1"和"users/2"、"orders/7"得到["orders", "users"]；不含sep的键不属于任何前缀，不计入结果。
+// 引擎没有正式的命名空间，该方法用于发现按前缀组织的逻辑表；需要读取全部键
+func (db *Database) ListPrefixes(sep byte) ([]string, error) {
+	state, err := db.stateAt(0)
+	if err != nil {
+		return nil, err
+	}
+	items, err := db.userEntries(liveRange(state, nil, nil, bytes.Compare))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	for _, item := range items {
+		if i := bytes.IndexByte(item.key, sep); i >= 0 {
+			seen[string(item.key[:i])] = struct{}{}
+		}
+	}
+	prefixes := make([]string, 0, len(seen))
+	for p := range seen {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	return prefixes, nil
+}
@@ -0,0 +1,31 @@
+package amdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListPrefixes(t *testing.T) {
+	db := openTestDB(t, nil)
+	if got, err := db.ListPrefixes('/'); err != nil || len(got) != 0 {
+		t.Fatalf("empty db: %v, %v", got, err)
+	}
+	for _, k := range []string{"users/1", "users/2", "orders/7", "orders/8/items", "config", "tmp/x", "/root"} {
+		mustPut(t, db, k, "v")
+	}
+	if err := db.Delete([]byte("tmp/x")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListPrefixes('/')
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 已删除的键不计入，以分隔符开头的键的首段为空
+	if want := []string{"", "orders", "users"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ListPrefixes('/') = %q, want %q", got, want)
+	}
+	if got, err := db.ListPrefixes(':'); err != nil || len(got) != 0 {
+		t.Fatalf("unused separator: %q, %v", got, err)
+	}
+}
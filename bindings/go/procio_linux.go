@@ -0,0 +1,24 @@
+//go:build linux
+
+package amdb
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+)
+
+// processWrittenBytes 返回本进程经write类系统调用累计写出的字节数（/proc/self/io中的wchar）
+func processWrittenBytes() (uint64, bool) {
+	data, err := os.ReadFile("/proc/self/io")
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if value, ok := bytes.CutPrefix(line, []byte("wchar:")); ok {
+			n, err := strconv.ParseUint(string(bytes.TrimSpace(value)), 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package amdb
+
+// processWrittenBytes 当前平台没有可用的进程写出字节计数
+func processWrittenBytes() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,568 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// MerkleProof 键的Merkle包含证明
+type MerkleProof struct {
+	Key   []byte
+	Value []byte
+	// Steps 从根到叶子的路径，第i步对应键的第i个nibble
+	Steps []ProofStep
+	// Root 证明所针对的根哈希
+	Root []byte
+	// LeafHash 仅GetProofOnly返回的证明：叶子节点哈希，此时Value为nil
+	LeafHash []byte
+	// Version 证明所针对的数据库版本，Root即该版本的根哈希（0表示未知，例如旧格式解码的证明）
+	// 校验方应核对Version是否为其认可的版本，而不是假定证明针对最新版本
+	Version uint32
+	// GeneratedAt 服务端生成（或从证明缓存返回）证明时的本机时间（零值表示未知）
+	GeneratedAt time.Time
+}
+
+// ProofStep 证明路径上的一个节点
+type ProofStep struct {
+	// Branch 为true表示分支节点，否则为扩展节点
+	Branch bool
+	// Nibble 路径在该节点经过的nibble
+	Nibble byte
+	// Siblings 仅分支节点：各下标其他子节点的哈希（自身所在下标和空子节点为nil）
+	Siblings [16][]byte
+}
+
+// 证明二进制编码格式版本
+const (
+	proofFormatV1 = 1
+	// proofFormatV2 在根哈希之后追加叶子哈希，用于不含值的证明
+	proofFormatV2 = 2
+	// proofFormatV3 总是包含叶子哈希字段（可为空），并在其后追加版本和生成时间
+	proofFormatV3 = 3
+)
+
+var (
+	// ErrBadProof 证明编码格式错误
+	ErrBadProof = errors.New("malformed proof")
+	// ErrProofMismatch 证明不能针对给定的根校验通过
+	ErrProofMismatch = errors.New("proof does not match root")
+	// ErrInconsistentTransition 新旧两个证明的路径不一致，不是只修改了该键的状态转换
+	ErrInconsistentTransition = errors.New("proofs do not form a single-key transition")
+)
+
+// GetWithProof 获取键在数据库版本version（0表示最新版本）时的值及Merkle证明
+// 需要在内存中重建该版本的整棵树，开销与键数量成正比；启用Options.ProofCacheEntries后
+// 同一键和版本的证明只计算一次。哈希键模式下证明中的Key和Value为存储形式
+func (db *Database) GetWithProof(key []byte, version uint32) (*MerkleProof, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		current, err := db.CurrentVersion()
+		if err != nil {
+			return nil, err
+		}
+		if current == 0 {
+			return nil, ErrNotFound
+		}
+		version = current
+	}
+
+	if !db.inKeyRange(key) {
+		return nil, ErrNotFound
+	}
+	stored := db.storedKey(key)
+	if proof, ok := db.proofs.get(stored, version); ok {
+		proof.GeneratedAt = time.Now()
+		return proof, nil
+	}
+
+	root, err := db.trieAt(version)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, ErrNotFound
+	}
+	leaf, steps := root.path(stored)
+	if leaf == nil || isDeleted(leaf.value) {
+		return nil, ErrNotFound
+	}
+	proof := &MerkleProof{Key: leaf.key, Value: leaf.value, Steps: steps, Root: root.hash, Version: version, GeneratedAt: time.Now()}
+	db.proofs.put(stored, version, proof)
+	return proof, nil
+}
+
+// ScanWithProofs 按迭代器的顺序对版本version（0表示最新版本）中[start, end)范围内的每个存活键调用fn，
+// 同时传入其Merkle证明，范围含义同NewRangeIterator。所有证明都针对同一个根（该版本的根哈希，即证明的Root），
+// 审计方可以边扫描边逐条校验。该版本的树只构建一次，证明在回调前逐个生成，不会一次性保存全部证明；
+// 回调返回后证明不再被引用，可以保留。fn返回错误时停止扫描并原样返回该错误；
+// 空数据库不调用fn。哈希键模式下传给fn的key和value为原始形式，证明中的Key和Value为存储形式
+func (db *Database) ScanWithProofs(start, end []byte, version uint32, fn func(key, value []byte, proof *MerkleProof) error) error {
+	if err := db.checkAuthenticated(); err != nil {
+		return err
+	}
+	if version == 0 {
+		current, err := db.CurrentVersion()
+		if err != nil {
+			return err
+		}
+		if current == 0 {
+			return nil
+		}
+		version = current
+	}
+
+	state, err := db.stateAt(version)
+	if err != nil {
+		return err
+	}
+	root, err := db.trieOf(state, version)
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return nil
+	}
+	start, end = db.clampRange(start, end)
+	for _, item := range liveRange(state, start, end, db.keyOrder()) {
+		leaf, steps := root.path(item.key)
+		if leaf == nil {
+			return ErrCorrupted
+		}
+		proof := &MerkleProof{Key: leaf.key, Value: leaf.value, Steps: steps, Root: root.hash, Version: version, GeneratedAt: time.Now()}
+		if db.hashKeys {
+			if item, err = db.userEntry(item); err != nil {
+				return err
+			}
+		}
+		if err := fn(item.key, item.value, proof); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetProofOnly 与GetWithProof相同，但证明中不包含值，只携带叶子哈希LeafHash
+// 适用于大值场景：已持有值的校验方用VerifyProof传入值校验，
+// 只关心承诺的校验方用VerifyLeafHash校验
+func (db *Database) GetProofOnly(key []byte, version uint32) (*MerkleProof, error) {
+	proof, err := db.GetWithProof(key, version)
+	if err != nil {
+		return nil, err
+	}
+	proof.LeafHash = LeafHash(proof.Key, proof.Value)
+	proof.Value = nil
+	return proof, nil
+}
+
+// LeafHash 返回键值对在Merkle树中的叶子节点哈希
+func LeafHash(key, value []byte) []byte {
+	h, _ := HashSHA256.sum(leafContent(key, value))
+	return h
+}
+
+// ProofSize 返回键在数据库版本version时的证明编码长度，即len(MarshalBinary())
+func (db *Database) ProofSize(key []byte, version uint32) (int, error) {
+	proof, err := db.GetWithProof(key, version)
+	if err != nil {
+		return 0, err
+	}
+	return proof.binarySize(), nil
+}
+
+// KeyDepth 返回键在数据库版本version（0表示最新版本）的树中所处的深度，即其证明的步数len(Steps)
+// 只沿路径下行而不收集兄弟哈希，键不存在（或已删除）时返回ErrNotFound；仍需重建该版本的整棵树
+func (db *Database) KeyDepth(key []byte, version uint32) (int, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return 0, err
+	}
+	root, err := db.trieAt(version)
+	if err != nil {
+		return 0, err
+	}
+	stored := db.storedKey(key)
+	node := root
+	for depth := 0; node != nil; depth++ {
+		switch node.kind {
+		case leafNode:
+			if !bytes.Equal(node.key, stored) || isDeleted(node.value) {
+				return 0, ErrNotFound
+			}
+			return depth, nil
+		case extNode:
+			if keyNibble(stored, depth) != node.nibble {
+				return 0, ErrNotFound
+			}
+			node = node.child
+		case branchNode:
+			node = node.children[keyNibble(stored, depth)]
+		}
+	}
+	return 0, ErrNotFound
+}
+
+// VerifyProof 校验proof能否证明key=value包含在根为root的树中
+func VerifyProof(root, key, value []byte, proof *MerkleProof) bool {
+	return VerifyLeafHash(root, key, LeafHash(key, value), proof)
+}
+
+// VerifyLeafHash 校验proof能否证明叶子哈希为leafHash的key包含在根为root的树中
+func VerifyLeafHash(root, key, leafHash []byte, proof *MerkleProof) bool {
+	if proof == nil || len(leafHash) == 0 {
+		return false
+	}
+	h, ok := rootFromLeaf(key, leafHash, proof.Steps)
+	return ok && bytes.Equal(h, root)
+}
+
+// rootFromLeaf 沿证明路径自叶子哈希向上计算根哈希，路径与key的nibble不一致时返回false
+func rootFromLeaf(key, leafHash []byte, steps []ProofStep) ([]byte, bool) {
+	h := leafHash
+	var err error
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.Nibble > 0x0F || step.Nibble != keyNibble(key, i) {
+			return nil, false
+		}
+		if step.Branch {
+			children := step.Siblings
+			children[step.Nibble] = h
+			h, err = HashSHA256.sum(branchContent(&children))
+		} else {
+			h, err = HashSHA256.sum(extContent(step.Nibble, h))
+		}
+		if err != nil {
+			return nil, false
+		}
+	}
+	return h, true
+}
+
+// UpdateRoot 由键在oldRoot下的包含证明预测把该键的值改为newValue之后的根哈希，不需要访问数据库
+// 修改已存在键的值不改变树的结构，只改变叶子哈希，因此沿证明路径以新叶子重新计算各层哈希即得新根，
+// 与数据库中仅对该键执行Put(key, newValue)后RootHashAtVersion的结果一致；同一版本中有其他写入时则不一致。
+// 证明不能针对oldRoot校验通过时返回ErrProofMismatch。证明可以包含值或只含LeafHash（GetProofOnly）；
+// 哈希键模式下证明中的键值为存储形式，newValue须同样为存储形式
+func UpdateRoot(oldRoot []byte, proof *MerkleProof, newValue []byte) (newRoot []byte, err error) {
+	if !proof.Verify(oldRoot) {
+		return nil, ErrProofMismatch
+	}
+	// 证明已校验通过，路径必然与键一致
+	newRoot, _ = rootFromLeaf(proof.Key, LeafHash(proof.Key, newValue), proof.Steps)
+	return newRoot, nil
+}
+
+// VerifyTransition 校验从oldRoot到newRoot的状态转换只把key的值从oldVal改为newVal，不需要访问数据库
+// 依次校验oldProof能证明key=oldVal包含在oldRoot下、newProof能证明key=newVal包含在newRoot下，
+// 以及两个证明的路径（各层节点类型、nibble与兄弟哈希）完全相同，即除该键的叶子外树中没有其他变化。
+// 前两项失败时返回包装ErrProofMismatch的错误，路径不同时返回ErrInconsistentTransition。
+// 只适用于修改已存在键的值（删除写入删除标记，同样属于修改）：新增键改变树的结构，
+// 同一版本中还有其他写入时路径上的兄弟哈希也会不同，都不能以此校验。哈希键模式下键值须为存储形式
+func VerifyTransition(oldRoot []byte, key, oldVal, newVal []byte, oldProof, newProof *MerkleProof, newRoot []byte) error {
+	if !VerifyProof(oldRoot, key, oldVal, oldProof) {
+		return fmt.Errorf("old proof: %w", ErrProofMismatch)
+	}
+	if !VerifyProof(newRoot, key, newVal, newProof) {
+		return fmt.Errorf("new proof: %w", ErrProofMismatch)
+	}
+	if len(oldProof.Steps) != len(newProof.Steps) {
+		return ErrInconsistentTransition
+	}
+	for i, old := range oldProof.Steps {
+		step := newProof.Steps[i]
+		if old.Branch != step.Branch || old.Nibble != step.Nibble {
+			return ErrInconsistentTransition
+		}
+		for j := range old.Siblings {
+			if !bytes.Equal(old.Siblings[j], step.Siblings[j]) || (old.Siblings[j] == nil) != (step.Siblings[j] == nil) {
+				return ErrInconsistentTransition
+			}
+		}
+	}
+	return nil
+}
+
+// Verify 校验证明中的键值是否包含在根为root的树中
+// 不含值的证明（GetProofOnly）按其LeafHash校验
+func (p *MerkleProof) Verify(root []byte) bool {
+	if p == nil {
+		return false
+	}
+	if p.Value == nil && p.LeafHash != nil {
+		return VerifyLeafHash(root, p.Key, p.LeafHash, p)
+	}
+	return VerifyProof(root, p.Key, p.Value, p)
+}
+
+// Equal 报告两个证明在结构上是否相同：键、值、版本、根哈希、叶子哈希和每一步的节点类型、nibble与兄弟哈希都相同
+// GeneratedAt只记录生成时间，不参与比较，因此同一版本上对同一键的两次请求得到的证明相等。
+// 哈希按常量时间比较（长度不同时立即判定不同）；两者都为nil时相等，只有一个为nil时不相等
+func (p *MerkleProof) Equal(other *MerkleProof) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	if p.Version != other.Version || len(p.Steps) != len(other.Steps) ||
+		!bytes.Equal(p.Key, other.Key) || !bytes.Equal(p.Value, other.Value) {
+		return false
+	}
+	// 各项都比较完再返回，耗时与哪一个哈希不同无关
+	equal := subtle.ConstantTimeCompare(p.Root, other.Root) & subtle.ConstantTimeCompare(p.LeafHash, other.LeafHash)
+	for i := range p.Steps {
+		a, b := &p.Steps[i], &other.Steps[i]
+		if a.Branch != b.Branch || a.Nibble != b.Nibble {
+			equal = 0
+		}
+		for j := range a.Siblings {
+			equal &= subtle.ConstantTimeCompare(a.Siblings[j], b.Siblings[j])
+		}
+	}
+	return equal == 1
+}
+
+// ProofEntry 待校验的键值及其证明
+type ProofEntry struct {
+	Key   []byte
+	Value []byte
+	Proof *MerkleProof
+}
+
+// VerifyProofs 针对同一个根并行校验多个证明，返回与entries一一对应的校验结果
+// 单个证明无效只会使对应结果为false，不影响其他条目
+func VerifyProofs(root []byte, entries []ProofEntry) ([]bool, error) {
+	if len(root) == 0 {
+		return nil, errors.New("empty root")
+	}
+
+	return verifyParallel(len(entries), func(i int) bool {
+		e := entries[i]
+		return VerifyProof(root, e.Key, e.Value, e.Proof)
+	}), nil
+}
+
+// RootProofEntry 待针对其自身根哈希校验的键值及其证明
+type RootProofEntry struct {
+	Root  []byte
+	Key   []byte
+	Value []byte
+	Proof *MerkleProof
+}
+
+// VerifyAcrossRoots 并行校验各自针对不同根哈希（如不同分片）的证明，返回与entries一一对应的校验结果
+// 单个条目无效（包括根哈希为空）只会使对应结果为false，不影响其他条目；错误保留给将来无法开始校验的情况，目前总是nil
+func VerifyAcrossRoots(entries []RootProofEntry) ([]bool, error) {
+	return verifyParallel(len(entries), func(i int) bool {
+		e := entries[i]
+		return len(e.Root) > 0 && VerifyProof(e.Root, e.Key, e.Value, e.Proof)
+	}), nil
+}
+
+// verifyParallel 以GOMAXPROCS个goroutine对0到n-1并行调用verify，返回各下标的结果
+func verifyParallel(n int, verify func(i int) bool) []bool {
+	results := make([]bool, n)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = verify(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// 二进制编码格式（整数均为大端）：
+//
+//	[1字节格式版本]
+//	[4字节键长度][键][4字节值长度][值][4字节根长度][根]
+//	[4字节叶子哈希长度][叶子哈希]（格式版本2在LeafHash非空时使用；格式版本3总是包含，可为空）
+//	[4字节版本][8字节生成时间UnixNano，0表示未知]（仅格式版本3，Version或GeneratedAt非零时使用）
+//	[4字节步数] 每步：[1字节类型(0扩展/1分支)][1字节nibble]
+//	  分支节点额外包含：[2字节兄弟位图] 位图中每个置位下标：[1字节哈希长度][哈希]
+
+// binarySize 返回MarshalBinary的编码长度
+func (p *MerkleProof) binarySize() int {
+	n := 1 + 4 + len(p.Key) + 4 + len(p.Value) + 4 + len(p.Root) + 4
+	switch p.format() {
+	case proofFormatV2:
+		n += 4 + len(p.LeafHash)
+	case proofFormatV3:
+		n += 4 + len(p.LeafHash) + 4 + 8
+	}
+	for _, step := range p.Steps {
+		n += 2
+		if step.Branch {
+			n += 2
+			for _, h := range step.Siblings {
+				if h != nil {
+					n += 1 + len(h)
+				}
+			}
+		}
+	}
+	return n
+}
+
+// format 返回编码证明所用的最低格式版本
+func (p *MerkleProof) format() byte {
+	switch {
+	case p.Version != 0 || !p.GeneratedAt.IsZero():
+		return proofFormatV3
+	case len(p.LeafHash) > 0:
+		return proofFormatV2
+	default:
+		return proofFormatV1
+	}
+}
+
+// MarshalBinary 实现encoding.BinaryMarshaler
+func (p *MerkleProof) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, p.binarySize())
+	format := p.format()
+	buf = append(buf, format)
+	buf = appendBytes32(buf, p.Key)
+	buf = appendBytes32(buf, p.Value)
+	buf = appendBytes32(buf, p.Root)
+	if format >= proofFormatV2 {
+		buf = appendBytes32(buf, p.LeafHash)
+	}
+	if format == proofFormatV3 {
+		buf = wireOrder.AppendUint32(buf, p.Version)
+		var generated int64
+		if !p.GeneratedAt.IsZero() {
+			generated = p.GeneratedAt.UnixNano()
+		}
+		buf = wireOrder.AppendUint64(buf, uint64(generated))
+	}
+	buf = wireOrder.AppendUint32(buf, uint32(len(p.Steps)))
+	for _, step := range p.Steps {
+		if step.Branch {
+			buf = append(buf, 1, step.Nibble)
+			var bitmap uint16
+			for i, h := range step.Siblings {
+				if h != nil {
+					bitmap |= 1 << i
+				}
+			}
+			buf = wireOrder.AppendUint16(buf, bitmap)
+			for _, h := range step.Siblings {
+				if h != nil {
+					if len(h) > 0xFF {
+						return nil, ErrBadProof
+					}
+					buf = append(buf, byte(len(h)))
+					buf = append(buf, h...)
+				}
+			}
+		} else {
+			buf = append(buf, 0, step.Nibble)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler
+func (p *MerkleProof) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	format, err := r.ReadByte()
+	if err != nil || format < proofFormatV1 || format > proofFormatV3 {
+		return ErrBadProof
+	}
+	var proof MerkleProof
+	if proof.Key, err = readLengthPrefixed(r); err != nil {
+		return ErrBadProof
+	}
+	if proof.Value, err = readLengthPrefixed(r); err != nil {
+		return ErrBadProof
+	}
+	if proof.Root, err = readLengthPrefixed(r); err != nil {
+		return ErrBadProof
+	}
+	if format >= proofFormatV2 {
+		if proof.LeafHash, err = readLengthPrefixed(r); err != nil {
+			return ErrBadProof
+		}
+		switch {
+		case len(proof.LeafHash) > 0:
+			if len(proof.Value) == 0 {
+				proof.Value = nil
+			}
+		case format == proofFormatV2:
+			return ErrBadProof
+		default:
+			proof.LeafHash = nil
+		}
+	}
+	if format == proofFormatV3 {
+		var generated int64
+		if binary.Read(r, wireOrder, &proof.Version) != nil || binary.Read(r, wireOrder, &generated) != nil {
+			return ErrBadProof
+		}
+		if generated != 0 {
+			proof.GeneratedAt = time.Unix(0, generated)
+		}
+	}
+	var count uint32
+	if binary.Read(r, wireOrder, &count) != nil || int64(count)*2 > int64(r.Len()) {
+		return ErrBadProof
+	}
+	proof.Steps = make([]ProofStep, count)
+	for i := range proof.Steps {
+		var header [2]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil || header[0] > 1 || header[1] > 0x0F {
+			return ErrBadProof
+		}
+		step := ProofStep{Branch: header[0] == 1, Nibble: header[1]}
+		if step.Branch {
+			var bitmap uint16
+			if binary.Read(r, wireOrder, &bitmap) != nil {
+				return ErrBadProof
+			}
+			for j := 0; j < 16; j++ {
+				if bitmap&(1<<j) == 0 {
+					continue
+				}
+				n, err := r.ReadByte()
+				if err != nil || int(n) > r.Len() {
+					return ErrBadProof
+				}
+				step.Siblings[j] = make([]byte, n)
+				if _, err := io.ReadFull(r, step.Siblings[j]); err != nil {
+					return ErrBadProof
+				}
+			}
+		}
+		proof.Steps[i] = step
+	}
+	if r.Len() != 0 {
+		return ErrBadProof
+	}
+	*p = proof
+	return nil
+}
+
+// appendBytes32 追加[4字节大端长度][数据]
+func appendBytes32(buf, data []byte) []byte {
+	buf = wireOrder.AppendUint32(buf, uint32(len(data)))
+	return append(buf, data...)
+}
@@ -0,0 +1,600 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// proofDB 返回写入了keys的数据库，keys共享长短不一的前缀，证明的深度各不相同
+func proofDB(t *testing.T, opts *Options) (*Database, []string) {
+	t.Helper()
+	db := openTestDB(t, opts)
+	keys := []string{"a", "ab", "abc", "abcd", "b", "zzzzzzzz"}
+	for i := 0; i < 40; i++ {
+		keys = append(keys, fmt.Sprintf("key-%03d", i))
+	}
+	items := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		items[k] = []byte("value of " + k)
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	return db, keys
+}
+
+func TestProofSizeMatchesMarshal(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	depths := map[int]bool{}
+	for _, k := range keys {
+		proof, err := db.GetWithProof([]byte(k), 0)
+		if err != nil {
+			t.Fatalf("%s: %v", k, err)
+		}
+		data, err := proof.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		size, err := db.ProofSize([]byte(k), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != len(data) {
+			t.Fatalf("%s: ProofSize %d, marshaled %d", k, size, len(data))
+		}
+		depths[len(proof.Steps)] = true
+	}
+	if len(depths) < 3 {
+		t.Fatalf("keys cover only depths %v", depths)
+	}
+	if _, err := db.ProofSize([]byte("missing"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing key: %v", err)
+	}
+}
+
+func TestProofRoundTripAndVerify(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	root, err := db.GetRootHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		proof, err := db.GetWithProof([]byte(k), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(proof.Root, root) || !VerifyProof(root, []byte(k), []byte("value of "+k), proof) {
+			t.Fatalf("%s: proof does not verify", k)
+		}
+		if VerifyProof(root, []byte(k), []byte("forged"), proof) {
+			t.Fatalf("%s: forged value verifies", k)
+		}
+		data, _ := proof.MarshalBinary()
+		var decoded MerkleProof
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Verify(root) || decoded.Version != proof.Version {
+			t.Fatalf("%s: decoded proof %+v", k, decoded)
+		}
+	}
+	var bad MerkleProof
+	if err := bad.UnmarshalBinary([]byte{0xFF}); !errors.Is(err, ErrBadProof) {
+		t.Fatalf("garbage: %v", err)
+	}
+}
+
+// proofEntries 返回db中keys的证明条目，每隔一条篡改值
+func proofEntries(t testing.TB, db *Database, keys []string, tamper bool) []ProofEntry {
+	t.Helper()
+	entries := make([]ProofEntry, len(keys))
+	for i, k := range keys {
+		proof, err := db.GetWithProof([]byte(k), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[i] = ProofEntry{Key: []byte(k), Value: proof.Value, Proof: proof}
+		if tamper && i%2 == 1 {
+			entries[i].Value = []byte("tampered")
+		}
+	}
+	return entries
+}
+
+func TestVerifyProofs(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	root, err := db.GetRootHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := proofEntries(t, db, keys, true)
+	entries = append(entries, ProofEntry{Key: []byte("nil proof")})
+	results, err := VerifyProofs(root, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ok := range results[:len(keys)] {
+		if ok != (i%2 == 0) {
+			t.Fatalf("entry %d (%s): %v", i, keys[i], ok)
+		}
+	}
+	if results[len(keys)] {
+		t.Fatal("entry without proof verified")
+	}
+	if _, err := VerifyProofs(nil, entries); err == nil {
+		t.Fatal("empty root accepted")
+	}
+	if results, err := VerifyProofs(root, nil); err != nil || len(results) != 0 {
+		t.Fatalf("no entries: %v, %v", results, err)
+	}
+}
+
+func BenchmarkVerifyProofs(b *testing.B) {
+	db := openTestDB(b, nil)
+	keys := make([]string, 256)
+	items := make(map[string][]byte, len(keys))
+	for i := range keys {
+		keys[i] = fmt.Sprintf("account-%04d", i)
+		items[keys[i]] = []byte(fmt.Sprint("balance ", i))
+	}
+	root, err := db.BatchPut(items)
+	if err != nil {
+		b.Fatal(err)
+	}
+	entries := proofEntries(b, db, keys, false)
+
+	b.Run("sequential", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for _, e := range entries {
+				if !VerifyProof(root, e.Key, e.Value, e.Proof) {
+					b.Fatal("proof does not verify")
+				}
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := VerifyProofs(root, entries); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestGetProofOnly(t *testing.T) {
+	db := openTestDB(t, nil)
+	large := bytes.Repeat([]byte("x"), 64<<10)
+	mustPut(t, db, "small", "s")
+	if _, err := db.Put([]byte("large"), large); err != nil {
+		t.Fatal(err)
+	}
+	root := rootOf(t, db)
+
+	proof, err := db.GetProofOnly([]byte("large"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Value != nil || !bytes.Equal(proof.LeafHash, LeafHash([]byte("large"), large)) {
+		t.Fatalf("proof carries value %d bytes, leaf hash %x", len(proof.Value), proof.LeafHash)
+	}
+	// 已持有值的校验方单独提供值
+	if !VerifyProof(root, []byte("large"), large, proof) {
+		t.Fatal("proof-only does not verify with the value")
+	}
+	if VerifyProof(root, []byte("large"), large[1:], proof) {
+		t.Fatal("proof-only verifies a different value")
+	}
+	if !VerifyLeafHash(root, []byte("large"), proof.LeafHash, proof) {
+		t.Fatal("proof-only does not verify with its leaf hash")
+	}
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > 4<<10 {
+		t.Fatalf("proof-only encodes to %d bytes", len(data))
+	}
+	var decoded MerkleProof
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyProof(root, []byte("large"), large, &decoded) {
+		t.Fatal("decoded proof-only does not verify")
+	}
+}
+
+func TestProofCarriesVersion(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "k", "1")
+	mustPut(t, db, "k", "2")
+	mustPut(t, db, "other", "x")
+	start := time.Now()
+
+	check := func(name string, proof *MerkleProof, version uint32) {
+		t.Helper()
+		if proof.Version != version {
+			t.Errorf("%s: Version %d, want %d", name, proof.Version, version)
+		}
+		root, err := db.RootHashAtVersion(proof.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(proof.Root, root) || !proof.Verify(root) {
+			t.Errorf("%s: root %x, RootHashAtVersion(%d) %x", name, proof.Root, proof.Version, root)
+		}
+		if proof.GeneratedAt.Before(start) || proof.GeneratedAt.After(time.Now()) {
+			t.Errorf("%s: GeneratedAt %v outside the test", name, proof.GeneratedAt)
+		}
+	}
+	for _, v := range []uint32{1, 2, 3} {
+		proof, err := db.GetWithProof([]byte("k"), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(fmt.Sprintf("GetWithProof@%d", v), proof, v)
+	}
+	latest, err := db.GetWithProof([]byte("k"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("GetWithProof@latest", latest, 3)
+
+	snap, err := db.SnapshotAt(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Release()
+	proof, err := snap.GetWithProof([]byte("k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Snapshot.GetWithProof", proof, 2)
+
+	data, err := latest.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded MerkleProof
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Version != 3 || !decoded.GeneratedAt.Equal(latest.GeneratedAt) {
+		t.Fatalf("round trip lost version or time: %d %v", decoded.Version, decoded.GeneratedAt)
+	}
+}
+
+func TestKeyDepthMatchesProof(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	if err := db.Delete([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	depths := map[int]bool{}
+	for _, k := range keys {
+		if k == "b" {
+			continue
+		}
+		proof, err := db.GetWithProof([]byte(k), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		depth, err := db.KeyDepth([]byte(k), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if depth != len(proof.Steps) {
+			t.Fatalf("%s: KeyDepth %d, proof has %d steps", k, depth, len(proof.Steps))
+		}
+		depths[depth] = true
+	}
+	if len(depths) < 2 {
+		t.Fatalf("all keys at the same depth %v; test data does not exercise KeyDepth", depths)
+	}
+
+	for _, k := range []string{"missing", "ab\x00", "b"} {
+		if _, err := db.KeyDepth([]byte(k), 0); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%q: %v", k, err)
+		}
+	}
+	if d, err := db.KeyDepth([]byte("b"), 1); err != nil || d == 0 {
+		t.Fatalf("b before delete: %d, %v", d, err)
+	}
+}
+
+func TestUpdateRootMatchesPut(t *testing.T) {
+	for _, opts := range []*Options{nil, {HashKeys: true}} {
+		db, keys := proofDB(t, opts)
+		for i, k := range []string{keys[0], "abc", "abcd", "zzzzzzzz", "key-017"} {
+			oldRoot := rootOf(t, db)
+			var proof *MerkleProof
+			var err error
+			if i%2 == 0 {
+				proof, err = db.GetWithProof([]byte(k), 0)
+			} else {
+				proof, err = db.GetProofOnly([]byte(k), 0)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			newValue := []byte("updated " + k)
+			stored := newValue
+			if opts != nil {
+				_, stored = db.storedEntry([]byte(k), newValue)
+			}
+			predicted, err := UpdateRoot(oldRoot, proof, stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := db.Put([]byte(k), newValue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(predicted, actual) || !bytes.Equal(predicted, rootOf(t, db)) {
+				t.Fatalf("hashKeys=%v %s: predicted root differs from the root after Put", opts != nil, k)
+			}
+		}
+
+		proof, err := db.GetWithProof([]byte("b"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := UpdateRoot(bytes.Repeat([]byte{7}, 32), proof, []byte("x")); !errors.Is(err, ErrProofMismatch) {
+			t.Fatalf("wrong old root: %v", err)
+		}
+		if _, err := UpdateRoot(rootOf(t, db), nil, []byte("x")); !errors.Is(err, ErrProofMismatch) {
+			t.Fatalf("nil proof: %v", err)
+		}
+	}
+}
+
+func TestVerifyTransition(t *testing.T) {
+	db, _ := proofDB(t, nil)
+	key := []byte("abc")
+	oldVal := []byte("value of abc")
+	newVal := []byte("changed")
+	oldRoot := rootOf(t, db)
+	oldProof, err := db.GetWithProof(key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRoot, err := db.Put(key, newVal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newProof, err := db.GetWithProof(key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTransition(oldRoot, key, oldVal, newVal, oldProof, newProof, newRoot); err != nil {
+		t.Fatalf("valid transition: %v", err)
+	}
+
+	forged := bytes.Clone(newRoot)
+	forged[0] ^= 1
+	if err := VerifyTransition(oldRoot, key, oldVal, newVal, oldProof, newProof, forged); !errors.Is(err, ErrProofMismatch) {
+		t.Fatalf("forged new root: %v", err)
+	}
+	if err := VerifyTransition(oldRoot, key, []byte("other"), newVal, oldProof, newProof, newRoot); !errors.Is(err, ErrProofMismatch) {
+		t.Fatalf("wrong old value: %v", err)
+	}
+
+	// 同一版本中另一个键也被修改时，路径上的兄弟哈希不同
+	if _, err := db.BatchPut(map[string][]byte{"abc": []byte("again"), "abcd": []byte("also changed")}); err != nil {
+		t.Fatal(err)
+	}
+	otherProof, err := db.GetWithProof(key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyTransition(newRoot, key, newVal, []byte("again"), newProof, otherProof, rootOf(t, db))
+	if !errors.Is(err, ErrInconsistentTransition) {
+		t.Fatalf("transition touching another key: %v", err)
+	}
+}
+
+func TestVerifyAcrossRoots(t *testing.T) {
+	shardA, keysA := proofDB(t, nil)
+	shardB := openTestDB(t, nil)
+	for i := 0; i < 20; i++ {
+		mustPut(t, shardB, fmt.Sprintf("b-%02d", i), fmt.Sprintf("vb%d", i))
+	}
+	rootA, rootB := rootOf(t, shardA), rootOf(t, shardB)
+
+	var entries []RootProofEntry
+	add := func(db *Database, root []byte, key string) {
+		proof, err := db.GetWithProof([]byte(key), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, RootProofEntry{Root: root, Key: proof.Key, Value: proof.Value, Proof: proof})
+	}
+	for _, k := range keysA[:8] {
+		add(shardA, rootA, k)
+	}
+	for i := 0; i < 8; i++ {
+		add(shardB, rootB, fmt.Sprintf("b-%02d", i))
+	}
+	// 篡改一个条目的值，另一个条目指向错误的分片根，再加一个空根
+	entries[3].Value = []byte("tampered")
+	entries[10].Root = rootA
+	entries = append(entries, RootProofEntry{Key: entries[0].Key, Value: entries[0].Value, Proof: entries[0].Proof})
+
+	results, err := VerifyAcrossRoots(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(entries) {
+		t.Fatalf("%d results for %d entries", len(results), len(entries))
+	}
+	for i, ok := range results {
+		want := i != 3 && i != 10 && i != len(entries)-1
+		if ok != want {
+			t.Fatalf("entry %d: %v, want %v", i, ok, want)
+		}
+	}
+	if results, err := VerifyAcrossRoots(nil); err != nil || len(results) != 0 {
+		t.Fatalf("no entries: %v, %v", results, err)
+	}
+}
+
+func TestMerkleProofEqual(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	proof, err := db.GetWithProof([]byte(keys[0]), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := db.GetWithProof([]byte(keys[0]), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proof.Equal(again) || !proof.Equal(proof.clone()) {
+		t.Fatal("identical proofs not equal")
+	}
+	other, err := db.GetWithProof([]byte(keys[1]), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Equal(other) {
+		t.Fatal("proofs of different keys equal")
+	}
+
+	// 只改一个兄弟哈希
+	changed := proof.clone()
+	step, index := -1, -1
+	for i := range changed.Steps {
+		for j, sibling := range changed.Steps[i].Siblings {
+			if sibling != nil && step < 0 {
+				step, index = i, j
+			}
+		}
+	}
+	if step < 0 {
+		t.Fatal("proof has no sibling hashes")
+	}
+	sibling := append([]byte(nil), changed.Steps[step].Siblings[index]...)
+	sibling[0] ^= 1
+	changed.Steps[step].Siblings[index] = sibling
+	if proof.Equal(changed) || changed.Equal(proof) {
+		t.Fatal("proofs differing in one sibling equal")
+	}
+
+	shorter := proof.clone()
+	shorter.Steps = shorter.Steps[:len(shorter.Steps)-1]
+	if proof.Equal(shorter) {
+		t.Fatal("proofs with different step counts equal")
+	}
+	root := proof.clone()
+	root.Root = bytes.Repeat([]byte{1}, len(proof.Root))
+	if proof.Equal(root) {
+		t.Fatal("proofs with different roots equal")
+	}
+
+	var none *MerkleProof
+	if !none.Equal(nil) || none.Equal(proof) || proof.Equal(nil) {
+		t.Fatal("nil proof comparison")
+	}
+}
+
+func TestScanWithProofs(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	if err := db.Delete([]byte("ab")); err != nil {
+		t.Fatal(err)
+	}
+	root := rootOf(t, db)
+	var seen []string
+	err := db.ScanWithProofs(nil, nil, 0, func(key, value []byte, proof *MerkleProof) error {
+		if !bytes.Equal(proof.Root, root) || !proof.Verify(root) {
+			return fmt.Errorf("proof of %q does not verify", key)
+		}
+		if !bytes.Equal(proof.Key, key) || !bytes.Equal(proof.Value, value) || string(value) != "value of "+string(key) {
+			return fmt.Errorf("proof of %q covers %q=%q", key, proof.Key, proof.Value)
+		}
+		seen = append(seen, string(key))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != len(keys)-1 || !sort.StringsAreSorted(seen) {
+		t.Fatalf("scanned %v", seen)
+	}
+
+	// 旧版本的证明针对该版本的根
+	old, err := db.RootHashAtVersion(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	err = db.ScanWithProofs([]byte("key-"), []byte("key-010"), 1, func(key, value []byte, proof *MerkleProof) error {
+		n++
+		if !proof.Verify(old) || proof.Version != 1 {
+			return fmt.Errorf("proof of %q at version 1 does not verify", key)
+		}
+		return nil
+	})
+	if err != nil || n != 10 {
+		t.Fatalf("range at version 1: %d keys, %v", n, err)
+	}
+
+	stop := errors.New("stop")
+	n = 0
+	err = db.ScanWithProofs(nil, nil, 0, func(key, value []byte, proof *MerkleProof) error {
+		if n++; n == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 3 {
+		t.Fatalf("callback error: %d calls, %v", n, err)
+	}
+
+	empty := openTestDB(t, nil)
+	if err := empty.ScanWithProofs(nil, nil, 0, func(key, value []byte, proof *MerkleProof) error {
+		return stop
+	}); err != nil {
+		t.Fatalf("empty database: %v", err)
+	}
+}
+
+func TestMerkleProofRejectsTruncation(t *testing.T) {
+	db, keys := proofDB(t, nil)
+	full, err := db.GetWithProof([]byte(keys[2]), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafOnly, err := db.GetProofOnly([]byte(keys[len(keys)-1]), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 去掉Version和GeneratedAt，分别编码为V1和V2
+	v1, v2 := full.clone(), leafOnly.clone()
+	v1.Version, v1.GeneratedAt = 0, time.Time{}
+	v2.Version, v2.GeneratedAt = 0, time.Time{}
+
+	// 分支节点之后以扩展节点结尾：截去最后一个字节只留下步骤头的第一个字节，剩余长度仍满足步骤数的下限
+	ext := &MerkleProof{Key: []byte("k"), Value: []byte("v"), Root: full.Root, Steps: []ProofStep{
+		{Branch: true, Nibble: 6, Siblings: [16][]byte{1: full.Root}},
+		{Nibble: 0xB},
+	}}
+
+	for _, p := range []*MerkleProof{full, leafOnly, v1, v2, ext} {
+		data, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded MerkleProof
+		if err := decoded.UnmarshalBinary(data); err != nil || !decoded.Equal(p) {
+			t.Fatalf("format %d: round trip: %v", data[0], err)
+		}
+		for n := 0; n < len(data); n++ {
+			if err := decoded.UnmarshalBinary(data[:n]); err != ErrBadProof {
+				t.Fatalf("format %d: truncated to %d of %d bytes: %v", data[0], n, len(data), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package amdb
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// proofCacheKey 证明缓存键：存储形式的键及具体的数据库版本
+type proofCacheKey struct {
+	key     string
+	version uint32
+}
+
+// proofCache 按LRU淘汰的Merkle证明缓存
+// 缓存键使用具体的数据库版本号，某个版本的证明不会再变化，写入只会产生新版本，
+// 因此条目只需按容量淘汰，无需在写入时失效。所有方法对nil接收者安全
+type proofCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[proofCacheKey]*list.Element
+	lru     *list.List // 元素为*proofCacheEntry，最近使用的在前
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// proofCacheEntry LRU链表中的条目
+type proofCacheEntry struct {
+	key   proofCacheKey
+	proof *MerkleProof
+}
+
+// newProofCache 创建容量为capacity的证明缓存
+func newProofCache(capacity int) *proofCache {
+	return &proofCache{
+		capacity: capacity,
+		entries:  make(map[proofCacheKey]*list.Element, capacity),
+		lru:      list.New(),
+	}
+}
+
+// get 查找缓存的证明，返回副本
+func (c *proofCache) get(key []byte, version uint32) (*MerkleProof, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[proofCacheKey{string(key), version}]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*proofCacheEntry).proof.clone(), true
+}
+
+// put 缓存证明的副本，超出容量时淘汰最久未使用的条目
+func (c *proofCache) put(key []byte, version uint32, proof *MerkleProof) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := proofCacheKey{string(key), version}
+	if elem, ok := c.entries[k]; ok {
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[k] = c.lru.PushFront(&proofCacheEntry{key: k, proof: proof.clone()})
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*proofCacheEntry).key)
+	}
+}
+
+// discardAfter 移除版本号大于version的条目，仅在Rewind丢弃这些版本后调用
+func (c *proofCache) discardAfter(version uint32) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, elem := range c.entries {
+		if k.version > version {
+			c.lru.Remove(elem)
+			delete(c.entries, k)
+		}
+	}
+}
+
+// clone 复制证明，调用方修改副本不会影响缓存（哈希和键值字节本身只读共享）
+func (p *MerkleProof) clone() *MerkleProof {
+	c := *p
+	c.Steps = append([]ProofStep(nil), p.Steps...)
+	return &c
+}
@@ -0,0 +1,75 @@
+package amdb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// sameProof 比较两个证明的内容（不含生成时间）
+func sameProof(a, b *MerkleProof) bool {
+	return bytes.Equal(a.Key, b.Key) && bytes.Equal(a.Value, b.Value) && bytes.Equal(a.Root, b.Root) &&
+		a.Version == b.Version && reflect.DeepEqual(a.Steps, b.Steps)
+}
+
+func TestProofCacheMatchesFresh(t *testing.T) {
+	db, keys := proofDB(t, &Options{ProofCacheEntries: 8})
+	fresh, _ := proofDB(t, nil)
+
+	for round := 0; round < 2; round++ {
+		for _, k := range keys[:4] {
+			cached, err := db.GetWithProof([]byte(k), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := fresh.GetWithProof([]byte(k), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameProof(cached, want) {
+				t.Fatalf("round %d %s: cached proof differs from fresh", round, k)
+			}
+			// 修改返回的证明不影响缓存
+			cached.Steps = nil
+		}
+	}
+	s := db.Stats()
+	if s.ProofCacheHits != 4 || s.ProofCacheMisses != 4 || s.ProofCacheHitRatio != 0.5 {
+		t.Fatalf("stats %+v", s)
+	}
+
+	// 写入产生新版本，最新版本的证明随之更新，旧版本的证明仍从缓存返回
+	old, _ := db.GetWithProof([]byte(keys[0]), 0)
+	mustPut(t, db, keys[0], "new")
+	proof, err := db.GetWithProof([]byte(keys[0]), 0)
+	if err != nil || string(proof.Value) != "new" || !proof.Verify(rootOf(t, db)) {
+		t.Fatalf("proof after write: %+v, %v", proof, err)
+	}
+	again, err := db.GetWithProof([]byte(keys[0]), old.Version)
+	if err != nil || !sameProof(again, old) {
+		t.Fatalf("historical proof: %+v, %v", again, err)
+	}
+}
+
+func TestProofCacheEviction(t *testing.T) {
+	c := newProofCache(2)
+	for i, k := range []string{"a", "b", "a", "c"} {
+		c.put([]byte(k), 1, &MerkleProof{Key: []byte(k), Version: uint32(i)})
+	}
+	if _, ok := c.get([]byte("b"), 1); ok {
+		t.Fatal("least recently used entry was not evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.get([]byte(k), 1); !ok {
+			t.Fatalf("%s evicted", k)
+		}
+	}
+	if _, ok := c.get([]byte("a"), 2); ok {
+		t.Fatal("hit for another version")
+	}
+	var disabled *proofCache
+	disabled.put([]byte("a"), 1, &MerkleProof{})
+	if _, ok := disabled.get([]byte("a"), 1); ok {
+		t.Fatal("nil cache returned a proof")
+	}
+}
@@ -0,0 +1,453 @@
+package amdb
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// KV 键值对
+type KV struct {
+	Key   []byte
+	Value []byte
+}
+
+// RangeNodeType 范围证明中节点的类型
+type RangeNodeType byte
+
+const (
+	// RangeNodeHash 完全位于证明范围之外的子树，只给出其哈希
+	RangeNodeHash RangeNodeType = iota
+	// RangeNodeLeaf 叶子节点，给出完整的键值（可能是删除标记或范围外的键）
+	RangeNodeLeaf
+	// RangeNodeExt 扩展节点，其后紧跟唯一的子节点
+	RangeNodeExt
+	// RangeNodeBranch 分支节点，其后按下标顺序紧跟Children中置位的各子节点
+	RangeNodeBranch
+)
+
+// RangeProofNode 范围证明中按先序排列的一个节点
+type RangeProofNode struct {
+	Type RangeNodeType
+	// Nibble 仅扩展节点：经过的nibble
+	Nibble byte
+	// Children 仅分支节点：非空子节点的下标位图
+	Children uint16
+	// Key、Value 仅叶子节点
+	Key   []byte
+	Value []byte
+	// Hash 仅RangeNodeHash：子树哈希
+	Hash []byte
+}
+
+// RangeProof 证明一组键值恰好是[Start, End)范围内全部存活键值的证明
+// 证明是一棵裁剪过的树：与范围相交的节点完整给出，完全位于范围外的子树只给出哈希，
+// 校验方由此重算根哈希，并确认范围内没有遗漏或多出的键
+type RangeProof struct {
+	// Root 证明所针对的根哈希
+	Root []byte
+	// Version 证明所针对的数据库版本
+	Version uint32
+	// Start 范围起点（含，nil表示不限制）
+	Start []byte
+	// End 范围终点（不含，nil表示直到最后一个键）；GetRangePageWithProof返回的证明中即下一页的起点
+	End []byte
+	// Nodes 裁剪后的树，按先序排列（空数据库为空）
+	Nodes []RangeProofNode
+}
+
+// rangeProofFormatV1 范围证明二进制编码格式版本
+const rangeProofFormatV1 = 1
+
+// GetRangePageWithProof 返回版本version（0表示当前版本）中从start（含，nil表示从头开始）起至多limit个存活键值，
+// 下一页的起点nextStart（没有更多键时为nil），以及证明本页即[start, nextStart)内全部存活键值的范围证明
+// 以nextStart为start继续调用即可逐页遍历；各页分别校验通过后，按顺序拼接即为完整范围的已验证结果。
+// 需要在内存中重建该版本的整棵树；哈希键模式下键、值与范围边界均为存储形式，与证明一致
+func (db *Database) GetRangePageWithProof(start []byte, limit int, version uint32) (kvs []KV, nextStart []byte, proof *RangeProof, err error) {
+	if limit <= 0 {
+		return nil, nil, nil, ErrInvalidArg
+	}
+	var live []kv
+	kvs, proof, err = db.rangeWithProof(start, version, func(state []kv) ([]kv, []byte) {
+		if live = liveRange(state, start, nil, bytes.Compare); len(live) > limit {
+			nextStart = live[limit].key
+			live = live[:limit]
+		}
+		return live, nextStart
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return kvs, nextStart, proof, nil
+}
+
+// GetRangeWithProof 返回版本version（0表示当前版本）中[start, end)范围内的全部存活键值及其范围证明，
+// start或end为nil表示不限制该方向；范围较大时应改用GetRangePageWithProof分页
+func (db *Database) GetRangeWithProof(start, end []byte, version uint32) ([]KV, *RangeProof, error) {
+	if start != nil && end != nil && bytes.Compare(start, end) >= 0 {
+		return nil, nil, ErrInvalidArg
+	}
+	return db.rangeWithProof(start, version, func(state []kv) ([]kv, []byte) {
+		return liveRange(state, start, end, bytes.Compare), end
+	})
+}
+
+// RangeProofSize 返回GetRangeWithProof(start, end, version)所得证明的编码长度，即len(MarshalBinary())
+// 结果是精确值：按与生成证明相同的规则遍历裁剪后的树并累加各节点的编码长度，但不构造证明也不编码。
+// 仍需在内存中重建该版本的整棵树
+func (db *Database) RangeProofSize(start, end []byte, version uint32) (int, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return 0, err
+	}
+	if start != nil && end != nil && bytes.Compare(start, end) >= 0 {
+		return 0, ErrInvalidArg
+	}
+	if version == 0 {
+		var err error
+		if version, err = db.CurrentVersion(); err != nil {
+			return 0, err
+		}
+	}
+	proof := RangeProof{Start: start, End: end}
+	if version == 0 {
+		return proof.binarySize(), nil
+	}
+	state, err := db.stateAt(version)
+	if err != nil {
+		return 0, err
+	}
+	root, err := db.trieOf(state, version)
+	if err != nil {
+		return 0, err
+	}
+	if root == nil {
+		return proof.binarySize(), nil
+	}
+	proof.Root = root.hash
+	return proof.binarySize() + rangeNodesSize(root, nil, start, end), nil
+}
+
+// rangeWithProof 生成版本version的范围证明，page由该版本的状态选出本页的存活键值及范围终点
+func (db *Database) rangeWithProof(start []byte, version uint32, page func(state []kv) ([]kv, []byte)) ([]KV, *RangeProof, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, nil, err
+	}
+	if version == 0 {
+		var err error
+		if version, err = db.CurrentVersion(); err != nil {
+			return nil, nil, err
+		}
+	}
+	proof := &RangeProof{Root: []byte{}, Version: version, Start: start}
+	if version == 0 {
+		_, proof.End = page(nil)
+		return nil, proof, nil
+	}
+
+	state, err := db.stateAt(version)
+	if err != nil {
+		return nil, nil, err
+	}
+	live, end := page(state)
+	root, err := db.trieOf(state, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kvs := make([]KV, len(live))
+	for i, item := range live {
+		kvs[i] = KV{Key: item.key, Value: item.value}
+	}
+	proof.End = end
+	if root != nil {
+		proof.Root = root.hash
+		proof.Nodes = appendRangeNodes(nil, root, nil, start, end)
+	}
+	return kvs, proof, nil
+}
+
+// appendRangeNodes 按先序追加位于nibble路径path处的节点n，完全位于[start, end)之外的子树只追加哈希
+func appendRangeNodes(nodes []RangeProofNode, n *trieNode, path []byte, start, end []byte) []RangeProofNode {
+	if outsideRange(path, start, end) {
+		return append(nodes, RangeProofNode{Type: RangeNodeHash, Hash: n.hash})
+	}
+	switch n.kind {
+	case leafNode:
+		return append(nodes, RangeProofNode{Type: RangeNodeLeaf, Key: n.key, Value: n.value})
+	case extNode:
+		nodes = append(nodes, RangeProofNode{Type: RangeNodeExt, Nibble: n.nibble})
+		return appendRangeNodes(nodes, n.child, append(path, n.nibble), start, end)
+	}
+	node := RangeProofNode{Type: RangeNodeBranch}
+	for i, child := range n.children {
+		if child != nil {
+			node.Children |= 1 << i
+		}
+	}
+	nodes = append(nodes, node)
+	for i, child := range n.children {
+		if child != nil {
+			nodes = appendRangeNodes(nodes, child, append(path[:len(path):len(path)], byte(i)), start, end)
+		}
+	}
+	return nodes
+}
+
+// rangeNodesSize 返回appendRangeNodes为节点n生成的全部节点的编码长度
+func rangeNodesSize(n *trieNode, path []byte, start, end []byte) int {
+	if outsideRange(path, start, end) {
+		return 1 + 4 + len(n.hash)
+	}
+	switch n.kind {
+	case leafNode:
+		return 1 + 4 + len(n.key) + 4 + len(n.value)
+	case extNode:
+		return 2 + rangeNodesSize(n.child, append(path, n.nibble), start, end)
+	}
+	size := 3
+	for i, child := range n.children {
+		if child != nil {
+			size += rangeNodesSize(child, append(path[:len(path):len(path)], byte(i)), start, end)
+		}
+	}
+	return size
+}
+
+// outsideRange 判断nibble路径以path开头的全部键是否都位于[start, end)之外
+// 键超出长度的部分视为0，因此按nibble比较的顺序与键的字典序一致；只有严格在边界之外的路径才能确定
+func outsideRange(path, start, end []byte) bool {
+	return (start != nil && comparePath(path, start) < 0) || (end != nil && comparePath(path, end) > 0)
+}
+
+// comparePath 比较nibble路径path与key的前len(path)个nibble
+func comparePath(path, key []byte) int {
+	for i, nibble := range path {
+		if k := keyNibble(key, i); nibble != k {
+			if nibble < k {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Verify 校验kvs是否恰好为根为root的树中[Start, End)范围内的全部存活键值（按键排序）
+// 校验方应同时核对Start是否为其请求的起点、Version是否为其认可的版本；
+// 逐页校验时下一页的Start须等于上一页的End，End为nil的一页是最后一页
+func (p *RangeProof) Verify(root []byte, kvs []KV) bool {
+	if p == nil || !bytes.Equal(p.Root, root) {
+		return false
+	}
+	if p.End != nil && p.Start != nil && bytes.Compare(p.Start, p.End) >= 0 {
+		return false
+	}
+	if len(p.Nodes) == 0 {
+		return len(root) == 0 && len(kvs) == 0
+	}
+	v := rangeVerifier{proof: p, kvs: kvs}
+	h, ok := v.node(nil)
+	return ok && v.pos == len(p.Nodes) && v.matched == len(kvs) && bytes.Equal(h, root)
+}
+
+// rangeVerifier 按先序消费证明节点并重算哈希
+type rangeVerifier struct {
+	proof   *RangeProof
+	kvs     []KV
+	pos     int // 下一个待消费的节点
+	matched int // 已与范围内叶子对应的kvs条目数
+}
+
+// node 重算位于nibble路径path处的子树哈希
+func (v *rangeVerifier) node(path []byte) ([]byte, bool) {
+	if v.pos >= len(v.proof.Nodes) || len(path) > 2*maxRangeKeyLen {
+		return nil, false
+	}
+	n := v.proof.Nodes[v.pos]
+	v.pos++
+	switch n.Type {
+	case RangeNodeHash:
+		if len(n.Hash) == 0 || !outsideRange(path, v.proof.Start, v.proof.End) {
+			return nil, false
+		}
+		return n.Hash, true
+	case RangeNodeLeaf:
+		if comparePath(path, n.Key) != 0 || !v.leaf(n.Key, n.Value) {
+			return nil, false
+		}
+		h, err := HashSHA256.sum(leafContent(n.Key, n.Value))
+		return h, err == nil
+	case RangeNodeExt:
+		if n.Nibble > 0x0F {
+			return nil, false
+		}
+		child, ok := v.node(append(path, n.Nibble))
+		if !ok {
+			return nil, false
+		}
+		h, err := HashSHA256.sum(extContent(n.Nibble, child))
+		return h, err == nil
+	case RangeNodeBranch:
+		var children [16][]byte
+		for i := range children {
+			if n.Children&(1<<i) == 0 {
+				continue
+			}
+			child, ok := v.node(append(path[:len(path):len(path)], byte(i)))
+			if !ok {
+				return nil, false
+			}
+			children[i] = child
+		}
+		h, err := HashSHA256.sum(branchContent(&children))
+		return h, err == nil
+	}
+	return nil, false
+}
+
+// leaf 核对按先序出现的叶子：范围内的存活键必须与kvs的下一条完全相同
+func (v *rangeVerifier) leaf(key, value []byte) bool {
+	if isDeleted(value) || !inRange(key, v.proof.Start, v.proof.End, bytes.Compare) {
+		return true
+	}
+	if v.matched >= len(v.kvs) {
+		return false
+	}
+	want := v.kvs[v.matched]
+	v.matched++
+	return bytes.Equal(want.Key, key) && bytes.Equal(want.Value, value)
+}
+
+// maxRangeKeyLen 校验时允许的最大树路径对应的键长度，防止恶意证明构造过深的路径
+const maxRangeKeyLen = 1 << 16
+
+// 二进制编码格式（整数均为大端）：
+//
+//	[1字节格式版本][4字节版本]
+//	[4字节长度][根][4字节长度][Start][4字节长度][End]（Start、End长度为0表示nil）
+//	[4字节节点数] 每个节点：[1字节类型]
+//	  哈希节点：[4字节长度][哈希]；叶子节点：[4字节长度][键][4字节长度][值]
+//	  扩展节点：[1字节nibble]；分支节点：[2字节子节点位图]
+
+// binarySize 返回MarshalBinary的编码长度
+func (p *RangeProof) binarySize() int {
+	return 1 + 4 + 4 + len(p.Root) + 4 + len(p.Start) + 4 + len(p.End) + rangeNodesBinarySize(p.Nodes)
+}
+
+// rangeNodesBinarySize 返回appendRangeNodesBinary的编码长度
+func rangeNodesBinarySize(nodes []RangeProofNode) int {
+	n := 4
+	for _, node := range nodes {
+		switch node.Type {
+		case RangeNodeHash:
+			n += 1 + 4 + len(node.Hash)
+		case RangeNodeLeaf:
+			n += 1 + 4 + len(node.Key) + 4 + len(node.Value)
+		case RangeNodeExt:
+			n += 2
+		case RangeNodeBranch:
+			n += 3
+		}
+	}
+	return n
+}
+
+// MarshalBinary 实现encoding.BinaryMarshaler
+func (p *RangeProof) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, p.binarySize())
+	buf = append(buf, rangeProofFormatV1)
+	buf = wireOrder.AppendUint32(buf, p.Version)
+	buf = appendBytes32(buf, p.Root)
+	buf = appendBytes32(buf, p.Start)
+	buf = appendBytes32(buf, p.End)
+	return appendRangeNodesBinary(buf, p.Nodes)
+}
+
+// appendRangeNodesBinary 追加节点数及按先序排列的各节点的编码
+func appendRangeNodesBinary(buf []byte, nodes []RangeProofNode) ([]byte, error) {
+	buf = wireOrder.AppendUint32(buf, uint32(len(nodes)))
+	for _, n := range nodes {
+		buf = append(buf, byte(n.Type))
+		switch n.Type {
+		case RangeNodeHash:
+			buf = appendBytes32(buf, n.Hash)
+		case RangeNodeLeaf:
+			buf = appendBytes32(buf, n.Key)
+			buf = appendBytes32(buf, n.Value)
+		case RangeNodeExt:
+			buf = append(buf, n.Nibble)
+		case RangeNodeBranch:
+			buf = wireOrder.AppendUint16(buf, n.Children)
+		default:
+			return nil, ErrBadProof
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler
+func (p *RangeProof) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	format, err := r.ReadByte()
+	if err != nil || format != rangeProofFormatV1 {
+		return ErrBadProof
+	}
+	var proof RangeProof
+	if binary.Read(r, wireOrder, &proof.Version) != nil {
+		return ErrBadProof
+	}
+	if proof.Root, err = readLengthPrefixed(r); err != nil {
+		return ErrBadProof
+	}
+	if proof.Start, err = readLengthPrefixed(r); err != nil {
+		return ErrBadProof
+	}
+	if proof.End, err = readLengthPrefixed(r); err != nil {
+		return ErrBadProof
+	}
+	if len(proof.Start) == 0 {
+		proof.Start = nil
+	}
+	if len(proof.End) == 0 {
+		proof.End = nil
+	}
+	if proof.Nodes, err = readRangeNodesBinary(r); err != nil || r.Len() != 0 {
+		return ErrBadProof
+	}
+	*p = proof
+	return nil
+}
+
+// readRangeNodesBinary 读取appendRangeNodesBinary的编码，格式错误时返回ErrBadProof
+func readRangeNodesBinary(r *bytes.Reader) ([]RangeProofNode, error) {
+	var count uint32
+	if binary.Read(r, wireOrder, &count) != nil || int64(count) > int64(r.Len()) {
+		return nil, ErrBadProof
+	}
+	nodes := make([]RangeProofNode, count)
+	for i := range nodes {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrBadProof
+		}
+		n := RangeProofNode{Type: RangeNodeType(kind)}
+		switch n.Type {
+		case RangeNodeHash:
+			n.Hash, err = readLengthPrefixed(r)
+		case RangeNodeLeaf:
+			if n.Key, err = readLengthPrefixed(r); err == nil {
+				n.Value, err = readLengthPrefixed(r)
+			}
+		case RangeNodeExt:
+			n.Nibble, err = r.ReadByte()
+		case RangeNodeBranch:
+			err = binary.Read(r, wireOrder, &n.Children)
+		default:
+			return nil, ErrBadProof
+		}
+		if err != nil {
+			return nil, ErrBadProof
+		}
+		nodes[i] = n
+	}
+	return nodes, nil
+}
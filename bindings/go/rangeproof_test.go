@@ -0,0 +1,156 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRangePagesChainToFullRange(t *testing.T) {
+	db := openTestDB(t, nil)
+	items := make(map[string][]byte)
+	for i := 0; i < 25; i++ {
+		items[fmt.Sprintf("k%02d", i)] = []byte(fmt.Sprintf("v%d", i))
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete([]byte("k07")); err != nil {
+		t.Fatal(err)
+	}
+	root := rootOf(t, db)
+
+	full, next, fullProof, err := db.GetRangePageWithProof(nil, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != nil || len(full) != 24 || !fullProof.Verify(root, full) {
+		t.Fatalf("full range: %d entries, next %q", len(full), next)
+	}
+
+	var paged []KV
+	var start []byte
+	for pages := 0; ; pages++ {
+		kvs, next, proof, err := db.GetRangePageWithProof(start, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(proof.Start, start) || !bytes.Equal(proof.End, next) {
+			t.Fatalf("page %d covers [%q, %q), want [%q, %q)", pages, proof.Start, proof.End, start, next)
+		}
+		if !proof.Verify(root, kvs) {
+			t.Fatalf("page %d does not verify", pages)
+		}
+		paged = append(paged, kvs...)
+		if next == nil {
+			if pages != 2 {
+				t.Fatalf("got %d pages, want 3", pages+1)
+			}
+			break
+		}
+		start = next
+	}
+	if !reflect.DeepEqual(paged, full) {
+		t.Fatalf("pages %v, full range %v", paged, full)
+	}
+}
+
+func TestRangePageProofRejectsTampering(t *testing.T) {
+	db := openTestDB(t, nil)
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		mustPut(t, db, k, "v"+k)
+	}
+	root := rootOf(t, db)
+	kvs, next, proof, err := db.GetRangePageWithProof([]byte("b"), 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(next) != "d" || len(kvs) != 2 || !proof.Verify(root, kvs) {
+		t.Fatalf("page %v, next %q", kvs, next)
+	}
+
+	if proof.Verify(root, kvs[:1]) {
+		t.Fatal("page with an omitted entry verified")
+	}
+	changed := []KV{kvs[0], {Key: kvs[1].Key, Value: []byte("x")}}
+	if proof.Verify(root, changed) {
+		t.Fatal("page with a changed value verified")
+	}
+	extended := append(append([]KV{}, kvs...), KV{Key: []byte("d"), Value: []byte("vd")})
+	if proof.Verify(root, extended) {
+		t.Fatal("page with an entry past its end verified")
+	}
+	if proof.Verify(bytes.Repeat([]byte{1}, len(root)), kvs) {
+		t.Fatal("page verified against a different root")
+	}
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded RangeProof
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Verify(root, kvs) || decoded.Version != proof.Version || !bytes.Equal(decoded.End, next) {
+		t.Fatal("decoded proof differs")
+	}
+
+	if _, _, _, err := db.GetRangePageWithProof(nil, 0, 0); err != ErrInvalidArg {
+		t.Fatalf("limit 0: %v", err)
+	}
+}
+
+func TestRangeProofSizeExact(t *testing.T) {
+	empty := openTestDB(t, nil)
+	db := openTestDB(t, nil)
+	items := make(map[string][]byte)
+	for i := 0; i < 60; i++ {
+		items[fmt.Sprintf("key-%03d", i)] = bytes.Repeat([]byte{byte(i)}, i)
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "key-100", "late")
+
+	for _, r := range []struct{ start, end string }{
+		{"", ""}, {"key-010", "key-020"}, {"key-000", ""}, {"", "key-001"},
+		{"key-05", "key-06"}, {"a", "b"}, {"zz", ""},
+	} {
+		var start, end []byte
+		if r.start != "" {
+			start = []byte(r.start)
+		}
+		if r.end != "" {
+			end = []byte(r.end)
+		}
+		for _, c := range []struct {
+			db       *Database
+			versions []uint32
+		}{{db, []uint32{0, 1, 2}}, {empty, []uint32{0}}} {
+			d := c.db
+			for _, version := range c.versions {
+				_, proof, err := d.GetRangeWithProof(start, end, version)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := proof.MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+				size, err := d.RangeProofSize(start, end, version)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if size != len(data) {
+					t.Fatalf("[%q, %q) at version %d: size %d, encoded %d", r.start, r.end, version, size, len(data))
+				}
+			}
+		}
+	}
+
+	if _, err := db.RangeProofSize([]byte("b"), []byte("a"), 0); err != ErrInvalidArg {
+		t.Fatalf("inverted range: %v", err)
+	}
+}
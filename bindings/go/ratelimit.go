@@ -0,0 +1,116 @@
+package amdb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WriteRateLimit 写入限速，两个维度各自为一个令牌桶，同时配置时两者都须满足
+type WriteRateLimit struct {
+	// OpsPerSecond 每秒写入的条目数（0表示不限制），Put计1条，批量写入按条目数计
+	OpsPerSecond float64
+	// BytesPerSecond 每秒写入的键值字节数（0表示不限制）
+	BytesPerSecond float64
+	// Burst 允许的突发量占一秒配额的倍数（0表示1，即桶容量为一秒的配额）
+	Burst float64
+}
+
+// tokenBucket 令牌桶，令牌可透支：超出余量的请求立即扣除，等待时间为偿还透支所需的时间
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	if burst <= 0 {
+		burst = 1
+	}
+	capacity := rate * burst
+	return &tokenBucket{rate: rate, capacity: capacity, tokens: capacity, last: time.Now()}
+}
+
+// reserve 扣除n个令牌，返回调用方在执行前需要等待的时间
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// refund 归还reserve扣除但未使用的令牌
+func (b *tokenBucket) refund(n float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += n
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+// writeLimiter Options.WriteRateLimit的实现，nil表示不限速
+type writeLimiter struct {
+	ops   *tokenBucket
+	bytes *tokenBucket
+}
+
+func newWriteLimiter(l *WriteRateLimit) *writeLimiter {
+	if l == nil || (l.OpsPerSecond <= 0 && l.BytesPerSecond <= 0) {
+		return nil
+	}
+	w := &writeLimiter{}
+	if l.OpsPerSecond > 0 {
+		w.ops = newTokenBucket(l.OpsPerSecond, l.Burst)
+	}
+	if l.BytesPerSecond > 0 {
+		w.bytes = newTokenBucket(l.BytesPerSecond, l.Burst)
+	}
+	return w
+}
+
+// throttle 在写入ops个条目、共size字节之前按限速等待
+// ctx取消时立即返回ctx.Err()并归还本次扣除的令牌；对nil接收者直接返回
+func (w *writeLimiter) throttle(ctx context.Context, ops, size int) error {
+	if w == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	wait := w.ops.reserve(float64(ops))
+	if d := w.bytes.reserve(float64(size)); d > wait {
+		wait = d
+	}
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		w.ops.refund(float64(ops))
+		w.bytes.refund(float64(size))
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,68 @@
+package amdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(100, 0.5)
+	if d := b.reserve(50); d != 0 {
+		t.Fatalf("within burst: wait %v", d)
+	}
+	// 透支50个令牌，按每秒100个偿还约需0.5秒
+	d := b.reserve(50)
+	if d < 400*time.Millisecond || d > 500*time.Millisecond {
+		t.Fatalf("overdraft wait %v, want about 500ms", d)
+	}
+	b.refund(50)
+	if d := b.reserve(0); d != 0 {
+		t.Fatalf("after refund: wait %v", d)
+	}
+	if (*tokenBucket)(nil).reserve(1e9) != 0 || newWriteLimiter(&WriteRateLimit{}) != nil {
+		t.Fatal("unconfigured limiter must not wait")
+	}
+}
+
+func TestWriteRateLimitThrottles(t *testing.T) {
+	const rate, puts = 50, 30
+	db := openTestDB(t, &Options{WriteRateLimit: &WriteRateLimit{OpsPerSecond: rate, Burst: 0.1}})
+	start := time.Now()
+	for i := 0; i < puts; i++ {
+		mustPut(t, db, fmt.Sprintf("k%d", i), "v")
+	}
+	// 突发5条之后每条间隔1/rate秒
+	elapsed := time.Since(start)
+	if min := time.Duration(puts-5) * time.Second / rate * 9 / 10; elapsed < min {
+		t.Fatalf("%d puts at %d/s took %v, want at least %v", puts, rate, elapsed, min)
+	}
+	if elapsed > 5*time.Second {
+		t.Fatalf("%d puts at %d/s took %v", puts, rate, elapsed)
+	}
+}
+
+func TestWriteRateLimitContext(t *testing.T) {
+	db := openTestDB(t, &Options{WriteRateLimit: &WriteRateLimit{BytesPerSecond: 100}})
+	// 第一次写入恰好用完桶中的配额，不需要等待
+	if _, err := db.PutContext(context.Background(), []byte("k0"), make([]byte, 98)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// 这次写入需要等待约10秒
+	_, err := db.BatchPutContext(ctx, map[string][]byte{"k": make([]byte, 999)})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("throttled write: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("cancelled write returned after %v", elapsed)
+	}
+	if _, err := db.Get([]byte("k"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("cancelled write was committed: %v", err)
+	}
+}
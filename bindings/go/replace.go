@@ -0,0 +1,52 @@
+package amdb
+
+import (
+	"bytes"
+	"context"
+)
+
+// ReplaceAll 以src最新版本的全部存活键值替换本数据库的内容，作为一次批量写入提交，返回新的根哈希
+// 替换只产生一个新版本：src中的键写入src的值，本库存在而src中没有的键写入删除标记；
+// 引擎将大批量写入拆成多批提交，各批共用同一提交时间，仍构成同一个版本。
+// 因此任一具体版本要么完全是替换前的内容，要么完全是替换后的内容；需要跨多个键的一致视图时，
+// 读取方应固定版本读取（SnapshotAt或CurrentVersion），已创建的快照不受影响，释放前始终读取旧内容。
+// 本库的历史版本保留，删除标记留在Merkle树中，所以新根哈希通常与src的根哈希不同。
+// src必须使用与本库相同的HashKeys和KeySalt设置，否则返回ErrInvalidArg；src为本库自身时不做任何修改
+func (db *Database) ReplaceAll(src *Database) (root []byte, err error) {
+	if src == nil || src.hashKeys != db.hashKeys || !bytes.Equal(src.keySalt, db.keySalt) {
+		return nil, ErrInvalidArg
+	}
+	if src == db {
+		return db.rootHash()
+	}
+	if _, span := db.startSpan(context.Background(), "amdb.ReplaceAll"); span != nil {
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	state, err := src.stateAt(0)
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[string][]byte, len(state))
+	for _, item := range state {
+		if !isDeleted(item.value) {
+			items[string(item.key)] = item.value
+		}
+	}
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	live, err := db.liveKeysAt(0)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range live {
+		if _, ok := items[string(key)]; !ok {
+			items[string(key)] = deletedValue
+		}
+	}
+	if len(items) == 0 {
+		return db.rootHash()
+	}
+	return db.batchPut(items)
+}
@@ -0,0 +1,157 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// replaceKeys 超过引擎单批上限（3000）的两倍，覆盖引擎的并行分批路径
+const replaceKeys = 7000
+
+func fillGeneration(t *testing.T, db *Database, gen string) {
+	t.Helper()
+	items := make(map[string][]byte, replaceKeys)
+	for i := 0; i < replaceKeys; i++ {
+		items[fmt.Sprintf("k%05d", i)] = []byte(gen)
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplaceAllSingleVersion(t *testing.T) {
+	db := openTestDB(t, nil)
+	fillGeneration(t, db, "old")
+	mustPut(t, db, "only-old", "x")
+	before, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := openTestDB(t, nil)
+	fillGeneration(t, src, "new")
+	root, err := db.ReplaceAll(src)
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	after, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after != before+1 {
+		t.Fatalf("replace produced %d versions, want 1", after-before)
+	}
+	if want := stateRoot(t, db); string(root) != string(want) {
+		t.Fatalf("root %x, state root %x", root, want)
+	}
+	if _, err := db.Get([]byte("only-old"), 0); err != ErrNotFound {
+		t.Fatalf("key missing from src: got %v, want ErrNotFound", err)
+	}
+	if got := mustGet(t, db, "only-old", before); got != "x" {
+		t.Fatalf("old version: %q", got)
+	}
+	for _, key := range []string{"k00000", "k03500", "k06999"} {
+		if got := mustGet(t, db, key, 0); got != "new" {
+			t.Fatalf("%s: %q", key, got)
+		}
+		if got := mustGet(t, db, key, before); got != "old" {
+			t.Fatalf("%s@%d: %q", key, before, got)
+		}
+	}
+}
+
+func TestReplaceAllReadersSeeWholeState(t *testing.T) {
+	db := openTestDB(t, nil)
+	fillGeneration(t, db, "old")
+	src := openTestDB(t, nil)
+	fillGeneration(t, src, "new")
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failure string
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				v, err := db.CurrentVersion()
+				if err != nil {
+					continue
+				}
+				state, err := db.stateAt(v)
+				if err != nil {
+					continue
+				}
+				gens := map[string]int{}
+				for _, item := range state {
+					gens[string(item.value)]++
+				}
+				if len(gens) != 1 || len(state) != replaceKeys {
+					mu.Lock()
+					failure = fmt.Sprintf("version %d: %d keys, generations %v", v, len(state), gens)
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	if _, err := db.ReplaceAll(src); err != nil {
+		t.Fatal(err)
+	}
+	close(done)
+	wg.Wait()
+	if failure != "" {
+		t.Fatal(failure)
+	}
+	if got := mustGet(t, db, "k01234", 0); !strings.EqualFold(got, "new") {
+		t.Fatalf("after replace: %q", got)
+	}
+}
+
+func TestReplaceAllKeepsSnapshotsAndChecksOptions(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "old")
+	mustPut(t, db, "b", "old")
+	src := openTestDB(t, nil)
+	mustPut(t, src, "a", "new")
+	mustPut(t, src, "c", "new")
+
+	snap, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Release()
+	if _, err := db.ReplaceAll(src); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := snap.Get([]byte("b")); err != nil || string(v) != "old" {
+		t.Fatalf("snapshot of old state: b = %q, %v", v, err)
+	}
+	if mustGet(t, db, "a", 0) != "new" || mustGet(t, db, "c", 0) != "new" {
+		t.Fatal("new content missing")
+	}
+	if _, err := db.Get([]byte("b"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("key absent from src survived: %v", err)
+	}
+
+	before := rootOf(t, db)
+	if root, err := db.ReplaceAll(db); err != nil || !bytes.Equal(root, before) {
+		t.Fatalf("ReplaceAll(self) = %x, %v", root, err)
+	}
+	if _, err := db.ReplaceAll(openTestDB(t, &Options{HashKeys: true})); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("mismatched HashKeys: %v", err)
+	}
+	if _, err := db.ReplaceAll(nil); !errors.Is(err, ErrInvalidArg) {
+		t.Fatalf("nil src: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package amdb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// replicationFormatV1 复制流格式版本
+const replicationFormatV1 = 1
+
+var (
+	// ErrBadReplicationStream 复制流格式错误
+	ErrBadReplicationStream = errors.New("malformed replication stream")
+	// ErrReplicationRootMismatch 应用增量后的根哈希与主库在该版本的根哈希不一致
+	ErrReplicationRootMismatch = errors.New("replication root mismatch")
+)
+
+// 复制流格式（整数均为大端）：
+//
+//	[1字节格式版本]
+//	之后为连续的版本增量直到流结束，每个增量为
+//	[4字节版本][4字节长度][该版本的根哈希][4字节记录数] 每条记录：[4字节长度][键][4字节长度][值]
+//
+// 键和值为存储形式，删除以删除标记值表示；根哈希包含删除标记，与Merkle树一致。
+// 主库和副本须使用相同的HashKeys设置
+
+// ReplicationStream 返回从fromVersion之后（不含）到调用时最新版本的逐版本增量流，供副本ApplyReplicationStream应用
+// fromVersion通常为副本的CurrentVersion，超过主库当前版本时返回ErrVersionNotFound。
+// 增量在读取时按需生成，每个版本需要读取该版本的完整状态，开销与版本数×键数量成正比；
+// 调用之后提交的版本不包含在流中
+func (db *Database) ReplicationStream(fromVersion uint32) (io.Reader, error) {
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	last := tl.current()
+	if fromVersion > last {
+		return nil, ErrVersionNotFound
+	}
+
+	changed := make(map[uint32][]string)
+	for key, history := range tl.keys {
+		for _, v := range history {
+			if v.dbVersion > fromVersion {
+				changed[v.dbVersion] = append(changed[v.dbVersion], key)
+			}
+		}
+	}
+	r := &replicationReader{db: db, next: fromVersion + 1, last: last, changed: changed}
+	r.buf.WriteByte(replicationFormatV1)
+	return r, nil
+}
+
+// replicationReader 按需编码版本增量的复制流
+type replicationReader struct {
+	db      *Database
+	next    uint32 // 下一个待编码的版本
+	last    uint32
+	changed map[uint32][]string // 每个版本写入的键（存储形式）
+	buf     bytes.Buffer
+	err     error
+}
+
+// Read 实现io.Reader
+func (r *replicationReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.next > r.last {
+			return 0, io.EOF
+		}
+		r.err = r.encodeDelta(r.next)
+		r.next++
+	}
+	return r.buf.Read(p)
+}
+
+// encodeDelta 将版本version的增量追加到缓冲区
+func (r *replicationReader) encodeDelta(version uint32) error {
+	state, err := r.db.stateAt(version)
+	if err != nil {
+		return err
+	}
+	root, err := subtreeRoot(state)
+	if err != nil {
+		return err
+	}
+	values := make(map[string][]byte, len(state))
+	for _, item := range state {
+		values[string(item.key)] = item.value
+	}
+	keys := r.changed[version]
+	sort.Strings(keys)
+
+	buf := wireOrder.AppendUint32(nil, version)
+	buf = appendBytes32(buf, root)
+	buf = wireOrder.AppendUint32(buf, uint32(len(keys)))
+	for _, key := range keys {
+		value, ok := values[key]
+		if !ok {
+			return fmt.Errorf("replication: key %q missing from version %d", key, version)
+		}
+		buf = appendBytes32(buf, stringBytes(key))
+		buf = appendBytes32(buf, value)
+	}
+	r.buf.Write(buf)
+	return nil
+}
+
+// ApplyReplicationStream 按顺序应用ReplicationStream产生的增量，每个增量作为一次批量写入产生一个新版本
+// 每个增量的版本必须恰好为副本当前版本+1，否则返回ErrUnexpectedVersion；
+// 写入前先计算应用后的根哈希，与主库在该版本的根哈希不一致时返回ErrReplicationRootMismatch且不写入。
+// 出错时已应用的增量保留，newVersion和root为最后一个成功应用的版本及其根哈希。
+// 应用期间持有写锁，其他经由本句柄的写入等待应用结束
+func (db *Database) ApplyReplicationStream(r io.Reader) (newVersion uint32, root []byte, err error) {
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	if newVersion, err = db.CurrentVersion(); err != nil {
+		return 0, nil, err
+	}
+	state, err := db.stateAt(newVersion)
+	if err != nil {
+		return 0, nil, err
+	}
+	current := make(map[string][]byte, len(state))
+	for _, item := range state {
+		current[string(item.key)] = item.value
+	}
+	if newVersion > 0 {
+		if root, err = subtreeRoot(state); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	br := bufio.NewReader(r)
+	format, err := br.ReadByte()
+	if err != nil || format != replicationFormatV1 {
+		return newVersion, root, ErrBadReplicationStream
+	}
+	for {
+		version, deltaRoot, items, err := readReplicationDelta(br)
+		if err == io.EOF {
+			return newVersion, root, nil
+		}
+		if err != nil {
+			return newVersion, root, err
+		}
+		if version != newVersion+1 {
+			return newVersion, root, fmt.Errorf("%w: expected %d, got %d", ErrUnexpectedVersion, newVersion+1, version)
+		}
+
+		next := make(map[string][]byte, len(current)+len(items))
+		for k, v := range current {
+			next[k] = v
+		}
+		keys := make([][]byte, len(items))
+		values := make([][]byte, len(items))
+		for i, item := range items {
+			next[string(item.key)] = item.value
+			keys[i], values[i] = item.key, item.value
+		}
+		merged := make([]kv, 0, len(next))
+		for k, v := range next {
+			merged = append(merged, kv{key: stringBytes(k), value: v})
+		}
+		got, err := subtreeRoot(merged)
+		if err != nil {
+			return newVersion, root, err
+		}
+		if !bytes.Equal(got, deltaRoot) {
+			return newVersion, root, fmt.Errorf("%w at version %d", ErrReplicationRootMismatch, version)
+		}
+
+		if _, err := db.batchPutSlices(keys, values); err != nil {
+			return newVersion, root, err
+		}
+		current, newVersion, root = next, version, got
+	}
+}
+
+// 复制流中各字段的上限，超出即视为流损坏：根哈希为SHA-256，键长度与C层MAX_KEY_SIZE一致，
+// 值长度和每个增量的记录数不超过C层可以传递的长度或数量
+const (
+	maxReplicationKeyLen   = 0xFFFF
+	maxReplicationValueLen = maxCLength
+	maxReplicationRecords  = maxCLength
+)
+
+// readReplicationDelta 读取一个版本增量，流在增量边界处结束时返回io.EOF
+// 记录不按记录数预先分配，大的长度字段分块读取，内存只随流中实际存在的数据增长
+func readReplicationDelta(r *bufio.Reader) (version uint32, root []byte, items []kv, err error) {
+	if _, err := r.Peek(1); err == io.EOF {
+		return 0, nil, nil, io.EOF
+	}
+	if binary.Read(r, wireOrder, &version) != nil {
+		return 0, nil, nil, ErrBadReplicationStream
+	}
+	if root, err = readLengthPrefixedMax(r, sha256.Size); err != nil || len(root) != sha256.Size {
+		return 0, nil, nil, ErrBadReplicationStream
+	}
+	var count uint32
+	if binary.Read(r, wireOrder, &count) != nil || count == 0 || count > maxReplicationRecords {
+		return 0, nil, nil, ErrBadReplicationStream
+	}
+	for i := uint32(0); i < count; i++ {
+		key, err := readLengthPrefixedMax(r, maxReplicationKeyLen)
+		if err != nil || len(key) == 0 {
+			return 0, nil, nil, ErrBadReplicationStream
+		}
+		value, err := readLengthPrefixedMax(r, maxReplicationValueLen)
+		if err != nil {
+			return 0, nil, nil, ErrBadReplicationStream
+		}
+		items = append(items, kv{key: key, value: value})
+	}
+	return version, root, items, nil
+}
@@ -0,0 +1,110 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// replicationPrimary 写入若干版本（包括批量写入和删除）的主库
+func replicationPrimary(t *testing.T) *Database {
+	t.Helper()
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "1")
+	if _, err := db.BatchPut(map[string][]byte{"a": []byte("2"), "c": []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestReplicationSync(t *testing.T) {
+	primary := replicationPrimary(t)
+	replica := openTestDB(t, nil)
+
+	stream, err := primary.ReplicationStream(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, root, err := replica.ApplyReplicationStream(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 4 || !bytes.Equal(root, rootOf(t, replica)) {
+		t.Fatalf("applied to version %d root %x", version, root)
+	}
+	for v := uint32(1); v <= 4; v++ {
+		want, err := primary.RootHashAtVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := replica.RootHashAtVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("version %d: replica root %x, primary %x", v, got, want)
+		}
+	}
+	if _, err := replica.Get([]byte("b"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted key on replica: %v", err)
+	}
+
+	// 增量同步：只传输副本之后的版本
+	mustPut(t, primary, "d", "1")
+	stream, err = primary.ReplicationStream(version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _, err = replica.ApplyReplicationStream(stream); err != nil || version != 5 {
+		t.Fatalf("incremental sync: version %d, %v", version, err)
+	}
+	if !bytes.Equal(rootOf(t, replica), rootOf(t, primary)) {
+		t.Fatal("roots differ after incremental sync")
+	}
+	if _, err := primary.ReplicationStream(6); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("fromVersion past current: %v", err)
+	}
+}
+
+func TestReplicationRejectsBadStreams(t *testing.T) {
+	primary := replicationPrimary(t)
+	stream, err := primary.ReplicationStream(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一个增量：[1字节格式][4字节版本][4字节长度][32字节根]...
+	tampered := bytes.Clone(data)
+	tampered[1+4+4] ^= 0xff
+	replica := openTestDB(t, nil)
+	version, _, err := replica.ApplyReplicationStream(bytes.NewReader(tampered))
+	if !errors.Is(err, ErrReplicationRootMismatch) || version != 0 {
+		t.Fatalf("tampered root: version %d, %v", version, err)
+	}
+	if v, _ := replica.CurrentVersion(); v != 0 {
+		t.Fatalf("rejected delta was written: version %d", v)
+	}
+
+	// 长度和记录数字段超出上限时直接拒绝，不按字段分配内存
+	header := []byte{replicationFormatV1, 0, 0, 0, 1}
+	root := append([]byte{0, 0, 0, 32}, make([]byte, 32)...)
+	for name, stream := range map[string][]byte{
+		"root length":  append(bytes.Clone(header), 0xff, 0xff, 0xff, 0xff),
+		"record count": append(append(bytes.Clone(header), root...), 0xff, 0xff, 0xff, 0xff),
+		"key length":   append(append(bytes.Clone(header), root...), 0, 0, 0, 1, 0, 1, 0, 0),
+		"value length": append(append(bytes.Clone(header), root...), 0, 0, 0, 1, 0, 0, 0, 1, 'k', 0xff, 0xff, 0xff, 0xff),
+	} {
+		if _, _, err := replica.ApplyReplicationStream(bytes.NewReader(stream)); !errors.Is(err, ErrBadReplicationStream) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
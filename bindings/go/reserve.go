@@ -0,0 +1,39 @@
+package amdb
+
+import "math"
+
+// Reserve 提示即将批量写入约expectedKeys个新键，sampleKeys为其中有代表性的键，用于预先分配结构、避免导入期间反复重建
+// 这只是性能提示，不改变写入结果与根哈希。引擎的LSM与Merkle树没有预分配接口，目前预先准备的是绑定层的结构：
+// 启用布隆过滤器时按预期键数一次扩容（否则导入期间每次超出容量都要读取全部存活键重建）；
+// 启用前缀压缩时把样本键的前缀预先记入前缀字典，导入时不再逐个新前缀刷盘。两者都未启用时为空操作，返回nil
+func (db *Database) Reserve(expectedKeys uint64, sampleKeys [][]byte) error {
+	if db.isClosing() {
+		return ErrClosed
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	if db.bloom.needsCapacity(expectedKeys) {
+		keys, err := db.liveKeys()
+		if err != nil {
+			return err
+		}
+		extra := expectedKeys
+		if limit := uint64(math.MaxInt32) - uint64(len(keys)); extra > limit {
+			extra = limit
+		}
+		db.bloom.reserve(keys, int(extra))
+	}
+
+	if db.prefixes != nil {
+		for _, key := range sampleKeys {
+			if len(key) == 0 {
+				continue
+			}
+			if _, err := db.prefixes.encode(key, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
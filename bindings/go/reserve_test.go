@@ -0,0 +1,57 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestReserveKeepsRoot(t *testing.T) {
+	const n = 3000
+	items := make(map[string][]byte, n)
+	var samples [][]byte
+	for i := 0; i < n; i++ {
+		k := fmt.Sprintf("tenants/acme/bulk/load/%05d", i)
+		items[k] = []byte(fmt.Sprintf("v%d", i))
+		if i%500 == 0 {
+			samples = append(samples, []byte(k))
+		}
+	}
+
+	var roots [][]byte
+	for _, opts := range []*Options{
+		nil,
+		{BloomFilterBits: 10},
+		{PrefixCompression: true},
+	} {
+		for _, reserve := range []bool{false, true} {
+			db := openTestDB(t, opts)
+			mustPut(t, db, "existing", "1")
+			if reserve {
+				if err := db.Reserve(n, samples); err != nil {
+					t.Fatal(err)
+				}
+				if db.bloom != nil && db.bloom.needsCapacity(n) {
+					t.Fatal("Reserve did not presize the bloom filter")
+				}
+			}
+			if _, err := db.BatchPut(items); err != nil {
+				t.Fatal(err)
+			}
+			roots = append(roots, rootOf(t, db))
+			if mustGet(t, db, "tenants/acme/bulk/load/02999", 0) != "v2999" {
+				t.Fatal("bulk-loaded key missing")
+			}
+		}
+	}
+	for i, root := range roots {
+		if !bytes.Equal(root, roots[0]) {
+			t.Fatalf("root %d differs", i)
+		}
+	}
+
+	// 没有可预分配的结构时为空操作
+	if err := openTestDB(t, nil).Reserve(0, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+)
+
+// resolveConflicts 对本库最新版本中存在（未删除）的键调用Options.ResolveConflict，keys与values为存储形式
+// 返回实际要写入的条目：解析结果为nil时写入删除标记，与现有值相同时不写入；
+// 传入的值为删除标记时不视为冲突。未配置解析函数时原样返回（调用方需持有wmu）
+func (db *Database) resolveConflicts(keys, values [][]byte) ([][]byte, [][]byte, error) {
+	if db.resolveConflict == nil {
+		return keys, values, nil
+	}
+	outKeys := make([][]byte, 0, len(keys))
+	outValues := make([][]byte, 0, len(values))
+	for i, key := range keys {
+		value := values[i]
+		if !isDeleted(value) {
+			existing, err := db.storedValueAt(key, 0)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, nil, err
+			}
+			if err == nil && !isDeleted(existing) {
+				current, err := db.userEntry(kv{key: key, value: existing})
+				if err != nil {
+					return nil, nil, err
+				}
+				incoming, err := db.userEntry(kv{key: key, value: value})
+				if err != nil {
+					return nil, nil, err
+				}
+				resolved := db.resolveConflict(current.key, current.value, incoming.value)
+				switch {
+				case resolved == nil:
+					value = deletedValue
+				case bytes.Equal(resolved, current.value):
+					continue
+				default:
+					_, value = db.storedEntry(current.key, resolved)
+				}
+			}
+		}
+		outKeys = append(outKeys, key)
+		outValues = append(outValues, value)
+	}
+	return outKeys, outValues, nil
+}
+
+// importBatch 按键排序写入导入的存储形式批次，冲突键经resolveConflicts处理（调用方需持有wmu）
+// 解析后没有需要写入的条目时不产生新版本
+func (db *Database) importBatch(items map[string][]byte) ([]byte, error) {
+	sorted := sortedKeys(items)
+	keys := make([][]byte, len(sorted))
+	values := make([][]byte, len(sorted))
+	for i, k := range sorted {
+		keys[i], values[i] = stringBytes(k), items[k]
+	}
+	keys, values, err := db.resolveConflicts(keys, values)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return db.rootHash()
+	}
+	return db.batchPutSlices(keys, values)
+}
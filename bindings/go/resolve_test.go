@@ -0,0 +1,123 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// largerValue 选择字典序较大的值并记录每次调用的键
+type largerValue struct{ calls []string }
+
+func (r *largerValue) resolve(key, existing, incoming []byte) []byte {
+	r.calls = append(r.calls, string(key))
+	if bytes.Compare(incoming, existing) > 0 {
+		return incoming
+	}
+	return existing
+}
+
+func TestResolveConflictOnEveryCollision(t *testing.T) {
+	existing := map[string]string{"a": "5", "b": "1", "c": "9", "only-dst": "x"}
+	incoming := map[string]string{"a": "3", "b": "7", "c": "9", "only-src": "y"}
+	want := map[string]string{"a": "5", "b": "7", "c": "9", "only-dst": "x", "only-src": "y"}
+
+	check := func(t *testing.T, db *Database, r *largerValue) {
+		t.Helper()
+		for k, v := range want {
+			if got := mustGet(t, db, k, 0); got != v {
+				t.Fatalf("%s = %q, want %q", k, got, v)
+			}
+		}
+		if fmt.Sprint(r.calls) != "[a b c]" {
+			t.Fatalf("resolver called for %v", r.calls)
+		}
+	}
+	open := func(t *testing.T) (*Database, *largerValue) {
+		r := &largerValue{}
+		db := openTestDB(t, &Options{ResolveConflict: r.resolve})
+		for k, v := range existing {
+			mustPut(t, db, k, v)
+		}
+		return db, r
+	}
+
+	t.Run("Merge", func(t *testing.T) {
+		db, r := open(t)
+		src := openTestDB(t, nil)
+		for k, v := range incoming {
+			mustPut(t, src, k, v)
+		}
+		if _, err := db.Merge(src, ConflictResolve); err != nil {
+			t.Fatal(err)
+		}
+		check(t, db, r)
+	})
+
+	t.Run("ImportFiltered", func(t *testing.T) {
+		db, r := open(t)
+		src := openTestDB(t, nil)
+		for k, v := range incoming {
+			mustPut(t, src, k, v)
+		}
+		var buf bytes.Buffer
+		if err := src.ExportFiltered(&buf, 0, func([]byte) bool { return true }); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ImportFiltered(&buf); err != nil {
+			t.Fatal(err)
+		}
+		check(t, db, r)
+	})
+
+	t.Run("ImportResumable", func(t *testing.T) {
+		db, r := open(t)
+		var stream []byte
+		for _, k := range []string{"a", "b", "c", "only-src"} {
+			stream = appendBytes32(appendBytes32(stream, []byte(k)), []byte(incoming[k]))
+		}
+		if _, err := db.ImportResumable(bytes.NewReader(stream), nil); err != nil {
+			t.Fatal(err)
+		}
+		check(t, db, r)
+	})
+}
+
+func TestResolveConflictNilDeletes(t *testing.T) {
+	db := openTestDB(t, &Options{ResolveConflict: func(key, existing, incoming []byte) []byte {
+		if string(key) == "drop" {
+			return nil
+		}
+		return existing
+	}})
+	mustPut(t, db, "drop", "1")
+	mustPut(t, db, "keep", "1")
+	before, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := openTestDB(t, nil)
+	mustPut(t, src, "keep", "2")
+	if _, err := db.Merge(src, ConflictResolve); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.CurrentVersion(); err != nil || v != before {
+		t.Fatalf("resolver keeping the existing value still wrote version %d, %v", v, err)
+	}
+
+	mustPut(t, src, "drop", "2")
+	if _, err := db.Merge(src, ConflictResolve); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get([]byte("drop"), 0); err == nil {
+		t.Fatal("nil from the resolver did not delete the key")
+	}
+	if mustGet(t, db, "keep", 0) != "1" {
+		t.Fatal("existing value not kept")
+	}
+
+	if _, err := openTestDB(t, nil).Merge(src, ConflictResolve); err != ErrInvalidArg {
+		t.Fatalf("ConflictResolve without a resolver: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package amdb
+
+/*
+#include <stdlib.h>
+#include "amdb.h"
+*/
+import "C"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"unsafe"
+)
+
+// Rewind 丢弃toVersion之后的全部版本，使toVersion成为当前版本并返回其根哈希，用于撤销错误的写入
+// 引擎不裁剪历史版本，1到当前版本之间的任一版本都可作为目标；toVersion为0时返回ErrInvalidArg，
+// 超过当前版本时返回ErrVersionNotFound，等于当前版本时不做任何修改。回退后的写入从toVersion+1开始编号。
+// 引擎没有删除版本的接口，回退在临时目录中按版本逐个重放1到toVersion的写入，再替换数据目录的内容并重新打开句柄：
+// 各版本的内容和根哈希保持不变，但提交时间变为重放时的时间，开销与历史写入总量成正比。
+// 回退全程持有写锁，替换期间其他调用等待其完成；已入队的PutAsync写入在回退之后提交。
+// 替换不是崩溃原子的：替换中途进程退出时数据目录可能只含部分内容，旧内容保存在Options.TempDir下的amdb-rewind-old-*目录中。
+// 有打开的VersionView固定了toVersion之后的版本时返回ErrVersionPinned；已封存的数据库返回ErrSealed；FromHandle包装的句柄没有数据目录，返回ErrInvalidArg
+func (db *Database) Rewind(toVersion uint32) (root []byte, err error) {
+	if db.dataDir == "" || db.borrowed || toVersion == 0 {
+		return nil, ErrInvalidArg
+	}
+	if _, span := db.startSpan(context.Background(), "amdb.Rewind"); span != nil {
+		span.SetAttribute(attrVersion, int(toVersion))
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	if err := db.checkSealed(); err != nil {
+		return nil, err
+	}
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	if toVersion > tl.current() {
+		return nil, ErrVersionNotFound
+	}
+	if toVersion == tl.current() {
+		return db.versionRoot(toVersion)
+	}
+	if db.pins.pinnedAfter(toVersion) {
+		return nil, ErrVersionPinned
+	}
+	if root, err = db.rewindTo(tl, toVersion); err != nil {
+		return nil, err
+	}
+	if db.mirror != nil {
+		if _, err := db.mirror.Rewind(toVersion); err != nil {
+			return nil, fmt.Errorf("mirror: %w", err)
+		}
+	}
+	return root, nil
+}
+
+// rewindTo 丢弃toVersion（可为0，即清空全部版本）之后的全部版本并返回toVersion的根哈希（调用方需持有wmu并已完成校验）
+func (db *Database) rewindTo(tl *timeline, toVersion uint32) (root []byte, err error) {
+	scratch, err := db.scratchDir("amdb-rewind-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+	if err := db.replayInto(scratch, tl, toVersion); err != nil {
+		return nil, err
+	}
+
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	db.pause()
+	err = db.replaceDataDir(scratch)
+	if err == nil {
+		// 重放经批量写入路径提交，新数据目录带有状态根标记，之后的根哈希由状态计算
+		var marked bool
+		if marked, err = hasStateRootMarker(db.dataDir); marked {
+			db.stateRoot.Store(true)
+		}
+	}
+	db.invalidateTimeline()
+	db.values.reset()
+	db.proofs.discardAfter(toVersion)
+	db.leafHashes.discardAfter(toVersion)
+	db.rootsMu.Lock()
+	for v := range db.roots {
+		if v > toVersion {
+			delete(db.roots, v)
+		}
+	}
+	db.rootsMu.Unlock()
+	db.resume()
+	db.leave()
+	if err != nil {
+		return nil, err
+	}
+	if db.bloom != nil {
+		db.bloomRebuild()
+	}
+
+	if root, err = db.versionRoot(toVersion); err != nil {
+		return nil, err
+	}
+	if db.auditLog != nil {
+		if err := db.auditLog.append(auditRewind, nil, nil, root); err != nil {
+			return nil, fmt.Errorf("audit log: %w", err)
+		}
+		if err := db.auditCommit(); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// replayInto 在空目录dir中新建数据库，按版本顺序重放1到toVersion的写入，每个版本一次批量写入
+func (db *Database) replayInto(dir string, tl *timeline, toVersion uint32) error {
+	changes := make([][][]byte, toVersion)
+	for key, history := range tl.keys {
+		for _, kv := range history {
+			if kv.dbVersion <= toVersion {
+				changes[kv.dbVersion-1] = append(changes[kv.dbVersion-1], stringBytes(key))
+			}
+		}
+	}
+
+	dest, err := NewDatabaseWithOptions(dir, &Options{HashKeys: db.hashKeys, KeySalt: db.keySalt})
+	if err != nil {
+		return err
+	}
+	// 重放的值直接分块写入本库的块存储、新前缀记入本库的前缀字典，替换时临时目录中不产生这些文件；
+	// 重放不需要各版本报告的根哈希，以PlainMode免去启用块存储时逐版本计算根哈希的开销
+	dest.chunks, dest.prefixes, dest.plain = db.chunks, db.prefixes, true
+	// 重放的结果在dest.Close时完整落盘，不需要批量写入日志
+	dest.journal = nil
+	for i, keys := range changes {
+		version := uint32(i) + 1
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		values := make([][]byte, len(keys))
+		for j, key := range keys {
+			values[j], err = db.storedValueAt(key, version)
+			if errors.Is(err, ErrNotFound) {
+				// 该版本写入的是删除标记
+				values[j], err = deletedValue, nil
+			}
+			if err != nil {
+				dest.Close()
+				return err
+			}
+		}
+		dest.wmu.Lock()
+		_, err = dest.batchPutSlices(keys, values)
+		dest.wmu.Unlock()
+		if err != nil {
+			dest.Close()
+			return err
+		}
+	}
+	return dest.Close()
+}
+
+// replaceDataDir 关闭C句柄，以src目录的内容替换数据目录的内容并重新打开句柄（调用方需已暂停其他调用）
+// 替换失败时将旧内容移回，无法移回时保留备份目录并在错误中指明；无论成败都重新打开句柄
+func (db *Database) replaceDataDir(src string) error {
+	backup, err := db.scratchDir("amdb-rewind-old-")
+	if err != nil {
+		return err
+	}
+	// 锁文件、按内容寻址的块存储和只追加的前缀字典保持不动；同在数据目录下的临时目录和审计日志不属于数据库内容
+	keep := map[string]bool{
+		absPath(filepath.Join(db.dataDir, "LOCK")):             true,
+		absPath(filepath.Join(db.dataDir, chunkDirName)):       true,
+		absPath(filepath.Join(db.dataDir, prefixDictName)):     true,
+		absPath(filepath.Join(db.dataDir, comparatorFileName)): true,
+		absPath(src):    true,
+		absPath(backup): true,
+	}
+	if db.opts.AuditLogPath != "" {
+		keep[absPath(db.opts.AuditLogPath)] = true
+		keep[absPath(db.opts.AuditLogPath+auditHeadSuffix)] = true
+	}
+
+	start := db.cgoStart()
+	status := C.amdb_close(db.handle)
+	db.cgoEnd(start)
+	if status != C.AMDB_OK {
+		os.RemoveAll(backup)
+		if err := db.reopenHandle(); err != nil {
+			return err
+		}
+		return statusError(status)
+	}
+
+	_, err = moveEntries(db.dataDir, backup, func(path string) bool { return keep[absPath(path)] })
+	if err == nil {
+		var moved []string
+		moved, err = moveEntries(src, db.dataDir, func(path string) bool {
+			name := filepath.Base(path)
+			return name == "LOCK" || name == cleanMarkerName
+		})
+		if err != nil {
+			for _, name := range moved {
+				os.RemoveAll(filepath.Join(db.dataDir, name))
+			}
+		}
+	}
+	restored := true
+	if err != nil {
+		if _, restoreErr := moveEntries(backup, db.dataDir, func(string) bool { return false }); restoreErr != nil {
+			restored = false
+			err = fmt.Errorf("rewind: %w; old contents left in %s: %v", err, backup, restoreErr)
+		}
+	}
+	if syncErr := syncDir(db.dataDir); err == nil {
+		err = syncErr
+	}
+	if reopenErr := db.reopenHandle(); reopenErr != nil && err == nil {
+		err = reopenErr
+	}
+	if restored {
+		os.RemoveAll(backup)
+	}
+	return err
+}
+
+// reopenHandle 以数据目录重新初始化C句柄
+func (db *Database) reopenHandle() error {
+	cDataDir := C.CString(db.dataDir)
+	defer C.free(unsafe.Pointer(cDataDir))
+	var handle C.amdb_handle_t
+	start := db.cgoStart()
+	status := C.amdb_init(cDataDir, &handle)
+	db.cgoEnd(start)
+	if status != C.AMDB_OK {
+		return statusError(status)
+	}
+	db.handle = handle
+	return nil
+}
+
+// moveEntries 将src目录下除skip(路径)为true之外的全部条目移动到dst目录，返回已移动的条目名
+// 优先重命名；跨文件系统无法重命名时复制后删除
+func moveEntries(src, dst string, skip func(path string) bool) ([]string, error) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return nil, err
+	}
+	var moved []string
+	for _, e := range entries {
+		from := filepath.Join(src, e.Name())
+		if skip(from) {
+			continue
+		}
+		to := filepath.Join(dst, e.Name())
+		if err := os.Rename(from, to); err != nil {
+			if e.IsDir() {
+				err = copyTree(from, to)
+			} else {
+				err = copyFile(from, to)
+			}
+			if err == nil {
+				err = os.RemoveAll(from)
+			}
+			if err != nil {
+				os.RemoveAll(to)
+				return moved, err
+			}
+		}
+		moved = append(moved, e.Name())
+	}
+	return moved, nil
+}
+
+// absPath 返回path的绝对路径，无法取得时返回清理后的path
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
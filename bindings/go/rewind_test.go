@@ -0,0 +1,104 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRewindRestoresVersion(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+	if _, err := db.BatchPut(map[string][]byte{"b": []byte("2"), "c": []byte("3")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete([]byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	target, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rootOf(t, db)
+
+	// 错误的一批写入
+	mustPut(t, db, "a", "bad")
+	if _, err := db.BatchPut(map[string][]byte{"c": []byte("bad"), "d": []byte("bad")}); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := db.Rewind(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, want) || !bytes.Equal(rootOf(t, db), want) {
+		t.Fatal("rewind did not restore the target root")
+	}
+	if v, err := db.CurrentVersion(); err != nil || v != target {
+		t.Fatalf("current version %d, %v", v, err)
+	}
+	if mustGet(t, db, "a", 0) != "1" || mustGet(t, db, "b", 0) != "2" {
+		t.Fatal("state of the target version not restored")
+	}
+	for _, key := range []string{"c", "d"} {
+		if _, err := db.Get([]byte(key), 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s after rewind: %v", key, err)
+		}
+	}
+	if mustGet(t, db, "a", 1) != "1" {
+		t.Fatal("earlier version lost")
+	}
+	if _, err := db.Get([]byte("a"), target+1); err == nil {
+		t.Fatal("discarded version still readable")
+	}
+
+	// 之后的写入从target+1开始编号，回退的结果在重新打开后保留
+	mustPut(t, db, "e", "5")
+	if v, err := db.CurrentVersion(); err != nil || v != target+1 {
+		t.Fatalf("version after rewind and put: %d, %v", v, err)
+	}
+	after := rootOf(t, db)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if !bytes.Equal(rootOf(t, db), after) || mustGet(t, db, "a", 0) != "1" {
+		t.Fatal("rewound state not persisted")
+	}
+	if root, err := db.RootHashAtVersion(target); err != nil || !bytes.Equal(root, want) {
+		t.Fatalf("root of the target version after reopen: %v", err)
+	}
+
+	if _, err := db.Rewind(0); err != ErrInvalidArg {
+		t.Fatalf("rewind to 0: %v", err)
+	}
+	if _, err := db.Rewind(target + 10); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("rewind past the current version: %v", err)
+	}
+	if root, err := db.Rewind(target + 1); err != nil || !bytes.Equal(root, after) {
+		t.Fatalf("rewind to the current version: %v", err)
+	}
+}
+
+func TestRewindPutOnlyDatabaseRoot(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	want := rootOf(t, db)
+	mustPut(t, db, "b", "2")
+
+	root, err := db.Rewind(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, want) || !bytes.Equal(rootOf(t, db), want) {
+		t.Fatal("root after rewinding a database written only by Put")
+	}
+}
@@ -0,0 +1,100 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// 根哈希链：c0为32个0字节，ci = SHA-256(c(i-1) || [4字节大端版本号i][4字节大端长度][版本i的根哈希])，链值为最后一个版本的cN。
+// 锚定链值即承诺了从版本1起全部根哈希的顺序；给出某版本之前的链值和之后各版本的根哈希，即可验证该版本的根在链中，
+// 版本号参与每一环的哈希，证明因此也确定了根所在的版本
+
+// RootChainProof 某个版本的根哈希在根哈希链中的证明
+type RootChainProof struct {
+	Version uint32
+	// Prev 该版本之前的链值（版本1为32个0字节）
+	Prev []byte
+	// Following 之后各版本的根哈希，按版本顺序，最后一个为生成证明时的最新版本
+	Following [][]byte
+}
+
+// RootChain 返回承诺从版本1到当前版本全部根哈希顺序的链值（空数据库为空）
+// 各版本的根经RootHashAtVersion缓存，首次调用需要为每个版本重建一次树。PlainMode下返回ErrNotAuthenticated
+func (db *Database) RootChain() ([]byte, error) {
+	roots, err := db.chainRoots()
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) == 0 {
+		return []byte{}, nil
+	}
+	return extendRootChain(make([]byte, sha256.Size), 1, roots), nil
+}
+
+// ProveRootInChain 返回版本version（0表示当前版本）的根哈希在当前RootChain中的证明
+// 证明包含之后每个版本的根哈希，大小与其后的版本数成正比；版本不存在时返回ErrVersionNotFound
+func (db *Database) ProveRootInChain(version uint32) (*RootChainProof, error) {
+	roots, err := db.chainRoots()
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = uint32(len(roots))
+	}
+	if version == 0 || int64(version) > int64(len(roots)) {
+		return nil, ErrVersionNotFound
+	}
+	following := make([][]byte, 0, len(roots)-int(version))
+	for _, root := range roots[version:] {
+		following = append(following, bytes.Clone(root))
+	}
+	return &RootChainProof{
+		Version:   version,
+		Prev:      extendRootChain(make([]byte, sha256.Size), 1, roots[:version-1]),
+		Following: following,
+	}, nil
+}
+
+// VerifyRootInChain 校验root是证明所述版本的根哈希且在链值为chain的根哈希链中
+func VerifyRootInChain(chain, root []byte, proof *RootChainProof) bool {
+	if proof == nil || proof.Version == 0 || len(proof.Prev) != sha256.Size {
+		return false
+	}
+	if proof.Version == 1 && !bytes.Equal(proof.Prev, make([]byte, sha256.Size)) {
+		return false
+	}
+	if int64(proof.Version)+int64(len(proof.Following)) > int64(^uint32(0)) {
+		return false
+	}
+	got := extendRootChain(proof.Prev, proof.Version, [][]byte{root})
+	return bytes.Equal(extendRootChain(got, proof.Version+1, proof.Following), chain)
+}
+
+// chainRoots 按版本顺序返回从版本1到当前版本的根哈希
+func (db *Database) chainRoots() ([][]byte, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	versions, err := db.RootsSince(1)
+	if err != nil {
+		return nil, err
+	}
+	roots := make([][]byte, len(versions))
+	for i, v := range versions {
+		roots[i] = v.Root
+	}
+	return roots, nil
+}
+
+// extendRootChain 从链值prev起依次链入roots，roots[i]为版本first+i的根哈希，返回最后的链值
+func extendRootChain(prev []byte, first uint32, roots [][]byte) []byte {
+	link := prev
+	for i, root := range roots {
+		h := sha256.New()
+		h.Write(link)
+		h.Write(wireOrder.AppendUint32(nil, first+uint32(i)))
+		h.Write(appendBytes32(nil, root))
+		link = h.Sum(nil)
+	}
+	return bytes.Clone(link)
+}
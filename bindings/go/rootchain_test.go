@@ -0,0 +1,80 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRootChainMembership(t *testing.T) {
+	db := openTestDB(t, nil)
+	if chain, err := db.RootChain(); err != nil || len(chain) != 0 {
+		t.Fatalf("empty database chain %x, %v", chain, err)
+	}
+	var roots [][]byte
+	for i := 0; i < 5; i++ {
+		root, err := db.Put([]byte(fmt.Sprintf("k%d", i)), []byte("v"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+
+	chain, err := db.RootChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := db.RootChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(chain, again) || len(chain) != sha256.Size {
+		t.Fatal("chain not stable for a fixed history")
+	}
+	// 与按文档公式独立计算的结果一致
+	link := make([]byte, sha256.Size)
+	for i, root := range roots {
+		h := sha256.Sum256(appendBytes32(wireOrder.AppendUint32(bytes.Clone(link), uint32(i+1)), root))
+		link = h[:]
+	}
+	if !bytes.Equal(chain, link) {
+		t.Fatal("chain differs from the documented construction")
+	}
+
+	for v := uint32(1); v <= 5; v++ {
+		proof, err := db.ProveRootInChain(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyRootInChain(chain, roots[v-1], proof) {
+			t.Fatalf("root of version %d not proven", v)
+		}
+		if VerifyRootInChain(chain, roots[(v)%5], proof) {
+			t.Fatalf("wrong root verified for version %d", v)
+		}
+	}
+	proof, err := db.ProveRootInChain(0)
+	if err != nil || proof.Version != 5 || len(proof.Following) != 0 {
+		t.Fatalf("proof for the current version: %+v, %v", proof, err)
+	}
+	forged := *proof
+	forged.Version = 4
+	if VerifyRootInChain(chain, roots[4], &forged) {
+		t.Fatal("proof verified for the wrong version")
+	}
+	if _, err := db.ProveRootInChain(6); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("future version: %v", err)
+	}
+
+	// 新的写入改变链值，旧证明不再针对新链值成立
+	mustPut(t, db, "k5", "v")
+	newChain, err := db.RootChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(newChain, chain) || VerifyRootInChain(newChain, roots[4], proof) {
+		t.Fatal("chain did not commit to the new version")
+	}
+}
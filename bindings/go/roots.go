@@ -0,0 +1,145 @@
+package amdb
+
+import (
+	"bytes"
+	"time"
+)
+
+// VersionRoot 数据库版本及其Merkle根哈希
+type VersionRoot struct {
+	Version uint32
+	Root    []byte
+}
+
+// RootHashAtVersion 返回数据库版本version（0表示最新版本）时的Merkle根哈希（空数据库为空）
+// 需要在内存中重建该版本的整棵树，开销与键数量成正比。
+// 已提交的版本不可变，计算过的根哈希会缓存，重复查询同一版本不再重建
+func (db *Database) RootHashAtVersion(version uint32) ([]byte, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		current, err := db.CurrentVersion()
+		if err != nil {
+			return nil, err
+		}
+		if current == 0 {
+			return []byte{}, nil
+		}
+		version = current
+	}
+
+	db.rootsMu.Lock()
+	root, ok := db.roots[version]
+	db.rootsMu.Unlock()
+	if ok {
+		return root, nil
+	}
+
+	trie, err := db.trieAt(version)
+	if err != nil {
+		return nil, err
+	}
+	root = []byte{}
+	if trie != nil {
+		root = trie.hash
+	}
+	db.rootsMu.Lock()
+	if db.roots == nil {
+		db.roots = make(map[uint32][]byte)
+	}
+	db.roots[version] = root
+	db.rootsMu.Unlock()
+	return root, nil
+}
+
+// KnowsRoot 报告root是否为本库某个版本的根哈希，用于在信任外部锚定的根之前确认本库能够到达它
+// 从当前版本向前查找，返回根哈希等于root的最新版本；引擎不裁剪历史版本，任一已提交版本都可找到。
+// 各版本的根经RootHashAtVersion缓存，首次调用最多为每个版本重建一次树。root为空时返回ErrInvalidArg
+func (db *Database) KnowsRoot(root []byte) (version uint32, known bool, err error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return 0, false, err
+	}
+	if len(root) == 0 {
+		return 0, false, ErrInvalidArg
+	}
+	current, err := db.CurrentVersion()
+	if err != nil {
+		return 0, false, err
+	}
+	for v := current; v > 0; v-- {
+		got, err := db.RootHashAtVersion(v)
+		if err != nil {
+			return 0, false, err
+		}
+		if bytes.Equal(got, root) {
+			return v, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// RootsSince 按版本顺序返回从fromVersion（含，0视为1）到当前版本的每个版本的根哈希
+// 引擎不裁剪历史版本，因此结果连续无缺口；fromVersion超过当前版本时返回空
+func (db *Database) RootsSince(fromVersion uint32) ([]VersionRoot, error) {
+	current, err := db.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	if fromVersion == 0 {
+		fromVersion = 1
+	}
+
+	var roots []VersionRoot
+	for v := fromVersion; v <= current; v++ {
+		root, err := db.RootHashAtVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, VersionRoot{Version: v, Root: root})
+	}
+	return roots, nil
+}
+
+// VersionInfo 数据库版本的概要
+type VersionInfo struct {
+	Version uint32
+	Root    []byte
+	// Timestamp 引擎记录的提交时间
+	Timestamp time.Time
+	// KeysChanged 该版本写入或删除的键数量
+	KeysChanged int
+}
+
+// History 按版本顺序返回每个数据库版本的根哈希、提交时间和变更键数量
+// 引擎不裁剪历史版本，因此结果覆盖从1到当前版本的全部版本。
+// 根哈希经RootHashAtVersion缓存，首次调用需要为每个版本重建一次树，之后只计算新增的版本
+func (db *Database) History() ([]VersionInfo, error) {
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]int, tl.current()+1)
+	for _, history := range tl.keys {
+		for _, entry := range history {
+			changed[entry.dbVersion]++
+		}
+	}
+
+	infos := make([]VersionInfo, 0, tl.current())
+	for v := uint32(1); v <= tl.current(); v++ {
+		var root []byte
+		if !db.plain {
+			if root, err = db.RootHashAtVersion(v); err != nil {
+				return nil, err
+			}
+		}
+		infos = append(infos, VersionInfo{
+			Version:     v,
+			Root:        root,
+			Timestamp:   stampTime(tl.stamps[v-1]),
+			KeysChanged: changed[v],
+		})
+	}
+	return infos, nil
+}
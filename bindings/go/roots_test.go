@@ -0,0 +1,123 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"testing"
+)
+
+func TestRootsSince(t *testing.T) {
+	db := openTestDB(t, nil)
+	var written [][]byte
+	for i := 0; i < 4; i++ {
+		root, err := db.Put([]byte(fmt.Sprintf("k%d", i)), []byte("v"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		written = append(written, root)
+	}
+	root, err := db.BatchPut(map[string][]byte{"k0": []byte("w"), "b": []byte("w")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	written = append(written, root)
+
+	roots, err := db.RootsSince(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != len(written) {
+		t.Fatalf("%d roots for %d versions", len(roots), len(written))
+	}
+	for i, vr := range roots {
+		want, err := db.RootHashAtVersion(vr.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vr.Version != uint32(i+1) || !bytes.Equal(vr.Root, want) || !bytes.Equal(vr.Root, written[i]) {
+			t.Fatalf("entry %d: v%d %x, want v%d %x (written %x)", i, vr.Version, vr.Root, i+1, want, written[i])
+		}
+	}
+
+	tail, err := db.RootsSince(4)
+	if err != nil || len(tail) != 2 || tail[0].Version != 4 || !bytes.Equal(tail[1].Root, written[4]) {
+		t.Fatalf("since 4: %v, %v", tail, err)
+	}
+	if none, err := db.RootsSince(6); err != nil || len(none) != 0 {
+		t.Fatalf("past current: %v, %v", none, err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "1")
+	if _, err := db.BatchPut(map[string][]byte{"a": []byte("2"), "c": []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := db.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("got %d versions, want 3", len(history))
+	}
+	wantChanged := []int{1, 1, 2}
+	for i, info := range history {
+		if info.Version != uint32(i+1) {
+			t.Errorf("entry %d has version %d", i, info.Version)
+		}
+		if i > 0 && info.Timestamp.Before(history[i-1].Timestamp) {
+			t.Errorf("version %d timestamp goes backwards", info.Version)
+		}
+		if info.KeysChanged != wantChanged[i] {
+			t.Errorf("version %d changed %d keys, want %d", info.Version, info.KeysChanged, wantChanged[i])
+		}
+		root, err := db.RootHashAtVersion(info.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(info.Root, root) {
+			t.Errorf("version %d root %x, RootHashAtVersion %x", info.Version, info.Root, root)
+		}
+	}
+
+	empty, err := openTestDB(t, nil).History()
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("empty database: %v, %v", empty, err)
+	}
+}
+
+func TestKnowsRoot(t *testing.T) {
+	db := openTestDB(t, nil)
+	if _, ok, err := db.KnowsRoot(bytes.Repeat([]byte{1}, 32)); err != nil || ok {
+		t.Fatalf("empty database: %v %v", ok, err)
+	}
+
+	var roots [][]byte
+	for i := 0; i < 3; i++ {
+		root, err := db.Put([]byte(fmt.Sprintf("k%d", i)), []byte("v"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+	for i, root := range roots {
+		if v, ok, err := db.KnowsRoot(root); err != nil || !ok || v != uint32(i+1) {
+			t.Fatalf("root of version %d: v%d %v %v", i+1, v, ok, err)
+		}
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := db.KnowsRoot(random); err != nil || ok {
+		t.Fatalf("random root: %v %v", ok, err)
+	}
+	if _, _, err := db.KnowsRoot(nil); err != ErrInvalidArg {
+		t.Fatalf("empty root: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package amdb
+
+import (
+	"testing"
+)
+
+func TestSafeModeCopiesInputs(t *testing.T) {
+	db := openTestDB(t, &Options{SafeMode: true})
+
+	key, value := []byte("k1"), []byte("v1")
+	if _, err := db.Put(key, value); err != nil {
+		t.Fatal(err)
+	}
+	copy(key, "xx")
+	copy(value, "yy")
+	if got := mustGet(t, db, "k1", 0); got != "v1" {
+		t.Fatalf("k1 = %q after mutating inputs", got)
+	}
+
+	// PutAsync在后台提交，返回后立即修改切片也不影响写入的内容
+	key, value = []byte("k2"), []byte("v2")
+	pending := db.PutAsync(key, value)
+	copy(key, "xx")
+	copy(value, "yy")
+	if res := <-pending; res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if got := mustGet(t, db, "k2", 0); got != "v2" {
+		t.Fatalf("k2 = %q after mutating inputs of PutAsync", got)
+	}
+	if _, err := db.Get([]byte("xx"), 0); err == nil {
+		t.Fatal("mutated key was stored")
+	}
+
+	keys, values := [][]byte{[]byte("k3")}, [][]byte{[]byte("v3")}
+	if _, err := db.BatchPutSlices(keys, values); err != nil {
+		t.Fatal(err)
+	}
+	copy(values[0], "yy")
+	if got := mustGet(t, db, "k3", 0); got != "v3" {
+		t.Fatalf("k3 = %q", got)
+	}
+}
+
+func TestOwned(t *testing.T) {
+	b := []byte("abc")
+	safe := &Database{safeMode: true}
+	if c := safe.owned(b); &c[0] == &b[0] || string(c) != "abc" {
+		t.Fatal("SafeMode must copy")
+	}
+	if c := (&Database{}).owned(b); &c[0] != &b[0] {
+		t.Fatal("default mode must not copy")
+	}
+	all := ownedAll([][]byte{b})
+	if &all[0][0] == &b[0] {
+		t.Fatal("ownedAll must copy each slice")
+	}
+}
@@ -0,0 +1,85 @@
+package amdb
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// scratchDir 为维护操作创建临时工作目录，位于Options.TempDir下（未配置时位于数据目录下）
+// 调用方负责在完成或失败后删除该目录
+func (db *Database) scratchDir(pattern string) (string, error) {
+	parent := db.tempParent
+	if parent == "" {
+		// FromHandle包装的句柄两者皆为空，此时使用系统临时目录
+		parent = db.dataDir
+	}
+	return os.MkdirTemp(parent, pattern)
+}
+
+// moveDir 将src目录移动为dst，dst必须不存在或为空目录
+// 优先重命名；跨文件系统无法重命名时复制后删除src，复制失败时删除已复制的部分
+func moveDir(src, dst string) error {
+	if isEmptyDir(dst) {
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	} else if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("destination %s is not empty", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return syncDir(filepath.Dir(dst))
+	}
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	if err := syncCreated(dst, []string{dst}); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree 递归复制src目录下的全部目录和普通文件到dst
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile 复制单个文件
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,87 @@
+package amdb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// assertEmptyDir 断言dir中没有任何条目
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("%s has leftover entries: %v", dir, entries)
+	}
+}
+
+func TestTempDirMaintenance(t *testing.T) {
+	scratch := t.TempDir()
+	db := openTestDB(t, &Options{TempDir: scratch})
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	assertEmptyDir(t, scratch)
+
+	dest := filepath.Join(t.TempDir(), "rehashed")
+	root, err := db.Rehash(dest, HashSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEmptyDir(t, scratch)
+	rehashed, err := NewDatabase(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rehashed.Close()
+	if got := rootOf(t, rehashed); !bytes.Equal(got, root) || mustGet(t, rehashed, "b", 0) != "2" {
+		t.Fatalf("rehashed database root %x, want %x", got, root)
+	}
+
+	// 目标的父路径是普通文件，新库构建完成后移动失败，临时目录仍被删除
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Rehash(filepath.Join(file, "db"), HashSHA256); err == nil {
+		t.Fatal("Rehash into a path under a file succeeded")
+	}
+	assertEmptyDir(t, scratch)
+}
+
+func TestMoveDirCopiesTree(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "versions"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"a": "1", filepath.Join("versions", "b"): "2"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := copyTree(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "versions", "b")); err != nil || string(data) != "2" {
+		t.Fatalf("copied file: %q, %v", data, err)
+	}
+
+	moved := filepath.Join(t.TempDir(), "moved")
+	if err := moveDir(dst, moved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("source still exists after move: %v", err)
+	}
+	if err := moveDir(src, moved); err == nil {
+		t.Fatal("move onto a non-empty directory succeeded")
+	}
+}
@@ -0,0 +1,109 @@
+package amdb
+
+/*
+#include "amdb.h"
+*/
+import "C"
+import (
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrSealed 数据库已封存，拒绝一切写入
+var ErrSealed = errors.New("database is sealed")
+
+// sealMarkerName 封存标记文件名，内容为封存时根哈希的十六进制表示
+// 标记一经创建便不再删除，之后每次打开都以封存状态打开
+const sealMarkerName = "SEALED"
+
+// Seal 永久封存数据库，用于分发不可变的数据集
+// 封存先将引擎内存中的数据刷盘，再在数据目录中写入封存标记（记录当时的根哈希，供分发后比对）。
+// 此后本句柄和之后打开的每个句柄都只读：Put、Delete、BatchPut等写入返回ErrSealed，
+// 读取、证明与GetRootHash不受影响。由于不再有写入，引擎的WAL也不会再追加记录；
+// 引擎没有关闭WAL或以只读方式打开文件的接口，打开时仍会重写元数据文件，因此目录本身不能设为只读权限。
+// 封存不可撤销，对已封存的数据库再次调用返回nil；FromHandle包装的句柄没有数据目录，返回ErrInvalidArg
+func (db *Database) Seal() error {
+	if db.dataDir == "" {
+		return ErrInvalidArg
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	if err := db.enter(); err != nil {
+		return err
+	}
+	defer db.leave()
+	if db.sealed {
+		return nil
+	}
+
+	start := db.cgoStart()
+	status := C.amdb_flush(db.handle)
+	db.cgoEnd(start)
+	if status != C.AMDB_OK {
+		return statusError(status)
+	}
+	if err := db.batchesDurable(); err != nil {
+		return err
+	}
+	root, err := db.rootHash()
+	if err != nil {
+		return err
+	}
+	if err := writeSealMarker(db.dataDir, root); err != nil {
+		return err
+	}
+	db.sealed = true
+	return nil
+}
+
+// Sealed 报告数据库是否已封存
+func (db *Database) Sealed() bool {
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	return db.sealed
+}
+
+// checkSealed 已封存时返回ErrSealed（调用方需持有wmu）
+func (db *Database) checkSealed() error {
+	if db.sealed {
+		return ErrSealed
+	}
+	return nil
+}
+
+// isSealed 报告数据目录中是否存在封存标记
+func isSealed(dataDir string) (bool, error) {
+	_, err := os.Stat(filepath.Join(dataDir, sealMarkerName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// writeSealMarker 原子写入封存标记并刷入磁盘
+func writeSealMarker(dataDir string, root []byte) error {
+	path := filepath.Join(dataDir, sealMarkerName)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0444)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(hex.EncodeToString(root) + "\n")
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(dataDir)
+}
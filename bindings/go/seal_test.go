@@ -0,0 +1,67 @@
+package amdb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSeal(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "k", "v")
+	root := rootOf(t, db)
+	if db.Sealed() {
+		t.Fatal("new database reports Sealed")
+	}
+	if err := db.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Seal(); err != nil {
+		t.Fatalf("second Seal: %v", err)
+	}
+	if _, err := db.Put([]byte("k"), []byte("w")); !errors.Is(err, ErrSealed) {
+		t.Fatalf("Put on sealed handle: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	marker, err := os.ReadFile(filepath.Join(dir, sealMarkerName))
+	if err != nil || string(bytes.TrimSpace(marker)) != hex.EncodeToString(root) {
+		t.Fatalf("seal marker %q, %v; want root %x", marker, err, root)
+	}
+
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if !db.Sealed() {
+		t.Fatal("reopened database is not sealed")
+	}
+	if got := mustGet(t, db, "k", 0); got != "v" {
+		t.Fatalf("k = %q", got)
+	}
+	if got := rootOf(t, db); !bytes.Equal(got, root) {
+		t.Fatalf("root after reopen %x, want %x", got, root)
+	}
+	if _, err := db.Put([]byte("k"), []byte("w")); !errors.Is(err, ErrSealed) {
+		t.Fatalf("Put after reopen: %v", err)
+	}
+	if err := db.Delete([]byte("k")); !errors.Is(err, ErrSealed) {
+		t.Fatalf("Delete after reopen: %v", err)
+	}
+	if _, err := db.BatchPut(map[string][]byte{"x": nil}); !errors.Is(err, ErrSealed) {
+		t.Fatalf("BatchPut after reopen: %v", err)
+	}
+	if v, _ := db.CurrentVersion(); v != 1 {
+		t.Fatalf("sealed database advanced to version %d", v)
+	}
+}
@@ -0,0 +1,140 @@
+package amdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShutdownTimeout 关闭期限已到但仍有操作未完成，数据库未被关闭
+var ErrShutdownTimeout = errors.New("shutdown timed out with operations in flight")
+
+// opGate 跟踪正在C层执行的调用，关闭时拒绝新调用并等待已有调用完成
+type opGate struct {
+	mu       sync.Mutex
+	closing  bool
+	inflight int
+	idle     chan struct{} // closing之后，inflight降为0时关闭
+	queued   int           // inflight中已入队、等待后台提交的PutAsync写入数
+	paused   chan struct{} // 非nil表示暂停接受新调用，resume时关闭
+	drained  chan struct{} // pause等待期间，除调用方和queued外的调用全部完成时关闭
+}
+
+// enter 开始一次C层调用，关闭开始后返回ErrClosed；暂停期间阻塞直到恢复
+func (db *Database) enter() error {
+	g := &db.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.paused != nil && !g.closing {
+		paused := g.paused
+		g.mu.Unlock()
+		<-paused
+		g.mu.Lock()
+	}
+	if g.closing {
+		return ErrClosed
+	}
+	g.inflight++
+	return nil
+}
+
+// enterQueued 与enter相同，但调用在后台队列中等待提交，pause不等待它完成
+func (db *Database) enterQueued() error {
+	if err := db.enter(); err != nil {
+		return err
+	}
+	db.gate.mu.Lock()
+	db.gate.queued++
+	db.gate.mu.Unlock()
+	return nil
+}
+
+// leave 结束一次C层调用
+func (db *Database) leave() {
+	g := &db.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inflight--
+	g.release()
+}
+
+// leaveQueued 结束一次经由enterQueued开始的调用
+func (db *Database) leaveQueued() {
+	g := &db.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inflight--
+	g.queued--
+	g.release()
+}
+
+// release 在inflight减少后唤醒等待的关闭或暂停（调用方需持有mu）
+func (g *opGate) release() {
+	if g.closing && g.inflight == 0 {
+		close(g.idle)
+	}
+	if g.drained != nil && g.inflight-g.queued <= 1 {
+		close(g.drained)
+		g.drained = nil
+	}
+}
+
+// pause 暂停接受新调用并等待其他正在执行的调用完成，调用方自身须处于enter与leave之间
+// 队列中的PutAsync写入在等待wmu，不在等待之列；调用方持有wmu时它们会在resume之后提交
+func (db *Database) pause() {
+	g := &db.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.paused = make(chan struct{})
+	for g.inflight-g.queued > 1 {
+		g.drained = make(chan struct{})
+		drained := g.drained
+		g.mu.Unlock()
+		<-drained
+		g.mu.Lock()
+	}
+}
+
+// resume 恢复接受新调用
+func (db *Database) resume() {
+	g := &db.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	close(g.paused)
+	g.paused = nil
+}
+
+// isClosing 判断关闭是否已经开始
+func (db *Database) isClosing() bool {
+	db.gate.mu.Lock()
+	defer db.gate.mu.Unlock()
+	return db.gate.closing
+}
+
+// beginClose 停止接受新调用，返回在已有调用全部完成时关闭的通道（可重复调用）
+func (db *Database) beginClose() <-chan struct{} {
+	g := &db.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.closing {
+		g.closing = true
+		g.idle = make(chan struct{})
+		if g.inflight == 0 {
+			close(g.idle)
+		}
+	}
+	return g.idle
+}
+
+// Shutdown 优雅关闭：停止接受新调用，在ctx截止前等待正在执行的调用完成，然后刷盘并关闭
+// 截止时仍有调用未完成则返回ErrShutdownTimeout且不强制关闭，句柄保持不接受新调用的状态，
+// 可以再次调用Shutdown或Close继续等待；句柄已关闭时返回nil。
+// 关闭开始后，尚未进入C层的调用（包括多步操作的后续步骤）返回ErrClosed
+func (db *Database) Shutdown(ctx context.Context) error {
+	select {
+	case <-db.beginClose():
+		return db.closeHandle()
+	case <-ctx.Done():
+		return ErrShutdownTimeout
+	}
+}
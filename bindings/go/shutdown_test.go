@@ -0,0 +1,82 @@
+package amdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowOp 模拟一次耗时d的C层调用，返回在调用结束时关闭的通道
+func slowOp(t *testing.T, db *Database, d time.Duration) <-chan struct{} {
+	t.Helper()
+	if err := db.enter(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(d)
+		db.leave()
+		close(done)
+	}()
+	return done
+}
+
+func TestShutdownWaitsForInflight(t *testing.T) {
+	db, err := NewDatabase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "k", "v")
+	done := slowOp(t, db, 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := db.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	select {
+	case <-done:
+	default:
+		t.Fatal("shutdown returned before the in-flight call finished")
+	}
+	if err := db.Ping(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("ping after shutdown: %v", err)
+	}
+	if err := db.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second shutdown: %v", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "k", "v")
+	done := slowOp(t, db, 300*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := db.Shutdown(ctx); !errors.Is(err, ErrShutdownTimeout) {
+		t.Fatalf("shutdown: %v", err)
+	}
+	// 未强制关闭，但不再接受新调用
+	if _, err := db.Put([]byte("k"), []byte("w")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("put during shutdown: %v", err)
+	}
+	<-done
+	if err := db.Shutdown(context.Background()); err != nil {
+		t.Fatalf("retry shutdown: %v", err)
+	}
+
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := mustGet(t, db, "k", 0); got != "v" {
+		t.Fatalf("k after reopen: %q", got)
+	}
+}
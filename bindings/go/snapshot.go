@@ -0,0 +1,196 @@
+package amdb
+
+import (
+	"errors"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// ErrSnapshotReleased 快照已释放
+var ErrSnapshotReleased = errors.New("snapshot released")
+
+// Snapshot 数据库某个版本的只读快照
+// 创建时将该版本的全部键值读入内存，之后的读取不再经过C API，
+// 也不受后续写入影响；不再使用时应调用Release释放内存
+type Snapshot struct {
+	db      *Database
+	version uint32
+
+	mu       sync.Mutex
+	state    []kv           // 该版本的全部键值（存储形式，包含删除标记）
+	index    map[string]int // 存储形式的键在state中的下标
+	trie     *trieNode      // 首次需要根哈希或证明时构建
+	released bool
+}
+
+// 内存估算使用的单项开销
+const (
+	snapshotEntryOverhead = int(unsafe.Sizeof(kv{})) + 64 // kv结构及索引map条目
+	snapshotNodeOverhead  = int(unsafe.Sizeof(trieNode{}))
+)
+
+// Snapshot 创建当前数据库版本的快照
+func (db *Database) Snapshot() (*Snapshot, error) {
+	return db.SnapshotAt(0)
+}
+
+// SnapshotAt 创建数据库版本version（0表示当前版本）的快照
+func (db *Database) SnapshotAt(version uint32) (*Snapshot, error) {
+	if version == 0 {
+		current, err := db.CurrentVersion()
+		if err != nil {
+			return nil, err
+		}
+		version = current
+	}
+
+	var state []kv
+	if version > 0 {
+		var err error
+		if state, err = db.stateAt(version); err != nil {
+			return nil, err
+		}
+	}
+	index := make(map[string]int, len(state))
+	for i, item := range state {
+		index[string(item.key)] = i
+	}
+	return &Snapshot{db: db, version: version, state: state, index: index}, nil
+}
+
+// Version 返回快照固定的数据库版本（空数据库为0）
+func (s *Snapshot) Version() uint32 {
+	return s.version
+}
+
+// Get 读取键在快照版本时的值
+func (s *Snapshot) Get(key []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return nil, ErrSnapshotReleased
+	}
+	i, ok := s.index[string(s.db.storedKey(key))]
+	if !ok || isDeleted(s.state[i].value) || !s.db.inKeyRange(key) {
+		return nil, ErrNotFound
+	}
+	entry, err := s.db.userEntry(s.state[i])
+	if err != nil {
+		return nil, err
+	}
+	return entry.value, nil
+}
+
+// Has 判断键在快照版本中是否存在
+func (s *Snapshot) Has(key []byte) (bool, error) {
+	_, err := s.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NewRangeIterator 创建遍历快照中[start, end)范围的迭代器，nil表示不限制该方向
+func (s *Snapshot) NewRangeIterator(start, end []byte) (*Iterator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return nil, ErrSnapshotReleased
+	}
+	start, end = s.db.clampRange(start, end)
+	return s.db.newIterator(liveRange(s.state, start, end, s.db.keyOrder()), s.version), nil
+}
+
+// GetRootHash 返回快照版本的Merkle根哈希（空数据库为空）
+func (s *Snapshot) GetRootHash() ([]byte, error) {
+	if err := s.db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	root, err := s.trieLocked()
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return []byte{}, nil
+	}
+	return root.hash, nil
+}
+
+// GetWithProof 获取键在快照版本时的值及Merkle证明
+func (s *Snapshot) GetWithProof(key []byte) (*MerkleProof, error) {
+	if err := s.db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	root, err := s.trieLocked()
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, ErrNotFound
+	}
+	leaf, steps := root.path(s.db.storedKey(key))
+	if leaf == nil || isDeleted(leaf.value) {
+		return nil, ErrNotFound
+	}
+	return &MerkleProof{Key: leaf.key, Value: leaf.value, Steps: steps, Root: root.hash, Version: s.version, GeneratedAt: time.Now()}, nil
+}
+
+// trieLocked 返回快照的MPT，首次调用时构建（调用方需持有mu）
+func (s *Snapshot) trieLocked() (*trieNode, error) {
+	if s.released {
+		return nil, ErrSnapshotReleased
+	}
+	if s.trie == nil && len(s.state) > 0 {
+		root, err := buildTrie(s.state, HashSHA256)
+		if err != nil {
+			return nil, err
+		}
+		s.trie = root
+	}
+	return s.trie, nil
+}
+
+// MemoryUsage 估算快照占用的内存字节数（键值数据、索引以及已构建的树节点），释放后为0
+// 引擎本身不裁剪历史版本，快照不会额外占用C层内存，内存全部由快照自身持有
+func (s *Snapshot) MemoryUsage() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return 0
+	}
+	n := 0
+	for _, item := range s.state {
+		n += snapshotEntryOverhead + 2*len(item.key) + len(item.value)
+	}
+	n += trieMemory(s.trie)
+	return uint64(n)
+}
+
+// trieMemory 估算树节点占用的内存（叶子节点与快照共享键值数据，不重复计入）
+func trieMemory(n *trieNode) int {
+	if n == nil {
+		return 0
+	}
+	size := snapshotNodeOverhead + len(n.hash)
+	size += trieMemory(n.child)
+	for _, child := range n.children {
+		size += trieMemory(child)
+	}
+	return size
+}
+
+// Release 释放快照持有的内存，之后的读取返回ErrSnapshotReleased；重复调用无副作用
+func (s *Snapshot) Release() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released = true
+	s.state = nil
+	s.index = nil
+	s.trie = nil
+	return nil
+}
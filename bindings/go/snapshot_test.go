@@ -0,0 +1,84 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSnapshotIsolatedFromWrites(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "1")
+	root := rootOf(t, db)
+	snap, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Release()
+
+	mustPut(t, db, "a", "2")
+	if err := db.Delete([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := snap.Get([]byte("a")); err != nil || string(got) != "1" {
+		t.Fatalf("a: %q, %v", got, err)
+	}
+	if ok, err := snap.Has([]byte("b")); err != nil || !ok {
+		t.Fatalf("b: %v, %v", ok, err)
+	}
+	if got, err := snap.GetRootHash(); err != nil || !bytes.Equal(got, root) {
+		t.Fatalf("snapshot root %x, want %x (%v)", got, root, err)
+	}
+	proof, err := snap.GetWithProof([]byte("a"))
+	if err != nil || !VerifyProof(root, []byte("a"), []byte("1"), proof) || proof.Version != snap.Version() {
+		t.Fatalf("proof: %+v, %v", proof, err)
+	}
+}
+
+func TestSnapshotMemoryUsage(t *testing.T) {
+	db := openTestDB(t, nil)
+	empty, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := empty.MemoryUsage(); got != 0 {
+		t.Fatalf("empty snapshot uses %d bytes", got)
+	}
+
+	items := make(map[string][]byte, 200)
+	for i := 0; i < 200; i++ {
+		items[fmt.Sprintf("key-%03d", i)] = bytes.Repeat([]byte{'v'}, 100)
+	}
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := snap.MemoryUsage()
+	if loaded < 200*100 {
+		t.Fatalf("snapshot of 20KB of values reports %d bytes", loaded)
+	}
+	if _, err := snap.GetRootHash(); err != nil {
+		t.Fatal(err)
+	}
+	if withTrie := snap.MemoryUsage(); withTrie <= loaded {
+		t.Fatalf("building the trie did not increase usage: %d -> %d", loaded, withTrie)
+	}
+
+	if err := snap.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if got := snap.MemoryUsage(); got != 0 {
+		t.Fatalf("released snapshot uses %d bytes", got)
+	}
+	if _, err := snap.Get([]byte("key-000")); !errors.Is(err, ErrSnapshotReleased) {
+		t.Fatalf("get after release: %v", err)
+	}
+	if err := snap.Release(); err != nil {
+		t.Fatalf("second release: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package amdb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// SQL驱动：以database/sql接口访问amdb，将数据库视为一张键值表kv(key, value)
+// 这只是Get/Put/Delete之上的薄封装，不是SQL引擎，仅支持以下语句（大小写不敏感）：
+//
+//	SELECT value FROM kv WHERE key = ?
+//	INSERT INTO kv (key, value) VALUES (?, ?)
+//	INSERT INTO kv (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value
+//	DELETE FROM kv WHERE key = ?
+//
+// 参数可以是[]byte或string。不带ON CONFLICT的INSERT在键已存在时返回错误。
+// 数据源名称（DSN）即数据目录：sql.Open("amdb", "./data/mydb")
+// 同一DSN的多个连接共享同一个数据库句柄，最后一个连接关闭时关闭句柄。
+
+// SQLDriverName 注册到database/sql的驱动名
+const SQLDriverName = "amdb"
+
+func init() {
+	sql.Register(SQLDriverName, &sqlDriver{dbs: make(map[string]*sharedDB)})
+}
+
+// ErrUnsupportedSQL 不支持的SQL语句
+var ErrUnsupportedSQL = errors.New("unsupported sql statement")
+
+// 语句类型
+type sqlOp int
+
+const (
+	sqlSelect sqlOp = iota
+	sqlInsert
+	sqlUpsert
+	sqlDelete
+)
+
+// sqlStatements 受支持语句的规范形式（见normalizeSQL）
+var sqlStatements = map[string]sqlOp{
+	"select value from kv where key = ?":                                                                       sqlSelect,
+	"insert into kv ( key , value ) values ( ? , ? )":                                                          sqlInsert,
+	"insert into kv ( key , value ) values ( ? , ? ) on conflict ( key ) do update set value = excluded.value": sqlUpsert,
+	"delete from kv where key = ?":                                                                             sqlDelete,
+}
+
+// normalizeSQL 转为小写，标点两侧加空格并合并空白，去掉结尾分号
+func normalizeSQL(query string) string {
+	q := strings.ToLower(query)
+	for _, p := range []string{"(", ")", ",", "=", ";"} {
+		q = strings.ReplaceAll(q, p, " "+p+" ")
+	}
+	q = strings.Join(strings.Fields(q), " ")
+	return strings.TrimSpace(strings.TrimSuffix(q, ";"))
+}
+
+// parseSQL 解析受支持的语句
+func parseSQL(query string) (sqlOp, error) {
+	if op, ok := sqlStatements[normalizeSQL(query)]; ok {
+		return op, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnsupportedSQL, query)
+}
+
+// sharedDB 同一DSN共享的数据库句柄
+type sharedDB struct {
+	db   *Database
+	refs int
+}
+
+// sqlDriver 实现driver.Driver
+type sqlDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*sharedDB
+}
+
+// Open 打开到数据目录name的连接
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	shared, ok := d.dbs[name]
+	if !ok {
+		db, err := NewDatabase(name)
+		if err != nil {
+			return nil, err
+		}
+		shared = &sharedDB{db: db}
+		d.dbs[name] = shared
+	}
+	shared.refs++
+	return &sqlConn{driver: d, name: name, db: shared.db}, nil
+}
+
+// release 释放一个连接对句柄的引用
+func (d *sqlDriver) release(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	shared, ok := d.dbs[name]
+	if !ok {
+		return nil
+	}
+	shared.refs--
+	if shared.refs > 0 {
+		return nil
+	}
+	delete(d.dbs, name)
+	return shared.db.Close()
+}
+
+// sqlConn 实现driver.Conn
+type sqlConn struct {
+	driver *sqlDriver
+	name   string
+	db     *Database
+	closed bool
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	op, err := parseSQL(query)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{db: c.db, op: op}, nil
+}
+
+func (c *sqlConn) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.driver.release(c.name)
+}
+
+// Begin 不支持事务
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("amdb sql driver does not support transactions")
+}
+
+// sqlStmt 实现driver.Stmt
+type sqlStmt struct {
+	db *Database
+	op sqlOp
+}
+
+func (s *sqlStmt) Close() error {
+	return nil
+}
+
+func (s *sqlStmt) NumInput() int {
+	if s.op == sqlInsert || s.op == sqlUpsert {
+		return 2
+	}
+	return 1
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	key, err := sqlBytesArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch s.op {
+	case sqlInsert, sqlUpsert:
+		value, err := sqlBytesArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if s.op == sqlInsert {
+			if _, err := s.db.Get(key, 0); err == nil {
+				return nil, fmt.Errorf("duplicate key: %q", key)
+			} else if err != ErrNotFound {
+				return nil, err
+			}
+		}
+		if _, err := s.db.Put(key, value); err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(1), nil
+	case sqlDelete:
+		if _, err := s.db.Get(key, 0); err == ErrNotFound {
+			return driver.RowsAffected(0), nil
+		} else if err != nil {
+			return nil, err
+		}
+		if err := s.db.Delete(key); err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(1), nil
+	}
+	return nil, errors.New("statement does not modify data, use Query")
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.op != sqlSelect {
+		return nil, errors.New("statement returns no rows, use Exec")
+	}
+	key, err := sqlBytesArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	value, err := s.db.Get(key, 0)
+	if err == ErrNotFound {
+		return &sqlRows{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlRows{values: [][]byte{value}}, nil
+}
+
+// sqlBytesArg 将参数转换为字节
+func sqlBytesArg(v driver.Value) ([]byte, error) {
+	switch x := v.(type) {
+	case []byte:
+		return x, nil
+	case string:
+		return []byte(x), nil
+	}
+	return nil, fmt.Errorf("unsupported argument type %T, want []byte or string", v)
+}
+
+// sqlRows 实现driver.Rows，只有一列value
+type sqlRows struct {
+	values [][]byte
+	pos    int
+}
+
+func (r *sqlRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *sqlRows) Close() error {
+	return nil
+}
+
+func (r *sqlRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+	dest[0] = r.values[r.pos]
+	r.pos++
+	return nil
+}
@@ -0,0 +1,72 @@
+package amdb
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestSQLDriver(t *testing.T) {
+	sqldb, err := sql.Open(SQLDriverName, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqldb.Close()
+
+	if _, err := sqldb.Exec("INSERT INTO kv (key, value) VALUES (?, ?)", "alice", []byte("10")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqldb.Exec("insert into kv(key,value) values(?,?)", "alice", "11"); err == nil {
+		t.Fatal("plain INSERT overwrote an existing key")
+	}
+	res, err := sqldb.Exec("INSERT INTO kv (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value;", "alice", "12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("upsert affected %d rows", n)
+	}
+
+	var value string
+	if err := sqldb.QueryRow("SELECT value FROM kv WHERE key = ?", "alice").Scan(&value); err != nil {
+		t.Fatal(err)
+	}
+	if value != "12" {
+		t.Fatalf("select: %q", value)
+	}
+	if err := sqldb.QueryRow("SELECT value FROM kv WHERE key = ?", "bob").Scan(&value); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("missing key: %v", err)
+	}
+
+	res, err = sqldb.Exec("DELETE FROM kv WHERE key = ?", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("delete affected %d rows", n)
+	}
+	res, err = sqldb.Exec("DELETE FROM kv WHERE key = ?", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 0 {
+		t.Fatalf("second delete affected %d rows", n)
+	}
+	if err := sqldb.QueryRow("SELECT value FROM kv WHERE key = ?", "alice").Scan(&value); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("deleted key: %v", err)
+	}
+}
+
+func TestSQLDriverRejectsUnsupported(t *testing.T) {
+	sqldb, err := sql.Open(SQLDriverName, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqldb.Close()
+	if _, err := sqldb.Exec("UPDATE kv SET value = ? WHERE key = ?", "v", "k"); !errors.Is(err, ErrUnsupportedSQL) {
+		t.Fatalf("got %v, want ErrUnsupportedSQL", err)
+	}
+	if _, err := sqldb.Exec("INSERT INTO kv (key, value) VALUES (?, ?)", 1, "v"); err == nil {
+		t.Fatal("integer key accepted")
+	}
+}
@@ -0,0 +1,189 @@
+package amdb
+
+/*
+#include "amdb.h"
+*/
+import "C"
+import (
+	"bytes"
+	"unsafe"
+)
+
+// deletedValue 引擎用于标记删除的值，删除的键仍以该值保留在Merkle树中
+var deletedValue = []byte("__DELETED__")
+
+// isDeleted 判断原始值是否为删除标记
+func isDeleted(value []byte) bool {
+	return bytes.Equal(value, deletedValue)
+}
+
+// stampAt 返回数据库版本对应的提交时间（0表示最新状态）
+func (db *Database) stampAt(version uint32) (float64, error) {
+	if version == 0 {
+		return 0, nil
+	}
+	tl, err := db.timeline()
+	if err != nil {
+		return 0, err
+	}
+	if version > tl.current() {
+		return 0, ErrVersionNotFound
+	}
+	return tl.stamps[version-1], nil
+}
+
+// stateAt 返回数据库版本version时的全部键值（包含删除标记，与引擎Merkle树的叶子一致）
+func (db *Database) stateAt(version uint32) ([]kv, error) {
+	ts, err := db.stampAt(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	defer db.leave()
+
+	var kvs *C.amdb_kv_t
+	var count C.size_t
+	start := db.cgoStart()
+	status := C.amdb_get_state(db.handle, C.double(ts), &kvs, &count)
+	db.cgoEnd(start)
+	if status != C.AMDB_OK {
+		return nil, statusError(status)
+	}
+	defer C.amdb_free_kvs(kvs, count)
+
+	n, err := cLength(count)
+	if err != nil {
+		return nil, err
+	}
+	entries := unsafe.Slice(kvs, n)
+	items := make([]kv, len(entries))
+	for i, e := range entries {
+		if items[i].key, err = goBytes(unsafe.Pointer(e.key), e.key_len); err != nil {
+			return nil, err
+		}
+		if items[i].key, err = db.decodeKey(items[i].key); err != nil {
+			return nil, err
+		}
+		if items[i].value, err = goBytes(e.value, e.value_len); err != nil {
+			return nil, err
+		}
+		if items[i].value, err = db.decodeValue(items[i].value); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// liveKeysAt 返回数据库版本version时的全部存活键（存储形式，未排序），不跨CGO复制值
+func (db *Database) liveKeysAt(version uint32) ([][]byte, error) {
+	ts, err := db.stampAt(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	defer db.leave()
+
+	var kvs *C.amdb_kv_t
+	var count C.size_t
+	start := db.cgoStart()
+	status := C.amdb_get_state_keys(db.handle, C.double(ts), &kvs, &count)
+	db.cgoEnd(start)
+	if status != C.AMDB_OK {
+		return nil, statusError(status)
+	}
+	defer C.amdb_free_kvs(kvs, count)
+
+	n, err := cLength(count)
+	if err != nil {
+		return nil, err
+	}
+	entries := unsafe.Slice(kvs, n)
+	keys := make([][]byte, len(entries))
+	for i, e := range entries {
+		if keys[i], err = goBytes(unsafe.Pointer(e.key), e.key_len); err != nil {
+			return nil, err
+		}
+		if keys[i], err = db.decodeKey(keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// storedValueAt 按存储形式的键读取版本version（须为具体版本号）时的存储形式的值，不经过缓存和布隆过滤器
+func (db *Database) storedValueAt(key []byte, version uint32) ([]byte, error) {
+	keyVer, err := db.keyVersionAt(key, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	defer db.leave()
+
+	cKey, err := db.encodeKey(key, false)
+	if err != nil {
+		return nil, err
+	}
+	var result C.amdb_result_t
+	start := db.cgoStart()
+	status := C.amdb_get(
+		db.handle,
+		cBytes(cKey), C.size_t(len(cKey)),
+		C.uint32_t(keyVer),
+		&result,
+	)
+	db.cgoEnd(start)
+	defer C.amdb_free_result(&result)
+	if status != C.AMDB_OK {
+		return nil, statusError(status)
+	}
+	value, err := goBytes(unsafe.Pointer(result.data), result.data_len)
+	if err != nil {
+		return nil, err
+	}
+	return db.decodeValue(value)
+}
+
+// trieAt 构建数据库版本version时的MPT（空数据库返回nil）
+func (db *Database) trieAt(version uint32) (*trieNode, error) {
+	if db.leafHashes == nil {
+		items, err := db.stateAt(version)
+		if err != nil {
+			return nil, err
+		}
+		return buildTrie(items, HashSHA256)
+	}
+
+	// 复用缓存的叶子哈希需要具体的版本号，先解析版本再读取状态
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		if version = tl.current(); version == 0 {
+			return nil, nil
+		}
+	}
+	items, err := db.stateAt(version)
+	if err != nil {
+		return nil, err
+	}
+	return buildTrieWith(items, HashSHA256, db.leafHasherAt(tl, version))
+}
+
+// trieOf 由版本version（须为具体版本号）的状态items构建树，供已读取状态的调用方避免再次读取
+func (db *Database) trieOf(items []kv, version uint32) (*trieNode, error) {
+	if db.leafHashes == nil {
+		return buildTrie(items, HashSHA256)
+	}
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	return buildTrieWith(items, HashSHA256, db.leafHasherAt(tl, version))
+}
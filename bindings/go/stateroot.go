@@ -0,0 +1,49 @@
+package amdb
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// stateRootMarkerName 状态根标记文件名
+// 引擎的批量写入路径不更新Merkle树，也不返回根哈希：经该路径提交的写入（BatchPut等批量写入，
+// PlainMode与LazyRoot下的Put和Delete）之后引擎维护的根哈希缺少这些键。首次经该路径写入前在数据目录中创建标记，
+// 之后本句柄和之后打开的每个句柄的根哈希都由状态计算（即RootHashAtVersion(0)）；标记一经创建便不再删除
+const stateRootMarkerName = "STATE_ROOT"
+
+// hasStateRootMarker 报告数据目录中是否存在状态根标记
+func hasStateRootMarker(dataDir string) (bool, error) {
+	_, err := os.Stat(filepath.Join(dataDir, stateRootMarkerName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// markStateRoot 在经引擎的批量写入路径提交前调用，首次调用时创建状态根标记（调用方需持有wmu）
+// FromHandle包装的句柄没有数据目录，只在本句柄内改由状态计算根哈希
+func (db *Database) markStateRoot() error {
+	if db.stateRoot.Load() {
+		return nil
+	}
+	if db.dataDir != "" {
+		f, err := os.OpenFile(filepath.Join(db.dataDir, stateRootMarkerName), os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := syncDir(db.dataDir); err != nil {
+			return err
+		}
+	}
+	db.stateRoot.Store(true)
+	return nil
+}
@@ -0,0 +1,139 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stateRoot 返回由当前状态计算的根哈希
+func stateRoot(t *testing.T, db *Database) []byte {
+	t.Helper()
+	root, err := db.RootHashAtVersion(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestPutRootMatchesState(t *testing.T) {
+	db := openTestDB(t, nil)
+	root, err := db.Put([]byte("a"), []byte("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := stateRoot(t, db); !bytes.Equal(root, want) {
+		t.Fatalf("put root %x, state root %x", root, want)
+	}
+	if err := db.Delete([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "b", "2")
+	got, err := db.GetRootHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := stateRoot(t, db); !bytes.Equal(got, want) {
+		t.Fatalf("GetRootHash %x, state root %x", got, want)
+	}
+	// 只经单键写入时根哈希直接取自引擎，不创建标记
+	if _, err := os.Stat(filepath.Join(db.dataDir, stateRootMarkerName)); !os.IsNotExist(err) {
+		t.Fatalf("marker after single-key writes: %v", err)
+	}
+}
+
+func TestBatchRootsFromState(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+
+	items := make(map[string][]byte)
+	for i := 0; i < 20; i++ {
+		items[fmt.Sprintf("k%02d", i)] = []byte(fmt.Sprintf("v%02d", i))
+	}
+	root, err := db.BatchPut(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(root, make([]byte, 32)) {
+		t.Fatal("BatchPut returned the zero root")
+	}
+	if want := stateRoot(t, db); !bytes.Equal(root, want) {
+		t.Fatalf("BatchPut root %x, state root %x", root, want)
+	}
+
+	root, err = db.BatchPutSlices([][]byte{[]byte("s1"), []byte("s2")}, [][]byte{[]byte("x"), []byte("y")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := stateRoot(t, db); !bytes.Equal(root, want) {
+		t.Fatalf("BatchPutSlices root %x, state root %x", root, want)
+	}
+
+	// 批量写入之后的单键写入与GetRootHash同样由状态计算
+	root, err = db.Put([]byte("b"), []byte("2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := stateRoot(t, db); !bytes.Equal(root, want) {
+		t.Fatalf("put root %x, state root %x", root, want)
+	}
+	got, err := db.GetRootHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, root) {
+		t.Fatalf("GetRootHash %x, want %x", got, root)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 标记持久化在数据目录中，重新打开后仍由状态计算
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	got, err = db.GetRootHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, root) {
+		t.Fatalf("GetRootHash after reopen %x, want %x", got, root)
+	}
+}
+
+func TestLazyRootThenEager(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabaseWithOptions(dir, &Options{LazyRoot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+	want, err := db.GetRootHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// 不带LazyRoot打开时GetRootHash包含LazyRoot下写入的键
+	db, err = NewDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	got, err := db.GetRootHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("GetRootHash %x, want %x", got, want)
+	}
+}
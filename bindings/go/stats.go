@@ -0,0 +1,112 @@
+package amdb
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+// Stats 数据库句柄的运行统计
+type Stats struct {
+	// BloomFilterBits 布隆过滤器每键位数（0表示未启用）
+	BloomFilterBits int
+	// BloomFilterKeys 过滤器中的键数（含已删除尚未重建的键）
+	BloomFilterKeys int
+	// BloomFilterFalsePositiveRate 按当前填充程度估算的误报率
+	BloomFilterFalsePositiveRate float64
+	// BloomFilterNegatives 被过滤器直接判定为不存在的查询次数
+	BloomFilterNegatives uint64
+	// BloomFilterFalsePositives 通过过滤器但实际不存在的查询次数
+	BloomFilterFalsePositives uint64
+
+	// ProofCacheHits 证明缓存命中次数
+	ProofCacheHits uint64
+	// ProofCacheMisses 证明缓存未命中次数
+	ProofCacheMisses uint64
+	// ProofCacheHitRatio 证明缓存命中率（尚无查询时为0）
+	ProofCacheHitRatio float64
+
+	// ValueCacheEntries 值缓存当前的条目数
+	ValueCacheEntries int
+	// ValueCachePinned 经Pin固定在值缓存中的键数，不计入ValueCacheEntries
+	ValueCachePinned int
+	// ValueCacheCapacity 值缓存的最大条目数（0表示未启用）
+	ValueCacheCapacity int
+	// ValueCacheHits 值缓存命中次数
+	ValueCacheHits uint64
+	// ValueCacheMisses 值缓存未命中次数
+	ValueCacheMisses uint64
+	// ValueCacheHitRatio 值缓存命中率（尚无查询时为0）
+	ValueCacheHitRatio float64
+
+	// LogicalBytesWritten 经由本句柄写入的逻辑字节数（需启用Options.TrackWriteAmplification）
+	LogicalBytesWritten uint64
+	// PhysicalBytesWritten 写入期间实际写出的物理字节数，口径见Options.TrackWriteAmplification
+	PhysicalBytesWritten uint64
+	// WriteAmplification 写入放大系数PhysicalBytesWritten/LogicalBytesWritten（尚无写入时为0）
+	WriteAmplification float64
+}
+
+// Stats 返回当前统计信息
+func (db *Database) Stats() Stats {
+	var s Stats
+	if f := db.bloom; f != nil {
+		f.mu.RLock()
+		s.BloomFilterKeys = f.keys
+		f.mu.RUnlock()
+		s.BloomFilterBits = f.bitsPerKey
+		s.BloomFilterFalsePositiveRate = f.falsePositiveRate()
+		s.BloomFilterNegatives = f.negatives.Load()
+		s.BloomFilterFalsePositives = f.falsePositives.Load()
+	}
+	if c := db.proofs; c != nil {
+		s.ProofCacheHits = c.hits.Load()
+		s.ProofCacheMisses = c.misses.Load()
+		if total := s.ProofCacheHits + s.ProofCacheMisses; total > 0 {
+			s.ProofCacheHitRatio = float64(s.ProofCacheHits) / float64(total)
+		}
+	}
+	if c := db.values; c != nil {
+		s.ValueCacheEntries = c.len()
+		s.ValueCachePinned = c.pinnedLen()
+		s.ValueCacheCapacity = c.capacity
+		s.ValueCacheHits = c.hits.Load()
+		s.ValueCacheMisses = c.misses.Load()
+		if total := s.ValueCacheHits + s.ValueCacheMisses; total > 0 {
+			s.ValueCacheHitRatio = float64(s.ValueCacheHits) / float64(total)
+		}
+	}
+	if w := db.writeAmp; w != nil {
+		s.LogicalBytesWritten = w.logical.Load()
+		s.PhysicalBytesWritten = w.physical.Load()
+		if s.LogicalBytesWritten > 0 {
+			s.WriteAmplification = float64(s.PhysicalBytesWritten) / float64(s.LogicalBytesWritten)
+		}
+	}
+	return s
+}
+
+// DiskUsage 返回数据目录中全部文件的总字节数，包括引擎的数据与WAL文件和块存储
+// 引擎在内存中缓冲部分写入，刷盘前的结果可能小于最终占用；FromHandle包装的句柄没有数据目录，返回ErrInvalidArg
+func (db *Database) DiskUsage() (int64, error) {
+	if db.dataDir == "" {
+		return 0, ErrInvalidArg
+	}
+	var total int64
+	err := filepath.WalkDir(db.dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// 遍历期间被删除的临时文件
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,159 @@
+package amdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// subtreeFormatV1 子树导出格式版本
+const subtreeFormatV1 = 1
+
+var (
+	// ErrBadSubtree 子树数据格式错误
+	ErrBadSubtree = errors.New("malformed subtree")
+	// ErrSubtreeRootMismatch 子树数据与其携带的根哈希不一致
+	ErrSubtreeRootMismatch = errors.New("subtree root mismatch")
+)
+
+// 子树二进制格式（整数均为大端）：
+//	[1字节格式版本][4字节长度][前缀][4字节长度][子树根哈希]
+//	[4字节记录数] 每条记录：[4字节长度][键][4字节长度][值]
+
+// SubtreeRoot 返回数据库版本version（0表示最新版本）中键前缀为prefix的全部键
+// 单独构成的MPT根哈希（没有键时为空）
+// 删除标记与引擎Merkle树一致计入子树
+func (db *Database) SubtreeRoot(prefix []byte, version uint32) ([]byte, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	items, err := db.subtreeAt(prefix, version)
+	if err != nil {
+		return nil, err
+	}
+	return subtreeRoot(items)
+}
+
+// ExportSubtree 将数据库版本version（0表示最新版本）中键前缀为prefix的全部键值
+// 导出为可移植的数据块，数据块携带子树根哈希，导入方据此校验完整性
+func (db *Database) ExportSubtree(prefix []byte, version uint32) ([]byte, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	items, err := db.subtreeAt(prefix, version)
+	if err != nil {
+		return nil, err
+	}
+	root, err := subtreeRoot(items)
+	if err != nil {
+		return nil, err
+	}
+
+	size := 1 + 4 + len(prefix) + 4 + len(root) + 4
+	for _, item := range items {
+		size += 8 + len(item.key) + len(item.value)
+	}
+	buf := make([]byte, 0, size)
+	buf = append(buf, subtreeFormatV1)
+	buf = appendBytes32(buf, prefix)
+	buf = appendBytes32(buf, root)
+	buf = wireOrder.AppendUint32(buf, uint32(len(items)))
+	for _, item := range items {
+		buf = appendBytes32(buf, item.key)
+		buf = appendBytes32(buf, item.value)
+	}
+	return buf, nil
+}
+
+// ImportSubtree 校验ExportSubtree导出的数据块并将其中的键值一次性写入本数据库，
+// 返回写入后的数据库根哈希。无论键数多少，导入都作为一次批量写入提交，只产生一个新版本
+// 根哈希不一致时返回ErrSubtreeRootMismatch且不写入。导入与本库已有的同前缀键合并（冲突键见Options.ResolveConflict），
+// 只有目标库中该前缀下原本没有其他键时，导入后的SubtreeRoot才与导出方一致
+func (db *Database) ImportSubtree(data []byte) ([]byte, error) {
+	prefix, root, items, err := decodeSubtree(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if !bytes.HasPrefix(item.key, prefix) {
+			return nil, ErrBadSubtree
+		}
+	}
+	got, err := subtreeRoot(items)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(got, root) {
+		return nil, ErrSubtreeRootMismatch
+	}
+	if len(items) == 0 {
+		return db.rootHash()
+	}
+
+	batch := make(map[string][]byte, len(items))
+	for _, item := range items {
+		batch[string(item.key)] = item.value
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	return db.importBatch(batch)
+}
+
+// subtreeAt 返回版本version时前缀为prefix的全部键值
+func (db *Database) subtreeAt(prefix []byte, version uint32) ([]kv, error) {
+	state, err := db.stateAt(version)
+	if err != nil {
+		return nil, err
+	}
+	items := state[:0]
+	for _, item := range state {
+		if bytes.HasPrefix(item.key, prefix) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// subtreeRoot 计算键值集合的MPT根哈希（空集合返回空）
+func subtreeRoot(items []kv) ([]byte, error) {
+	root, err := buildTrie(items, HashSHA256)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return []byte{}, nil
+	}
+	return root.hash, nil
+}
+
+// decodeSubtree 解析子树数据块
+func decodeSubtree(data []byte) (prefix, root []byte, items []kv, err error) {
+	r := bytes.NewReader(data)
+	format, err := r.ReadByte()
+	if err != nil || format != subtreeFormatV1 {
+		return nil, nil, nil, ErrBadSubtree
+	}
+	if prefix, err = readLengthPrefixed(r); err != nil {
+		return nil, nil, nil, ErrBadSubtree
+	}
+	if root, err = readLengthPrefixed(r); err != nil {
+		return nil, nil, nil, ErrBadSubtree
+	}
+	var count uint32
+	if binary.Read(r, wireOrder, &count) != nil || int64(count)*8 > int64(r.Len()) {
+		return nil, nil, nil, ErrBadSubtree
+	}
+	items = make([]kv, count)
+	for i := range items {
+		if items[i].key, err = readLengthPrefixed(r); err != nil || len(items[i].key) == 0 {
+			return nil, nil, nil, ErrBadSubtree
+		}
+		if items[i].value, err = readLengthPrefixed(r); err != nil {
+			return nil, nil, nil, ErrBadSubtree
+		}
+	}
+	if r.Len() != 0 {
+		return nil, nil, nil, ErrBadSubtree
+	}
+	return prefix, root, items, nil
+}
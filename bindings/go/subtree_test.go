@@ -0,0 +1,85 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExportImportSubtree(t *testing.T) {
+	src := openTestDB(t, nil)
+	// 超过引擎单批上限，覆盖引擎的分批提交
+	items := make(map[string][]byte)
+	for i := 0; i < 3500; i++ {
+		items[fmt.Sprintf("shard1/%05d", i)] = []byte(fmt.Sprint(i))
+	}
+	items["shard2/a"] = []byte("other")
+	if _, err := src.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Delete([]byte("shard1/00007")); err != nil {
+		t.Fatal(err)
+	}
+	want, err := src.SubtreeRoot([]byte("shard1/"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := src.ExportSubtree([]byte("shard1/"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := openTestDB(t, nil)
+	mustPut(t, dst, "local", "x")
+	before, _ := dst.CurrentVersion()
+	root, err := dst.ImportSubtree(blob)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if after, _ := dst.CurrentVersion(); after != before+1 {
+		t.Fatalf("import produced %d versions, want 1", after-before)
+	}
+	if !bytes.Equal(root, stateRoot(t, dst)) {
+		t.Fatal("import root differs from the state root")
+	}
+	got, err := dst.SubtreeRoot([]byte("shard1/"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("subtree root %x, want %x", got, want)
+	}
+	if got := mustGet(t, dst, "shard1/03499", 0); got != "3499" {
+		t.Fatalf("imported value %q", got)
+	}
+	if _, err := dst.Get([]byte("shard1/00007"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted key: %v", err)
+	}
+	if _, err := dst.Get([]byte("shard2/a"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatal("key outside the prefix was imported")
+	}
+}
+
+func TestImportSubtreeRejectsTampering(t *testing.T) {
+	src := openTestDB(t, nil)
+	mustPut(t, src, "p/a", "1")
+	mustPut(t, src, "p/b", "2")
+	blob, err := src.ExportSubtree([]byte("p/"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := openTestDB(t, nil)
+
+	tampered := bytes.Clone(blob)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := dst.ImportSubtree(tampered); !errors.Is(err, ErrSubtreeRootMismatch) {
+		t.Fatalf("tampered value: %v", err)
+	}
+	if _, err := dst.ImportSubtree(blob[:len(blob)-1]); !errors.Is(err, ErrBadSubtree) {
+		t.Fatalf("truncated: %v", err)
+	}
+	if v, _ := dst.CurrentVersion(); v != 0 {
+		t.Fatal("rejected import wrote data")
+	}
+}
@@ -0,0 +1,107 @@
+package amdb
+
+import (
+	"errors"
+	"time"
+)
+
+// summaryFormatV1 状态摘要编码格式版本
+const summaryFormatV1 = 1
+
+// ErrBadSummary 状态摘要编码格式错误
+var ErrBadSummary = errors.New("malformed state summary")
+
+// StateSummary 数据库状态的元数据摘要（不含数据）
+type StateSummary struct {
+	// Version 当前数据库版本
+	Version uint32
+	// Root 当前版本的Merkle根哈希（空数据库为空）
+	Root []byte
+	// Count 当前版本的存活键数
+	Count uint64
+	// HashAlgorithm Merkle树使用的哈希算法
+	HashAlgorithm HashAlgorithm
+	// CreatedAt 第一个版本的提交时间（空数据库为零值）
+	CreatedAt time.Time
+}
+
+// 二进制编码格式（整数均为大端）：
+//
+//	[1字节格式版本][4字节版本][1字节哈希算法][8字节键数]
+//	[8字节创建时间（Unix纳秒，0表示未知）][1字节根长度][根]
+
+// StateSummary 返回当前状态摘要的二进制编码，可用于检测数据漂移或在恢复前预检
+func (db *Database) StateSummary() ([]byte, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	version := tl.current()
+
+	summary := StateSummary{Version: version, Root: []byte{}, HashAlgorithm: HashSHA256}
+	if version > 0 {
+		state, err := db.stateAt(version)
+		if err != nil {
+			return nil, err
+		}
+		root, err := buildTrie(state, HashSHA256)
+		if err != nil {
+			return nil, err
+		}
+		summary.Root = root.hash
+		for _, item := range state {
+			if !isDeleted(item.value) {
+				summary.Count++
+			}
+		}
+		summary.CreatedAt = stampTime(tl.stamps[0])
+	}
+	return summary.MarshalBinary()
+}
+
+// MarshalBinary 实现encoding.BinaryMarshaler
+func (s StateSummary) MarshalBinary() ([]byte, error) {
+	if len(s.Root) > 0xFF {
+		return nil, ErrBadSummary
+	}
+	buf := make([]byte, 0, 23+len(s.Root))
+	buf = append(buf, summaryFormatV1)
+	buf = appendVersion(buf, s.Version)
+	buf = append(buf, byte(s.HashAlgorithm))
+	buf = wireOrder.AppendUint64(buf, s.Count)
+	var created int64
+	if !s.CreatedAt.IsZero() {
+		created = s.CreatedAt.UnixNano()
+	}
+	buf = wireOrder.AppendUint64(buf, uint64(created))
+	buf = append(buf, byte(len(s.Root)))
+	return append(buf, s.Root...), nil
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler
+func (s *StateSummary) UnmarshalBinary(data []byte) error {
+	if len(data) < 23 || data[0] != summaryFormatV1 || int(data[22]) != len(data)-23 {
+		return ErrBadSummary
+	}
+	summary := StateSummary{
+		Version:       decodeVersion(data[1:5]),
+		HashAlgorithm: HashAlgorithm(data[5]),
+		Count:         wireOrder.Uint64(data[6:14]),
+		Root:          append([]byte{}, data[23:]...),
+	}
+	if created := int64(wireOrder.Uint64(data[14:22])); created != 0 {
+		summary.CreatedAt = time.Unix(0, created)
+	}
+	*s = summary
+	return nil
+}
+
+// ParseStateSummary 解析StateSummary返回的编码
+func ParseStateSummary(data []byte) (StateSummary, error) {
+	var s StateSummary
+	err := s.UnmarshalBinary(data)
+	return s, err
+}
@@ -0,0 +1,86 @@
+package amdb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+// goldenSummary 格式版本1的编码，格式变化时该测试应失败
+const goldenSummary = "01" + "00000007" + "00" + "0000000000000003" + "17979cfe362a0005" + "04" + "deadbeef"
+
+func TestStateSummaryGolden(t *testing.T) {
+	s := StateSummary{
+		Version:       7,
+		Root:          []byte{0xde, 0xad, 0xbe, 0xef},
+		Count:         3,
+		HashAlgorithm: HashSHA256,
+		CreatedAt:     time.Unix(1700000000, 5),
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(data); got != goldenSummary {
+		t.Fatalf("encoding\n%s\nwant\n%s", got, goldenSummary)
+	}
+
+	golden, _ := hex.DecodeString(goldenSummary)
+	parsed, err := ParseStateSummary(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Version != 7 || parsed.Count != 3 || parsed.HashAlgorithm != HashSHA256 ||
+		!bytes.Equal(parsed.Root, s.Root) || !parsed.CreatedAt.Equal(s.CreatedAt) {
+		t.Fatalf("parsed %+v", parsed)
+	}
+
+	empty, err := StateSummary{Root: []byte{}}.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(empty); got != "01"+"00000000"+"00"+"0000000000000000"+"0000000000000000"+"00" {
+		t.Fatalf("empty summary %s", got)
+	}
+	if parsed, err := ParseStateSummary(empty); err != nil || !parsed.CreatedAt.IsZero() || len(parsed.Root) != 0 {
+		t.Fatalf("empty summary parsed as %+v, %v", parsed, err)
+	}
+}
+
+func TestStateSummaryRejectsMalformed(t *testing.T) {
+	golden, _ := hex.DecodeString(goldenSummary)
+	bad := [][]byte{
+		nil,
+		golden[:22],
+		golden[:len(golden)-1],
+		append(append([]byte{}, golden...), 0),
+		append([]byte{2}, golden[1:]...),
+	}
+	for _, data := range bad {
+		if _, err := ParseStateSummary(data); !errors.Is(err, ErrBadSummary) {
+			t.Fatalf("%x: %v", data, err)
+		}
+	}
+}
+
+func TestStateSummaryOfDatabase(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "1")
+	if err := db.Delete([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := db.StateSummary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ParseStateSummary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != 3 || s.Count != 1 || !bytes.Equal(s.Root, stateRoot(t, db)) || s.CreatedAt.IsZero() {
+		t.Fatalf("summary %+v", s)
+	}
+}
@@ -0,0 +1,33 @@
+package amdb
+
+import (
+	"context"
+	"errors"
+)
+
+// Swap 写入键值对并返回写入前的旧值，键不存在（或已删除）时oldValue为nil
+// 读取与写入在同一把写锁内完成，期间不会有其他写入插入
+func (db *Database) Swap(key, value []byte) (oldValue []byte, root []byte, err error) {
+	if _, span := db.startSpan(context.Background(), "amdb.Swap"); span != nil {
+		span.SetAttribute(attrKeySize, len(key))
+		span.SetAttribute(attrValueSize, len(value))
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	oldValue, err = db.Get(key, 0)
+	if errors.Is(err, ErrNotFound) {
+		oldValue, err = nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root, err = db.put(key, value)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldValue, root, nil
+}
@@ -0,0 +1,37 @@
+package amdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSwap(t *testing.T) {
+	db := openTestDB(t, nil)
+	old, root, err := db.Swap([]byte("k"), []byte("1"))
+	if err != nil || old != nil {
+		t.Fatalf("fresh insert: old %q, %v", old, err)
+	}
+	if !bytes.Equal(root, rootOf(t, db)) {
+		t.Fatalf("root %x differs from current root", root)
+	}
+
+	old, _, err = db.Swap([]byte("k"), []byte("2"))
+	if err != nil || string(old) != "1" {
+		t.Fatalf("overwrite: old %q, %v", old, err)
+	}
+	if got := mustGet(t, db, "k", 0); got != "2" {
+		t.Fatalf("k: %q", got)
+	}
+
+	// 空旧值与不存在的旧值可区分
+	mustPut(t, db, "e", "")
+	if old, _, err := db.Swap([]byte("e"), []byte("x")); err != nil || old == nil || len(old) != 0 {
+		t.Fatalf("empty old value: %q, %v", old, err)
+	}
+	if err := db.Delete([]byte("k")); err != nil {
+		t.Fatal(err)
+	}
+	if old, _, err := db.Swap([]byte("k"), []byte("3")); err != nil || old != nil {
+		t.Fatalf("after delete: old %q, %v", old, err)
+	}
+}
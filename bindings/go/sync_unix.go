@@ -0,0 +1,15 @@
+//go:build !windows
+
+package amdb
+
+import "os"
+
+// syncDir 将目录项刷入磁盘
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package amdb
+
+// syncDir Windows不支持对目录fsync，目录项由文件系统保证
+func syncDir(dir string) error {
+	return nil
+}
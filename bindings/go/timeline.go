@@ -0,0 +1,207 @@
+package amdb
+
+/*
+#include "amdb.h"
+*/
+import "C"
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// LatestVersion 表示最新数据库版本，可传给所有接受版本号的方法，效果等同于先调用CurrentVersion
+// 数据库版本从1开始编号，0保留为该含义；读取时在调用当刻解析为最新版本，
+// 不需要调用方先查询CurrentVersion
+const LatestVersion uint32 = 0
+
+// timeline 数据库版本时间线
+// 引擎为每个键单独维护版本链并记录提交时间，同一次提交共享同一时间戳。
+// 绑定层将所有不同的提交时间按先后排序，第i次提交即数据库版本i（从1开始），
+// 在引擎的键内版本之上提供全库统一的版本号
+type timeline struct {
+	stamps []float64               // stamps[i]为数据库版本i+1的提交时间
+	keys   map[string][]keyVersion // 每个键按版本先后排序的版本记录
+
+	liveMu sync.Mutex
+	live   map[uint32][][]byte // 按版本缓存的有序存活键，见Database.sortedLiveKeys
+}
+
+// keyVersion 键在某个数据库版本上对应的键内版本号
+type keyVersion struct {
+	dbVersion  uint32
+	keyVersion uint32
+}
+
+// loadTimeline 从引擎读取所有版本记录并构建时间线，decodeKey将引擎中的键还原为原始的键
+func loadTimeline(handle C.amdb_handle_t, decodeKey func([]byte) ([]byte, error)) (*timeline, error) {
+	var infos *C.amdb_version_info_t
+	var count C.size_t
+	status := C.amdb_list_versions(handle, &infos, &count)
+	if status != C.AMDB_OK {
+		return nil, statusError(status)
+	}
+	defer C.amdb_free_version_infos(infos, count)
+
+	n, err := cLength(count)
+	if err != nil {
+		return nil, err
+	}
+	entries := unsafe.Slice(infos, n)
+	seen := make(map[float64]struct{}, len(entries))
+	stamps := make([]float64, 0, len(entries))
+	for _, e := range entries {
+		ts := float64(e.timestamp)
+		if _, ok := seen[ts]; !ok {
+			seen[ts] = struct{}{}
+			stamps = append(stamps, ts)
+		}
+	}
+	sort.Float64s(stamps)
+
+	dbVersions := make(map[float64]uint32, len(stamps))
+	for i, ts := range stamps {
+		dbVersions[ts] = uint32(i + 1)
+	}
+
+	keys := make(map[string][]keyVersion)
+	for _, e := range entries {
+		b, err := goBytes(unsafe.Pointer(e.key), e.key_len)
+		if err != nil {
+			return nil, err
+		}
+		if b, err = decodeKey(b); err != nil {
+			return nil, err
+		}
+		key := string(b)
+		keys[key] = append(keys[key], keyVersion{
+			dbVersion:  dbVersions[float64(e.timestamp)],
+			keyVersion: uint32(e.version),
+		})
+	}
+	for _, history := range keys {
+		sort.Slice(history, func(i, j int) bool { return history[i].keyVersion < history[j].keyVersion })
+	}
+
+	return &timeline{stamps: stamps, keys: keys}, nil
+}
+
+// current 返回最新的数据库版本（尚无写入时为0）
+func (t *timeline) current() uint32 {
+	return uint32(len(t.stamps))
+}
+
+// resolve 返回键在数据库版本version时的键内版本号，键在该版本尚未写入时返回false
+func (t *timeline) resolve(key []byte, version uint32) (uint32, bool) {
+	history := t.keys[string(key)]
+	i := sort.Search(len(history), func(i int) bool { return history[i].dbVersion > version })
+	if i == 0 {
+		return 0, false
+	}
+	return history[i-1].keyVersion, true
+}
+
+// writtenAt 返回键在数据库版本version时的值是由哪个数据库版本写入的，键在该版本尚未写入时返回false
+func (t *timeline) writtenAt(key []byte, version uint32) (uint32, bool) {
+	history := t.keys[string(key)]
+	i := sort.Search(len(history), func(i int) bool { return history[i].dbVersion > version })
+	if i == 0 {
+		return 0, false
+	}
+	return history[i-1].dbVersion, true
+}
+
+// stampTime 将引擎记录的Unix时间戳（秒）转换为time.Time
+func stampTime(ts float64) time.Time {
+	return time.Unix(0, int64(ts*1e9))
+}
+
+// timeline 返回缓存的时间线，写入后首次调用时重新构建
+func (db *Database) timeline() (*timeline, error) {
+	if err := db.enter(); err != nil {
+		return nil, err
+	}
+	defer db.leave()
+	db.tlMu.Lock()
+	defer db.tlMu.Unlock()
+	if db.tl == nil {
+		start := db.cgoStart()
+		tl, err := loadTimeline(db.handle, db.decodeKey)
+		db.cgoEnd(start)
+		if err != nil {
+			return nil, err
+		}
+		db.tl = tl
+	}
+	return db.tl, nil
+}
+
+// invalidateTimeline 写入后使缓存的时间线失效
+func (db *Database) invalidateTimeline() {
+	db.tlMu.Lock()
+	db.tl = nil
+	db.tlMu.Unlock()
+}
+
+// keyVersionAt 将数据库版本转换为键内版本号（0表示最新，原样返回）
+func (db *Database) keyVersionAt(key []byte, version uint32) (uint32, error) {
+	if version == 0 {
+		return 0, nil
+	}
+	tl, err := db.timeline()
+	if err != nil {
+		return 0, err
+	}
+	if version > tl.current() {
+		return 0, ErrVersionNotFound
+	}
+	keyVer, ok := tl.resolve(key, version)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return keyVer, nil
+}
+
+// CurrentVersion 返回当前数据库版本（尚无写入时为0）
+// 每次Put、Delete或BatchPut提交产生一个新的数据库版本
+func (db *Database) CurrentVersion() (uint32, error) {
+	tl, err := db.timeline()
+	if err != nil {
+		return 0, err
+	}
+	return tl.current(), nil
+}
+
+// VersionAsOf 返回在t时刻或之前提交的最新数据库版本，可直接传给Get等读取方法
+// 版本时间为引擎提交时记录的本机时钟时间；t早于最早的版本（或数据库尚无写入）时返回ErrVersionNotFound
+func (db *Database) VersionAsOf(t time.Time) (uint32, error) {
+	tl, err := db.timeline()
+	if err != nil {
+		return 0, err
+	}
+	ts := float64(t.UnixNano()) / 1e9
+	i := sort.Search(len(tl.stamps), func(i int) bool { return tl.stamps[i] > ts })
+	if i == 0 {
+		return 0, ErrVersionNotFound
+	}
+	return uint32(i), nil
+}
+
+// PutAtVersion 写入键值对，并要求本次写入恰好产生数据库版本version
+// 用于按事件日志确定性重放：version必须严格等于CurrentVersion()+1，
+// 出现跳号或乱序时返回ErrUnexpectedVersion且不写入
+func (db *Database) PutAtVersion(key, value []byte, version uint32) ([]byte, error) {
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	current, err := db.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	if version != current+1 {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrUnexpectedVersion, current+1, version)
+	}
+	return db.put(key, value)
+}
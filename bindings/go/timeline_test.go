@@ -0,0 +1,121 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type logEntry struct {
+	key, value string
+}
+
+var replayLog = []logEntry{
+	{"alice", "10"}, {"bob", "20"}, {"alice", "15"}, {"carol", "5"}, {"bob", "25"},
+}
+
+// replay 按日志顺序以PutAtVersion写入，返回每个版本的根哈希
+func replay(t *testing.T, db *Database) [][]byte {
+	t.Helper()
+	var roots [][]byte
+	for i, e := range replayLog {
+		root, err := db.PutAtVersion([]byte(e.key), []byte(e.value), uint32(i+1))
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+func TestPutAtVersionReplayIsDeterministic(t *testing.T) {
+	first := replay(t, openTestDB(t, nil))
+	db := openTestDB(t, nil)
+	second := replay(t, db)
+	for i := range first {
+		if !bytes.Equal(first[i], second[i]) {
+			t.Fatalf("version %d: roots %x and %x differ", i+1, first[i], second[i])
+		}
+		at, err := db.RootHashAtVersion(uint32(i + 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(at, second[i]) {
+			t.Fatalf("RootHashAtVersion(%d) %x, write returned %x", i+1, at, second[i])
+		}
+	}
+	if got := mustGet(t, db, "alice", 2); got != "10" {
+		t.Fatalf("alice@2: %q", got)
+	}
+}
+
+func TestPutAtVersionRejectsGapsAndReordering(t *testing.T) {
+	db := openTestDB(t, nil)
+	if _, err := db.PutAtVersion([]byte("k"), []byte("v"), 2); !errors.Is(err, ErrUnexpectedVersion) {
+		t.Fatalf("gap: got %v", err)
+	}
+	if _, err := db.PutAtVersion([]byte("k"), []byte("v"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.PutAtVersion([]byte("k"), []byte("w"), 1); !errors.Is(err, ErrUnexpectedVersion) {
+		t.Fatalf("repeat: got %v", err)
+	}
+	if v, err := db.CurrentVersion(); err != nil || v != 1 {
+		t.Fatalf("rejected writes changed the version: %d, %v", v, err)
+	}
+}
+
+func TestCurrentVersionCountsCommits(t *testing.T) {
+	db := openTestDB(t, nil)
+	if v, err := db.CurrentVersion(); err != nil || v != 0 {
+		t.Fatalf("empty: %d, %v", v, err)
+	}
+	mustPut(t, db, "a", "1")
+	items := map[string][]byte{}
+	for i := 0; i < 10; i++ {
+		items[fmt.Sprint(i)] = []byte("x")
+	}
+	// 一次批量写入只算一个版本
+	if _, err := db.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.CurrentVersion(); err != nil || v != 2 {
+		t.Fatalf("after batch: %d, %v", v, err)
+	}
+}
+
+func TestVersionAsOf(t *testing.T) {
+	db := openTestDB(t, nil)
+	start := time.Now()
+	if _, err := db.VersionAsOf(start); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("empty database: %v", err)
+	}
+	// 版本时间由引擎在提交时记录，每次提交后记下一个时刻
+	var marks []time.Time
+	for i := 0; i < 3; i++ {
+		time.Sleep(20 * time.Millisecond)
+		mustPut(t, db, "k", fmt.Sprint(i))
+		time.Sleep(20 * time.Millisecond)
+		marks = append(marks, time.Now())
+	}
+	if _, err := db.VersionAsOf(start); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("before the first version: %v", err)
+	}
+	for i, mark := range marks {
+		v, err := db.VersionAsOf(mark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != uint32(i+1) {
+			t.Fatalf("as of mark %d: version %d", i, v)
+		}
+		if got := mustGet(t, db, "k", v); got != fmt.Sprint(i) {
+			t.Fatalf("value as of mark %d: %q", i, got)
+		}
+	}
+	if v, err := db.VersionAsOf(time.Now().Add(time.Hour)); err != nil || v != 3 {
+		t.Fatalf("future: %d, %v", v, err)
+	}
+}
@@ -0,0 +1,53 @@
+package amdb
+
+import (
+	"context"
+	"encoding/hex"
+)
+
+// TracerProvider 分布式追踪接口，用于对接OpenTelemetry等追踪实现
+// 本包不直接依赖OpenTelemetry，调用方通过适配器实现该接口即可
+type TracerProvider interface {
+	// StartSpan 在ctx所属的追踪中开始一个名为name的子span，返回携带该span的context
+	// 带context的方法（PutContext、BatchPutContext、CompactContext、NewRangeIteratorContext等）传入调用方的ctx，
+	// span因此挂在调用方的请求追踪之下；没有context参数的方法传入context.Background()
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span 单次操作的追踪span
+type Span interface {
+	// SetAttribute 设置span属性
+	SetAttribute(key string, value interface{})
+	// RecordError 记录操作错误
+	RecordError(err error)
+	// End 结束span
+	End()
+}
+
+// span属性名
+const (
+	attrKeySize    = "amdb.key_size"
+	attrValueSize  = "amdb.value_size"
+	attrVersion    = "amdb.version"
+	attrBatchSize  = "amdb.batch_size"
+	attrRootPrefix = "amdb.root_prefix"
+)
+
+// startSpan 在ctx中开始一个操作span；未配置TracerProvider时原样返回ctx和nil，调用方据此跳过追踪
+func (db *Database) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if db.tracer == nil {
+		return ctx, nil
+	}
+	return db.tracer.StartSpan(ctx, name)
+}
+
+// endSpan 记录操作结果并结束span
+func endSpan(span Span, root []byte, err error) {
+	if len(root) >= 8 {
+		span.SetAttribute(attrRootPrefix, hex.EncodeToString(root[:8]))
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
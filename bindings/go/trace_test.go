@@ -0,0 +1,138 @@
+package amdb
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// recordingTracer 记录创建的全部span
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name string
+	// parent 创建时ctx中的span（没有时为nil）
+	parent *recordedSpan
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+// spanKey 在context中保存recordedSpan的键
+type spanKey struct{}
+
+func (tr *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	s := &recordedSpan{name: name, parent: parent, attrs: map[string]interface{}{}}
+	tr.spans = append(tr.spans, s)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *recordedSpan) RecordError(err error)                      { s.err = err }
+func (s *recordedSpan) End()                                       { s.ended = true }
+
+// span 返回名为name的第一个span
+func (tr *recordingTracer) span(t *testing.T, name string) *recordedSpan {
+	t.Helper()
+	for _, s := range tr.spans {
+		if s.name == name {
+			return s
+		}
+	}
+	t.Fatalf("no span %q", name)
+	return nil
+}
+
+func TestTracerSpans(t *testing.T) {
+	tr := &recordingTracer{}
+	db := openTestDB(t, &Options{TracerProvider: tr})
+
+	root, err := db.Put([]byte("key"), []byte("value!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := tr.span(t, "amdb.Put")
+	if put.attrs[attrKeySize] != 3 || put.attrs[attrValueSize] != 6 || !put.ended {
+		t.Fatalf("put span %+v", put)
+	}
+	if put.attrs[attrRootPrefix] != hex.EncodeToString(root[:8]) {
+		t.Fatalf("root prefix %v, root %x", put.attrs[attrRootPrefix], root)
+	}
+
+	if _, err := db.Get([]byte("missing"), 1); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	get := tr.span(t, "amdb.Get")
+	if get.attrs[attrVersion] != uint32(1) || !errors.Is(get.err, ErrNotFound) || !get.ended {
+		t.Fatalf("get span %+v", get)
+	}
+
+	if _, err := db.BatchPut(map[string][]byte{"a": []byte("1"), "b": []byte("2")}); err != nil {
+		t.Fatal(err)
+	}
+	if batch := tr.span(t, "amdb.BatchPut"); batch.attrs[attrBatchSize] != 2 || batch.attrs[attrRootPrefix] == nil {
+		t.Fatalf("batch span %+v", batch)
+	}
+	if err := db.Delete([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	tr.span(t, "amdb.Delete")
+}
+
+func TestNoTracerNoSpans(t *testing.T) {
+	db := openTestDB(t, nil)
+	ctx := context.WithValue(context.Background(), spanKey{}, "caller")
+	if got, span := db.startSpan(ctx, "amdb.Put"); span != nil || got != ctx {
+		t.Fatal("span created without a provider")
+	}
+	mustPut(t, db, "k", "v")
+}
+
+func TestSpansAttachToCallerContext(t *testing.T) {
+	tr := &recordingTracer{}
+	db := openTestDB(t, &Options{TracerProvider: tr})
+	ctx, _ := tr.StartSpan(context.Background(), "request")
+	request := tr.span(t, "request")
+
+	if _, err := db.PutContext(ctx, []byte("k"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.BatchPutContext(ctx, map[string][]byte{"a": []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CompactContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	it, err := db.NewRangeIteratorContext(ctx, nil, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it.Close()
+	if it, err = db.NewPrefixIteratorContext(ctx, []byte("a"), 0); err != nil {
+		t.Fatal(err)
+	}
+	it.Close()
+	for _, name := range []string{"amdb.Put", "amdb.BatchPut", "amdb.Compact", "amdb.NewRangeIterator"} {
+		if s := tr.span(t, name); s.parent != request || !s.ended {
+			t.Fatalf("%s span not a child of the caller's span: %+v", name, s)
+		}
+	}
+	prefix := tr.spans[len(tr.spans)-1]
+	if prefix.name != "amdb.NewRangeIterator" || prefix.parent != request {
+		t.Fatalf("prefix iterator span %+v", prefix)
+	}
+
+	// 没有context参数的方法从context.Background()开始
+	mustPut(t, db, "k", "v2")
+	if s := tr.spans[len(tr.spans)-1]; s.name != "amdb.Put" || s.parent != nil {
+		t.Fatalf("Put span %+v", s)
+	}
+}
@@ -0,0 +1,277 @@
+package amdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// HashAlgorithm Merkle树使用的哈希算法
+type HashAlgorithm int
+
+const (
+	// HashSHA256 SHA-256（引擎默认算法）
+	HashSHA256 HashAlgorithm = iota
+)
+
+// String 返回算法名称
+func (a HashAlgorithm) String() string {
+	switch a {
+	case HashSHA256:
+		return "sha256"
+	}
+	return fmt.Sprintf("HashAlgorithm(%d)", int(a))
+}
+
+// newHash 创建算法对应的哈希实例
+func (a HashAlgorithm) newHash() (hash.Hash, error) {
+	switch a {
+	case HashSHA256:
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm: %v", a)
+}
+
+// 节点类型，与引擎merkle_tree.py中的NodeType对应
+type nodeKind int
+
+const (
+	leafNode nodeKind = iota
+	extNode
+	branchNode
+)
+
+// trieNode 内存中的MPT节点
+// 结构与哈希规则与引擎保持一致：
+//   - 叶子节点：H("leaf:" + key + ":" + value)
+//   - 扩展节点：H("ext:" + nibble + ":" + childHash)，每个扩展节点只含一个nibble
+//   - 分支节点：H("branch:" + 按下标顺序拼接的非空子节点哈希)
+type trieNode struct {
+	kind     nodeKind
+	key      []byte
+	value    []byte
+	nibble   byte
+	child    *trieNode
+	children [16]*trieNode
+	hash     []byte
+}
+
+// kv 键值对
+type kv struct {
+	key   []byte
+	value []byte
+}
+
+// errKeysIndistinguishable 两个键补零后完全相同（如"a"与"a\x00"），引擎无法区分
+var errKeysIndistinguishable = errors.New("keys are indistinguishable in trie")
+
+// keyNibble 返回键在pos位置的nibble，超出键长度时视为0（与引擎一致）
+func keyNibble(key []byte, pos int) byte {
+	if pos >= len(key)*2 {
+		return 0
+	}
+	b := key[pos/2]
+	if pos%2 == 0 {
+		return b >> 4
+	}
+	return b & 0x0F
+}
+
+// leafHasher 返回键值对的叶子哈希，用于复用已知的叶子哈希而不重新哈希值
+type leafHasher func(item kv) ([]byte, error)
+
+// buildTrie 由键值对构建MPT，空集合返回nil
+func buildTrie(items []kv, algo HashAlgorithm) (*trieNode, error) {
+	return buildTrieWith(items, algo, nil)
+}
+
+// buildTrieWith 与buildTrie相同，leaf非nil时由其提供叶子哈希
+func buildTrieWith(items []kv, algo HashAlgorithm, leaf leafHasher) (*trieNode, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	maxNibbles := 0
+	for _, item := range items {
+		if n := len(item.key) * 2; n > maxNibbles {
+			maxNibbles = n
+		}
+	}
+	return buildNode(items, 0, maxNibbles, algo, leaf)
+}
+
+// buildNode 递归构建pos位置开始的子树
+func buildNode(items []kv, pos, maxNibbles int, algo HashAlgorithm, leaf leafHasher) (*trieNode, error) {
+	if len(items) == 1 {
+		node := &trieNode{kind: leafNode, key: items[0].key, value: items[0].value}
+		if leaf != nil {
+			var err error
+			node.hash, err = leaf(items[0])
+			return node, err
+		}
+		return node, node.computeHash(algo)
+	}
+	if pos >= maxNibbles {
+		return nil, errKeysIndistinguishable
+	}
+
+	var groups [16][]kv
+	distinct := 0
+	for _, item := range items {
+		n := keyNibble(item.key, pos)
+		if groups[n] == nil {
+			distinct++
+		}
+		groups[n] = append(groups[n], item)
+	}
+
+	if distinct == 1 {
+		for n, group := range groups {
+			if group == nil {
+				continue
+			}
+			child, err := buildNode(group, pos+1, maxNibbles, algo, leaf)
+			if err != nil {
+				return nil, err
+			}
+			node := &trieNode{kind: extNode, nibble: byte(n), child: child}
+			return node, node.computeHash(algo)
+		}
+	}
+
+	node := &trieNode{kind: branchNode}
+	for n, group := range groups {
+		if group == nil {
+			continue
+		}
+		child, err := buildNode(group, pos+1, maxNibbles, algo, leaf)
+		if err != nil {
+			return nil, err
+		}
+		node.children[n] = child
+	}
+	return node, node.computeHash(algo)
+}
+
+// computeHash 计算并缓存节点哈希
+func (n *trieNode) computeHash(algo HashAlgorithm) error {
+	h, err := algo.sum(n.content())
+	if err != nil {
+		return err
+	}
+	n.hash = h
+	return nil
+}
+
+// content 返回参与哈希计算的节点内容
+func (n *trieNode) content() []byte {
+	switch n.kind {
+	case leafNode:
+		return leafContent(n.key, n.value)
+	case extNode:
+		return extContent(n.nibble, n.child.hash)
+	}
+	var children [16][]byte
+	for i, child := range n.children {
+		if child != nil {
+			children[i] = child.hash
+		}
+	}
+	return branchContent(&children)
+}
+
+// sum 计算content的哈希
+func (a HashAlgorithm) sum(content []byte) ([]byte, error) {
+	h, err := a.newHash()
+	if err != nil {
+		return nil, err
+	}
+	h.Write(content)
+	return h.Sum(nil), nil
+}
+
+// leafContent 叶子节点哈希内容："leaf:" + key + ":" + value
+func leafContent(key, value []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("leaf:")
+	buf.Write(key)
+	buf.WriteByte(':')
+	buf.Write(value)
+	return buf.Bytes()
+}
+
+// extContent 扩展节点哈希内容："ext:" + nibble + ":" + childHash
+func extContent(nibble byte, childHash []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ext:")
+	buf.WriteByte(nibble)
+	buf.WriteByte(':')
+	buf.Write(childHash)
+	return buf.Bytes()
+}
+
+// branchContent 分支节点哈希内容："branch:" + 按下标顺序拼接的非空子节点哈希
+func branchContent(children *[16][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("branch:")
+	for _, h := range children {
+		buf.Write(h)
+	}
+	return buf.Bytes()
+}
+
+// path 查找key对应的叶子节点，并返回从根到叶子的证明路径
+// 键不在树中时返回nil
+func (n *trieNode) path(key []byte) (*trieNode, []ProofStep) {
+	var steps []ProofStep
+	node := n
+	for pos := 0; node != nil; pos++ {
+		switch node.kind {
+		case leafNode:
+			if !bytes.Equal(node.key, key) {
+				return nil, nil
+			}
+			return node, steps
+		case extNode:
+			if keyNibble(key, pos) != node.nibble {
+				return nil, nil
+			}
+			steps = append(steps, ProofStep{Nibble: node.nibble})
+			node = node.child
+		case branchNode:
+			nibble := keyNibble(key, pos)
+			step := ProofStep{Branch: true, Nibble: nibble}
+			for i, child := range node.children {
+				if child != nil && i != int(nibble) {
+					step.Siblings[i] = child.hash
+				}
+			}
+			steps = append(steps, step)
+			node = node.children[nibble]
+		}
+	}
+	return nil, nil
+}
+
+// ComputeRoot 在内存中构建与引擎相同的MPT并返回根哈希，不需要数据目录
+// 结果与最新状态为相同键值集合的数据库的GetRootHash一致，批量写入之后同样成立（见GetRootHash）。
+// 键值按存储形式比较：删除过的键以删除标记留在数据库的树中，哈希键模式、块存储等的键值也需先转换；
+// 空集合返回长度为0的空根
+func ComputeRoot(kvs map[string][]byte, algo HashAlgorithm) ([]byte, error) {
+	if _, err := algo.newHash(); err != nil {
+		return nil, err
+	}
+	items := make([]kv, 0, len(kvs))
+	for k, v := range kvs {
+		items = append(items, kv{key: []byte(k), value: v})
+	}
+	root, err := buildTrie(items, algo)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return []byte{}, nil
+	}
+	return root.hash, nil
+}
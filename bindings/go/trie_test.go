@@ -0,0 +1,90 @@
+package amdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestComputeRootEmpty(t *testing.T) {
+	root, err := ComputeRoot(nil, HashSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root == nil || len(root) != 0 {
+		t.Fatalf("empty root %x", root)
+	}
+	if _, err := ComputeRoot(nil, HashAlgorithm(7)); err == nil {
+		t.Fatal("unsupported algorithm accepted")
+	}
+}
+
+func TestComputeRootSingleKey(t *testing.T) {
+	root, err := ComputeRoot(map[string][]byte{"k": []byte("v")}, HashSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 单键的树只有一个叶子节点
+	leaf := &trieNode{kind: leafNode, key: []byte("k"), value: []byte("v")}
+	if err := leaf.computeHash(HashSHA256); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, leaf.hash) {
+		t.Fatalf("root %x, leaf hash %x", root, leaf.hash)
+	}
+}
+
+func TestComputeRootMatchesDatabase(t *testing.T) {
+	cases := map[string]map[string][]byte{
+		"single": {"k": []byte("v")},
+		"shared prefixes": {
+			"a": []byte("1"), "ab": []byte("2"), "abc": []byte("3"), "abd": []byte("4"), "b": []byte("5"),
+		},
+		"empty value": {"x": {}, "y": []byte("y")},
+	}
+	for name, kvs := range cases {
+		t.Run(name, func(t *testing.T) {
+			want, err := ComputeRoot(kvs, HashSHA256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 逐键写入时根哈希取自引擎
+			db := openTestDB(t, nil)
+			for _, k := range sortedKeys(kvs) {
+				if _, err := db.Put([]byte(k), kvs[k]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got, err := db.GetRootHash(); err != nil || !bytes.Equal(got, want) {
+				t.Fatalf("put: GetRootHash %x (%v), ComputeRoot %x", got, err, want)
+			}
+			// 批量写入之后根哈希由状态计算
+			batch := openTestDB(t, nil)
+			if _, err := batch.BatchPut(kvs); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := batch.GetRootHash(); err != nil || !bytes.Equal(got, want) {
+				t.Fatalf("batch: GetRootHash %x (%v), ComputeRoot %x", got, err, want)
+			}
+		})
+	}
+}
+
+func TestComputeRootManyKeys(t *testing.T) {
+	kvs := make(map[string][]byte, 1000)
+	for i := 0; i < 1000; i++ {
+		kvs[fmt.Sprintf("key-%04d", i)] = []byte(fmt.Sprint(i))
+	}
+	want, err := ComputeRoot(kvs, HashSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := openTestDB(t, nil)
+	root, err := db.BatchPut(kvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, want) {
+		t.Fatalf("BatchPut root %x, ComputeRoot %x", root, want)
+	}
+}
@@ -0,0 +1,149 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ReadTxn View中的只读事务，所有读取都针对View开始时的同一个数据库版本
+// ReadTxn只在fn执行期间有效，fn返回后其方法返回ErrViewClosed
+type ReadTxn struct {
+	view *VersionView
+}
+
+// View 在当前版本上打开只读事务并以其调用fn，返回fn的错误
+// 事务固定开始时的版本（见OpenVersion），fn中的读取不受同时进行的写入影响；fn返回后自动解除固定。
+// 空数据库上事务针对空状态，Get返回ErrNotFound，Iterate不产生条目。事务是fn内多次读取的写法简化，
+// 需要在fn之外持有版本时使用OpenVersion
+func (db *Database) View(fn func(tx *ReadTxn) error) error {
+	view, err := db.OpenVersion(0)
+	if errors.Is(err, ErrVersionNotFound) {
+		view, err = &VersionView{db: db}, nil
+	}
+	if err != nil {
+		return err
+	}
+	defer view.Close()
+	return fn(&ReadTxn{view: view})
+}
+
+// Version 返回事务读取的数据库版本，空数据库为0
+func (tx *ReadTxn) Version() uint32 {
+	return tx.view.Version()
+}
+
+// Get 读取键在事务版本时的值，键不存在或已删除时返回ErrNotFound
+func (tx *ReadTxn) Get(key []byte) ([]byte, error) {
+	if tx.view.version == 0 && !tx.view.isClosed() {
+		return nil, ErrNotFound
+	}
+	return tx.view.Get(key)
+}
+
+// Has 判断键在事务版本中是否存在（已删除的键视为不存在）
+func (tx *ReadTxn) Has(key []byte) (bool, error) {
+	if tx.view.version == 0 && !tx.view.isClosed() {
+		return false, nil
+	}
+	return tx.view.Has(key)
+}
+
+// Iterate 创建遍历事务版本中[start, end)范围的迭代器，nil表示不限制该方向
+func (tx *ReadTxn) Iterate(start, end []byte) (*Iterator, error) {
+	if tx.view.version == 0 && !tx.view.isClosed() {
+		return tx.view.db.newIterator(nil, 0), nil
+	}
+	return tx.view.Iterate(start, end)
+}
+
+// WriteTxn Update中的写事务，暂存的写入在fn成功返回时作为一次批量写入原子提交
+// 事务内的Get能读到本事务暂存的写入。WriteTxn只在fn执行期间有效，fn返回后其方法返回ErrClosed
+type WriteTxn struct {
+	db     *Database
+	batch  *WriteBatch
+	staged map[string]batchOp // 按原始形式的键索引的最后一次暂存操作
+	done   bool
+}
+
+// Update 以写事务调用fn：fn返回nil时原子提交暂存的全部写入并返回提交后的根哈希，
+// 返回错误（或panic）时丢弃暂存的写入，数据库保持不变，返回fn的错误
+// fn执行期间持有写锁，其他写入等待事务结束，因此事务内的读取与提交之间没有其他写入；
+// fn中不能经由db写入（Put、Delete、BatchPut等会死锁），应使用tx。事务中没有写入时不产生新版本，返回当前根哈希。
+// 根哈希即RootHashAtVersion(0)，PlainMode下为nil
+func (db *Database) Update(fn func(tx *WriteTxn) error) (root []byte, err error) {
+	if db.isClosing() {
+		return nil, ErrClosed
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	if err := db.checkSealed(); err != nil {
+		return nil, err
+	}
+	tx := &WriteTxn{db: db, batch: NewWriteBatch(), staged: make(map[string]batchOp)}
+	defer func() { tx.done = true }()
+	if err := fn(tx); err != nil {
+		return nil, err
+	}
+	tx.done = true
+	if tx.batch.Len() == 0 {
+		return db.versionRoot(0)
+	}
+	if _, err := db.write(tx.batch); err != nil {
+		return nil, err
+	}
+	// 引擎的批量写入不更新其Merkle树，根哈希由提交后的状态计算
+	return db.versionRoot(0)
+}
+
+// Put 暂存一次写入，键值被复制，调用方返回后可复用缓冲区
+func (tx *WriteTxn) Put(key, value []byte) error {
+	return tx.stage(batchOp{key: bytes.Clone(key), value: bytes.Clone(value)})
+}
+
+// Delete 暂存一次删除
+func (tx *WriteTxn) Delete(key []byte) error {
+	return tx.stage(batchOp{key: bytes.Clone(key), delete: true})
+}
+
+// stage 校验键并暂存操作
+func (tx *WriteTxn) stage(op batchOp) error {
+	if tx.done {
+		return ErrClosed
+	}
+	if len(op.key) == 0 {
+		return ErrInvalidArg
+	}
+	if err := tx.db.checkWriteKey(tx.db.storedKey(op.key)); err != nil {
+		return err
+	}
+	if op.delete {
+		tx.batch.Delete(op.key)
+	} else {
+		tx.batch.Put(op.key, op.value)
+	}
+	tx.staged[string(op.key)] = op
+	return nil
+}
+
+// Get 读取键的值：本事务暂存过该键时返回暂存的值（暂存删除时返回ErrNotFound），否则读取当前版本
+func (tx *WriteTxn) Get(key []byte) ([]byte, error) {
+	if tx.done {
+		return nil, ErrClosed
+	}
+	if op, ok := tx.staged[string(key)]; ok {
+		if op.delete {
+			return nil, ErrNotFound
+		}
+		return bytes.Clone(op.value), nil
+	}
+	return tx.db.Get(key, 0)
+}
+
+// Has 判断键在本事务看来是否存在（已删除的键视为不存在）
+func (tx *WriteTxn) Has(key []byte) (bool, error) {
+	_, err := tx.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,184 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestViewUnaffectedByConcurrentPut(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+
+	var escaped *ReadTxn
+	err := db.View(func(tx *ReadTxn) error {
+		escaped = tx
+		done := make(chan error)
+		go func() {
+			if _, err := db.Put([]byte("a"), []byte("changed")); err != nil {
+				done <- err
+				return
+			}
+			_, err := db.Put([]byte("c"), []byte("3"))
+			done <- err
+		}()
+		if err := <-done; err != nil {
+			return err
+		}
+		if mustGet(t, db, "a", 0) != "changed" {
+			t.Fatal("concurrent Put not committed")
+		}
+
+		if got, err := tx.Get([]byte("a")); err != nil || string(got) != "1" {
+			return fmt.Errorf("Get(a) in View: %q, %v", got, err)
+		}
+		if has, err := tx.Has([]byte("c")); err != nil || has {
+			return fmt.Errorf("Has(c) in View: %v, %v", has, err)
+		}
+		it, err := tx.Iterate(nil, nil)
+		if err != nil {
+			return err
+		}
+		if got := collect(t, it); fmt.Sprint(got) != "[a=1 b=2]" {
+			return fmt.Errorf("Iterate in View: %v", got)
+		}
+		if tx.Version() != 2 {
+			return fmt.Errorf("View version %d", tx.Version())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := escaped.Get([]byte("a")); !errors.Is(err, ErrViewClosed) {
+		t.Fatalf("Get after View returned: %v", err)
+	}
+	// View返回后不再固定版本
+	if _, err := db.Rewind(1); err != nil {
+		t.Fatalf("version still pinned after View: %v", err)
+	}
+
+	sentinel := errors.New("stop")
+	if err := db.View(func(*ReadTxn) error { return sentinel }); err != sentinel {
+		t.Fatalf("View did not return fn's error: %v", err)
+	}
+}
+
+func TestViewOnEmptyDatabase(t *testing.T) {
+	db := openTestDB(t, nil)
+	err := db.View(func(tx *ReadTxn) error {
+		if _, err := tx.Get([]byte("a")); !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("Get: %v", err)
+		}
+		if has, err := tx.Has([]byte("a")); err != nil || has {
+			return fmt.Errorf("Has: %v, %v", has, err)
+		}
+		it, err := tx.Iterate(nil, nil)
+		if err != nil {
+			return err
+		}
+		if it.Next() {
+			return errors.New("iterator on the empty state produced an entry")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateCommitsOrRollsBack(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "2")
+	before := rootOf(t, db)
+
+	sentinel := errors.New("abort")
+	_, err := db.Update(func(tx *WriteTxn) error {
+		if err := tx.Put([]byte("a"), []byte("staged")); err != nil {
+			return err
+		}
+		if err := tx.Delete([]byte("b")); err != nil {
+			return err
+		}
+		return sentinel
+	})
+	if err != sentinel {
+		t.Fatalf("Update returned %v, want fn's error", err)
+	}
+	if !bytes.Equal(rootOf(t, db), before) || mustGet(t, db, "a", 0) != "1" || mustGet(t, db, "b", 0) != "2" {
+		t.Fatal("failed Update changed the database")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic in fn not propagated")
+			}
+		}()
+		db.Update(func(tx *WriteTxn) error {
+			tx.Put([]byte("a"), []byte("panicked"))
+			panic("boom")
+		})
+	}()
+	if mustGet(t, db, "a", 0) != "1" {
+		t.Fatal("panicking Update changed the database")
+	}
+
+	var escaped *WriteTxn
+	root, err := db.Update(func(tx *WriteTxn) error {
+		escaped = tx
+		if err := tx.Put([]byte("a"), []byte("new")); err != nil {
+			return err
+		}
+		if err := tx.Delete([]byte("b")); err != nil {
+			return err
+		}
+		if err := tx.Put([]byte("c"), []byte("3")); err != nil {
+			return err
+		}
+		// 事务内能读到本事务暂存的写入
+		if got, err := tx.Get([]byte("a")); err != nil || string(got) != "new" {
+			return fmt.Errorf("Get(a) in Update: %q, %v", got, err)
+		}
+		if has, err := tx.Has([]byte("b")); err != nil || has {
+			return fmt.Errorf("Has(b) in Update: %v, %v", has, err)
+		}
+		if _, err := db.Get([]byte("c"), 0); !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("staged write visible outside the transaction: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, stateRoot(t, db)) {
+		t.Fatal("Update did not return the post-commit root")
+	}
+	history, err := db.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := history[len(history)-1]; len(history) != 3 || last.KeysChanged != 3 {
+		t.Fatalf("staged writes not committed as one version: %+v", history)
+	}
+	if mustGet(t, db, "a", 0) != "new" || mustGet(t, db, "c", 0) != "3" {
+		t.Fatal("committed writes missing")
+	}
+	if _, err := db.Get([]byte("b"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted key: %v", err)
+	}
+	if err := escaped.Put([]byte("d"), nil); err != ErrClosed {
+		t.Fatalf("Put after Update returned: %v", err)
+	}
+
+	// 没有写入的事务不产生新版本
+	if root, err := db.Update(func(*WriteTxn) error { return nil }); err != nil || !bytes.Equal(root, stateRoot(t, db)) {
+		t.Fatalf("empty Update: %v", err)
+	}
+	if v, err := db.CurrentVersion(); err != nil || v != 3 {
+		t.Fatalf("empty Update created version %d, %v", v, err)
+	}
+}
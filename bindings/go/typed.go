@@ -0,0 +1,59 @@
+package amdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrCodec 值与Go类型之间的编解码失败，具体错误见*CodecError
+var ErrCodec = errors.New("value codec error")
+
+// CodecError PutJSON或GetJSON在编码或解码值时失败，与数据库本身返回的错误相区分
+type CodecError struct {
+	// Key 出错的键
+	Key []byte
+	// Op 失败的步骤："marshal"或"unmarshal"
+	Op string
+	// Err encoding/json返回的具体错误
+	Err error
+}
+
+func (e *CodecError) Error() string {
+	return fmt.Sprintf("%v: %s key %q: %v", ErrCodec, e.Op, e.Key, e.Err)
+}
+
+// Is 使errors.Is(err, ErrCodec)成立
+func (e *CodecError) Is(target error) bool {
+	return target == ErrCodec
+}
+
+// Unwrap 返回encoding/json的具体错误
+func (e *CodecError) Unwrap() error {
+	return e.Err
+}
+
+// PutJSON 将v编码为JSON后写入key，返回新的根哈希
+// 编码失败时返回*CodecError且不写入；写入失败时原样返回Put的错误
+func PutJSON[T any](db *Database, key []byte, v T) ([]byte, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, &CodecError{Key: key, Op: "marshal", Err: err}
+	}
+	return db.Put(key, value)
+}
+
+// GetJSON 读取版本version（0表示当前版本）中key的值并按JSON解码为T
+// 读取失败时返回T的零值和Get的原始错误（如ErrNotFound）；解码失败时返回*CodecError
+func GetJSON[T any](db *Database, key []byte, version uint32) (T, error) {
+	var v T
+	value, err := db.Get(key, version)
+	if err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(value, &v); err != nil {
+		var zero T
+		return zero, &CodecError{Key: key, Op: "unmarshal", Err: err}
+	}
+	return v, nil
+}
@@ -0,0 +1,55 @@
+package amdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type typedRecord struct {
+	Name  string            `json:"name"`
+	Count int               `json:"count"`
+	Tags  map[string]string `json:"tags"`
+}
+
+func TestPutGetJSON(t *testing.T) {
+	db := openTestDB(t, nil)
+	want := typedRecord{Name: "tenant", Count: 3, Tags: map[string]string{"tier": "gold"}}
+	root, err := PutJSON(db, []byte("rec"), want)
+	if err != nil || len(root) == 0 {
+		t.Fatalf("PutJSON: %x, %v", root, err)
+	}
+	got, err := GetJSON[typedRecord](db, []byte("rec"), 0)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetJSON = %+v, %v; want %+v", got, err, want)
+	}
+	if raw := mustGet(t, db, "rec", 0); raw != `{"name":"tenant","count":3,"tags":{"tier":"gold"}}` {
+		t.Fatalf("stored %s", raw)
+	}
+}
+
+func TestJSONErrorsDistinguishable(t *testing.T) {
+	db := openTestDB(t, nil)
+
+	_, err := PutJSON(db, []byte("ch"), make(chan int))
+	var ce *CodecError
+	if !errors.Is(err, ErrCodec) || !errors.As(err, &ce) || ce.Op != "marshal" {
+		t.Fatalf("unencodable value: %v", err)
+	}
+	if _, err := db.Get([]byte("ch"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatal("failed PutJSON wrote a value")
+	}
+
+	if _, err := GetJSON[typedRecord](db, []byte("missing"), 0); !errors.Is(err, ErrNotFound) || errors.Is(err, ErrCodec) {
+		t.Fatalf("missing key: %v", err)
+	}
+
+	mustPut(t, db, "bad", "not json")
+	got, err := GetJSON[typedRecord](db, []byte("bad"), 0)
+	if !errors.As(err, &ce) || ce.Op != "unmarshal" || string(ce.Key) != "bad" || errors.Is(err, ErrNotFound) {
+		t.Fatalf("malformed value: %v", err)
+	}
+	if !reflect.DeepEqual(got, typedRecord{}) {
+		t.Fatalf("decode failure returned %+v, want zero value", got)
+	}
+}
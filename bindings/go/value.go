@@ -0,0 +1,41 @@
+package amdb
+
+// Value 带元数据的读取结果
+// C层amdb_result_t只携带状态码和值数据，版本与哈希由绑定层根据版本时间线和值计算
+type Value struct {
+	// Data 值数据
+	Data []byte
+	// Version 写入该值的数据库版本
+	Version uint32
+	// Hash 该键值对在Merkle树中的叶子哈希，与证明中的叶子哈希一致
+	// （哈希键模式下按存储形式计算）
+	Hash []byte
+}
+
+// GetValue 读取键在数据库版本version（0表示最新版本）时的值及其元数据
+func (db *Database) GetValue(key []byte, version uint32) (*Value, error) {
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = tl.current()
+	}
+	if version > tl.current() {
+		return nil, ErrVersionNotFound
+	}
+	writtenAt, ok := tl.writtenAt(db.storedKey(key), version)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	data, err := db.Get(key, version)
+	if err != nil {
+		return nil, err
+	}
+	return &Value{
+		Data:    data,
+		Version: writtenAt,
+		Hash:    LeafHash(db.storedEntry(key, data)),
+	}, nil
+}
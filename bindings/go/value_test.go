@@ -0,0 +1,54 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestGetValueMetadata(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "k", "1")
+	mustPut(t, db, "other", "x")
+	mustPut(t, db, "k", "2")
+
+	v, err := db.GetValue([]byte("k"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v.Data) != "2" || v.Version != 3 || !bytes.Equal(v.Hash, LeafHash([]byte("k"), []byte("2"))) {
+		t.Fatalf("latest: %+v", v)
+	}
+	proof, err := db.GetProofOnly([]byte("k"), 0)
+	if err != nil || !bytes.Equal(proof.LeafHash, v.Hash) {
+		t.Fatalf("value hash %x, proof leaf hash %x (%v)", v.Hash, proof.LeafHash, err)
+	}
+
+	// 版本2时k的值仍由版本1写入
+	v, err = db.GetValue([]byte("k"), 2)
+	if err != nil || string(v.Data) != "1" || v.Version != 1 || !bytes.Equal(v.Hash, LeafHash([]byte("k"), []byte("1"))) {
+		t.Fatalf("v2: %+v, %v", v, err)
+	}
+	if _, err := db.GetValue([]byte("missing"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing: %v", err)
+	}
+	if _, err := db.GetValue([]byte("k"), 4); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("future version: %v", err)
+	}
+}
+
+func TestGetValueHashKeys(t *testing.T) {
+	db := openTestDB(t, &Options{HashKeys: true})
+	mustPut(t, db, "k", "v")
+	v, err := db.GetValue([]byte("k"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := db.GetWithProof([]byte("k"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v.Data) != "v" || !bytes.Equal(v.Hash, LeafHash(proof.Key, proof.Value)) {
+		t.Fatalf("hash-keys value %+v", v)
+	}
+}
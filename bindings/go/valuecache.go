@@ -0,0 +1,218 @@
+package amdb
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// valueCache 按LRU淘汰的最新版本值缓存，键为存储形式，值为用户形式
+// 写入后对应条目失效。读取在C层调用前记录代数gen，写入使gen递增，
+// 读取结束时gen已变化则不缓存，避免与并发写入交错时缓存旧值。所有方法对nil接收者安全
+// 固定（Pin）的键不在LRU链表中、不占用容量，也不会被淘汰，写入时由update就地更新
+type valueCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // 元素为*valueCacheEntry，最近使用的在前
+	pinned  map[string]*pinnedValue
+	gen     uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// valueCacheEntry LRU链表中的条目
+type valueCacheEntry struct {
+	key   string
+	value []byte
+}
+
+// pinnedValue 固定的键的缓存值，loaded为false表示尚未缓存（键不存在、已删除或Rewind之后）
+type pinnedValue struct {
+	value  []byte
+	loaded bool
+}
+
+// newValueCache 创建容量为capacity的值缓存
+func newValueCache(capacity int) *valueCache {
+	return &valueCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element, capacity),
+		lru:      list.New(),
+		pinned:   make(map[string]*pinnedValue),
+	}
+}
+
+// get 查找缓存的值，返回的切片只读，调用方须复制后再交给用户
+func (c *valueCache) get(key []byte) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pinned[string(key)]; ok && p.loaded {
+		c.hits.Add(1)
+		return p.value, true
+	}
+	elem, ok := c.entries[string(key)]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*valueCacheEntry).value, true
+}
+
+// generation 返回当前代数，读取前调用并传给put
+func (c *valueCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// put 缓存value的副本，gen与当前代数不同（期间有写入）时不缓存，超出容量时淘汰最久未使用的条目
+func (c *valueCache) put(key, value []byte, gen uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	k := string(key)
+	if p, ok := c.pinned[k]; ok {
+		p.value, p.loaded = append([]byte{}, value...), true
+		return
+	}
+	if elem, ok := c.entries[k]; ok {
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[k] = c.lru.PushFront(&valueCacheEntry{key: k, value: append([]byte{}, value...)})
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*valueCacheEntry).key)
+	}
+}
+
+// full 返回缓存是否已满
+func (c *valueCache) full() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len() >= c.capacity
+}
+
+// len 返回缓存的条目数
+func (c *valueCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// invalidate 写入后调用，移除keys对应的条目并递增代数
+func (c *valueCache) invalidate(keys ...[]byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, k := range keys {
+		if p, ok := c.pinned[string(k)]; ok {
+			p.value, p.loaded = nil, false
+		}
+		if elem, ok := c.entries[string(k)]; ok {
+			c.lru.Remove(elem)
+			delete(c.entries, string(k))
+		}
+	}
+}
+
+// reset 移除全部条目并递增代数，用于Rewind替换数据库内容之后
+func (c *valueCache) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.entries = make(map[string]*list.Element, c.capacity)
+	c.lru.Init()
+	for _, p := range c.pinned {
+		p.value, p.loaded = nil, false
+	}
+}
+
+// update 写入成功后就地更新固定的键的缓存值（调用方需持有wmu，在invalidate之后调用），未固定的键不做任何事
+func (c *valueCache) update(key, value []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pinned[string(key)]; ok {
+		p.value, p.loaded = append([]byte{}, value...), true
+	}
+}
+
+// pin 固定key，已在LRU链表中的条目移出链表并保留其值
+func (c *valueCache) pin(key []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := string(key)
+	if _, ok := c.pinned[k]; ok {
+		return
+	}
+	p := &pinnedValue{}
+	if elem, ok := c.entries[k]; ok {
+		p.value, p.loaded = elem.Value.(*valueCacheEntry).value, true
+		c.lru.Remove(elem)
+		delete(c.entries, k)
+	}
+	c.pinned[k] = p
+}
+
+// unpin 取消固定key，已缓存的值作为最近使用的条目放回LRU链表，超出容量时淘汰最久未使用的条目
+func (c *valueCache) unpin(key []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := string(key)
+	p, ok := c.pinned[k]
+	if !ok {
+		return
+	}
+	delete(c.pinned, k)
+	if !p.loaded {
+		return
+	}
+	c.entries[k] = c.lru.PushFront(&valueCacheEntry{key: k, value: p.value})
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*valueCacheEntry).key)
+	}
+}
+
+// pinnedLen 返回固定的键数
+func (c *valueCache) pinnedLen() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pinned)
+}
@@ -0,0 +1,147 @@
+package amdb
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+)
+
+// leafHashKey 叶子哈希缓存键：存储形式的键及写入该值的数据库版本
+// 同一个键在同一版本写入的值不会再变化，因此条目无需失效
+type leafHashKey struct {
+	key     string
+	written uint32
+}
+
+// leafHashEntry LRU链表中的条目
+type leafHashEntry struct {
+	key     leafHashKey
+	hash    []byte
+	deleted bool // 该版本写入的是删除标记
+}
+
+// leafHashCache 按LRU淘汰的叶子哈希缓存，所有方法对nil接收者安全
+type leafHashCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[leafHashKey]*list.Element
+	lru     *list.List // 元素为*leafHashEntry，最近使用的在前
+}
+
+// newLeafHashCache 创建容量为capacity的叶子哈希缓存
+func newLeafHashCache(capacity int) *leafHashCache {
+	return &leafHashCache{
+		capacity: capacity,
+		entries:  make(map[leafHashKey]*list.Element, capacity),
+		lru:      list.New(),
+	}
+}
+
+// get 查找缓存的叶子哈希，返回的哈希只读
+func (c *leafHashCache) get(k leafHashKey) (*leafHashEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*leafHashEntry), true
+}
+
+// put 缓存叶子哈希，超出容量时淘汰最久未使用的条目
+func (c *leafHashCache) put(k leafHashKey, hash []byte, deleted bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[k]; ok {
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[k] = c.lru.PushFront(&leafHashEntry{key: k, hash: hash, deleted: deleted})
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*leafHashEntry).key)
+	}
+}
+
+// discardAfter 移除写入版本大于version的条目，仅在Rewind丢弃这些版本后调用
+func (c *leafHashCache) discardAfter(version uint32) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, elem := range c.entries {
+		if k.written > version {
+			c.lru.Remove(elem)
+			delete(c.entries, k)
+		}
+	}
+}
+
+// leafHasherAt 返回为版本version（具体版本号）构建树时复用缓存叶子哈希的leafHasher
+// 未启用Options.ValueHashEntries时返回nil
+func (db *Database) leafHasherAt(tl *timeline, version uint32) leafHasher {
+	if db.leafHashes == nil {
+		return nil
+	}
+	return func(item kv) ([]byte, error) {
+		written, ok := tl.writtenAt(item.key, version)
+		if !ok {
+			return LeafHash(item.key, item.value), nil
+		}
+		k := leafHashKey{key: string(item.key), written: written}
+		if entry, ok := db.leafHashes.get(k); ok {
+			return entry.hash, nil
+		}
+		h := LeafHash(item.key, item.value)
+		db.leafHashes.put(k, h, isDeleted(item.value))
+		return h, nil
+	}
+}
+
+// ValueHash 返回键在数据库版本version（0表示最新版本）时的值所对应的叶子哈希，即LeafHash(key, value)
+// 哈希键模式下按存储形式计算，与证明中的叶子一致。键不存在（或已删除）时返回ErrNotFound。
+// 启用Options.ValueHashEntries后，已计算过的叶子哈希直接从缓存返回而不读取、不重新哈希值
+func (db *Database) ValueHash(key []byte, version uint32) ([]byte, error) {
+	tl, err := db.timeline()
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = tl.current()
+	}
+	if version > tl.current() {
+		return nil, ErrVersionNotFound
+	}
+	stored := db.storedKey(key)
+	written, ok := tl.writtenAt(stored, version)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	k := leafHashKey{key: string(stored), written: written}
+	if entry, ok := db.leafHashes.get(k); ok {
+		if entry.deleted {
+			return nil, ErrNotFound
+		}
+		return bytes.Clone(entry.hash), nil
+	}
+
+	value, err := db.Get(key, version)
+	if err != nil {
+		return nil, err
+	}
+	storedKey, storedValue := db.storedEntry(key, value)
+	h := LeafHash(storedKey, storedValue)
+	db.ReleaseValue(value)
+	db.leafHashes.put(k, h, false)
+	return bytes.Clone(h), nil
+}
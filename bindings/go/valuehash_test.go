@@ -0,0 +1,63 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestValueHash(t *testing.T) {
+	cached := openTestDB(t, &Options{ValueHashEntries: 100})
+	plain := openTestDB(t, nil)
+	for _, db := range []*Database{cached, plain} {
+		mustPut(t, db, "a", "1")
+		mustPut(t, db, "b", "large value")
+		mustPut(t, db, "a", "2")
+		if err := db.Delete([]byte("b")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		key, value string
+		version    uint32
+	}{{"a", "1", 1}, {"a", "1", 2}, {"b", "large value", 3}, {"a", "2", 0}} {
+		want := LeafHash([]byte(tc.key), []byte(tc.value))
+		for i := 0; i < 2; i++ { // 第二次由缓存返回
+			got, err := cached.ValueHash([]byte(tc.key), tc.version)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Fatalf("ValueHash(%s@%d) = %x, %v; want %x", tc.key, tc.version, got, err, want)
+			}
+		}
+		if got, err := plain.ValueHash([]byte(tc.key), tc.version); err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("uncached ValueHash(%s@%d) = %x, %v", tc.key, tc.version, got, err)
+		}
+	}
+	for _, v := range []uint32{0, 4} {
+		if _, err := cached.ValueHash([]byte("b"), v); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("deleted key at %d: %v", v, err)
+		}
+	}
+	if _, err := cached.ValueHash([]byte("a"), 9); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("future version: %v", err)
+	}
+
+	// 复用缓存的叶子哈希构建的树与直接计算的一致，证明仍能校验
+	for v := uint32(1); v <= 4; v++ {
+		want, err := plain.RootHashAtVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := cached.RootHashAtVersion(v)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("version %d root %x, want %x (%v)", v, got, want, err)
+		}
+	}
+	proof, err := cached.GetWithProof([]byte("a"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyProof(rootOf(t, cached), []byte("a"), []byte("2"), proof) {
+		t.Fatal("proof built from cached leaf hashes does not verify")
+	}
+}
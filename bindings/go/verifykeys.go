@@ -0,0 +1,64 @@
+package amdb
+
+import (
+	"context"
+	"errors"
+)
+
+// VerifyKeys 抽查多个键：为每个键读取当前值并生成包含证明，针对GetRootHash返回的根并行校验
+// 结果按原始键索引，值被篡改、与根不一致或键不存在时为false；比完整校验轻量，适合迁移后的自检。
+// 证明取自当前版本的键值重建的树，读取的值与证明承诺的值或引擎维护的根任一不符都会使结果为false。
+// ctx取消时停止并返回ctx.Err()
+func (db *Database) VerifyKeys(ctx context.Context, keys [][]byte) (map[string]bool, error) {
+	root, err := db.GetRootHash()
+	if err != nil {
+		return nil, err
+	}
+	current, err := db.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	var trie *trieNode
+	if current > 0 {
+		if trie, err = db.trieAt(current); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]ProofEntry, len(keys))
+	for i, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		value, err := db.Get(key, current)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidArg) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		storedKey, storedValue := db.storedEntry(key, value)
+		entries[i] = ProofEntry{Key: storedKey, Value: storedValue}
+		if trie != nil {
+			if leaf, steps := trie.path(storedKey); leaf != nil {
+				entries[i].Proof = &MerkleProof{Key: leaf.key, Value: leaf.value, Steps: steps, Root: trie.hash}
+			}
+		}
+	}
+
+	results := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		results[string(key)] = false
+	}
+	if len(root) == 0 || len(keys) == 0 {
+		return results, nil
+	}
+	valid, err := VerifyProofs(root, entries)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		results[string(key)] = valid[i]
+	}
+	return results, nil
+}
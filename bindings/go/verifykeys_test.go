@@ -0,0 +1,55 @@
+package amdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestVerifyKeys(t *testing.T) {
+	db := openTestDB(t, nil)
+	for _, k := range []string{"a", "b", "c"} {
+		mustPut(t, db, k, "old")
+	}
+	if _, err := db.BatchPut(map[string][]byte{"a": []byte("new"), "b": []byte("new")}); err != nil {
+		t.Fatal(err)
+	}
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("missing")}
+
+	results, err := db.VerifyKeys(context.Background(), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]bool{"a": true, "b": true, "c": true, "missing": false} {
+		if results[k] != want {
+			t.Fatalf("%s: %v, want %v", k, results[k], want)
+		}
+	}
+
+	// 模拟篡改：使缓存的时间线将b的最新版本解析到旧的键内版本，读取得到的值与状态中的值不再一致
+	tl, err := db.timeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := tl.keys["b"]
+	history[len(history)-1].keyVersion = history[0].keyVersion
+	if got := mustGet(t, db, "b", tl.current()); got != "old" {
+		t.Fatalf("tampering not in effect: b = %q", got)
+	}
+
+	results, err = db.VerifyKeys(context.Background(), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]bool{"a": true, "b": false, "c": true, "missing": false} {
+		if results[k] != want {
+			t.Fatalf("after tampering %s: %v, want %v", k, results[k], want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.VerifyKeys(ctx, keys); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package amdb
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrViewClosed 版本视图已关闭
+	ErrViewClosed = errors.New("version view closed")
+	// ErrVersionPinned 目标版本之后的版本仍被打开的版本视图固定
+	ErrVersionPinned = errors.New("version is pinned by an open view")
+)
+
+// VersionView 固定在某个数据库版本的只读视图，用于跨多个历史版本的并发查询
+// 与Snapshot不同，视图不把该版本读入内存，每次读取都按固定的版本号访问引擎，创建开销很小；
+// 多个视图之间以及与写入之间互不影响，可以在多个goroutine中并发使用。
+// 视图打开期间该版本保留：Rewind不能丢弃它，返回ErrVersionPinned。不再使用时应调用Close
+type VersionView struct {
+	db      *Database
+	version uint32
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// versionPins 打开的版本视图固定的版本及其计数
+type versionPins struct {
+	mu     sync.Mutex
+	counts map[uint32]int
+}
+
+// OpenVersion 打开数据库版本version（0表示当前版本）的只读视图
+// 版本不存在（包括空数据库）时返回ErrVersionNotFound
+func (db *Database) OpenVersion(version uint32) (*VersionView, error) {
+	// 持有写锁使校验与固定不会与Rewind交错
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	current, err := db.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = current
+	}
+	if version == 0 || version > current {
+		return nil, ErrVersionNotFound
+	}
+	db.pins.add(version)
+	return &VersionView{db: db, version: version}, nil
+}
+
+// Version 返回视图固定的数据库版本
+func (v *VersionView) Version() uint32 {
+	return v.version
+}
+
+// Get 读取键在视图版本时的值，键不存在或已删除时返回ErrNotFound
+func (v *VersionView) Get(key []byte) ([]byte, error) {
+	if v.isClosed() {
+		return nil, ErrViewClosed
+	}
+	return v.db.Get(key, v.version)
+}
+
+// Has 判断键在视图版本中是否存在（已删除的键视为不存在）
+func (v *VersionView) Has(key []byte) (bool, error) {
+	_, err := v.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Iterate 创建遍历视图版本中[start, end)范围的迭代器，nil表示不限制该方向
+// 迭代器创建时读取该范围，关闭视图不影响已创建的迭代器
+func (v *VersionView) Iterate(start, end []byte) (*Iterator, error) {
+	if v.isClosed() {
+		return nil, ErrViewClosed
+	}
+	return v.db.NewRangeIterator(start, end, v.version)
+}
+
+// Close 关闭视图并解除对版本的固定，重复调用返回nil
+func (v *VersionView) Close() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.closed {
+		v.closed = true
+		// View在空数据库上使用的版本0视图不固定版本
+		if v.version > 0 {
+			v.db.pins.remove(v.version)
+		}
+	}
+	return nil
+}
+
+// isClosed 报告视图是否已关闭
+func (v *VersionView) isClosed() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.closed
+}
+
+// add 固定版本version
+func (p *versionPins) add(version uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.counts == nil {
+		p.counts = make(map[uint32]int)
+	}
+	p.counts[version]++
+}
+
+// remove 解除一次对版本version的固定
+func (p *versionPins) remove(version uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.counts[version]--; p.counts[version] <= 0 {
+		delete(p.counts, version)
+	}
+}
+
+// pinnedAfter 报告是否有大于version的版本被固定
+func (p *versionPins) pinnedAfter(version uint32) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for v := range p.counts {
+		if v > version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package amdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestVersionViewsConcurrent(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "k", "v1")
+	mustPut(t, db, "only2", "x")
+	mustPut(t, db, "k", "v3")
+
+	views := make([]*VersionView, 3)
+	for i := range views {
+		view, err := db.OpenVersion(uint32(i + 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer view.Close()
+		views[i] = view
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	// 视图读取与新的写入并发进行
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			if _, err := db.Put([]byte("k"), []byte(fmt.Sprintf("live%d", i))); err != nil {
+				errs <- err
+			}
+		}
+	}()
+	want := []string{"v1", "v1", "v3"}
+	for i, view := range views {
+		wg.Add(1)
+		go func(view *VersionView, want string, hasOnly2 bool) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				got, err := view.Get([]byte("k"))
+				if err != nil || string(got) != want {
+					errs <- fmt.Errorf("version %d: %q, %v", view.Version(), got, err)
+					return
+				}
+				if has, err := view.Has([]byte("only2")); err != nil || has != hasOnly2 {
+					errs <- fmt.Errorf("version %d: Has(only2) = %v, %v", view.Version(), has, err)
+					return
+				}
+			}
+		}(view, want[i], i > 0)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	it, err := views[1].Iterate(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, it); fmt.Sprint(got) != "[k=v1 only2=x]" {
+		t.Fatalf("iterate version 2: %v", got)
+	}
+}
+
+func TestVersionViewPinsVersion(t *testing.T) {
+	db := openTestDB(t, nil)
+	if _, err := db.OpenVersion(0); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("empty database: %v", err)
+	}
+	mustPut(t, db, "k", "v1")
+	mustPut(t, db, "k", "v2")
+	if _, err := db.OpenVersion(3); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("future version: %v", err)
+	}
+
+	view, err := db.OpenVersion(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Version() != 2 {
+		t.Fatalf("version 0 opened %d", view.Version())
+	}
+	if _, err := db.Rewind(1); !errors.Is(err, ErrVersionPinned) {
+		t.Fatalf("rewind past an open view: %v", err)
+	}
+	if err := view.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := view.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	if _, err := view.Get([]byte("k")); !errors.Is(err, ErrViewClosed) {
+		t.Fatalf("Get after Close: %v", err)
+	}
+	if _, err := view.Iterate(nil, nil); !errors.Is(err, ErrViewClosed) {
+		t.Fatalf("Iterate after Close: %v", err)
+	}
+	if _, err := db.Rewind(1); err != nil {
+		t.Fatalf("rewind after closing the view: %v", err)
+	}
+}
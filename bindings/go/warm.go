@@ -0,0 +1,73 @@
+package amdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+// Warm 将最新版本中键前缀为prefix（nil表示全部键）的值预先读入值缓存，适合在启动后为热点命名空间调用
+// 缓存填满即停止，不会淘汰已缓存的条目；未配置Options.ValueCacheEntries时直接返回nil。
+// 每读取一个键检查一次ctx，取消时返回ctx.Err()，已读入的条目保留在缓存中。
+// 哈希键模式下prefix匹配原始键，需要读取全部存活键值后再筛选
+func (db *Database) Warm(ctx context.Context, prefix []byte) error {
+	if db.values == nil {
+		return nil
+	}
+	if db.hashKeys {
+		return db.warmHashed(ctx, prefix)
+	}
+	keys, err := db.liveKeysAt(0)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if db.values.full() {
+			return nil
+		}
+		if !bytes.HasPrefix(key, prefix) {
+			continue
+		}
+		value, err := db.Get(key, 0)
+		if errors.Is(err, ErrNotFound) {
+			// 读取键列表后被删除
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		db.ReleaseValue(value)
+	}
+	return nil
+}
+
+// warmHashed 哈希键模式下的Warm：存储形式的键无法按原始键前缀筛选，先读取全部存活键值
+func (db *Database) warmHashed(ctx context.Context, prefix []byte) error {
+	gen := db.values.generation()
+	state, err := db.stateAt(0)
+	if err != nil {
+		return err
+	}
+	for _, item := range state {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if db.values.full() {
+			return nil
+		}
+		if isDeleted(item.value) {
+			continue
+		}
+		entry, err := db.userEntry(item)
+		if err != nil {
+			return err
+		}
+		if bytes.HasPrefix(entry.key, prefix) {
+			db.values.put(item.key, entry.value, gen)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package amdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// warmDB 写入a1、a2、b1、b2、b3并返回启用了值缓存的数据库
+func warmDB(t *testing.T, opts *Options) *Database {
+	t.Helper()
+	db := openTestDB(t, opts)
+	for _, k := range []string{"a1", "a2", "b1", "b2", "b3"} {
+		mustPut(t, db, k, "v"+k)
+	}
+	return db
+}
+
+func TestWarm(t *testing.T) {
+	for _, hashKeys := range []bool{false, true} {
+		db := warmDB(t, &Options{ValueCacheEntries: 10, HashKeys: hashKeys})
+		if n := db.Stats().ValueCacheEntries; n != 0 {
+			t.Fatalf("hashKeys=%v: %d entries before Warm", hashKeys, n)
+		}
+		if err := db.Warm(context.Background(), []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		if n := db.Stats().ValueCacheEntries; n != 2 {
+			t.Fatalf("hashKeys=%v: %d entries after Warm(a), want 2", hashKeys, n)
+		}
+
+		hits := db.Stats().ValueCacheHits
+		if got := mustGet(t, db, "a2", 0); got != "va2" {
+			t.Fatalf("a2 = %q", got)
+		}
+		if db.Stats().ValueCacheHits != hits+1 {
+			t.Fatalf("hashKeys=%v: read of warmed key missed the cache", hashKeys)
+		}
+	}
+}
+
+func TestWarmRespectsCapacityAndContext(t *testing.T) {
+	db := warmDB(t, &Options{ValueCacheEntries: 3})
+	if err := db.Warm(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats(); s.ValueCacheEntries != 3 {
+		t.Fatalf("%d entries, capacity 3", s.ValueCacheEntries)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cold := warmDB(t, &Options{ValueCacheEntries: 10})
+	if err := cold.Warm(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled Warm: %v", err)
+	}
+	if n := cold.Stats().ValueCacheEntries; n != 0 {
+		t.Fatalf("canceled Warm cached %d entries", n)
+	}
+
+	if err := warmDB(t, nil).Warm(context.Background(), nil); err != nil {
+		t.Fatalf("Warm without a cache: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package amdb
+
+import (
+	"bytes"
+	"sync"
+)
+
+// watchBuffer 每个订阅通道的缓冲事件数
+const watchBuffer = 16
+
+// ChangeEvent 键的一次变更
+type ChangeEvent struct {
+	Key     []byte // 原始形式的键
+	Value   []byte // 写入后的值，删除时为nil
+	Deleted bool
+}
+
+// watchHub 按键（存储形式）登记的订阅者
+type watchHub struct {
+	mu       sync.Mutex
+	watchers map[string][]*keyWatcher
+}
+
+// keyWatcher 单个键的订阅者
+type keyWatcher struct {
+	key  []byte
+	ch   chan ChangeEvent
+	once sync.Once
+}
+
+// WatchKey 订阅单个键的变更，每次经由本句柄的Put、Delete或批量写入修改该键时发出一个事件，
+// 其他键的变更不会发出事件；同一个键可以有多个订阅者，各自收到全部事件
+// 返回的函数取消订阅并关闭通道，可重复调用；数据库关闭时所有订阅通道同样被关闭。
+// 通道带有缓冲，消费过慢时缓冲区满后的事件被丢弃而不会阻塞写入，
+// 需要完整变更记录的调用方应配合Diff按版本补齐
+func (db *Database) WatchKey(key []byte) (<-chan ChangeEvent, func()) {
+	w := &keyWatcher{key: bytes.Clone(key), ch: make(chan ChangeEvent, watchBuffer)}
+	stored := string(db.storedKey(key))
+
+	h := &db.watches
+	h.mu.Lock()
+	if h.watchers == nil {
+		h.watchers = make(map[string][]*keyWatcher)
+	}
+	h.watchers[stored] = append(h.watchers[stored], w)
+	h.mu.Unlock()
+
+	return w.ch, func() { h.remove(stored, w) }
+}
+
+// remove 注销订阅者并关闭其通道
+func (h *watchHub) remove(stored string, w *keyWatcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	list := h.watchers[stored]
+	for i, other := range list {
+		if other == w {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(h.watchers, stored)
+	} else {
+		h.watchers[stored] = list
+	}
+	w.once.Do(func() { close(w.ch) })
+}
+
+// closeAll 关闭全部订阅通道
+func (h *watchHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, list := range h.watchers {
+		for _, w := range list {
+			w.once.Do(func() { close(w.ch) })
+		}
+	}
+	h.watchers = nil
+}
+
+// notifyChange 向键的订阅者发出变更事件，key和value为成功写入的存储形式
+func (db *Database) notifyChange(key, value []byte) {
+	h := &db.watches
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	list := h.watchers[string(key)]
+	if len(list) == 0 {
+		return
+	}
+
+	var event ChangeEvent
+	if isDeleted(value) {
+		event.Deleted = true
+	} else {
+		entry, err := db.userEntry(kv{key: key, value: value})
+		if err != nil {
+			return
+		}
+		event.Value = bytes.Clone(entry.value)
+	}
+	for _, w := range list {
+		event.Key = w.key
+		select {
+		case w.ch <- event:
+		default:
+		}
+	}
+}
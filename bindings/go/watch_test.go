@@ -0,0 +1,87 @@
+package amdb
+
+import (
+	"testing"
+	"time"
+)
+
+// nextEvent 在超时前从ch读取一个事件
+func nextEvent(t *testing.T, ch <-chan ChangeEvent) ChangeEvent {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			t.Fatal("watch channel closed")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	return ChangeEvent{}
+}
+
+// noEvent 确认ch中没有待读取的事件
+func noEvent(t *testing.T, ch <-chan ChangeEvent) {
+	t.Helper()
+	select {
+	case e := <-ch:
+		t.Fatalf("unexpected event %+v", e)
+	default:
+	}
+}
+
+func TestWatchKey(t *testing.T) {
+	db := openTestDB(t, nil)
+	first, cancelFirst := db.WatchKey([]byte("config"))
+	second, cancelSecond := db.WatchKey([]byte("config"))
+	defer cancelSecond()
+
+	mustPut(t, db, "other", "x")
+	noEvent(t, first)
+	noEvent(t, second)
+
+	mustPut(t, db, "config", "v1")
+	for _, ch := range []<-chan ChangeEvent{first, second} {
+		if e := nextEvent(t, ch); string(e.Key) != "config" || string(e.Value) != "v1" || e.Deleted {
+			t.Fatalf("put event %+v", e)
+		}
+	}
+	if _, err := db.BatchPut(map[string][]byte{"config": []byte("v2"), "other": []byte("y")}); err != nil {
+		t.Fatal(err)
+	}
+	if e := nextEvent(t, first); string(e.Value) != "v2" {
+		t.Fatalf("batch event %+v", e)
+	}
+	noEvent(t, first)
+	if err := db.Delete([]byte("config")); err != nil {
+		t.Fatal(err)
+	}
+	if e := nextEvent(t, first); !e.Deleted || e.Value != nil {
+		t.Fatalf("delete event %+v", e)
+	}
+
+	cancelFirst()
+	cancelFirst()
+	if _, ok := <-first; ok {
+		t.Fatal("cancelled channel not closed")
+	}
+	mustPut(t, db, "config", "v3")
+	nextEvent(t, second) // v2
+	nextEvent(t, second) // 删除
+	if e := nextEvent(t, second); string(e.Value) != "v3" {
+		t.Fatalf("remaining watcher event %+v", e)
+	}
+}
+
+func TestWatchKeyClosedOnClose(t *testing.T) {
+	db, err := NewDatabase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, cancel := db.WatchKey([]byte("k"))
+	db.Close()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel open after Close")
+	}
+	cancel()
+}
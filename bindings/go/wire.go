@@ -0,0 +1,19 @@
+package amdb
+
+import "encoding/binary"
+
+// wireOrder 所有二进制格式使用的字节序
+// 导入流、检查点、证明、子树、状态摘要等格式中的整数（包括数据库版本号）一律按大端编码，
+// 与主机字节序无关，在一种架构上生成的数据可以在任何其他架构上读取。
+// 新增的序列化格式必须通过wireOrder或appendVersion/decodeVersion编码整数，不得使用主机字节序
+var wireOrder = binary.BigEndian
+
+// appendVersion 追加4字节大端编码的数据库版本号
+func appendVersion(buf []byte, version uint32) []byte {
+	return wireOrder.AppendUint32(buf, version)
+}
+
+// decodeVersion 解码4字节大端编码的数据库版本号
+func decodeVersion(b []byte) uint32 {
+	return wireOrder.Uint32(b)
+}
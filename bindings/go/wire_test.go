@@ -0,0 +1,61 @@
+package amdb
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+func TestVersionWireEncoding(t *testing.T) {
+	const version = 0x01020304
+	if got := hex.EncodeToString(appendVersion(nil, version)); got != "01020304" {
+		t.Fatalf("appendVersion = %s", got)
+	}
+	// 小端主机按本机字节序解码会得到0x04030201，decodeVersion不依赖主机字节序
+	wire := []byte{0x01, 0x02, 0x03, 0x04}
+	if got := decodeVersion(wire); got != version {
+		t.Fatalf("decodeVersion = %#x", got)
+	}
+	if binary.LittleEndian.Uint32(wire) == version {
+		t.Fatal("test bytes do not distinguish byte orders")
+	}
+}
+
+// goldenProof 格式版本3的证明编码，版本号为0x01020304，格式变化时该测试应失败
+const goldenProof = "03" +
+	"00000001" + "6b" + // 键"k"
+	"00000001" + "76" + // 值"v"
+	"00000002" + "aabb" + // 根
+	"00000000" + // 叶子哈希（空）
+	"01020304" + "0000000000000000" + // 版本、生成时间（未知）
+	"00000002" + // 步数
+	"0006" + // 扩展节点，nibble 6
+	"010b" + "0002" + "01cc" // 分支节点，nibble 11，下标1的兄弟哈希
+
+func TestProofVersionGolden(t *testing.T) {
+	proof := &MerkleProof{
+		Key:     []byte("k"),
+		Value:   []byte("v"),
+		Root:    []byte{0xaa, 0xbb},
+		Version: 0x01020304,
+		Steps:   []ProofStep{{Nibble: 6}, {Branch: true, Nibble: 11}},
+	}
+	proof.Steps[1].Siblings[1] = []byte{0xcc}
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(data); got != goldenProof {
+		t.Fatalf("encoding\n%s\nwant\n%s", got, goldenProof)
+	}
+
+	golden, _ := hex.DecodeString(goldenProof)
+	var decoded MerkleProof
+	if err := decoded.UnmarshalBinary(golden); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Version != 0x01020304 || !decoded.GeneratedAt.IsZero() || len(decoded.Steps) != 2 ||
+		decoded.Steps[1].Siblings[1][0] != 0xcc || string(decoded.Value) != "v" {
+		t.Fatalf("decoded %+v", decoded)
+	}
+}
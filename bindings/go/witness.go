@@ -0,0 +1,346 @@
+package amdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// ErrWitnessIncomplete 读取或写入的键的路径经过见证中只给出哈希的子树，见证不足以计算结果
+var ErrWitnessIncomplete = errors.New("witness does not cover key")
+
+// witnessFormatV1 见证二进制编码格式版本
+const witnessFormatV1 = 1
+
+// hashNode 由见证重建的树中只给出哈希的子树，其hash为子树哈希；只出现在见证的树中，不经过computeHash
+const hashNode = branchNode + 1
+
+// Witness 一组键的见证：读取和写入这些键所需的最少树节点，供只持有见证而没有数据库的执行方
+// 计算读取结果和写入后的根哈希（无状态执行）。见证是一棵裁剪过的树，节点格式与RangeProof相同：
+// 从根到每个键的路径上的节点完整给出，路径之外的子树只给出哈希。
+// 见证同样能确定路径上不存在的键：此类键的读取返回ErrNotFound，写入时在相应位置插入叶子。
+// 键与值为存储形式；删除与数据库一致以删除标记写入，删除标记计入根哈希
+type Witness struct {
+	// Root 见证所针对的根哈希（空数据库为空）
+	Root []byte
+	// Version 见证所针对的数据库版本
+	Version uint32
+	// Nodes 裁剪后的树，按先序排列（空数据库为空）
+	Nodes []RangeProofNode
+}
+
+// ExtractWitness 返回版本version（0表示当前版本）中keys的见证，需要在内存中重建该版本的整棵树
+// 哈希键模式下执行方要由原始键计算存储形式的键，而这需要本库的KeySalt，返回ErrInvalidArg；
+// PlainMode下返回ErrNotAuthenticated，OpenRangeView限定范围之外的键返回ErrOutOfRange
+func (db *Database) ExtractWitness(keys [][]byte, version uint32) (*Witness, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	if db.hashKeys {
+		return nil, ErrInvalidArg
+	}
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, ErrInvalidArg
+		}
+		if !db.inKeyRange(key) {
+			return nil, ErrOutOfRange
+		}
+	}
+	if version == 0 {
+		var err error
+		if version, err = db.CurrentVersion(); err != nil {
+			return nil, err
+		}
+	}
+	w := &Witness{Root: []byte{}, Version: version}
+	if version == 0 {
+		return w, nil
+	}
+
+	state, err := db.stateAt(version)
+	if err != nil {
+		return nil, err
+	}
+	root, err := db.trieOf(state, version)
+	if err != nil {
+		return nil, err
+	}
+	if root != nil {
+		w.Root = root.hash
+		w.Nodes = appendWitnessNodes(nil, root, 0, keys)
+	}
+	return w, nil
+}
+
+// appendWitnessNodes 按先序追加位于第pos个nibble处的节点n，keys为路径经过n的键，没有这样的键时只追加哈希
+func appendWitnessNodes(nodes []RangeProofNode, n *trieNode, pos int, keys [][]byte) []RangeProofNode {
+	if len(keys) == 0 {
+		return append(nodes, RangeProofNode{Type: RangeNodeHash, Hash: n.hash})
+	}
+	switch n.kind {
+	case leafNode:
+		return append(nodes, RangeProofNode{Type: RangeNodeLeaf, Key: n.key, Value: n.value})
+	case extNode:
+		nodes = append(nodes, RangeProofNode{Type: RangeNodeExt, Nibble: n.nibble})
+		return appendWitnessNodes(nodes, n.child, pos+1, keysWithNibble(keys, pos, n.nibble))
+	}
+	node := RangeProofNode{Type: RangeNodeBranch}
+	for i, child := range n.children {
+		if child != nil {
+			node.Children |= 1 << i
+		}
+	}
+	nodes = append(nodes, node)
+	for i, child := range n.children {
+		if child != nil {
+			nodes = appendWitnessNodes(nodes, child, pos+1, keysWithNibble(keys, pos, byte(i)))
+		}
+	}
+	return nodes
+}
+
+// keysWithNibble 返回keys中第pos个nibble为nibble的键
+func keysWithNibble(keys [][]byte, pos int, nibble byte) [][]byte {
+	var matched [][]byte
+	for _, key := range keys {
+		if keyNibble(key, pos) == nibble {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
+
+// Get 由见证读取key的值，键不存在或已删除时返回ErrNotFound，见证不足以确定时返回ErrWitnessIncomplete
+// 见证与其Root不一致时返回ErrProofMismatch
+func (w *Witness) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, ErrInvalidArg
+	}
+	root, err := w.trie()
+	if err != nil {
+		return nil, err
+	}
+	n := root
+	for pos := 0; n != nil; pos++ {
+		switch n.kind {
+		case hashNode:
+			return nil, ErrWitnessIncomplete
+		case leafNode:
+			if !bytes.Equal(n.key, key) || isDeleted(n.value) {
+				return nil, ErrNotFound
+			}
+			return bytes.Clone(n.value), nil
+		case extNode:
+			if keyNibble(key, pos) != n.nibble {
+				return nil, ErrNotFound
+			}
+			n = n.child
+		case branchNode:
+			n = n.children[keyNibble(key, pos)]
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Apply 只凭见证计算以Database.Write提交batch之后的根哈希，结果与在完整数据库上提交的根哈希一致
+// 批次中的键都须被见证覆盖：路径经过只给出哈希的子树时返回*BatchError，其Err为ErrWitnessIncomplete，
+// Index为该操作在批次中的下标。见证与其Root不一致时返回ErrProofMismatch。Apply不修改见证
+func (w *Witness) Apply(batch *WriteBatch) (newRoot []byte, err error) {
+	root, err := w.trie()
+	if err != nil {
+		return nil, err
+	}
+	// 同一个键以最后一次操作为准，与storedOps一致
+	last := make(map[string]int, len(batch.ops))
+	for i, op := range batch.ops {
+		if len(op.key) == 0 {
+			return nil, &BatchError{Key: op.key, Index: i, Err: ErrInvalidArg}
+		}
+		last[string(op.key)] = i
+	}
+	indexes := make([]int, 0, len(last))
+	for _, i := range last {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		op := batch.ops[i]
+		value := op.value
+		if op.delete {
+			value = deletedValue
+		}
+		if root, err = witnessInsert(root, 0, kv{key: op.key, value: value}); err != nil {
+			return nil, &BatchError{Key: op.key, Index: i, Err: err}
+		}
+	}
+	if root == nil {
+		return []byte{}, nil
+	}
+	return bytes.Clone(root.hash), nil
+}
+
+// witnessInsert 在位于第pos个nibble处的子树n中写入item，返回新的子树；n及其下的节点不被修改
+// 新子树与由写入后的键值集合直接构建的子树相同：叶子的键不同时按buildNode的规则分裂，
+// 扩展节点的nibble不同时变为分支节点，两者的子树都在原位置保持不变
+func witnessInsert(n *trieNode, pos int, item kv) (*trieNode, error) {
+	if n == nil {
+		node := &trieNode{kind: leafNode, key: item.key, value: item.value}
+		return node, node.computeHash(HashSHA256)
+	}
+	var node *trieNode
+	switch n.kind {
+	case hashNode:
+		return nil, ErrWitnessIncomplete
+	case leafNode:
+		if !bytes.Equal(n.key, item.key) {
+			items := []kv{{key: n.key, value: n.value}, item}
+			maxNibbles := 2 * len(n.key)
+			if m := 2 * len(item.key); m > maxNibbles {
+				maxNibbles = m
+			}
+			return buildNode(items, pos, maxNibbles, HashSHA256, nil)
+		}
+		node = &trieNode{kind: leafNode, key: item.key, value: item.value}
+	case extNode:
+		nibble := keyNibble(item.key, pos)
+		if nibble != n.nibble {
+			node = &trieNode{kind: branchNode}
+			node.children[n.nibble] = n.child
+			leaf, err := witnessInsert(nil, pos+1, item)
+			if err != nil {
+				return nil, err
+			}
+			node.children[nibble] = leaf
+			break
+		}
+		child, err := witnessInsert(n.child, pos+1, item)
+		if err != nil {
+			return nil, err
+		}
+		node = &trieNode{kind: extNode, nibble: n.nibble, child: child}
+	case branchNode:
+		nibble := keyNibble(item.key, pos)
+		child, err := witnessInsert(n.children[nibble], pos+1, item)
+		if err != nil {
+			return nil, err
+		}
+		node = &trieNode{kind: branchNode, children: n.children}
+		node.children[nibble] = child
+	}
+	return node, node.computeHash(HashSHA256)
+}
+
+// trie 由见证的节点重建裁剪过的树并核对其根哈希（空见证返回nil）
+func (w *Witness) trie() (*trieNode, error) {
+	if w == nil {
+		return nil, ErrInvalidArg
+	}
+	if len(w.Nodes) == 0 {
+		if len(w.Root) != 0 {
+			return nil, ErrProofMismatch
+		}
+		return nil, nil
+	}
+	b := witnessBuilder{nodes: w.Nodes}
+	root, err := b.node(nil)
+	if err != nil {
+		return nil, err
+	}
+	if b.pos != len(w.Nodes) || !bytes.Equal(root.hash, w.Root) {
+		return nil, ErrProofMismatch
+	}
+	return root, nil
+}
+
+// witnessBuilder 按先序消费见证节点并重建树
+type witnessBuilder struct {
+	nodes []RangeProofNode
+	pos   int // 下一个待消费的节点
+}
+
+// node 重建位于nibble路径path处的子树
+func (b *witnessBuilder) node(path []byte) (*trieNode, error) {
+	if b.pos >= len(b.nodes) || len(path) > 2*maxRangeKeyLen {
+		return nil, ErrBadProof
+	}
+	n := b.nodes[b.pos]
+	b.pos++
+	var node *trieNode
+	switch n.Type {
+	case RangeNodeHash:
+		if len(n.Hash) == 0 {
+			return nil, ErrBadProof
+		}
+		return &trieNode{kind: hashNode, hash: n.Hash}, nil
+	case RangeNodeLeaf:
+		if comparePath(path, n.Key) != 0 {
+			return nil, ErrBadProof
+		}
+		node = &trieNode{kind: leafNode, key: n.Key, value: n.Value}
+	case RangeNodeExt:
+		if n.Nibble > 0x0F {
+			return nil, ErrBadProof
+		}
+		child, err := b.node(append(path, n.Nibble))
+		if err != nil {
+			return nil, err
+		}
+		node = &trieNode{kind: extNode, nibble: n.Nibble, child: child}
+	case RangeNodeBranch:
+		node = &trieNode{kind: branchNode}
+		for i := range node.children {
+			if n.Children&(1<<i) == 0 {
+				continue
+			}
+			child, err := b.node(append(path[:len(path):len(path)], byte(i)))
+			if err != nil {
+				return nil, err
+			}
+			node.children[i] = child
+		}
+	default:
+		return nil, ErrBadProof
+	}
+	return node, node.computeHash(HashSHA256)
+}
+
+// 二进制编码格式（整数均为大端）：
+//
+//	[1字节格式版本][4字节版本][4字节长度][根]
+//	[4字节节点数] 每个节点的编码与RangeProof相同
+
+// MarshalBinary 实现encoding.BinaryMarshaler
+func (w *Witness) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+4+4+len(w.Root)+rangeNodesBinarySize(w.Nodes))
+	buf = append(buf, witnessFormatV1)
+	buf = wireOrder.AppendUint32(buf, w.Version)
+	buf = appendBytes32(buf, w.Root)
+	return appendRangeNodesBinary(buf, w.Nodes)
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler
+func (w *Witness) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	format, err := r.ReadByte()
+	if err != nil || format != witnessFormatV1 {
+		return ErrBadProof
+	}
+	var witness Witness
+	if binary.Read(r, wireOrder, &witness.Version) != nil {
+		return ErrBadProof
+	}
+	if witness.Root, err = readLengthPrefixed(r); err != nil {
+		return ErrBadProof
+	}
+	if witness.Nodes, err = readRangeNodesBinary(r); err != nil || r.Len() != 0 {
+		return ErrBadProof
+	}
+	if len(witness.Nodes) == 0 {
+		witness.Nodes = nil
+	}
+	*w = witness
+	return nil
+}
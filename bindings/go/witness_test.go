@@ -0,0 +1,96 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWitnessApplyMatchesWrite(t *testing.T) {
+	db, _ := proofDB(t, nil)
+	touched := [][]byte{[]byte("ab"), []byte("key-007"), []byte("zzzzzzzz"), []byte("abx"), []byte("new-key")}
+	w, err := db.ExtractWitness(touched, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w.Root, rootOf(t, db)) {
+		t.Fatal("witness root differs from the database root")
+	}
+	for _, key := range touched {
+		want, wantErr := db.Get(key, 0)
+		got, err := w.Get(key)
+		if !bytes.Equal(got, want) || err != wantErr {
+			t.Fatalf("witness read of %q: %q, %v; database %q, %v", key, got, err, want, wantErr)
+		}
+	}
+
+	data, err := w.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Witness
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := NewWriteBatch()
+	batch.Put([]byte("ab"), []byte("changed"))
+	batch.Delete([]byte("key-007"))
+	batch.Put([]byte("abx"), []byte("split"))
+	batch.Put([]byte("new-key"), []byte("inserted"))
+	batch.Put([]byte("zzzzzzzz"), []byte("first"))
+	batch.Put([]byte("zzzzzzzz"), []byte("last"))
+	applied, err := decoded.Apply(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := db.Write(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(applied, written) || !bytes.Equal(applied, rootOf(t, db)) {
+		t.Fatalf("witness root %x, database root %x", applied, written)
+	}
+	if !bytes.Equal(decoded.Root, w.Root) {
+		t.Fatal("Apply modified the witness")
+	}
+
+	uncovered := NewWriteBatch()
+	uncovered.Put([]byte("ab"), []byte("x"))
+	uncovered.Put([]byte("key-020"), []byte("x"))
+	var batchErr *BatchError
+	if _, err := w.Apply(uncovered); !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, ErrWitnessIncomplete) {
+		t.Fatalf("key outside the witness: %v", err)
+	}
+	if _, err := w.Get([]byte("key-020")); err != ErrWitnessIncomplete {
+		t.Fatalf("read outside the witness: %v", err)
+	}
+}
+
+func TestWitnessOfEmptyDatabase(t *testing.T) {
+	db := openTestDB(t, nil)
+	w, err := db.ExtractWitness([][]byte{[]byte("a")}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Get([]byte("a")); err != ErrNotFound {
+		t.Fatalf("read from an empty witness: %v", err)
+	}
+	batch := NewWriteBatch()
+	batch.Put([]byte("a"), []byte("1"))
+	batch.Put([]byte("b"), []byte("2"))
+	applied, err := w.Apply(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(applied, rootOf(t, db)) {
+		t.Fatal("witness root differs from the database root after Write")
+	}
+
+	if _, err := openTestDB(t, &Options{HashKeys: true}).ExtractWitness([][]byte{[]byte("a")}, 0); err != ErrInvalidArg {
+		t.Fatalf("hashed keys: %v", err)
+	}
+}
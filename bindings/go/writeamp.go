@@ -0,0 +1,38 @@
+package amdb
+
+import "sync/atomic"
+
+// writeAmpCounter 写入放大计数器，nil表示未启用统计
+type writeAmpCounter struct {
+	logical  atomic.Uint64
+	physical atomic.Uint64
+}
+
+// writeAmpStart 在写入类C调用前调用，返回进程累计写出的字节数（未启用统计或平台不支持时为0）
+func (db *Database) writeAmpStart() uint64 {
+	if db.writeAmp == nil {
+		return 0
+	}
+	n, _ := processWrittenBytes()
+	return n
+}
+
+// writeAmpEnd 在写入类C调用成功后调用，记录logical字节的逻辑写入及调用期间进程写出的字节数
+func (db *Database) writeAmpEnd(start uint64, logical int) {
+	if db.writeAmp == nil {
+		return
+	}
+	db.writeAmp.logical.Add(uint64(logical))
+	if n, ok := processWrittenBytes(); ok && n > start {
+		db.writeAmp.physical.Add(n - start)
+	}
+}
+
+// entrySize 返回keys和values的总字节数
+func entrySize(keys, values [][]byte) int {
+	n := 0
+	for i := range keys {
+		n += len(keys[i]) + len(values[i])
+	}
+	return n
+}
@@ -0,0 +1,43 @@
+package amdb
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWriteAmplification(t *testing.T) {
+	if s := openTestDB(t, nil).Stats(); s.LogicalBytesWritten != 0 || s.PhysicalBytesWritten != 0 {
+		t.Fatalf("untracked database reports %+v", s)
+	}
+
+	db := openTestDB(t, &Options{TrackWriteAmplification: true})
+	// 共享长前缀的键使新键位于树的深处，写入要重写其全部祖先节点
+	prefix := strings.Repeat("deep/", 20)
+	for _, suffix := range []string{"a", "b", "c", "d"} {
+		mustPut(t, db, prefix+suffix, "v")
+	}
+	if err := db.Delete([]byte(prefix + "d")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := db.Stats()
+	if want := uint64(4*(len(prefix)+2) + len(prefix) + 1); s.LogicalBytesWritten != want {
+		t.Fatalf("logical bytes %d, want %d", s.LogicalBytesWritten, want)
+	}
+	if runtime.GOOS != "linux" {
+		if s.PhysicalBytesWritten != 0 {
+			t.Fatalf("physical bytes %d on %s", s.PhysicalBytesWritten, runtime.GOOS)
+		}
+		return
+	}
+	if s.PhysicalBytesWritten <= s.LogicalBytesWritten || s.WriteAmplification <= 1 {
+		t.Fatalf("physical %d, logical %d, ratio %.2f", s.PhysicalBytesWritten, s.LogicalBytesWritten, s.WriteAmplification)
+	}
+	if got := float64(s.PhysicalBytesWritten) / float64(s.LogicalBytesWritten); got != s.WriteAmplification {
+		t.Fatalf("ratio %v, want %v", s.WriteAmplification, got)
+	}
+}
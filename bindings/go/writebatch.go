@@ -0,0 +1,165 @@
+package amdb
+
+import (
+	"context"
+	"time"
+)
+
+// WriteBatch 一组待原子提交的写入和删除
+// 同一个键在批次中出现多次时，以最后一次操作为准
+type WriteBatch struct {
+	ops []batchOp
+}
+
+// batchOp 批次中的一次操作
+type batchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// NewWriteBatch 创建空的写入批次
+func NewWriteBatch() *WriteBatch {
+	return &WriteBatch{}
+}
+
+// Put 向批次中加入一次写入
+func (b *WriteBatch) Put(key, value []byte) {
+	b.ops = append(b.ops, batchOp{key: key, value: value})
+}
+
+// Delete 向批次中加入一次删除
+func (b *WriteBatch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{key: key, delete: true})
+}
+
+// Len 返回批次中的操作数
+func (b *WriteBatch) Len() int {
+	return len(b.ops)
+}
+
+// Reset 清空批次以便复用
+func (b *WriteBatch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// Write 原子提交批次中的全部操作，返回提交后的根哈希
+// 根哈希由提交后的状态计算，与RootHashAtVersion(0)及以CommitWithProofs提交同一批次得到的根一致
+// （PlainMode和LazyRoot下为nil）。批次作为一次引擎批量写入提交，删除以删除标记写入。某个操作无效时返回*BatchError，
+// 其Index为该操作在批次中的下标，且整批都不写入
+func (db *Database) Write(b *WriteBatch) (root []byte, err error) {
+	if _, span := db.startSpan(context.Background(), "amdb.Write"); span != nil {
+		span.SetAttribute(attrBatchSize, b.Len())
+		defer func() { endSpan(span, root, err) }()
+	}
+
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	return db.write(b)
+}
+
+// write 提交批次（调用方需持有wmu）
+func (db *Database) write(b *WriteBatch) ([]byte, error) {
+	stored, err := db.storedOps(b)
+	if err != nil {
+		return nil, err
+	}
+	return db.batchPut(stored)
+}
+
+// storedOps 校验批次并转换为存储形式的批量写入
+func (db *Database) storedOps(b *WriteBatch) (map[string][]byte, error) {
+	stored := make(map[string][]byte, len(b.ops))
+	for i, op := range b.ops {
+		err := ErrInvalidArg
+		if len(op.key) > 0 {
+			err = db.checkWriteKey(db.storedKey(op.key))
+		}
+		if err != nil {
+			return nil, &BatchError{Key: op.key, Index: i, Err: err}
+		}
+		if op.delete {
+			stored[string(db.storedKey(op.key))] = deletedValue
+			continue
+		}
+		key, value := db.storedEntry(op.key, op.value)
+		stored[string(key)] = value
+	}
+	return stored, nil
+}
+
+// CommitWithProofs 原子提交批次，并返回提交后状态的根哈希及批次中每个写入键在该根下的包含证明
+// 证明按键（原始形式）索引；删除的键没有包含证明，不出现在结果中。
+// 根哈希由提交后的状态计算，所有证明均针对该根，开销与键数量成正比
+func (db *Database) CommitWithProofs(b *WriteBatch) (root []byte, proofs map[string]*MerkleProof, err error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, nil, err
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	if _, err := db.write(b); err != nil {
+		return nil, nil, err
+	}
+	version, err := db.CurrentVersion()
+	if err != nil {
+		return nil, nil, err
+	}
+	trie, err := db.trieAt(version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if trie == nil {
+		return []byte{}, map[string]*MerkleProof{}, nil
+	}
+
+	proofs = make(map[string]*MerkleProof, len(b.ops))
+	generated := time.Now()
+	for _, op := range b.ops {
+		leaf, steps := trie.path(db.storedKey(op.key))
+		if leaf == nil || isDeleted(leaf.value) {
+			delete(proofs, string(op.key))
+			continue
+		}
+		proofs[string(op.key)] = &MerkleProof{Key: leaf.key, Value: leaf.value, Steps: steps, Root: trie.hash, Version: version, GeneratedAt: generated}
+	}
+	return trie.hash, proofs, nil
+}
+
+// DryRunRoot 计算批次提交后将得到的根哈希，但不写入，数据库保持不变
+// 根哈希由当前状态叠加批次操作后计算，与立即以CommitWithProofs提交同一批次得到的根一致；
+// 批次无效时返回与Write相同的*BatchError
+func (db *Database) DryRunRoot(b *WriteBatch) ([]byte, error) {
+	if err := db.checkAuthenticated(); err != nil {
+		return nil, err
+	}
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+
+	stored, err := db.storedOps(b)
+	if err != nil {
+		return nil, err
+	}
+	state, err := db.stateAt(0)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]kv, 0, len(state)+len(stored))
+	for _, item := range state {
+		if _, ok := stored[string(item.key)]; !ok {
+			items = append(items, item)
+		}
+	}
+	for key, value := range stored {
+		items = append(items, kv{key: []byte(key), value: value})
+	}
+
+	trie, err := buildTrie(items, HashSHA256)
+	if err != nil {
+		return nil, err
+	}
+	if trie == nil {
+		return []byte{}, nil
+	}
+	return trie.hash, nil
+}
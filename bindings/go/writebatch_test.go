@@ -0,0 +1,107 @@
+package amdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriteReturnsStateRoot(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "1")
+
+	b := NewWriteBatch()
+	b.Put([]byte("c"), []byte("3"))
+	b.Delete([]byte("a"))
+	b.Put([]byte("b"), []byte("x"))
+	b.Put([]byte("b"), []byte("2")) // 以最后一次为准
+	root, err := db.Write(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, stateRoot(t, db)) || !bytes.Equal(rootOf(t, db), root) {
+		t.Fatalf("Write root %x, state %x", root, stateRoot(t, db))
+	}
+	if got := mustGet(t, db, "b", 0); got != "2" {
+		t.Fatalf("b: %q", got)
+	}
+	if _, err := db.Get([]byte("a"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted key: %v", err)
+	}
+
+	bad := NewWriteBatch()
+	bad.Put([]byte("d"), []byte("4"))
+	bad.Put(nil, []byte("5"))
+	var be *BatchError
+	if _, err := db.Write(bad); !errors.As(err, &be) || be.Index != 1 {
+		t.Fatalf("invalid batch: %v", err)
+	}
+	if _, err := db.Get([]byte("d"), 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("invalid batch was partly written: %v", err)
+	}
+}
+
+func TestCommitWithProofs(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "gone", "1")
+	b := NewWriteBatch()
+	for _, k := range []string{"a", "ab", "abc", "b"} {
+		b.Put([]byte(k), []byte("v"+k))
+	}
+	b.Delete([]byte("gone"))
+
+	root, proofs, err := db.CommitWithProofs(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, stateRoot(t, db)) {
+		t.Fatalf("root %x, state %x", root, stateRoot(t, db))
+	}
+	if len(proofs) != 4 || proofs["gone"] != nil {
+		t.Fatalf("%d proofs", len(proofs))
+	}
+	for k, proof := range proofs {
+		if !VerifyProof(root, []byte(k), []byte("v"+k), proof) {
+			t.Fatalf("%s: proof does not verify against the returned root", k)
+		}
+	}
+}
+
+func TestDryRunRoot(t *testing.T) {
+	db := openTestDB(t, nil)
+	mustPut(t, db, "a", "1")
+	mustPut(t, db, "b", "1")
+	before := rootOf(t, db)
+	version, err := db.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewWriteBatch()
+	b.Put([]byte("c"), []byte("3"))
+	b.Put([]byte("a"), []byte("2"))
+	b.Delete([]byte("b"))
+	dry, err := db.DryRunRoot(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if now, _ := db.CurrentVersion(); now != version || !bytes.Equal(rootOf(t, db), before) || mustGet(t, db, "a", 0) != "1" {
+		t.Fatal("DryRunRoot modified the database")
+	}
+
+	root, err := db.Write(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dry, root) {
+		t.Fatalf("dry run %x, committed %x", dry, root)
+	}
+
+	bad := NewWriteBatch()
+	bad.Put(nil, []byte("x"))
+	var be *BatchError
+	if _, err := db.DryRunRoot(bad); !errors.As(err, &be) || be.Index != 0 {
+		t.Fatalf("invalid batch: %v", err)
+	}
+}
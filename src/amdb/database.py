@@ -447,6 +447,9 @@ class Database:
         if not items:
             return (True, self.get_root_hash())
         
+        # 整个批量写入共用一个时间戳：分批和并行处理时各批的版本仍属于同一个时间点
+        timestamp = time.time()
+        
         # 优化：减少锁持有时间，先准备数据，再快速写入
         # 优化：添加异常处理和资源清理，避免崩溃
         try:
@@ -475,7 +478,7 @@ class Database:
                     futures = []
                     for i in range(0, len(items), MAX_BATCH_SIZE):
                         batch = items[i:i+MAX_BATCH_SIZE]
-                        future = executor.submit(self._batch_put_internal, batch)
+                        future = executor.submit(self._batch_put_internal, batch, timestamp)
                         futures.append(future)
                     
                     # 等待所有批次完成
@@ -504,7 +507,7 @@ class Database:
                 results = []
                 for i in range(0, len(items), MAX_BATCH_SIZE):
                     batch = items[i:i+MAX_BATCH_SIZE]
-                    result = self._batch_put_internal(batch)
+                    result = self._batch_put_internal(batch, timestamp)
                     results.append(result)
                     if not result[0]:
                         break  # 如果失败，立即返回
@@ -512,13 +515,14 @@ class Database:
                 # 返回最后一个结果
                 return results[-1] if results else (True, b'')
             else:
-                return self._batch_put_internal(items)
+                return self._batch_put_internal(items, timestamp)
         except Exception as e:
             import traceback
             traceback.print_exc()
             return (False, b'')
     
-    def _batch_put_internal(self, items: List[Tuple[bytes, bytes]]) -> Tuple[bool, bytes]:
+    def _batch_put_internal(self, items: List[Tuple[bytes, bytes]],
+                            timestamp: Optional[float] = None) -> Tuple[bool, bytes]:
         """内部批量写入方法（优化版本，稳定性优先）"""
         try:
             # 高性能批量写入优化（对标LevelDB性能，达到并超越）：
@@ -534,7 +538,7 @@ class Database:
                 # 优化阈值：500以上使用快速路径，平衡性能和稳定性
                 if items_len > 500:
                     # 使用快速路径：创建Version对象但不计算prev_hash
-                    version_objs = self.version_manager.create_versions_batch(items, return_versions_only=True)
+                    version_objs = self.version_manager.create_versions_batch(items, timestamp)
                     # 验证返回的是Version对象列表
                     if len(version_objs) != items_len:
                         print(f"版本对象数量不匹配: {len(version_objs)} != {items_len}")
@@ -550,7 +554,7 @@ class Database:
                         batch_items[i] = (key, value, version)
                 else:
                     # 小批量：创建Version对象（保持兼容性）
-                    version_objs = self.version_manager.create_versions_batch(items)
+                    version_objs = self.version_manager.create_versions_batch(items, timestamp)
                     if len(version_objs) != items_len:
                         print(f"版本对象数量不匹配: {len(version_objs)} != {items_len}")
                         return (False, b'')
@@ -739,10 +743,12 @@ class Database:
             max_wait = 30  # 最多等待30秒
             wait_time = 0
             while wait_time < max_wait:
-                # 检查是否还有未刷新的MemTable
-                if hasattr(self.storage.lsm_tree, 'immutable_memtables'):
-                    if len(self.storage.lsm_tree.immutable_memtables) == 0:
-                        break
+                # 检查是否还有未刷新的MemTable（分片LSM树按分片保存列表，分片键在列表清空后仍保留）
+                pending = getattr(self.storage.lsm_tree, 'immutable_memtables', None)
+                if isinstance(pending, dict):
+                    pending = [m for shard in pending.values() for m in shard]
+                if not pending:
+                    break
                 time.sleep(0.1)
                 wait_time += 0.1
         
@@ -78,34 +78,39 @@ class VersionManager:
             
             return version
     
-    def create_versions_batch(self, items: List[Tuple[bytes, bytes]]) -> List[Version]:
+    def create_versions_batch(self, items: List[Tuple[bytes, bytes]],
+                              timestamp: Optional[float] = None) -> List[Version]:
         """
         批量创建版本（简化稳定版本）
         优化：批量字典操作，减少单次查找和更新
         Args:
             items: [(key, value), ...]
+            timestamp: 版本时间戳（None表示当前时间），分批处理时各批共用，使整批属于同一个时间点
         Returns:
             List[Version]
         """
         # 优化：限制批量大小，避免内存问题和崩溃
         # 使用缓存的配置值（避免重复访问配置对象）
         MAX_BATCH_SIZE = self._batch_max_size
+        if timestamp is None:
+            timestamp = time.time()
         if len(items) > MAX_BATCH_SIZE:
             # 分批处理
             all_versions = []
             for i in range(0, len(items), MAX_BATCH_SIZE):
                 batch = items[i:i+MAX_BATCH_SIZE]
-                versions = self._create_versions_batch_internal(batch)
+                versions = self._create_versions_batch_internal(batch, timestamp)
                 all_versions.extend(versions)
             return all_versions
         else:
-            return self._create_versions_batch_internal(items)
+            return self._create_versions_batch_internal(items, timestamp)
     
-    def _create_versions_batch_internal(self, items: List[Tuple[bytes, bytes]]) -> List[Version]:
+    def _create_versions_batch_internal(self, items: List[Tuple[bytes, bytes]],
+                                        timestamp: float) -> List[Version]:
         """内部批量创建版本方法（简化稳定版本）"""
         try:
             with self.lock:
-                current_time = time.time()
+                current_time = timestamp
                 versions = []
                 updates_dict = {}
                 